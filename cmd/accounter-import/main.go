@@ -0,0 +1,202 @@
+// Command accounter-import imports transactions from a CSV file, an Alipay or WeChat Pay bill statement,
+// an OFX or QIF bank statement or a beancount or ledger journal through the API of a running accounter server, so the server stays the only writer of its storage.
+//
+//	accounter-import -token $TOKEN -mapping mapping.json -dry-run history.csv
+//	accounter-import -token $TOKEN -format alipay -account 2 alipay_record.csv
+//	accounter-import -token $TOKEN -format ofx -account 3 statement.ofx
+//	accounter-import -token $TOKEN -format journal main.beancount
+//
+// The mapping file holds the mapping of POST /api/import/csv, e.g.
+//
+//	{"date_column": "日期", "amount_column": "金额", "desc_columns": ["备注"], "category_column": "分类"}
+//
+// or, optionally, the mapping of POST /api/import/journal, e.g.
+//
+//	{"categories": {"餐饮": "Expenses:Dining"}, "accounts": {"招商银行": "Assets:Bank:CMB"}}
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+var (
+	flagServer      string
+	flagToken       string
+	flagFormat      string
+	flagMapping     string
+	flagAccount     int64
+	flagDryRun      bool
+	flagSkipInvalid bool
+)
+
+func init() {
+	flag.StringVar(&flagServer, "server", "http://localhost:8000", "address of the accounter HTTP server")
+	flag.StringVar(&flagToken, "token", os.Getenv("ACCOUNTER_TOKEN"), "access token or API token with the write scope, defaults to $ACCOUNTER_TOKEN")
+	flag.StringVar(&flagFormat, "format", "csv", "format of the file: csv, alipay, wechat, ofx, qif or journal")
+	flag.StringVar(&flagMapping, "mapping", "", "JSON file with the column mapping, required for csv, or the account mapping of a journal")
+	flag.Int64Var(&flagAccount, "account", 0, "account of the alipay, wechat, ofx and qif transactions, 0 for none")
+	flag.BoolVar(&flagDryRun, "dry-run", false, "only preview the rows, nothing is imported")
+	flag.BoolVar(&flagSkipInvalid, "skip-invalid", false, "import the valid rows of a file with invalid rows")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] file.csv\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+}
+
+// importRequest is the body of POST /api/import/csv and /api/import/journal
+type importRequest struct {
+	Content     string          `json:"content"`
+	Mapping     json.RawMessage `json:"mapping,omitempty"`
+	DryRun      bool            `json:"dry_run"`
+	SkipInvalid bool            `json:"skip_invalid"`
+}
+
+// billRequest is the body of POST /api/import/alipay, /api/import/wechat, /api/import/ofx
+// and /api/import/qif, the content is sent as is since Alipay exports GBK
+type billRequest struct {
+	Content     []byte `json:"content"`
+	AccountID   int64  `json:"account_id"`
+	DryRun      bool   `json:"dry_run"`
+	SkipInvalid bool   `json:"skip_invalid"`
+}
+
+// importReply is the part of the reply that is printed
+type importReply struct {
+	Rows []struct {
+		Line        int    `json:"line"`
+		Category    string `json:"category"`
+		Duplicate   bool   `json:"duplicate"`
+		Skip        string `json:"skip"`
+		Error       string `json:"error"`
+		Transaction *struct {
+			Type          interface{} `json:"type"`
+			Desc          string      `json:"desc"`
+			AmountDecimal string      `json:"amountDecimal"`
+			Currency      string      `json:"currency"`
+			CategoryID    json.Number `json:"categoryId"`
+			Date          string      `json:"date"`
+		} `json:"transaction"`
+	} `json:"rows"`
+	Invalid int    `json:"invalid"`
+	Message string `json:"message"`
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() != 1 || flagFormat == "csv" && flagMapping == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	invalid, err := run(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "accounter-import:", err)
+		os.Exit(1)
+	}
+	if invalid > 0 && !flagSkipInvalid {
+		os.Exit(1)
+	}
+}
+
+// run imports a file and prints the reply, it returns the number of invalid rows
+func run(path string) (int, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	var request interface{}
+	switch flagFormat {
+	case "csv", "journal":
+		var mapping []byte
+		if flagMapping != "" {
+			if mapping, err = os.ReadFile(flagMapping); err != nil {
+				return 0, err
+			}
+			if !json.Valid(mapping) {
+				return 0, fmt.Errorf("%s is not valid JSON", flagMapping)
+			}
+		}
+		request = importRequest{Content: string(content), Mapping: mapping, DryRun: flagDryRun, SkipInvalid: flagSkipInvalid}
+	case "alipay", "wechat", "ofx", "qif":
+		request = billRequest{Content: content, AccountID: flagAccount, DryRun: flagDryRun, SkipInvalid: flagSkipInvalid}
+	default:
+		return 0, fmt.Errorf("unknown format %s, want csv, alipay, wechat, ofx, qif or journal", flagFormat)
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(flagServer, "/")+"/api/import/"+flagFormat, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if flagToken != "" {
+		req.Header.Set("Authorization", "Bearer "+flagToken)
+	}
+	resp, err := (&http.Client{Timeout: 5 * time.Minute}).Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Reason  string `json:"reason"`
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(data, &e) == nil && e.Message != "" {
+			return 0, fmt.Errorf("%s: %s", e.Reason, e.Message)
+		}
+		return 0, fmt.Errorf("server returned %s", resp.Status)
+	}
+
+	var reply importReply
+	if err := json.Unmarshal(data, &reply); err != nil {
+		return 0, fmt.Errorf("reading the reply: %v", err)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "LINE\tDATE\tTYPE\tAMOUNT\tCATEGORY\tDESCRIPTION\tSTATUS")
+	for _, row := range reply.Rows {
+		if row.Transaction == nil {
+			status := "error: " + row.Error
+			if row.Error == "" {
+				status = "skipped: " + row.Skip
+			}
+			fmt.Fprintf(w, "%d\t\t\t\t\t\t%s\n", row.Line, status)
+			continue
+		}
+		t := row.Transaction
+		status := "ok"
+		switch {
+		case row.Error != "":
+			status = "error: " + row.Error
+		case row.Skip != "":
+			status = "skipped: " + row.Skip
+		case row.Duplicate:
+			status = "duplicate"
+		}
+		category := string(t.CategoryID)
+		if row.Category != "" {
+			category = row.Category + "?"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%v\t%s %s\t%s\t%s\t%s\n", row.Line, t.Date, t.Type, t.AmountDecimal, t.Currency, category, t.Desc, status)
+	}
+	if err := w.Flush(); err != nil {
+		return 0, err
+	}
+	fmt.Println(reply.Message)
+	return reply.Invalid, nil
+}
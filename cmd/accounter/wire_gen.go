@@ -23,7 +23,7 @@ import (
 // Injectors from wire.go:
 
 // wireApp init kratos application.
-func wireApp(confServer *conf.Server, confData *conf.Data, logger log.Logger) (*kratos.App, func(), error) {
+func wireApp(confServer *conf.Server, confData *conf.Data, auth *conf.Auth, logger log.Logger) (*kratos.App, func(), error) {
 	dataData, cleanup, err := data.NewData(confData, logger)
 	if err != nil {
 		return nil, nil, err
@@ -31,13 +31,76 @@ func wireApp(confServer *conf.Server, confData *conf.Data, logger log.Logger) (*
 	greeterRepo := data.NewGreeterRepo(dataData, logger)
 	greeterUseCase := biz.NewGreeterUseCase(greeterRepo, logger)
 	greeterService := service.NewGreeterService(greeterUseCase)
-	accounterRepo := data.NewAccounterFileRepo(confData, logger)
-	accounterUseCase := biz.NewAccounterUsecase(accounterRepo, logger)
+	accounterRepo, cleanup2, err := data.NewAccounterRepo(confData, dataData, logger)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	categoryRepo, err := data.NewCategoryRepo(confData, dataData, logger)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	exchangeRateRepo, err := data.NewExchangeRateRepo(confData, dataData, logger)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	accountRepo, err := data.NewAccountRepo(confData, dataData, logger)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	accounterUseCase := biz.NewAccounterUsecase(accounterRepo, categoryRepo, exchangeRateRepo, accountRepo, logger)
 	accounterService := service.NewAccounterService(accounterUseCase)
-	grpcServer := server.NewGRPCServer(confServer, greeterService, accounterService, logger)
-	httpServer := server.NewHTTPServer(confServer, greeterService, accounterService, logger)
-	app := newApp(logger, grpcServer, httpServer)
+	userRepo, err := data.NewUserRepo(confData, dataData, logger)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	apiTokenRepo, err := data.NewAPITokenRepo(confData, dataData, logger)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	authUseCase := biz.NewAuthUseCase(userRepo, apiTokenRepo, auth, logger)
+	authService := service.NewAuthService(authUseCase)
+	categoryUseCase := biz.NewCategoryUseCase(categoryRepo, accounterRepo, logger)
+	categoryService := service.NewCategoryService(categoryUseCase)
+	exchangeRateUseCase := biz.NewExchangeRateUseCase(exchangeRateRepo, logger)
+	exchangeRateService := service.NewExchangeRateService(exchangeRateUseCase)
+	accountUseCase := biz.NewAccountUseCase(accountRepo, accounterRepo, logger)
+	accountService := service.NewAccountService(accountUseCase)
+	budgetRepo, err := data.NewBudgetRepo(confData, dataData, logger)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	budgetUseCase := biz.NewBudgetUseCase(budgetRepo, categoryRepo, accounterUseCase, logger)
+	budgetService := service.NewBudgetService(budgetUseCase)
+	recurringRepo, err := data.NewRecurringRepo(confData, dataData, logger)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	recurringUseCase := biz.NewRecurringUseCase(recurringRepo, accounterUseCase, logger)
+	recurringService := service.NewRecurringService(recurringUseCase)
+	importService := service.NewImportService(accounterUseCase)
+	tagService := service.NewTagService(accounterUseCase)
+	grpcServer := server.NewGRPCServer(confServer, greeterService, accounterService, authService, categoryService, exchangeRateService, accountService, budgetService, recurringService, importService, tagService, authUseCase, logger)
+	exportService := service.NewExportService(accounterUseCase)
+	httpServer := server.NewHTTPServer(confServer, greeterService, accounterService, authService, categoryService, exchangeRateService, accountService, budgetService, recurringService, importService, exportService, tagService, authUseCase, logger)
+	scheduler := server.NewScheduler(confServer, recurringUseCase, logger)
+	app := newApp(logger, grpcServer, httpServer, scheduler)
 	return app, func() {
+		cleanup2()
 		cleanup()
 	}, nil
 }
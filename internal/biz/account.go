@@ -0,0 +1,205 @@
+package biz
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	v1 "accounter_go/api/accounter/v1"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+var (
+	// ErrAccountNotFound is account not found, also returned for accounts of other users.
+	ErrAccountNotFound = errors.NotFound("ACCOUNT_NOT_FOUND", "account not found")
+	// ErrAccountInUse is deleting an account that still has transactions.
+	ErrAccountInUse = errors.Conflict("ACCOUNT_IN_USE", "account still has transactions")
+	// ErrAccountCurrencyMismatch is a transaction in another currency than its account.
+	ErrAccountCurrencyMismatch = errors.BadRequest("ACCOUNT_CURRENCY_MISMATCH", "transaction currency must match the currency of its account")
+)
+
+// Account is where the money of a transaction comes from or goes to,
+// such as a bank card, Alipay, WeChat Pay or cash.
+type Account struct {
+	ID             int64
+	UserID         int64
+	Name           string
+	Type           v1.AccountType
+	Currency       string // every transaction of the account is in this currency
+	OpeningBalance Money
+}
+
+// AccountRepo is an Account repo.
+// FindByID returns ErrAccountNotFound for unknown accounts.
+type AccountRepo interface {
+	Save(context.Context, *Account) (*Account, error)
+	Update(context.Context, *Account) (*Account, error)
+	FindByID(context.Context, int64) (*Account, error)
+	ListByUserID(context.Context, int64) ([]*Account, error)
+	Delete(context.Context, int64) error
+}
+
+// AccountPatch holds the fields of a partial account update, nil fields are left unchanged.
+// The currency of an account can't be changed.
+type AccountPatch struct {
+	Name           *string
+	Type           *v1.AccountType
+	OpeningBalance *Money
+}
+
+// AccountStat represents the income, expense and transfers of an account, in the currency of the account.
+// TransferOut includes the fees of the transfers.
+type AccountStat struct {
+	AccountID   int64
+	Income      Money
+	Expense     Money
+	TransferIn  Money
+	TransferOut Money
+	Count       int32
+}
+
+// AccountBalance is the running balance of an account,
+// its opening balance plus its income and incoming transfers minus its expense and outgoing transfers
+type AccountBalance struct {
+	Account     *Account
+	Income      Money
+	Expense     Money
+	TransferIn  Money
+	TransferOut Money
+	Balance     Money
+	Count       int32
+}
+
+// AccountUseCase is an Account usecase.
+type AccountUseCase struct {
+	repo       AccountRepo
+	accounters AccounterRepo
+	Log        *log.Helper
+}
+
+// NewAccountUseCase new an Account usecase.
+func NewAccountUseCase(repo AccountRepo, accounters AccounterRepo, logger log.Logger) *AccountUseCase {
+	return &AccountUseCase{repo: repo, accounters: accounters, Log: log.NewHelper(logger)}
+}
+
+// ListAccounts lists the accounts of userID
+func (uc *AccountUseCase) ListAccounts(ctx context.Context, userID int64) ([]*Account, error) {
+	uc.Log.WithContext(ctx).Infof("ListAccounts")
+	return uc.repo.ListByUserID(ctx, userID)
+}
+
+// CreateAccount creates an account owned by userID
+func (uc *AccountUseCase) CreateAccount(ctx context.Context, userID int64, a *Account) (*Account, error) {
+	uc.Log.WithContext(ctx).Infof("CreateAccount: %s", a.Name)
+	a.UserID = userID
+	currency, err := NormalizeCurrency(a.Currency)
+	if err != nil {
+		return nil, err
+	}
+	a.Currency = currency
+	if err := validateAccount(a); err != nil {
+		return nil, err
+	}
+	return uc.repo.Save(ctx, a)
+}
+
+// UpdateAccount applies a partial update to an account owned by userID
+func (uc *AccountUseCase) UpdateAccount(ctx context.Context, userID, id int64, patch *AccountPatch) (*Account, error) {
+	uc.Log.WithContext(ctx).Infof("UpdateAccount: %d", id)
+	a, err := ownAccount(ctx, uc.repo, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if patch.Name != nil {
+		a.Name = *patch.Name
+	}
+	if patch.Type != nil {
+		a.Type = *patch.Type
+	}
+	if patch.OpeningBalance != nil {
+		a.OpeningBalance = *patch.OpeningBalance
+	}
+	if err := validateAccount(a); err != nil {
+		return nil, err
+	}
+	return uc.repo.Update(ctx, a)
+}
+
+// DeleteAccount deletes an account owned by userID that has no transactions
+func (uc *AccountUseCase) DeleteAccount(ctx context.Context, userID, id int64) error {
+	uc.Log.WithContext(ctx).Infof("DeleteAccount: %d", id)
+	if _, err := ownAccount(ctx, uc.repo, userID, id); err != nil {
+		return err
+	}
+
+	_, total, err := uc.accounters.ListWithFilters(ctx, &ListFilter{UserID: userID, AccountID: &id, Page: 1, PageSize: 1})
+	if err != nil {
+		return err
+	}
+	if total > 0 {
+		return ErrAccountInUse
+	}
+	return uc.repo.Delete(ctx, id)
+}
+
+// ListBalances returns the balance of every account of userID,
+// counting the transactions up to asOf when it is set
+func (uc *AccountUseCase) ListBalances(ctx context.Context, userID int64, asOf *time.Time) ([]*AccountBalance, error) {
+	uc.Log.WithContext(ctx).Infof("ListBalances")
+	accounts, err := uc.repo.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := uc.accounters.GetAccountStats(ctx, &StatsFilter{UserID: userID, EndDate: asOf})
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int64]*AccountStat, len(stats))
+	for _, stat := range stats {
+		byID[stat.AccountID] = stat
+	}
+
+	balances := make([]*AccountBalance, len(accounts))
+	for i, a := range accounts {
+		balance := &AccountBalance{Account: a, Balance: a.OpeningBalance}
+		if stat, ok := byID[a.ID]; ok {
+			balance.Income = stat.Income
+			balance.Expense = stat.Expense
+			balance.TransferIn = stat.TransferIn
+			balance.TransferOut = stat.TransferOut
+			balance.Count = stat.Count
+			balance.Balance += stat.Income - stat.Expense + stat.TransferIn - stat.TransferOut
+		}
+		balances[i] = balance
+	}
+	return balances, nil
+}
+
+// validateAccount checks the name and type of an account and rounds its opening balance
+func validateAccount(a *Account) error {
+	a.Name = strings.TrimSpace(a.Name)
+	if n := utf8.RuneCountInString(a.Name); n == 0 || n > 50 {
+		return errors.BadRequest("INVALID_ACCOUNT_NAME", "name must be 1 to 50 characters")
+	}
+	if _, ok := v1.AccountType_name[int32(a.Type)]; !ok || a.Type == v1.AccountType_ACCOUNT_TYPE_UNKNOWN {
+		return errors.BadRequest("INVALID_ACCOUNT_TYPE", "unknown account type")
+	}
+	a.OpeningBalance = a.OpeningBalance.Round(a.Currency)
+	return nil
+}
+
+// ownAccount loads an account of userID
+func ownAccount(ctx context.Context, repo AccountRepo, userID, id int64) (*Account, error) {
+	a, err := repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if a.UserID != userID {
+		return nil, ErrAccountNotFound
+	}
+	return a, nil
+}
@@ -0,0 +1,109 @@
+package biz_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	v1 "accounter_go/api/accounter/v1"
+	"accounter_go/internal/biz"
+	"accounter_go/internal/data"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+func TestAccountBalances(t *testing.T) {
+	logger := log.NewStdLogger(io.Discard)
+	accounters := data.NewAccounterMemoryRepo(logger)
+	accounts := data.NewAccountMemoryRepo(logger)
+	uc := biz.NewAccounterUsecase(accounters, data.NewCategoryMemoryRepo(logger), data.NewExchangeRateMemoryRepo(logger), accounts, logger)
+	accountUC := biz.NewAccountUseCase(accounts, accounters, logger)
+	ctx := context.Background()
+	day := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+
+	card, err := accountUC.CreateAccount(ctx, 1, &biz.Account{Name: " 招商银行 ", Type: v1.AccountType_DEBIT_CARD, OpeningBalance: money("1000.005")})
+	if err != nil {
+		t.Fatalf("CreateAccount: %v", err)
+	}
+	if card.Name != "招商银行" || card.Currency != "CNY" || card.OpeningBalance != money("1000.01") {
+		t.Errorf("CreateAccount = %+v", card)
+	}
+	wise, err := accountUC.CreateAccount(ctx, 1, &biz.Account{Name: "Wise", Type: v1.AccountType_OTHER, Currency: "usd"})
+	if err != nil {
+		t.Fatalf("CreateAccount: %v", err)
+	}
+	if _, err := accountUC.CreateAccount(ctx, 1, &biz.Account{Name: "钱包"}); err == nil {
+		t.Errorf("CreateAccount without a type should fail")
+	}
+
+	for _, a := range []*biz.Accounter{
+		{Type: v1.Type_Income, Amount: money("500"), AccountID: card.ID, Date: day("2025-06-01")},
+		{Type: v1.Type_Expense, Amount: money("120.5"), AccountID: card.ID, Date: day("2025-07-01")},
+		{Type: v1.Type_Expense, Amount: money("9.99"), AccountID: wise.ID, Date: day("2025-06-15")},
+		{Type: v1.Type_Expense, Amount: money("30"), Date: day("2025-06-15")},
+	} {
+		a.UserID = 1
+		if _, err := uc.CreateAccounter(ctx, a); err != nil {
+			t.Fatalf("CreateAccounter: %v", err)
+		}
+	}
+
+	// A transaction without a currency takes the one of its account
+	list, _, err := uc.ListAccounters(ctx, &biz.ListFilter{UserID: 1, AccountID: &wise.ID, Page: 1, PageSize: 10})
+	if err != nil || len(list) != 1 || list[0].Currency != "USD" {
+		t.Errorf("transactions of the USD account = %+v, %v", list, err)
+	}
+	if _, err := uc.CreateAccounter(ctx, &biz.Accounter{UserID: 1, Amount: money("1"), Currency: "EUR", AccountID: wise.ID, Date: day("2025-06-15")}); !errors.Is(err, biz.ErrAccountCurrencyMismatch) {
+		t.Errorf("CreateAccounter in another currency = %v, want ErrAccountCurrencyMismatch", err)
+	}
+	if _, err := uc.CreateAccounter(ctx, &biz.Accounter{UserID: 2, Amount: money("1"), AccountID: card.ID, Date: day("2025-06-15")}); !errors.Is(err, biz.ErrAccountNotFound) {
+		t.Errorf("CreateAccounter in another user's account = %v, want ErrAccountNotFound", err)
+	}
+
+	// Moving a transaction into an account adopts its currency, changing only the currency is checked
+	moved, err := uc.UpdateAccounter(ctx, 1, list[0].TransactionID, &biz.AccounterPatch{AccountID: &card.ID})
+	if err != nil || moved.Currency != "CNY" {
+		t.Errorf("UpdateAccounter to the CNY account = %+v, %v", moved, err)
+	}
+	usd := "USD"
+	if _, err := uc.UpdateAccounter(ctx, 1, moved.TransactionID, &biz.AccounterPatch{Currency: &usd}); !errors.Is(err, biz.ErrAccountCurrencyMismatch) {
+		t.Errorf("UpdateAccounter currency = %v, want ErrAccountCurrencyMismatch", err)
+	}
+	if _, err := uc.UpdateAccounter(ctx, 1, moved.TransactionID, &biz.AccounterPatch{AccountID: &wise.ID}); err != nil {
+		t.Errorf("UpdateAccounter back to the USD account: %v", err)
+	}
+
+	balances, err := accountUC.ListBalances(ctx, 1, nil)
+	if err != nil {
+		t.Fatalf("ListBalances: %v", err)
+	}
+	if len(balances) != 2 || balances[0].Balance != money("1379.51") || balances[0].Count != 2 ||
+		balances[1].Balance != money("-9.99") || balances[1].Account.Currency != "USD" {
+		t.Errorf("ListBalances = %+v %+v", balances[0], balances[1])
+	}
+	asOf := day("2025-06-30")
+	if balances, err = accountUC.ListBalances(ctx, 1, &asOf); err != nil || balances[0].Balance != money("1500.01") {
+		t.Errorf("ListBalances as of June = %+v, %v", balances[0], err)
+	}
+
+	stats, err := uc.GetStats(ctx, &biz.StatsFilter{UserID: 1, AccountID: &card.ID})
+	if err != nil || stats.TotalIncome != money("500") || stats.TotalExpense != money("120.5") {
+		t.Errorf("GetStats of the card = %+v, %v", stats, err)
+	}
+
+	if err := accountUC.DeleteAccount(ctx, 1, card.ID); !errors.Is(err, biz.ErrAccountInUse) {
+		t.Errorf("deleting an account with transactions = %v, want ErrAccountInUse", err)
+	}
+	if err := accountUC.DeleteAccount(ctx, 2, wise.ID); !errors.Is(err, biz.ErrAccountNotFound) {
+		t.Errorf("deleting another user's account = %v, want ErrAccountNotFound", err)
+	}
+	empty, _ := accountUC.CreateAccount(ctx, 1, &biz.Account{Name: "现金", Type: v1.AccountType_CASH})
+	if err := accountUC.DeleteAccount(ctx, 1, empty.ID); err != nil {
+		t.Errorf("DeleteAccount of an empty account: %v", err)
+	}
+}
@@ -2,134 +2,491 @@ package biz
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"time"
 
 	v1 "accounter_go/api/accounter/v1"
+	"github.com/go-kratos/kratos/v2/errors"
 	"github.com/go-kratos/kratos/v2/log"
 )
 
+var (
+	// ErrAccounterNotFound is transaction not found, also returned for transactions of other users.
+	ErrAccounterNotFound = errors.NotFound("TRANSACTION_NOT_FOUND", "transaction not found")
+	// ErrInvalidFilter is an amount range that ends before it starts or an unknown sort field.
+	ErrInvalidFilter = errors.BadRequest("INVALID_FILTER", "invalid filter")
+)
+
 // Accounter is a Accounter model.
 type Accounter struct {
 	TransactionID int64
 	UserID        int64
 	Type          v1.Type
-	Category      v1.Category
+	CategoryID    int64
 	Desc          string
-	Amount        float64
-	Date          time.Time
+	Amount        Money  // rounded to the minor unit of Currency
+	Currency      string // ISO 4217 code, see NormalizeCurrency
+	AccountID     int64  // 0 for transactions recorded before accounts existed
+	// A transfer moves Amount plus Fee out of AccountID and ToAmount in ToCurrency into ToAccountID,
+	// the other types leave these fields empty
+	ToAccountID int64
+	ToAmount    Money
+	ToCurrency  string
+	Fee         Money
+	RecurringID int64    // recurring rule that created the transaction, 0 for transactions entered by hand
+	ExternalID  string   // ID at the source it was imported from, such as the FITID of a bank statement
+	Tags        []string // see NormalizeTags
+	Date        time.Time
+	CreatedAt   time.Time // when it was recorded, set by the repos
 }
 
 // AccounterRepo is a Accounter repo.
 type AccounterRepo interface {
 	Save(context.Context, *Accounter) (*Accounter, error)
+	// SaveBatch saves all transactions or, when it fails, none of them
+	SaveBatch(context.Context, []*Accounter) ([]*Accounter, error)
 	Update(context.Context, *Accounter) (*Accounter, error)
 	FindByID(context.Context, int64) (*Accounter, error)
 	ListByUserID(context.Context, int64) ([]*Accounter, error)
 	ListAll(context.Context) ([]*Accounter, error)
 	ListWithFilters(context.Context, *ListFilter) ([]*Accounter, int32, error)
+	// EachWithFilters calls fn with every transaction that matches the filter in its sort order,
+	// starting after filter.After when it is set, Page and PageSize don't apply
+	EachWithFilters(context.Context, *ListFilter, func(*Accounter) error) error
 	Delete(context.Context, int64) error
+	// DeleteBatch deletes all transactions with the distinct IDs or, when it fails, none of them
+	DeleteBatch(context.Context, []int64) error
 	GetStats(context.Context, *StatsFilter) (*Stats, error)
 	GetPeriodStats(context.Context, *PeriodStatsFilter) (*PeriodStats, error)
+	// GetAccountStats sums income and expense per account in the currencies of the accounts,
+	// only UserID, StartDate and EndDate of the filter apply
+	GetAccountStats(context.Context, *StatsFilter) ([]*AccountStat, error)
+	// ListTags lists the tags of a user's transactions ordered by name
+	ListTags(context.Context, int64) ([]*Tag, error)
 }
 
 // AccounterUseCase is a Accounter usecase.
 type AccounterUseCase struct {
-	repo AccounterRepo
-	Log  *log.Helper
+	repo       AccounterRepo
+	categories CategoryRepo
+	rates      ExchangeRateRepo
+	accounts   AccountRepo
+	Log        *log.Helper
 }
 
 // NewAccounterUsecase new a Accounter usecase.
-func NewAccounterUsecase(repo AccounterRepo, logger log.Logger) *AccounterUseCase {
-	return &AccounterUseCase{repo: repo, Log: log.NewHelper(logger)}
+func NewAccounterUsecase(repo AccounterRepo, categories CategoryRepo, rates ExchangeRateRepo, accounts AccountRepo, logger log.Logger) *AccounterUseCase {
+	return &AccounterUseCase{repo: repo, categories: categories, rates: rates, accounts: accounts, Log: log.NewHelper(logger)}
 }
 
 // ListFilter represents filters for listing transactions
 type ListFilter struct {
-	UserID    int64
-	Type      *v1.Type
-	Category  *v1.Category
-	StartDate *time.Time
-	EndDate   *time.Time
-	Page      int32
-	PageSize  int32
+	UserID      int64
+	Types       []v1.Type // transactions of any of these types
+	CategoryIDs []int64   // transactions in any of these categories
+	AccountID   *int64
+	MinAmount   *Money   // inclusive, compared with the amount in the currency of each transaction
+	MaxAmount   *Money   // inclusive
+	Tags        []string // transactions with all of these tags
+	Query       string   // words the description or tags contain, see SearchTerms
+	StartDate   *time.Time
+	EndDate     *time.Time
+	SortBy      v1.SortField // the order of creation by default, ties are broken by ID so pages don't overlap
+	Descending  bool
+	After       *ListCursor // start right after this transaction instead of at Page, see ListAccounterPage
+	Page        int32
+	PageSize    int32
+}
+
+// AccounterPatch holds the fields of a partial update, nil fields are left unchanged
+type AccounterPatch struct {
+	Type        *v1.Type
+	CategoryID  *int64
+	Desc        *string
+	Amount      *Money
+	Currency    *string
+	AccountID   *int64
+	ToAccountID *int64
+	ToAmount    *Money
+	Fee         *Money
+	Tags        *[]string // replaces the tags, an empty list removes them
+	Date        *time.Time
 }
 
 // StatsFilter represents filters for getting statistics
 type StatsFilter struct {
-	UserID    int64
-	StartDate *time.Time
-	EndDate   *time.Time
+	UserID       int64
+	AccountID    *int64
+	Tags         []string // transactions with all of these tags
+	StartDate    *time.Time
+	EndDate      *time.Time
+	Rollup       bool      // merge subcategories into their top-level category
+	BaseCurrency string    // currency of the totals, DefaultCurrency when empty
+	Converter    Converter // converts amounts into the base currency, nil sums them as they are
 }
 
 // PeriodStatsFilter represents filters for getting period statistics
 type PeriodStatsFilter struct {
-	UserID     int64
-	PeriodType v1.PeriodType
-	Year       int32
-	Month      int32
-	Week       int32
+	UserID       int64
+	AccountID    *int64
+	PeriodType   v1.PeriodType
+	Year         int32
+	Month        int32
+	Week         int32
+	BaseCurrency string    // currency of the totals, DefaultCurrency when empty
+	Converter    Converter // converts amounts into the base currency, nil sums them as they are
 }
 
 // CategoryStat represents statistics for a category
 type CategoryStat struct {
-	Category     v1.Category
+	CategoryID   int64
 	CategoryName string
-	Amount       float64
+	Amount       Money
 	Count        int32
 }
 
+// CurrencyStat represents the totals of one currency, in that currency
+type CurrencyStat struct {
+	Currency string
+	Income   Money
+	Expense  Money
+	Balance  Money
+	Count    int32
+}
+
 // PeriodData represents statistics for a specific period
 type PeriodData struct {
 	PeriodName       string
-	Income           float64
-	Expense          float64
-	Balance          float64
+	Income           Money
+	Expense          Money
+	Balance          Money
 	TransactionCount int32
 }
 
-// Stats represents financial statistics
+// Stats represents financial statistics, amounts are in BaseCurrency except for ByCurrency
 type Stats struct {
-	TotalIncome       float64
-	TotalExpense      float64
-	Balance           float64
+	BaseCurrency      string
+	TotalIncome       Money
+	TotalExpense      Money
+	Balance           Money
 	IncomeByCategory  []*CategoryStat
 	ExpenseByCategory []*CategoryStat
+	IncomeByTag       []*TagStat
+	ExpenseByTag      []*TagStat
+	ByCurrency        []*CurrencyStat
 }
 
 // PeriodStats represents period-based statistics
 type PeriodStats struct {
-	Periods       []*PeriodData
-	TotalIncome   float64
-	TotalExpense  float64
-	TotalBalance  float64
+	BaseCurrency string
+	ByCurrency   []*CurrencyStat
+	Periods      []*PeriodData
+	TotalIncome  Money
+	TotalExpense Money
+	TotalBalance Money
 }
 
 // CreateAccounter creates a Accounter, and returns the new Accounter.
 func (uc *AccounterUseCase) CreateAccounter(ctx context.Context, g *Accounter) (*Accounter, error) {
 	uc.Log.WithContext(ctx).Infof("CreateAccounter: %v", g.Desc)
+	if err := uc.checkNew(ctx, g, nil); err != nil {
+		return nil, err
+	}
 	return uc.repo.Save(ctx, g)
 }
 
+// checkNew checks a new transaction and normalizes its tags, currency and amounts,
+// checked remembers the categories that were looked up already when it isn't nil
+func (uc *AccounterUseCase) checkNew(ctx context.Context, g *Accounter, checked map[int64]error) error {
+	err, ok := checked[g.CategoryID]
+	if !ok {
+		err = uc.checkCategory(ctx, g.UserID, g.CategoryID)
+		if checked != nil {
+			checked[g.CategoryID] = err
+		}
+	}
+	if err != nil {
+		return err
+	}
+	tags, err := NormalizeTags(g.Tags)
+	if err != nil {
+		return err
+	}
+	g.Tags = tags
+	// Without a currency the transaction is in the currency of its account
+	adopt := g.Currency == ""
+	currency, err := NormalizeCurrency(g.Currency)
+	if err != nil {
+		return err
+	}
+	g.Currency = currency
+	if g.Type == v1.Type_Transfer {
+		err = uc.checkTransfer(ctx, g, adopt)
+	} else {
+		clearTransfer(g)
+		err = uc.checkAccount(ctx, g, adopt)
+	}
+	if err != nil {
+		return err
+	}
+	return roundAmount(g)
+}
+
+// roundAmount rounds the amount of a transaction to its currency, it has to stay above zero
+func roundAmount(a *Accounter) error {
+	a.Amount = a.Amount.Round(a.Currency)
+	if a.Amount <= 0 {
+		return errors.BadRequest(ErrInvalidAmount.Reason, "the amount must be above zero")
+	}
+	return nil
+}
+
 // ListAccounters lists accounters with filters
 func (uc *AccounterUseCase) ListAccounters(ctx context.Context, filter *ListFilter) ([]*Accounter, int32, error) {
 	uc.Log.WithContext(ctx).Infof("ListAccounters with filters")
+	if err := normalizeListFilter(filter); err != nil {
+		return nil, 0, err
+	}
 	return uc.repo.ListWithFilters(ctx, filter)
 }
 
-// DeleteAccounter deletes an accounter by ID
-func (uc *AccounterUseCase) DeleteAccounter(ctx context.Context, id int64) error {
+// normalizeListFilter brings the filter values into the form the repos compare with
+func normalizeListFilter(filter *ListFilter) error {
+	tags, err := NormalizeTags(filter.Tags)
+	if err != nil {
+		return err
+	}
+	filter.Tags = tags
+	terms, err := SearchTerms(filter.Query)
+	if err != nil {
+		return err
+	}
+	filter.Query = strings.Join(terms, " ")
+	if filter.MinAmount != nil && filter.MaxAmount != nil && *filter.MinAmount > *filter.MaxAmount {
+		return errors.BadRequest(ErrInvalidFilter.Reason, fmt.Sprintf("min amount %s is above max amount %s", filter.MinAmount, filter.MaxAmount))
+	}
+	switch filter.SortBy {
+	case v1.SortField_DEFAULT, v1.SortField_DATE, v1.SortField_AMOUNT, v1.SortField_CREATED_AT:
+	default:
+		return errors.BadRequest(ErrInvalidFilter.Reason, fmt.Sprintf("unknown sort field %d", filter.SortBy))
+	}
+	return nil
+}
+
+// GetAccounter gets an accounter owned by userID by ID
+func (uc *AccounterUseCase) GetAccounter(ctx context.Context, userID, id int64) (*Accounter, error) {
+	uc.Log.WithContext(ctx).Infof("GetAccounter: %d", id)
+	accounter, err := uc.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// Don't reveal that the transaction exists to other users
+	if accounter.UserID != userID {
+		return nil, ErrAccounterNotFound
+	}
+	return accounter, nil
+}
+
+// UpdateAccounter applies a partial update to an accounter owned by userID, and returns the updated Accounter.
+func (uc *AccounterUseCase) UpdateAccounter(ctx context.Context, userID, id int64, patch *AccounterPatch) (*Accounter, error) {
+	uc.Log.WithContext(ctx).Infof("UpdateAccounter: %d", id)
+	accounter, err := uc.GetAccounter(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if patch.Type != nil {
+		accounter.Type = *patch.Type
+	}
+	if patch.CategoryID != nil {
+		if err := uc.checkCategory(ctx, userID, *patch.CategoryID); err != nil {
+			return nil, err
+		}
+		accounter.CategoryID = *patch.CategoryID
+	}
+	if patch.Desc != nil {
+		accounter.Desc = *patch.Desc
+	}
+	if patch.Amount != nil {
+		accounter.Amount = *patch.Amount
+	}
+	if patch.Currency != nil {
+		if accounter.Currency, err = NormalizeCurrency(*patch.Currency); err != nil {
+			return nil, err
+		}
+	}
+	if patch.AccountID != nil {
+		accounter.AccountID = *patch.AccountID
+	}
+	if patch.ToAccountID != nil {
+		accounter.ToAccountID = *patch.ToAccountID
+	}
+	if patch.ToAmount != nil {
+		accounter.ToAmount = *patch.ToAmount
+	}
+	if patch.Fee != nil {
+		accounter.Fee = *patch.Fee
+	}
+	if patch.Tags != nil {
+		if accounter.Tags, err = NormalizeTags(*patch.Tags); err != nil {
+			return nil, err
+		}
+	}
+	// A transaction moved to an account without a new currency takes the currency of the account
+	adopt := patch.Currency == nil && patch.AccountID != nil
+	if accounter.Type == v1.Type_Transfer {
+		if patch.ToAmount == nil && accounter.ToCurrency == accounter.Currency {
+			// Within a currency the arriving amount follows the amount
+			accounter.ToAmount = 0
+		}
+		if err := uc.checkTransfer(ctx, accounter, adopt); err != nil {
+			return nil, err
+		}
+	} else {
+		clearTransfer(accounter)
+		if patch.AccountID != nil || patch.Currency != nil {
+			if err := uc.checkAccount(ctx, accounter, adopt); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if patch.Date != nil {
+		accounter.Date = *patch.Date
+	}
+	if err := roundAmount(accounter); err != nil {
+		return nil, err
+	}
+	return uc.repo.Update(ctx, accounter)
+}
+
+// DeleteAccounter deletes an accounter owned by userID by ID
+func (uc *AccounterUseCase) DeleteAccounter(ctx context.Context, userID, id int64) error {
 	uc.Log.WithContext(ctx).Infof("DeleteAccounter: %d", id)
+	if _, err := uc.GetAccounter(ctx, userID, id); err != nil {
+		return err
+	}
 	return uc.repo.Delete(ctx, id)
 }
 
 // GetStats gets financial statistics
 func (uc *AccounterUseCase) GetStats(ctx context.Context, filter *StatsFilter) (*Stats, error) {
 	uc.Log.WithContext(ctx).Infof("GetStats")
-	return uc.repo.GetStats(ctx, filter)
+	tags, err := NormalizeTags(filter.Tags)
+	if err != nil {
+		return nil, err
+	}
+	filter.Tags = tags
+	base, converter, err := uc.converter(ctx, filter.UserID, filter.BaseCurrency)
+	if err != nil {
+		return nil, err
+	}
+	filter.BaseCurrency, filter.Converter = base, converter
+	stats, err := uc.repo.GetStats(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	stats.BaseCurrency = base
+	roundStats(stats, base)
+
+	categories, err := uc.categories.ListByUserID(ctx, filter.UserID)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int64]*Category, len(categories))
+	for _, c := range categories {
+		byID[c.ID] = c
+	}
+	stats.IncomeByCategory = nameCategoryStats(stats.IncomeByCategory, byID, filter.Rollup)
+	stats.ExpenseByCategory = nameCategoryStats(stats.ExpenseByCategory, byID, filter.Rollup)
+	return stats, nil
 }
 
 // GetPeriodStats gets period-based statistics
 func (uc *AccounterUseCase) GetPeriodStats(ctx context.Context, filter *PeriodStatsFilter) (*PeriodStats, error) {
 	uc.Log.WithContext(ctx).Infof("GetPeriodStats: %v", filter.PeriodType)
-	return uc.repo.GetPeriodStats(ctx, filter)
+	base, converter, err := uc.converter(ctx, filter.UserID, filter.BaseCurrency)
+	if err != nil {
+		return nil, err
+	}
+	filter.BaseCurrency, filter.Converter = base, converter
+	stats, err := uc.repo.GetPeriodStats(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	stats.BaseCurrency = base
+	roundPeriodStats(stats, base)
+	return stats, nil
+}
+
+// roundStats rounds the converted amounts, which keep the precision of Money,
+// to the base currency once they are summed
+func roundStats(stats *Stats, base string) {
+	stats.TotalIncome = stats.TotalIncome.Round(base)
+	stats.TotalExpense = stats.TotalExpense.Round(base)
+	stats.Balance = stats.TotalIncome - stats.TotalExpense
+	for _, stat := range stats.IncomeByCategory {
+		stat.Amount = stat.Amount.Round(base)
+	}
+	for _, stat := range stats.ExpenseByCategory {
+		stat.Amount = stat.Amount.Round(base)
+	}
+	for _, stat := range stats.IncomeByTag {
+		stat.Amount = stat.Amount.Round(base)
+	}
+	for _, stat := range stats.ExpenseByTag {
+		stat.Amount = stat.Amount.Round(base)
+	}
+}
+
+// roundPeriodStats is roundStats for period statistics
+func roundPeriodStats(stats *PeriodStats, base string) {
+	stats.TotalIncome = stats.TotalIncome.Round(base)
+	stats.TotalExpense = stats.TotalExpense.Round(base)
+	stats.TotalBalance = stats.TotalIncome - stats.TotalExpense
+	for _, period := range stats.Periods {
+		period.Income = period.Income.Round(base)
+		period.Expense = period.Expense.Round(base)
+		period.Balance = period.Income - period.Expense
+	}
+}
+
+// converter loads the exchange rates of userID for statistics in the base currency
+func (uc *AccounterUseCase) converter(ctx context.Context, userID int64, base string) (string, Converter, error) {
+	base, err := NormalizeCurrency(base)
+	if err != nil {
+		return "", nil, err
+	}
+	table, err := newRateTable(ctx, uc.rates, userID, base)
+	if err != nil {
+		return "", nil, err
+	}
+	return base, table, nil
+}
+
+// checkCategory checks that userID may file transactions under a category, 0 leaves them uncategorized
+func (uc *AccounterUseCase) checkCategory(ctx context.Context, userID, categoryID int64) error {
+	if categoryID == 0 {
+		return nil
+	}
+	_, err := visibleCategory(ctx, uc.categories, userID, categoryID)
+	return err
+}
+
+// checkAccount checks that the account of a transaction belongs to its user and has the transaction's currency,
+// with adopt the transaction takes the currency of the account instead. AccountID 0 is no account.
+func (uc *AccounterUseCase) checkAccount(ctx context.Context, a *Accounter, adopt bool) error {
+	if a.AccountID == 0 {
+		return nil
+	}
+	account, err := ownAccount(ctx, uc.accounts, a.UserID, a.AccountID)
+	if err != nil {
+		return err
+	}
+	if adopt {
+		a.Currency = account.Currency
+	} else if a.Currency != account.Currency {
+		return ErrAccountCurrencyMismatch
+	}
+	return nil
 }
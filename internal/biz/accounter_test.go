@@ -0,0 +1,107 @@
+package biz_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	v1 "accounter_go/api/accounter/v1"
+	"accounter_go/internal/biz"
+	"accounter_go/internal/data"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+func newTestUseCase(t *testing.T) (*biz.AccounterUseCase, *biz.Accounter) {
+	t.Helper()
+	logger := log.NewStdLogger(io.Discard)
+	uc := biz.NewAccounterUsecase(data.NewAccounterMemoryRepo(logger), data.NewCategoryMemoryRepo(logger), data.NewExchangeRateMemoryRepo(logger), data.NewAccountMemoryRepo(logger), logger)
+	saved, err := uc.CreateAccounter(context.Background(), &biz.Accounter{
+		UserID:     1,
+		Type:       v1.Type_Expense,
+		CategoryID: int64(v1.Category_Food),
+		Desc:       "lunch",
+		Amount:     money("25"),
+		Date:       time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("CreateAccounter: %v", err)
+	}
+	return uc, saved
+}
+
+func TestGetAccounterChecksOwner(t *testing.T) {
+	uc, saved := newTestUseCase(t)
+	ctx := context.Background()
+
+	got, err := uc.GetAccounter(ctx, 1, saved.TransactionID)
+	if err != nil {
+		t.Fatalf("GetAccounter: %v", err)
+	}
+	if got.Desc != "lunch" {
+		t.Errorf("GetAccounter got %+v", got)
+	}
+
+	if _, err := uc.GetAccounter(ctx, 2, saved.TransactionID); !errors.Is(err, biz.ErrAccounterNotFound) {
+		t.Errorf("GetAccounter of another user = %v, want ErrAccounterNotFound", err)
+	}
+	if _, err := uc.GetAccounter(ctx, 1, 99999); !errors.Is(err, biz.ErrAccounterNotFound) {
+		t.Errorf("GetAccounter of a missing record = %v, want ErrAccounterNotFound", err)
+	}
+}
+
+func TestUpdateAccounterIsPartial(t *testing.T) {
+	uc, saved := newTestUseCase(t)
+	ctx := context.Background()
+
+	desc := "brunch"
+	updated, err := uc.UpdateAccounter(ctx, 1, saved.TransactionID, &biz.AccounterPatch{Desc: &desc})
+	if err != nil {
+		t.Fatalf("UpdateAccounter: %v", err)
+	}
+	if updated.TransactionID != saved.TransactionID || updated.Desc != "brunch" ||
+		updated.Amount != money("25") || updated.CategoryID != int64(v1.Category_Food) || !updated.Date.Equal(saved.Date) {
+		t.Errorf("UpdateAccounter got %+v", updated)
+	}
+
+	if _, err := uc.UpdateAccounter(ctx, 2, saved.TransactionID, &biz.AccounterPatch{Desc: &desc}); !errors.Is(err, biz.ErrAccounterNotFound) {
+		t.Errorf("UpdateAccounter of another user = %v, want ErrAccounterNotFound", err)
+	}
+}
+
+func TestAccounterAmountMustBeAboveZero(t *testing.T) {
+	uc, saved := newTestUseCase(t)
+	ctx := context.Background()
+
+	for _, amount := range []biz.Money{0, money("-5"), money("0.001")} {
+		if _, err := uc.UpdateAccounter(ctx, 1, saved.TransactionID, &biz.AccounterPatch{Amount: &amount}); !errors.Is(err, biz.ErrInvalidAmount) {
+			t.Errorf("UpdateAccounter to amount %s = %v, want ErrInvalidAmount", amount, err)
+		}
+		if _, err := uc.CreateAccounter(ctx, &biz.Accounter{UserID: 1, Type: v1.Type_Expense, Desc: "free", Amount: amount, Date: saved.Date}); !errors.Is(err, biz.ErrInvalidAmount) {
+			t.Errorf("CreateAccounter with amount %s = %v, want ErrInvalidAmount", amount, err)
+		}
+	}
+	if got, err := uc.GetAccounter(ctx, 1, saved.TransactionID); err != nil || got.Amount != money("25") {
+		t.Errorf("transaction after rejected updates = %+v, %v", got, err)
+	}
+}
+
+func TestListAccountersRejectsInvalidFilters(t *testing.T) {
+	uc, _ := newTestUseCase(t)
+	ctx := context.Background()
+
+	high, low := money("100"), money("10")
+	for name, filter := range map[string]*biz.ListFilter{
+		"reversed amount range": {UserID: 1, MinAmount: &high, MaxAmount: &low, Page: 1, PageSize: 10},
+		"unknown sort field":    {UserID: 1, SortBy: v1.SortField(42), Page: 1, PageSize: 10},
+	} {
+		if _, _, err := uc.ListAccounters(ctx, filter); !errors.Is(err, biz.ErrInvalidFilter) {
+			t.Errorf("ListAccounters with a %s = %v, want ErrInvalidFilter", name, err)
+		}
+	}
+	if list, _, err := uc.ListAccounters(ctx, &biz.ListFilter{UserID: 1, MinAmount: &low, MaxAmount: &low, Page: 1, PageSize: 10}); err != nil || len(list) != 0 {
+		t.Errorf("ListAccounters of amounts equal to 10 = %v, %v", list, err)
+	}
+}
@@ -0,0 +1,222 @@
+package biz
+
+import (
+	"context"
+	"crypto/rand"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"accounter_go/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// ErrInvalidCredentials is wrong username or password.
+	ErrInvalidCredentials = errors.Unauthorized("INVALID_CREDENTIALS", "invalid username or password")
+	// ErrUnauthorized is a missing, invalid or expired token.
+	ErrUnauthorized = errors.Unauthorized("UNAUTHORIZED", "missing or invalid token")
+)
+
+const (
+	defaultAccessTokenTTL  = 2 * time.Hour
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+
+	tokenTypeAccess  = "access"
+	tokenTypeRefresh = "refresh"
+)
+
+// dummyHash is compared against when the username doesn't exist, so a failed
+// login takes the same time whether or not the user is registered.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+
+// TokenPair is the result of a successful register, login or refresh.
+type TokenPair struct {
+	User         *User
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    time.Duration
+}
+
+// tokenClaims are the JWT claims of both token types, Subject holds the user ID.
+type tokenClaims struct {
+	Type string `json:"typ"`
+	jwt.RegisteredClaims
+}
+
+// AuthUseCase is a user authentication usecase.
+type AuthUseCase struct {
+	repo       UserRepo
+	tokens     APITokenRepo
+	secret     []byte
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+	Log        *log.Helper
+}
+
+// NewAuthUseCase new an authentication usecase.
+func NewAuthUseCase(repo UserRepo, tokens APITokenRepo, c *conf.Auth, logger log.Logger) *AuthUseCase {
+	uc := &AuthUseCase{
+		repo:       repo,
+		tokens:     tokens,
+		secret:     []byte(c.GetJwtSecret()),
+		accessTTL:  defaultAccessTokenTTL,
+		refreshTTL: defaultRefreshTokenTTL,
+		Log:        log.NewHelper(logger),
+	}
+	if ttl := c.GetAccessTokenTtl(); ttl != nil {
+		uc.accessTTL = ttl.AsDuration()
+	}
+	if ttl := c.GetRefreshTokenTtl(); ttl != nil {
+		uc.refreshTTL = ttl.AsDuration()
+	}
+	if len(uc.secret) == 0 {
+		uc.Log.Warn("auth.jwt_secret is not set, using a random secret: tokens won't survive a restart")
+		uc.secret = make([]byte, 32)
+		if _, err := rand.Read(uc.secret); err != nil {
+			panic(err)
+		}
+	}
+	return uc
+}
+
+// Register creates a user and logs it in.
+func (uc *AuthUseCase) Register(ctx context.Context, username, password string) (*TokenPair, error) {
+	username = strings.TrimSpace(username)
+	uc.Log.WithContext(ctx).Infof("Register: %s", username)
+	if n := utf8.RuneCountInString(username); n < 3 || n > 50 {
+		return nil, errors.BadRequest("INVALID_USERNAME", "username must be 3 to 50 characters")
+	}
+	// bcrypt only uses the first 72 bytes of the password
+	if len(password) < 8 || len(password) > 72 {
+		return nil, errors.BadRequest("INVALID_PASSWORD", "password must be 8 to 72 bytes")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	user, err := uc.repo.Save(ctx, &User{
+		Username:     username,
+		PasswordHash: string(hash),
+		CreatedAt:    time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return uc.issueTokens(user)
+}
+
+// Login checks the password of a user and issues a new token pair.
+func (uc *AuthUseCase) Login(ctx context.Context, username, password string) (*TokenPair, error) {
+	username = strings.TrimSpace(username)
+	uc.Log.WithContext(ctx).Infof("Login: %s", username)
+	user, err := uc.repo.FindByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return uc.issueTokens(user)
+}
+
+// Refresh exchanges a refresh token for a new token pair.
+func (uc *AuthUseCase) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	userID, err := uc.parseToken(refreshToken, tokenTypeRefresh)
+	if err != nil {
+		return nil, err
+	}
+	user, err := uc.repo.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return nil, ErrUnauthorized
+		}
+		return nil, err
+	}
+	return uc.issueTokens(user)
+}
+
+// Authenticate resolves a bearer credential, either a JWT access token or a
+// personal API token, to the calling user. Interactive logins get every scope.
+func (uc *AuthUseCase) Authenticate(ctx context.Context, token string) (*Principal, error) {
+	if strings.HasPrefix(token, apiTokenPrefix) {
+		return uc.authenticateAPIToken(ctx, token)
+	}
+	userID, err := uc.parseToken(token, tokenTypeAccess)
+	if err != nil {
+		return nil, err
+	}
+	return &Principal{UserID: userID, Scopes: []string{ScopeAdmin}}, nil
+}
+
+func (uc *AuthUseCase) issueTokens(user *User) (*TokenPair, error) {
+	accessToken, err := uc.signToken(user.UserID, tokenTypeAccess, uc.accessTTL)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := uc.signToken(user.UserID, tokenTypeRefresh, uc.refreshTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		User:         user,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    uc.accessTTL,
+	}, nil
+}
+
+func (uc *AuthUseCase) signToken(userID int64, tokenType string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := tokenClaims{
+		Type: tokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatInt(userID, 10),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(uc.secret)
+}
+
+// parseToken verifies the signature, expiry and type of a token and returns its user ID.
+func (uc *AuthUseCase) parseToken(token, tokenType string) (int64, error) {
+	var claims tokenClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (interface{}, error) {
+		return uc.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil || claims.Type != tokenType {
+		return 0, ErrUnauthorized
+	}
+	userID, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil {
+		return 0, ErrUnauthorized
+	}
+	return userID, nil
+}
+
+type principalKey struct{}
+
+// NewContextWithPrincipal returns a context carrying the authenticated caller.
+func NewContextWithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// UserIDFromContext returns the authenticated user ID put into the context by the auth middleware.
+func UserIDFromContext(ctx context.Context) (int64, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	if !ok {
+		return 0, false
+	}
+	return p.UserID, true
+}
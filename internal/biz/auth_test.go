@@ -0,0 +1,137 @@
+package biz_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"accounter_go/internal/biz"
+	"accounter_go/internal/conf"
+	"accounter_go/internal/data"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func newTestAuthUseCase(c *conf.Auth) *biz.AuthUseCase {
+	logger := log.NewStdLogger(io.Discard)
+	return biz.NewAuthUseCase(data.NewUserMemoryRepo(logger), data.NewAPITokenMemoryRepo(logger), c, logger)
+}
+
+func TestAuthRegisterAndLogin(t *testing.T) {
+	uc := newTestAuthUseCase(&conf.Auth{JwtSecret: "test-secret"})
+	ctx := context.Background()
+
+	registered, err := uc.Register(ctx, "alice", "correct horse")
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if registered.User.UserID != 1 {
+		t.Errorf("first user ID = %d, want 1", registered.User.UserID)
+	}
+	if _, err := uc.Register(ctx, "alice", "another password"); !errors.Is(err, biz.ErrUsernameTaken) {
+		t.Errorf("duplicate Register = %v, want ErrUsernameTaken", err)
+	}
+	if _, err := uc.Register(ctx, "bob", "short"); err == nil {
+		t.Errorf("Register with a short password should fail")
+	}
+
+	loggedIn, err := uc.Login(ctx, "alice", "correct horse")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	principal, err := uc.Authenticate(ctx, loggedIn.AccessToken)
+	if err != nil || principal.UserID != 1 {
+		t.Errorf("Authenticate = %+v, %v, want user 1", principal, err)
+	}
+
+	if _, err := uc.Login(ctx, "alice", "wrong password"); !errors.Is(err, biz.ErrInvalidCredentials) {
+		t.Errorf("Login with a wrong password = %v, want ErrInvalidCredentials", err)
+	}
+	if _, err := uc.Login(ctx, "nobody", "correct horse"); !errors.Is(err, biz.ErrInvalidCredentials) {
+		t.Errorf("Login of an unknown user = %v, want ErrInvalidCredentials", err)
+	}
+}
+
+func TestAuthTokens(t *testing.T) {
+	uc := newTestAuthUseCase(&conf.Auth{JwtSecret: "test-secret"})
+	ctx := context.Background()
+	tokens, err := uc.Register(ctx, "alice", "correct horse")
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	// A refresh token must not be usable as an access token and vice versa
+	if _, err := uc.Authenticate(ctx, tokens.RefreshToken); !errors.Is(err, biz.ErrUnauthorized) {
+		t.Errorf("Authenticate with a refresh token = %v, want ErrUnauthorized", err)
+	}
+	if _, err := uc.Refresh(ctx, tokens.AccessToken); !errors.Is(err, biz.ErrUnauthorized) {
+		t.Errorf("Refresh with an access token = %v, want ErrUnauthorized", err)
+	}
+
+	refreshed, err := uc.Refresh(ctx, tokens.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if principal, err := uc.Authenticate(ctx, refreshed.AccessToken); err != nil || principal.UserID != tokens.User.UserID {
+		t.Errorf("Authenticate refreshed token = %+v, %v", principal, err)
+	}
+
+	other := newTestAuthUseCase(&conf.Auth{JwtSecret: "other-secret"})
+	if _, err := other.Authenticate(ctx, tokens.AccessToken); !errors.Is(err, biz.ErrUnauthorized) {
+		t.Errorf("token signed with another secret = %v, want ErrUnauthorized", err)
+	}
+
+	expiring := newTestAuthUseCase(&conf.Auth{JwtSecret: "test-secret", AccessTokenTtl: durationpb.New(-1)})
+	expired, err := expiring.Register(ctx, "bob", "correct horse")
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if _, err := expiring.Authenticate(ctx, expired.AccessToken); !errors.Is(err, biz.ErrUnauthorized) {
+		t.Errorf("expired token = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestAPITokens(t *testing.T) {
+	uc := newTestAuthUseCase(&conf.Auth{JwtSecret: "test-secret"})
+	ctx := context.Background()
+
+	if _, _, err := uc.CreateAPIToken(ctx, 1, "cron", []string{"superuser"}); err == nil {
+		t.Errorf("CreateAPIToken with an unknown scope should fail")
+	}
+	token, secret, err := uc.CreateAPIToken(ctx, 1, "cron", []string{biz.ScopeWrite})
+	if err != nil {
+		t.Fatalf("CreateAPIToken: %v", err)
+	}
+	if token.TokenHash == secret || !strings.HasPrefix(secret, token.Prefix) {
+		t.Errorf("token %+v doesn't match secret %q", token, secret)
+	}
+
+	principal, err := uc.Authenticate(ctx, secret)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if principal.UserID != 1 || !principal.HasScope(biz.ScopeRead) || !principal.HasScope(biz.ScopeWrite) || principal.HasScope(biz.ScopeAdmin) {
+		t.Errorf("Authenticate = %+v", principal)
+	}
+
+	tokens, err := uc.ListAPITokens(ctx, 1)
+	if err != nil || len(tokens) != 1 || tokens[0].LastUsedAt == nil {
+		t.Fatalf("ListAPITokens = %+v, %v, want one used token", tokens, err)
+	}
+	if others, _ := uc.ListAPITokens(ctx, 2); len(others) != 0 {
+		t.Errorf("tokens leaked to another user: %+v", others)
+	}
+
+	if err := uc.RevokeAPIToken(ctx, 2, token.ID); !errors.Is(err, biz.ErrAPITokenNotFound) {
+		t.Errorf("RevokeAPIToken of another user = %v, want ErrAPITokenNotFound", err)
+	}
+	if err := uc.RevokeAPIToken(ctx, 1, token.ID); err != nil {
+		t.Fatalf("RevokeAPIToken: %v", err)
+	}
+	if _, err := uc.Authenticate(ctx, secret); !errors.Is(err, biz.ErrUnauthorized) {
+		t.Errorf("revoked token = %v, want ErrUnauthorized", err)
+	}
+}
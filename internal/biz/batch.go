@@ -0,0 +1,118 @@
+package biz
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+// maxBatchSize is the most transactions one batch may create or delete
+const maxBatchSize = 1000
+
+// ErrInvalidBatch is a batch with too many items, errors of single items are reported per item.
+var ErrInvalidBatch = errors.BadRequest("INVALID_BATCH", "invalid batch")
+
+// BatchOptions controls how a batch with invalid items is applied
+type BatchOptions struct {
+	// Partial applies the valid items of a batch with invalid items,
+	// by default such a batch changes nothing
+	Partial bool
+}
+
+// BatchItem is the result of one item of a batch
+type BatchItem struct {
+	ID        int64      // the transaction to delete, or the ID of the created transaction
+	Accounter *Accounter // the created transaction, nil for deletes and invalid items
+	Error     string     // why the item is invalid, empty for valid items
+}
+
+// BatchResult reports every item of a batch in the order of the request
+type BatchResult struct {
+	Items   []*BatchItem
+	Applied int // items that were created or deleted
+	Invalid int
+}
+
+func checkBatchSize(n int) error {
+	if n > maxBatchSize {
+		return errors.BadRequest(ErrInvalidBatch.Reason, fmt.Sprintf("a batch has at most %d items, got %d", maxBatchSize, n))
+	}
+	return nil
+}
+
+// BatchCreateAccounters checks every transaction like CreateAccounter and saves the valid
+// ones with a single write to the repo, all of them or none when it fails
+func (uc *AccounterUseCase) BatchCreateAccounters(ctx context.Context, userID int64, accounters []*Accounter, opts BatchOptions) (*BatchResult, error) {
+	uc.Log.WithContext(ctx).Infof("BatchCreateAccounters: %d", len(accounters))
+	if err := checkBatchSize(len(accounters)); err != nil {
+		return nil, err
+	}
+
+	result := &BatchResult{Items: make([]*BatchItem, len(accounters))}
+	var batch []*Accounter
+	var batchItems []*BatchItem
+	checked := make(map[int64]error)
+	for i, a := range accounters {
+		a.UserID = userID
+		result.Items[i] = &BatchItem{}
+		if err := uc.checkNew(ctx, a, checked); err != nil {
+			result.Items[i].Error = errorMessage(err)
+			result.Invalid++
+			continue
+		}
+		batch = append(batch, a)
+		batchItems = append(batchItems, result.Items[i])
+	}
+
+	if len(batch) == 0 || result.Invalid > 0 && !opts.Partial {
+		return result, nil
+	}
+	saved, err := uc.repo.SaveBatch(ctx, batch)
+	if err != nil {
+		return nil, err
+	}
+	for i, a := range saved {
+		batchItems[i].ID, batchItems[i].Accounter = a.TransactionID, a
+	}
+	result.Applied = len(saved)
+	return result, nil
+}
+
+// BatchDeleteAccounters deletes the transactions of userID with the given IDs with a single
+// write to the repo, IDs of other users' transactions and repeated IDs are invalid
+func (uc *AccounterUseCase) BatchDeleteAccounters(ctx context.Context, userID int64, ids []int64, opts BatchOptions) (*BatchResult, error) {
+	uc.Log.WithContext(ctx).Infof("BatchDeleteAccounters: %d", len(ids))
+	if err := checkBatchSize(len(ids)); err != nil {
+		return nil, err
+	}
+
+	result := &BatchResult{Items: make([]*BatchItem, len(ids))}
+	var batch []int64
+	seen := make(map[int64]bool, len(ids))
+	for i, id := range ids {
+		result.Items[i] = &BatchItem{ID: id}
+		var err error
+		if seen[id] {
+			err = errors.BadRequest(ErrInvalidBatch.Reason, fmt.Sprintf("transaction %d is listed more than once", id))
+		} else {
+			_, err = uc.GetAccounter(ctx, userID, id)
+		}
+		seen[id] = true
+		if err != nil {
+			result.Items[i].Error = errorMessage(err)
+			result.Invalid++
+			continue
+		}
+		batch = append(batch, id)
+	}
+
+	if len(batch) == 0 || result.Invalid > 0 && !opts.Partial {
+		return result, nil
+	}
+	if err := uc.repo.DeleteBatch(ctx, batch); err != nil {
+		return nil, err
+	}
+	result.Applied = len(batch)
+	return result, nil
+}
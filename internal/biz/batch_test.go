@@ -0,0 +1,103 @@
+package biz_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	v1 "accounter_go/api/accounter/v1"
+	"accounter_go/internal/biz"
+)
+
+func TestBatchCreateAccounters(t *testing.T) {
+	uc, _ := newTestUseCase(t)
+	ctx := context.Background()
+	batch := func() []*biz.Accounter {
+		date := time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC)
+		return []*biz.Accounter{
+			{Type: v1.Type_Expense, Desc: "coffee", Amount: money("18.005"), Tags: []string{"#Work"}, Date: date},
+			{Type: v1.Type_Expense, Desc: "unknown", Amount: money("1"), CategoryID: 9999, Date: date},
+			{Type: v1.Type_Income, Desc: "bonus", Amount: money("500"), Date: date},
+		}
+	}
+	count := func() int32 {
+		t.Helper()
+		_, total, err := uc.ListAccounters(ctx, &biz.ListFilter{UserID: 1, Page: 1, PageSize: 1})
+		if err != nil {
+			t.Fatalf("ListAccounters: %v", err)
+		}
+		return total
+	}
+
+	// By default an invalid item keeps the others from being saved
+	result, err := uc.BatchCreateAccounters(ctx, 1, batch(), biz.BatchOptions{})
+	if err != nil {
+		t.Fatalf("BatchCreateAccounters: %v", err)
+	}
+	if result.Applied != 0 || result.Invalid != 1 || result.Items[1].Error == "" || result.Items[0].Accounter != nil || count() != 1 {
+		t.Errorf("all-or-nothing batch applied %d, invalid %d, %d transactions stored", result.Applied, result.Invalid, count())
+	}
+
+	result, err = uc.BatchCreateAccounters(ctx, 1, batch(), biz.BatchOptions{Partial: true})
+	if err != nil {
+		t.Fatalf("BatchCreateAccounters: %v", err)
+	}
+	if result.Applied != 2 || result.Invalid != 1 || count() != 3 {
+		t.Fatalf("partial batch applied %d, invalid %d, %d transactions stored", result.Applied, result.Invalid, count())
+	}
+	coffee := result.Items[0].Accounter
+	if coffee == nil || result.Items[0].ID != coffee.TransactionID || coffee.UserID != 1 || coffee.Amount != money("18.01") || coffee.Currency != "CNY" || len(coffee.Tags) != 1 || coffee.Tags[0] != "work" {
+		t.Errorf("created transaction = %+v", coffee)
+	}
+	if result.Items[1].Accounter != nil || result.Items[2].Accounter == nil {
+		t.Errorf("items = %+v", result.Items)
+	}
+
+	if _, err := uc.BatchCreateAccounters(ctx, 1, make([]*biz.Accounter, 1001), biz.BatchOptions{}); !errors.Is(err, biz.ErrInvalidBatch) {
+		t.Errorf("BatchCreateAccounters of 1001 items = %v, want ErrInvalidBatch", err)
+	}
+}
+
+func TestBatchDeleteAccounters(t *testing.T) {
+	uc, lunch := newTestUseCase(t)
+	ctx := context.Background()
+	date := time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC)
+	dinner, err := uc.CreateAccounter(ctx, &biz.Accounter{UserID: 1, Type: v1.Type_Expense, Desc: "dinner", Amount: money("60"), Date: date})
+	if err != nil {
+		t.Fatalf("CreateAccounter: %v", err)
+	}
+	other, err := uc.CreateAccounter(ctx, &biz.Accounter{UserID: 2, Type: v1.Type_Expense, Desc: "other", Amount: money("1"), Date: date})
+	if err != nil {
+		t.Fatalf("CreateAccounter: %v", err)
+	}
+
+	// Transactions of other users and repeated IDs are invalid
+	ids := []int64{lunch.TransactionID, other.TransactionID, lunch.TransactionID, dinner.TransactionID}
+	result, err := uc.BatchDeleteAccounters(ctx, 1, ids, biz.BatchOptions{})
+	if err != nil {
+		t.Fatalf("BatchDeleteAccounters: %v", err)
+	}
+	if result.Applied != 0 || result.Invalid != 2 || result.Items[1].Error == "" || result.Items[2].Error == "" {
+		t.Errorf("all-or-nothing batch applied %d, invalid %d", result.Applied, result.Invalid)
+	}
+	if _, err := uc.GetAccounter(ctx, 1, lunch.TransactionID); err != nil {
+		t.Errorf("GetAccounter after an invalid batch = %v", err)
+	}
+
+	result, err = uc.BatchDeleteAccounters(ctx, 1, ids, biz.BatchOptions{Partial: true})
+	if err != nil {
+		t.Fatalf("BatchDeleteAccounters: %v", err)
+	}
+	if result.Applied != 2 || result.Invalid != 2 || result.Items[0].Error != "" || result.Items[3].Error != "" {
+		t.Errorf("partial batch applied %d, invalid %d", result.Applied, result.Invalid)
+	}
+	for _, id := range []int64{lunch.TransactionID, dinner.TransactionID} {
+		if _, err := uc.GetAccounter(ctx, 1, id); !errors.Is(err, biz.ErrAccounterNotFound) {
+			t.Errorf("GetAccounter(%d) after delete = %v, want ErrAccounterNotFound", id, err)
+		}
+	}
+	if _, err := uc.GetAccounter(ctx, 2, other.TransactionID); err != nil {
+		t.Errorf("the other user's transaction = %v", err)
+	}
+}
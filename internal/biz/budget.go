@@ -0,0 +1,357 @@
+package biz
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	v1 "accounter_go/api/accounter/v1"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+var (
+	// ErrBudgetNotFound is budget not found, also returned for budgets of other users.
+	ErrBudgetNotFound = errors.NotFound("BUDGET_NOT_FOUND", "budget not found")
+	// ErrBudgetExists is a second budget for the same category and period.
+	ErrBudgetExists = errors.Conflict("BUDGET_EXISTS", "the category already has a budget for this period")
+	// ErrInvalidBudgetPeriod is a budget that is neither monthly nor yearly.
+	ErrInvalidBudgetPeriod = errors.BadRequest("INVALID_BUDGET_PERIOD", "a budget is either monthly or yearly")
+)
+
+const (
+	// maxRolloverPeriods bounds the earlier periods whose unspent budget carries over,
+	// so the status of an old budget doesn't load the statistics of every period since it started
+	maxRolloverPeriods = 12
+	// minBudgetYear is the earliest year a budget can start in
+	minBudgetYear = 1970
+)
+
+// Budget caps the expense of a category and its subcategories per month or per year.
+type Budget struct {
+	ID         int64
+	UserID     int64
+	CategoryID int64
+	Period     v1.PeriodType // PeriodType_MONTHLY or PeriodType_YEARLY
+	Amount     Money
+	Currency   string // spending in other currencies is converted with the exchange rates
+	Rollover   bool   // unspent budget carries over into the next period
+	StartDate  time.Time
+}
+
+// BudgetRepo is a Budget repo.
+// FindByID returns ErrBudgetNotFound for unknown budgets.
+type BudgetRepo interface {
+	Save(context.Context, *Budget) (*Budget, error)
+	Update(context.Context, *Budget) (*Budget, error)
+	FindByID(context.Context, int64) (*Budget, error)
+	ListByUserID(context.Context, int64) ([]*Budget, error)
+	Delete(context.Context, int64) error
+}
+
+// BudgetPatch holds the fields of a partial budget update, nil fields are left unchanged.
+// The category, period and currency of a budget can't be changed.
+type BudgetPatch struct {
+	Amount    *Money
+	Rollover  *bool
+	StartDate *time.Time
+}
+
+// BudgetStatus is the progress of a budget in one period, amounts are in the currency of the budget.
+// Available is the budget plus what was carried over from earlier periods.
+type BudgetStatus struct {
+	Budget       *Budget
+	CategoryName string
+	PeriodName   string
+	StartDate    time.Time
+	EndDate      time.Time // last instant of the period
+	Carried      Money
+	Available    Money
+	Spent        Money
+	Remaining    Money // negative when over budget
+	PercentUsed  float64
+}
+
+// Over reports whether more was spent than available.
+func (s *BudgetStatus) Over() bool {
+	return s.Spent > s.Available
+}
+
+// BudgetUseCase is a Budget usecase.
+type BudgetUseCase struct {
+	repo       BudgetRepo
+	categories CategoryRepo
+	accounters *AccounterUseCase
+	Log        *log.Helper
+}
+
+// NewBudgetUseCase new a Budget usecase.
+func NewBudgetUseCase(repo BudgetRepo, categories CategoryRepo, accounters *AccounterUseCase, logger log.Logger) *BudgetUseCase {
+	return &BudgetUseCase{repo: repo, categories: categories, accounters: accounters, Log: log.NewHelper(logger)}
+}
+
+// ListBudgets lists the budgets of userID
+func (uc *BudgetUseCase) ListBudgets(ctx context.Context, userID int64) ([]*Budget, error) {
+	uc.Log.WithContext(ctx).Infof("ListBudgets")
+	return uc.repo.ListByUserID(ctx, userID)
+}
+
+// CreateBudget creates a budget owned by userID, it starts in the current period unless StartDate is set
+func (uc *BudgetUseCase) CreateBudget(ctx context.Context, userID int64, b *Budget) (*Budget, error) {
+	uc.Log.WithContext(ctx).Infof("CreateBudget: %d", b.CategoryID)
+	b.UserID = userID
+	currency, err := NormalizeCurrency(b.Currency)
+	if err != nil {
+		return nil, err
+	}
+	b.Currency = currency
+	if b.StartDate.IsZero() {
+		b.StartDate = time.Now().UTC()
+	}
+	if err := uc.validate(ctx, b); err != nil {
+		return nil, err
+	}
+
+	budgets, err := uc.repo.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, other := range budgets {
+		if other.CategoryID == b.CategoryID && other.Period == b.Period {
+			return nil, ErrBudgetExists
+		}
+	}
+	return uc.repo.Save(ctx, b)
+}
+
+// UpdateBudget applies a partial update to a budget owned by userID
+func (uc *BudgetUseCase) UpdateBudget(ctx context.Context, userID, id int64, patch *BudgetPatch) (*Budget, error) {
+	uc.Log.WithContext(ctx).Infof("UpdateBudget: %d", id)
+	b, err := uc.ownBudget(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if patch.Amount != nil {
+		b.Amount = *patch.Amount
+	}
+	if patch.Rollover != nil {
+		b.Rollover = *patch.Rollover
+	}
+	if patch.StartDate != nil {
+		b.StartDate = *patch.StartDate
+	}
+	if err := uc.validate(ctx, b); err != nil {
+		return nil, err
+	}
+	return uc.repo.Update(ctx, b)
+}
+
+// DeleteBudget deletes a budget owned by userID
+func (uc *BudgetUseCase) DeleteBudget(ctx context.Context, userID, id int64) error {
+	uc.Log.WithContext(ctx).Infof("DeleteBudget: %d", id)
+	if _, err := uc.ownBudget(ctx, userID, id); err != nil {
+		return err
+	}
+	return uc.repo.Delete(ctx, id)
+}
+
+// BudgetStatus returns the progress of the budgets of userID that are in effect in a month,
+// monthly budgets for the month and yearly budgets for its year.
+// The spending comes from the same statistics as GetStats.
+func (uc *BudgetUseCase) BudgetStatus(ctx context.Context, userID int64, year int, month time.Month) ([]*BudgetStatus, error) {
+	uc.Log.WithContext(ctx).Infof("BudgetStatus: %d-%02d", year, month)
+	budgets, err := uc.repo.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	categories, err := uc.categories.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int64]*Category, len(categories))
+	for _, c := range categories {
+		byID[c.ID] = c
+	}
+
+	spending := &budgetSpending{uc: uc.accounters, userID: userID, byID: byID, stats: make(map[budgetStatsKey]*Stats)}
+	day := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	statuses := make([]*BudgetStatus, 0, len(budgets))
+	for _, b := range budgets {
+		start := budgetPeriodStart(b.Period, day)
+		if start.Before(budgetPeriodStart(b.Period, b.StartDate)) {
+			continue // not in effect yet
+		}
+
+		// Carry the unspent budget of the earlier periods since the budget started, overspending isn't carried
+		var carried Money
+		if b.Rollover {
+			for p := budgetRolloverStart(b, start); p.Before(start); p = budgetPeriodNext(b.Period, p) {
+				spent, err := spending.spent(ctx, b, p)
+				if err != nil {
+					return nil, err
+				}
+				if left := b.Amount + carried - spent; left > 0 {
+					carried = left
+				} else {
+					carried = 0
+				}
+			}
+		}
+		spent, err := spending.spent(ctx, b, start)
+		if err != nil {
+			return nil, err
+		}
+
+		status := &BudgetStatus{
+			Budget:       b,
+			CategoryName: categoryName(byID, b.CategoryID),
+			PeriodName:   budgetPeriodName(b.Period, start),
+			StartDate:    start,
+			EndDate:      budgetPeriodNext(b.Period, start).Add(-time.Nanosecond),
+			Carried:      carried,
+			Available:    b.Amount + carried,
+			Spent:        spent,
+		}
+		status.Remaining = status.Available - status.Spent
+		switch {
+		case status.Available > 0:
+			status.PercentUsed = math.Round(float64(status.Spent)/float64(status.Available)*10000) / 100
+		case status.Spent > 0:
+			status.PercentUsed = 100
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// budgetStatsKey identifies the statistics of one period in one currency
+type budgetStatsKey struct {
+	currency string
+	period   v1.PeriodType
+	start    time.Time
+}
+
+// budgetSpending sums the expense of budget categories, loading the statistics of every period and currency once
+type budgetSpending struct {
+	uc     *AccounterUseCase
+	userID int64
+	byID   map[int64]*Category
+	stats  map[budgetStatsKey]*Stats
+}
+
+// spent returns the expense of the category of b and its subcategories in the period starting at start
+func (s *budgetSpending) spent(ctx context.Context, b *Budget, start time.Time) (Money, error) {
+	key := budgetStatsKey{currency: b.Currency, period: b.Period, start: start}
+	stats, ok := s.stats[key]
+	if !ok {
+		end := budgetPeriodNext(b.Period, start).Add(-time.Nanosecond)
+		var err error
+		stats, err = s.uc.GetStats(ctx, &StatsFilter{UserID: s.userID, StartDate: &start, EndDate: &end, BaseCurrency: b.Currency})
+		if err != nil {
+			return 0, err
+		}
+		s.stats[key] = stats
+	}
+
+	var spent Money
+	for _, stat := range stats.ExpenseByCategory {
+		if inCategory(s.byID, stat.CategoryID, b.CategoryID) {
+			spent += stat.Amount
+		}
+	}
+	return spent, nil
+}
+
+// inCategory reports whether a category is ancestor or one of its subcategories
+func inCategory(byID map[int64]*Category, id, ancestor int64) bool {
+	for depth := 0; depth < maxCategoryDepth; depth++ {
+		if id == ancestor {
+			return true
+		}
+		c, ok := byID[id]
+		if !ok || c.ParentID == 0 {
+			return false
+		}
+		id = c.ParentID
+	}
+	return false
+}
+
+// budgetPeriodStart returns the first day of the month or year of t
+func budgetPeriodStart(period v1.PeriodType, t time.Time) time.Time {
+	if period == v1.PeriodType_YEARLY {
+		return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// budgetPeriodNext returns the start of the period after the one starting at start
+func budgetPeriodNext(period v1.PeriodType, start time.Time) time.Time {
+	if period == v1.PeriodType_YEARLY {
+		return start.AddDate(1, 0, 0)
+	}
+	return start.AddDate(0, 1, 0)
+}
+
+// budgetRolloverStart returns the first period that carries over into the one starting at start,
+// the start of the budget or maxRolloverPeriods before start when the budget is older
+func budgetRolloverStart(b *Budget, start time.Time) time.Time {
+	earliest := start.AddDate(0, -maxRolloverPeriods, 0)
+	if b.Period == v1.PeriodType_YEARLY {
+		earliest = start.AddDate(-maxRolloverPeriods, 0, 0)
+	}
+	if first := budgetPeriodStart(b.Period, b.StartDate); first.After(earliest) {
+		return first
+	}
+	return earliest
+}
+
+// budgetPeriodName names a budget period the way period statistics do
+func budgetPeriodName(period v1.PeriodType, start time.Time) string {
+	if period == v1.PeriodType_YEARLY {
+		return start.Format("2006年")
+	}
+	return start.Format("2006年1月")
+}
+
+// validate checks the category, period and amount of a budget owned by b.UserID,
+// rounds its amount and moves its start to the beginning of its period
+func (uc *BudgetUseCase) validate(ctx context.Context, b *Budget) error {
+	if b.Period != v1.PeriodType_MONTHLY && b.Period != v1.PeriodType_YEARLY {
+		return ErrInvalidBudgetPeriod
+	}
+	if b.CategoryID == 0 {
+		return errors.BadRequest("INVALID_BUDGET_CATEGORY", "a budget needs a category")
+	}
+	c, err := visibleCategory(ctx, uc.categories, b.UserID, b.CategoryID)
+	if err != nil {
+		return err
+	}
+	if c.Type == v1.Type_Income {
+		return errors.BadRequest("INVALID_BUDGET_CATEGORY", "budgets are for expense categories")
+	}
+	b.Amount = b.Amount.Round(b.Currency)
+	if b.Amount <= 0 {
+		return errors.BadRequest("INVALID_BUDGET_AMOUNT", "budget amount must be positive")
+	}
+	if b.StartDate.Year() < minBudgetYear {
+		return errors.BadRequest("INVALID_BUDGET_START", fmt.Sprintf("a budget starts in %d or later", minBudgetYear))
+	}
+	b.StartDate = budgetPeriodStart(b.Period, b.StartDate)
+	return nil
+}
+
+// ownBudget loads a budget of userID
+func (uc *BudgetUseCase) ownBudget(ctx context.Context, userID, id int64) (*Budget, error) {
+	b, err := uc.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if b.UserID != userID {
+		return nil, ErrBudgetNotFound
+	}
+	return b, nil
+}
@@ -0,0 +1,147 @@
+package biz_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	v1 "accounter_go/api/accounter/v1"
+	"accounter_go/internal/biz"
+	"accounter_go/internal/data"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+func TestBudgetStatus(t *testing.T) {
+	logger := log.NewStdLogger(io.Discard)
+	accounters := data.NewAccounterMemoryRepo(logger)
+	categories := data.NewCategoryMemoryRepo(logger)
+	rates := data.NewExchangeRateMemoryRepo(logger)
+	uc := biz.NewAccounterUsecase(accounters, categories, rates, data.NewAccountMemoryRepo(logger), logger)
+	budgetUC := biz.NewBudgetUseCase(data.NewBudgetMemoryRepo(logger), categories, uc, logger)
+	ctx := context.Background()
+	day := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+
+	coffee, err := biz.NewCategoryUseCase(categories, accounters, logger).CreateCategory(ctx, 1, &biz.Category{ParentID: int64(v1.Category_Food), Name: "咖啡"})
+	if err != nil {
+		t.Fatalf("CreateCategory: %v", err)
+	}
+	if _, err := rates.Save(ctx, &biz.ExchangeRate{UserID: 1, From: "USD", To: "CNY", Rate: 7, Date: day("2025-01-01")}); err != nil {
+		t.Fatalf("Save rate: %v", err)
+	}
+	for _, a := range []*biz.Accounter{
+		{CategoryID: int64(v1.Category_Food), Amount: money("500"), Date: day("2025-05-03")},
+		{CategoryID: coffee.ID, Amount: money("100"), Date: day("2025-05-20")},
+		{CategoryID: int64(v1.Category_Food), Amount: money("1200"), Date: day("2025-06-30")},
+		{CategoryID: coffee.ID, Amount: money("300"), Date: day("2025-07-31")},
+		{CategoryID: int64(v1.Category_Snacks), Amount: money("20"), Date: day("2025-07-02")},
+		{CategoryID: int64(v1.Category_Travel), Amount: money("100"), Currency: "USD", Date: day("2025-02-01")},
+		{CategoryID: int64(v1.Category_Travel), Amount: money("700"), Date: day("2025-07-01")},
+	} {
+		a.UserID, a.Type = 1, v1.Type_Expense
+		if _, err := uc.CreateAccounter(ctx, a); err != nil {
+			t.Fatalf("CreateAccounter: %v", err)
+		}
+	}
+
+	food, err := budgetUC.CreateBudget(ctx, 1, &biz.Budget{CategoryID: int64(v1.Category_Food), Period: v1.PeriodType_MONTHLY, Amount: money("1000"), Rollover: true, StartDate: day("2025-05-17")})
+	if err != nil {
+		t.Fatalf("CreateBudget: %v", err)
+	}
+	if food.Currency != "CNY" || !food.StartDate.Equal(day("2025-05-01")) {
+		t.Errorf("CreateBudget = %+v", food)
+	}
+	if _, err := budgetUC.CreateBudget(ctx, 1, &biz.Budget{CategoryID: int64(v1.Category_Snacks), Period: v1.PeriodType_MONTHLY, Amount: money("10"), StartDate: day("2025-01-01")}); err != nil {
+		t.Fatalf("CreateBudget: %v", err)
+	}
+	if _, err := budgetUC.CreateBudget(ctx, 1, &biz.Budget{CategoryID: int64(v1.Category_Travel), Period: v1.PeriodType_YEARLY, Amount: money("500"), Currency: "usd", StartDate: day("2025-03-01")}); err != nil {
+		t.Fatalf("CreateBudget: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		userID int64
+		b      *biz.Budget
+		want   error
+	}{
+		{"for the same category and period", 1, &biz.Budget{CategoryID: int64(v1.Category_Food), Period: v1.PeriodType_MONTHLY, Amount: money("1")}, biz.ErrBudgetExists},
+		{"per week", 1, &biz.Budget{CategoryID: int64(v1.Category_Game), Period: v1.PeriodType_WEEKLY, Amount: money("1")}, biz.ErrInvalidBudgetPeriod},
+		{"for another user's category", 2, &biz.Budget{CategoryID: coffee.ID, Period: v1.PeriodType_MONTHLY, Amount: money("1")}, biz.ErrCategoryNotFound},
+	} {
+		if _, err := budgetUC.CreateBudget(ctx, tc.userID, tc.b); !errors.Is(err, tc.want) {
+			t.Errorf("CreateBudget %s = %v, want %v", tc.name, err, tc.want)
+		}
+	}
+	if _, err := budgetUC.CreateBudget(ctx, 1, &biz.Budget{CategoryID: int64(v1.Category_Salary), Period: v1.PeriodType_MONTHLY, Amount: money("1")}); err == nil {
+		t.Errorf("CreateBudget for an income category should fail")
+	}
+	if _, err := budgetUC.CreateBudget(ctx, 1, &biz.Budget{CategoryID: int64(v1.Category_Game), Period: v1.PeriodType_MONTHLY, Amount: money("0.001")}); err == nil {
+		t.Errorf("CreateBudget without an amount should fail")
+	}
+
+	// May leaves 400 for June, June spends 1200 of its 1400 and leaves 200 for July
+	statuses, err := budgetUC.BudgetStatus(ctx, 1, 2025, time.July)
+	if err != nil {
+		t.Fatalf("BudgetStatus: %v", err)
+	}
+	if len(statuses) != 3 {
+		t.Fatalf("BudgetStatus = %d budgets, want 3", len(statuses))
+	}
+	if got := statuses[0]; got.CategoryName != "餐饮" || got.PeriodName != "2025年7月" || got.Carried != money("200") || got.Available != money("1200") ||
+		got.Spent != money("300") || got.Remaining != money("900") || got.PercentUsed != 25 || got.Over() || !got.EndDate.Before(day("2025-08-01")) {
+		t.Errorf("food budget in July = %+v", got)
+	}
+	if got := statuses[1]; got.Spent != money("20") || got.Remaining != money("-10") || got.PercentUsed != 200 || !got.Over() {
+		t.Errorf("snacks budget in July = %+v", got)
+	}
+	if got := statuses[2]; got.PeriodName != "2025年" || got.Spent != money("200") || got.PercentUsed != 40 || got.Carried != 0 {
+		t.Errorf("travel budget in 2025 = %+v", got)
+	}
+
+	// Budgets that start later aren't in effect yet
+	statuses, err = budgetUC.BudgetStatus(ctx, 1, 2025, time.April)
+	if err != nil || len(statuses) != 2 || statuses[0].Budget.CategoryID != int64(v1.Category_Snacks) {
+		t.Errorf("BudgetStatus in April = %+v, %v", statuses, err)
+	}
+
+	rollover := false
+	if _, err := budgetUC.UpdateBudget(ctx, 1, food.ID, &biz.BudgetPatch{Rollover: &rollover}); err != nil {
+		t.Fatalf("UpdateBudget: %v", err)
+	}
+	if statuses, _ = budgetUC.BudgetStatus(ctx, 1, 2025, time.June); statuses[0].Carried != 0 || statuses[0].PercentUsed != 120 || !statuses[0].Over() {
+		t.Errorf("food budget in June without rollover = %+v", statuses[0])
+	}
+
+	if _, err := budgetUC.UpdateBudget(ctx, 2, food.ID, &biz.BudgetPatch{Rollover: &rollover}); !errors.Is(err, biz.ErrBudgetNotFound) {
+		t.Errorf("UpdateBudget of another user = %v, want ErrBudgetNotFound", err)
+	}
+	if err := budgetUC.DeleteBudget(ctx, 1, food.ID); err != nil {
+		t.Errorf("DeleteBudget: %v", err)
+	}
+}
+
+func TestBudgetRolloverIsBounded(t *testing.T) {
+	logger := log.NewStdLogger(io.Discard)
+	categories := data.NewCategoryMemoryRepo(logger)
+	uc := biz.NewAccounterUsecase(data.NewAccounterMemoryRepo(logger), categories, data.NewExchangeRateMemoryRepo(logger), data.NewAccountMemoryRepo(logger), logger)
+	budgetUC := biz.NewBudgetUseCase(data.NewBudgetMemoryRepo(logger), categories, uc, logger)
+	ctx := context.Background()
+
+	if _, err := budgetUC.CreateBudget(ctx, 1, &biz.Budget{CategoryID: int64(v1.Category_Food), Period: v1.PeriodType_MONTHLY, Amount: money("10"), Rollover: true, StartDate: time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)}); err == nil {
+		t.Errorf("CreateBudget starting in 1900 succeeded")
+	}
+	if _, err := budgetUC.CreateBudget(ctx, 1, &biz.Budget{CategoryID: int64(v1.Category_Food), Period: v1.PeriodType_MONTHLY, Amount: money("10"), Rollover: true, StartDate: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}); err != nil {
+		t.Fatalf("CreateBudget: %v", err)
+	}
+
+	// Only the twelve months before June carry over, not every month since 2020
+	statuses, err := budgetUC.BudgetStatus(ctx, 1, 2025, time.June)
+	if err != nil || len(statuses) != 1 || statuses[0].Carried != money("120") {
+		t.Errorf("BudgetStatus = %+v, %v", statuses, err)
+	}
+}
@@ -0,0 +1,279 @@
+package biz
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	v1 "accounter_go/api/accounter/v1"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+var (
+	// ErrCategoryNotFound is category not found, also returned for categories of other users.
+	ErrCategoryNotFound = errors.NotFound("CATEGORY_NOT_FOUND", "category not found")
+	// ErrBuiltinCategory is a change to one of the shared built-in categories.
+	ErrBuiltinCategory = errors.Forbidden("BUILTIN_CATEGORY", "built-in categories can't be changed")
+	// ErrCategoryInUse is deleting a category that still has subcategories or transactions.
+	ErrCategoryInUse = errors.Conflict("CATEGORY_IN_USE", "category still has subcategories or transactions")
+	// ErrCategoryCycle is moving a category below itself.
+	ErrCategoryCycle = errors.BadRequest("CATEGORY_CYCLE", "a category can't be nested below itself")
+)
+
+const (
+	uncategorizedName   = "默认"
+	unknownCategoryName = "未知"
+)
+
+// Category is a transaction category.
+// Built-in categories have no owner (UserID 0) and are shared by every user,
+// users nest their own categories below them or below each other.
+type Category struct {
+	ID       int64
+	UserID   int64
+	ParentID int64
+	Name     string
+	Type     v1.Type // v1.Type_None means the category fits both income and expense
+	Icon     string
+	Color    string
+}
+
+// BuiltIn reports whether c is one of the shared built-in categories.
+func (c *Category) BuiltIn() bool {
+	return c.UserID == 0
+}
+
+// DefaultCategories are the built-in categories every CategoryRepo provides.
+// Their IDs are the values of the former v1.Category enum, so transactions
+// recorded before categories became editable keep their category.
+var DefaultCategories = []Category{
+	{ID: int64(v1.Category_Game), Name: "游戏", Icon: "🎮"},
+	{ID: int64(v1.Category_Food), Name: "餐饮", Icon: "🍜"},
+	{ID: int64(v1.Category_Travel), Name: "旅行", Icon: "✈️"},
+	{ID: int64(v1.Category_Education), Name: "教育", Icon: "📚"},
+	{ID: int64(v1.Category_Health), Name: "健康", Icon: "💊"},
+	{ID: int64(v1.Category_Shopping), Name: "购物", Icon: "🛍️"},
+	{ID: int64(v1.Category_Other), Name: "其他", Icon: "📦"},
+	{ID: int64(v1.Category_Transport), Name: "交通", Icon: "🚇"},
+	{ID: int64(v1.Category_Entertainment), Name: "娱乐", Icon: "🎬"},
+	{ID: int64(v1.Category_Investment), Name: "投资", Icon: "📈"},
+	{ID: int64(v1.Category_Loan), Name: "借款", Icon: "💸"},
+	{ID: int64(v1.Category_Salary), Name: "工资", Type: v1.Type_Income, Icon: "💰"},
+	{ID: int64(v1.Category_OtherIncome), Name: "其他收入", Type: v1.Type_Income, Icon: "🧧"},
+	{ID: int64(v1.Category_App), Name: "应用", Icon: "📱"},
+	{ID: int64(v1.Category_House), Name: "住房", Icon: "🏠"},
+	{ID: int64(v1.Category_Utility), Name: "水电费", Icon: "💡"},
+	{ID: int64(v1.Category_Gift), Name: "礼物", Icon: "🎁"},
+	{ID: int64(v1.Category_Snacks), Name: "零食", Icon: "🍿"},
+}
+
+// CategoryRepo is a Category repo.
+// FindByID returns ErrCategoryNotFound for unknown categories, ListByUserID
+// returns the built-in categories followed by the ones of the user.
+type CategoryRepo interface {
+	Save(context.Context, *Category) (*Category, error)
+	Update(context.Context, *Category) (*Category, error)
+	FindByID(context.Context, int64) (*Category, error)
+	ListByUserID(context.Context, int64) ([]*Category, error)
+	Delete(context.Context, int64) error
+}
+
+// CategoryPatch holds the fields of a partial category update, nil fields are left unchanged
+type CategoryPatch struct {
+	ParentID *int64
+	Name     *string
+	Type     *v1.Type
+	Icon     *string
+	Color    *string
+}
+
+// CategoryUseCase is a Category usecase.
+type CategoryUseCase struct {
+	repo       CategoryRepo
+	accounters AccounterRepo
+	Log        *log.Helper
+}
+
+// NewCategoryUseCase new a Category usecase.
+func NewCategoryUseCase(repo CategoryRepo, accounters AccounterRepo, logger log.Logger) *CategoryUseCase {
+	return &CategoryUseCase{repo: repo, accounters: accounters, Log: log.NewHelper(logger)}
+}
+
+// ListCategories lists the built-in categories and the categories of userID
+func (uc *CategoryUseCase) ListCategories(ctx context.Context, userID int64) ([]*Category, error) {
+	uc.Log.WithContext(ctx).Infof("ListCategories")
+	return uc.repo.ListByUserID(ctx, userID)
+}
+
+// CreateCategory creates a category owned by userID
+func (uc *CategoryUseCase) CreateCategory(ctx context.Context, userID int64, c *Category) (*Category, error) {
+	uc.Log.WithContext(ctx).Infof("CreateCategory: %s", c.Name)
+	c.UserID = userID
+	if err := uc.validate(ctx, c); err != nil {
+		return nil, err
+	}
+	return uc.repo.Save(ctx, c)
+}
+
+// UpdateCategory applies a partial update to a category owned by userID
+func (uc *CategoryUseCase) UpdateCategory(ctx context.Context, userID, id int64, patch *CategoryPatch) (*Category, error) {
+	uc.Log.WithContext(ctx).Infof("UpdateCategory: %d", id)
+	c, err := uc.ownCategory(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if patch.ParentID != nil {
+		c.ParentID = *patch.ParentID
+	}
+	if patch.Name != nil {
+		c.Name = *patch.Name
+	}
+	if patch.Type != nil {
+		c.Type = *patch.Type
+	}
+	if patch.Icon != nil {
+		c.Icon = *patch.Icon
+	}
+	if patch.Color != nil {
+		c.Color = *patch.Color
+	}
+	if err := uc.validate(ctx, c); err != nil {
+		return nil, err
+	}
+	return uc.repo.Update(ctx, c)
+}
+
+// DeleteCategory deletes a category owned by userID that has no subcategories and no transactions
+func (uc *CategoryUseCase) DeleteCategory(ctx context.Context, userID, id int64) error {
+	uc.Log.WithContext(ctx).Infof("DeleteCategory: %d", id)
+	if _, err := uc.ownCategory(ctx, userID, id); err != nil {
+		return err
+	}
+
+	categories, err := uc.repo.ListByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, c := range categories {
+		if c.ParentID == id {
+			return ErrCategoryInUse
+		}
+	}
+	_, total, err := uc.accounters.ListWithFilters(ctx, &ListFilter{UserID: userID, CategoryIDs: []int64{id}, Page: 1, PageSize: 1})
+	if err != nil {
+		return err
+	}
+	if total > 0 {
+		return ErrCategoryInUse
+	}
+	return uc.repo.Delete(ctx, id)
+}
+
+// ownCategory loads a category that userID may change
+func (uc *CategoryUseCase) ownCategory(ctx context.Context, userID, id int64) (*Category, error) {
+	c, err := uc.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if c.BuiltIn() {
+		return nil, ErrBuiltinCategory
+	}
+	if c.UserID != userID {
+		return nil, ErrCategoryNotFound
+	}
+	return c, nil
+}
+
+// validate checks the name and the parent of a category owned by c.UserID
+func (uc *CategoryUseCase) validate(ctx context.Context, c *Category) error {
+	c.Name = strings.TrimSpace(c.Name)
+	if n := utf8.RuneCountInString(c.Name); n == 0 || n > 50 {
+		return errors.BadRequest("INVALID_CATEGORY_NAME", "name must be 1 to 50 characters")
+	}
+	if c.ParentID == 0 {
+		return nil
+	}
+
+	// Walk up from the new parent, reaching c itself would create a cycle
+	for parentID, depth := c.ParentID, 0; parentID != 0; depth++ {
+		if parentID == c.ID || depth > maxCategoryDepth {
+			return ErrCategoryCycle
+		}
+		parent, err := visibleCategory(ctx, uc.repo, c.UserID, parentID)
+		if err != nil {
+			return err
+		}
+		parentID = parent.ParentID
+	}
+	return nil
+}
+
+// maxCategoryDepth bounds the walks up the category tree
+const maxCategoryDepth = 16
+
+// visibleCategory loads a category that userID may use, a built-in one or one of its own
+func visibleCategory(ctx context.Context, repo CategoryRepo, userID, id int64) (*Category, error) {
+	c, err := repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !c.BuiltIn() && c.UserID != userID {
+		return nil, ErrCategoryNotFound
+	}
+	return c, nil
+}
+
+// categoryName returns the display name of a category
+func categoryName(byID map[int64]*Category, id int64) string {
+	if id == 0 {
+		return uncategorizedName
+	}
+	if c, ok := byID[id]; ok {
+		return c.Name
+	}
+	return unknownCategoryName
+}
+
+// rootCategoryID returns the top-level ancestor of a category
+func rootCategoryID(byID map[int64]*Category, id int64) int64 {
+	for depth := 0; depth < maxCategoryDepth; depth++ {
+		c, ok := byID[id]
+		if !ok || c.ParentID == 0 {
+			break
+		}
+		id = c.ParentID
+	}
+	return id
+}
+
+// nameCategoryStats fills in the category names, and with rollup merges every
+// subcategory into its top-level category
+func nameCategoryStats(stats []*CategoryStat, byID map[int64]*Category, rollup bool) []*CategoryStat {
+	if rollup {
+		merged := make(map[int64]*CategoryStat)
+		for _, stat := range stats {
+			root := rootCategoryID(byID, stat.CategoryID)
+			if m, ok := merged[root]; ok {
+				m.Amount += stat.Amount
+				m.Count += stat.Count
+			} else {
+				merged[root] = &CategoryStat{CategoryID: root, Amount: stat.Amount, Count: stat.Count}
+			}
+		}
+		stats = make([]*CategoryStat, 0, len(merged))
+		for _, stat := range merged {
+			stats = append(stats, stat)
+		}
+		sort.Slice(stats, func(i, j int) bool {
+			return stats[i].CategoryID < stats[j].CategoryID
+		})
+	}
+	for _, stat := range stats {
+		stat.CategoryName = categoryName(byID, stat.CategoryID)
+	}
+	return stats
+}
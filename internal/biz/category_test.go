@@ -0,0 +1,116 @@
+package biz_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	v1 "accounter_go/api/accounter/v1"
+	"accounter_go/internal/biz"
+	"accounter_go/internal/data"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+func TestCategoryTree(t *testing.T) {
+	logger := log.NewStdLogger(io.Discard)
+	accounters := data.NewAccounterMemoryRepo(logger)
+	categories := data.NewCategoryMemoryRepo(logger)
+	uc := biz.NewCategoryUseCase(categories, accounters, logger)
+	ctx := context.Background()
+
+	coffee, err := uc.CreateCategory(ctx, 1, &biz.Category{ParentID: int64(v1.Category_Food), Name: " 咖啡 "})
+	if err != nil {
+		t.Fatalf("CreateCategory: %v", err)
+	}
+	if coffee.Name != "咖啡" || coffee.UserID != 1 {
+		t.Errorf("CreateCategory = %+v", coffee)
+	}
+	latte, err := uc.CreateCategory(ctx, 1, &biz.Category{ParentID: coffee.ID, Name: "拿铁"})
+	if err != nil {
+		t.Fatalf("CreateCategory: %v", err)
+	}
+
+	if _, err := uc.CreateCategory(ctx, 2, &biz.Category{ParentID: coffee.ID, Name: "偷用"}); !errors.Is(err, biz.ErrCategoryNotFound) {
+		t.Errorf("nesting below another user's category = %v, want ErrCategoryNotFound", err)
+	}
+	if _, err := uc.CreateCategory(ctx, 1, &biz.Category{Name: "  "}); err == nil {
+		t.Errorf("CreateCategory with a blank name should fail")
+	}
+
+	parentID := latte.ID
+	if _, err := uc.UpdateCategory(ctx, 1, coffee.ID, &biz.CategoryPatch{ParentID: &parentID}); !errors.Is(err, biz.ErrCategoryCycle) {
+		t.Errorf("moving a category below its child = %v, want ErrCategoryCycle", err)
+	}
+	name := "零食饮料"
+	if _, err := uc.UpdateCategory(ctx, 1, int64(v1.Category_Snacks), &biz.CategoryPatch{Name: &name}); !errors.Is(err, biz.ErrBuiltinCategory) {
+		t.Errorf("renaming a built-in category = %v, want ErrBuiltinCategory", err)
+	}
+	if _, err := uc.UpdateCategory(ctx, 2, coffee.ID, &biz.CategoryPatch{Name: &name}); !errors.Is(err, biz.ErrCategoryNotFound) {
+		t.Errorf("UpdateCategory of another user = %v, want ErrCategoryNotFound", err)
+	}
+
+	if err := uc.DeleteCategory(ctx, 1, coffee.ID); !errors.Is(err, biz.ErrCategoryInUse) {
+		t.Errorf("deleting a category with subcategories = %v, want ErrCategoryInUse", err)
+	}
+	if _, err := accounters.Save(ctx, &biz.Accounter{UserID: 1, Type: v1.Type_Expense, CategoryID: latte.ID, Amount: money("30"), Date: time.Now()}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := uc.DeleteCategory(ctx, 1, latte.ID); !errors.Is(err, biz.ErrCategoryInUse) {
+		t.Errorf("deleting a category with transactions = %v, want ErrCategoryInUse", err)
+	}
+}
+
+func TestStatsRollup(t *testing.T) {
+	logger := log.NewStdLogger(io.Discard)
+	accounters := data.NewAccounterMemoryRepo(logger)
+	categories := data.NewCategoryMemoryRepo(logger)
+	uc := biz.NewAccounterUsecase(accounters, categories, data.NewExchangeRateMemoryRepo(logger), data.NewAccountMemoryRepo(logger), logger)
+	ctx := context.Background()
+
+	coffee, err := biz.NewCategoryUseCase(categories, accounters, logger).CreateCategory(ctx, 1, &biz.Category{ParentID: int64(v1.Category_Food), Name: "咖啡"})
+	if err != nil {
+		t.Fatalf("CreateCategory: %v", err)
+	}
+	for _, acc := range []*biz.Accounter{
+		{UserID: 1, Type: v1.Type_Expense, CategoryID: int64(v1.Category_Food), Amount: money("25")},
+		{UserID: 1, Type: v1.Type_Expense, CategoryID: coffee.ID, Amount: money("30")},
+		{UserID: 1, Type: v1.Type_Expense, CategoryID: int64(v1.Category_Transport), Amount: money("5")},
+	} {
+		if _, err := uc.CreateAccounter(ctx, acc); err != nil {
+			t.Fatalf("CreateAccounter: %v", err)
+		}
+	}
+	if _, err := uc.CreateAccounter(ctx, &biz.Accounter{UserID: 2, Type: v1.Type_Expense, CategoryID: coffee.ID, Amount: money("1")}); !errors.Is(err, biz.ErrCategoryNotFound) {
+		t.Errorf("CreateAccounter with another user's category = %v, want ErrCategoryNotFound", err)
+	}
+
+	stats, err := uc.GetStats(ctx, &biz.StatsFilter{UserID: 1})
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+	if got := statNames(stats.ExpenseByCategory); got != "餐饮=25 交通=5 咖啡=30 " {
+		t.Errorf("GetStats expenses = %q", got)
+	}
+
+	stats, err = uc.GetStats(ctx, &biz.StatsFilter{UserID: 1, Rollup: true})
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+	if got := statNames(stats.ExpenseByCategory); got != "餐饮=55 交通=5 " {
+		t.Errorf("GetStats rolled up expenses = %q", got)
+	}
+	if stats.ExpenseByCategory[0].Count != 2 {
+		t.Errorf("rolled up count = %d, want 2", stats.ExpenseByCategory[0].Count)
+	}
+}
+
+func statNames(stats []*biz.CategoryStat) string {
+	var s string
+	for _, stat := range stats {
+		s += stat.CategoryName + "=" + stat.Amount.String() + " "
+	}
+	return s
+}
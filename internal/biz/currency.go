@@ -0,0 +1,191 @@
+package biz
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// DefaultCurrency is the currency of transactions recorded without one, and the default base currency of statistics
+const DefaultCurrency = "CNY"
+
+var (
+	// ErrInvalidCurrency is a currency that isn't a three letter ISO 4217 code.
+	ErrInvalidCurrency = errors.BadRequest("INVALID_CURRENCY", "currency must be a three letter code such as CNY or USD")
+	// ErrExchangeRateNotFound is a missing exchange rate, also returned for rates of other users.
+	ErrExchangeRateNotFound = errors.NotFound("EXCHANGE_RATE_NOT_FOUND", "exchange rate not found")
+)
+
+// NormalizeCurrency upper-cases a currency code, an empty code is the DefaultCurrency
+func NormalizeCurrency(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return DefaultCurrency, nil
+	}
+	if len(code) != 3 {
+		return "", ErrInvalidCurrency
+	}
+	for _, c := range code {
+		if c < 'A' || c > 'Z' {
+			return "", ErrInvalidCurrency
+		}
+	}
+	return code, nil
+}
+
+// ExchangeRate says one unit of From is worth Rate units of To from Date on,
+// until a later rate of the same pair replaces it.
+type ExchangeRate struct {
+	ID     int64
+	UserID int64
+	From   string
+	To     string
+	Rate   float64
+	Date   time.Time // midnight UTC of the day the rate applies from
+}
+
+// ExchangeRateRepo is an ExchangeRate repo.
+// Save replaces the rate of the same user, pair and date, ListByUserID
+// returns the rates of a user ordered by date.
+type ExchangeRateRepo interface {
+	Save(context.Context, *ExchangeRate) (*ExchangeRate, error)
+	ListByUserID(context.Context, int64) ([]*ExchangeRate, error)
+	Delete(ctx context.Context, userID, id int64) error
+}
+
+// Converter converts an amount of a transaction into the base currency of a statistics request.
+// The result keeps the precision of Money, it is rounded to the base currency once summed.
+type Converter interface {
+	Convert(amount Money, currency string, date time.Time) (Money, error)
+}
+
+// ExchangeRateUseCase is an ExchangeRate usecase.
+type ExchangeRateUseCase struct {
+	repo ExchangeRateRepo
+	Log  *log.Helper
+}
+
+// NewExchangeRateUseCase new an ExchangeRate usecase.
+func NewExchangeRateUseCase(repo ExchangeRateRepo, logger log.Logger) *ExchangeRateUseCase {
+	return &ExchangeRateUseCase{repo: repo, Log: log.NewHelper(logger)}
+}
+
+// SetRate records the rate of a currency pair of userID from a day on
+func (uc *ExchangeRateUseCase) SetRate(ctx context.Context, userID int64, rate *ExchangeRate) (*ExchangeRate, error) {
+	uc.Log.WithContext(ctx).Infof("SetRate: %s/%s", rate.From, rate.To)
+	var err error
+	if rate.From, err = NormalizeCurrency(rate.From); err != nil {
+		return nil, err
+	}
+	if rate.To, err = NormalizeCurrency(rate.To); err != nil {
+		return nil, err
+	}
+	if rate.From == rate.To {
+		return nil, errors.BadRequest("INVALID_EXCHANGE_RATE", "from and to must be different currencies")
+	}
+	if !(rate.Rate > 0) {
+		return nil, errors.BadRequest("INVALID_EXCHANGE_RATE", "rate must be positive")
+	}
+	rate.UserID = userID
+	rate.Date = time.Date(rate.Date.Year(), rate.Date.Month(), rate.Date.Day(), 0, 0, 0, 0, time.UTC)
+	return uc.repo.Save(ctx, rate)
+}
+
+// ListRates lists the rates of userID, optionally only those of one currency pair
+func (uc *ExchangeRateUseCase) ListRates(ctx context.Context, userID int64, from, to string) ([]*ExchangeRate, error) {
+	uc.Log.WithContext(ctx).Infof("ListRates")
+	rates, err := uc.repo.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	filtered := make([]*ExchangeRate, 0, len(rates))
+	for _, rate := range rates {
+		if (from == "" || rate.From == from) && (to == "" || rate.To == to) {
+			filtered = append(filtered, rate)
+		}
+	}
+	return filtered, nil
+}
+
+// DeleteRate deletes a rate of userID
+func (uc *ExchangeRateUseCase) DeleteRate(ctx context.Context, userID, id int64) error {
+	uc.Log.WithContext(ctx).Infof("DeleteRate: %d", id)
+	return uc.repo.Delete(ctx, userID, id)
+}
+
+// rateTable converts amounts into a base currency with the rates of one user
+type rateTable struct {
+	base  string
+	rates map[[2]string][]*ExchangeRate // ordered by date
+}
+
+// newRateTable loads the rates of userID for converting into base
+func newRateTable(ctx context.Context, repo ExchangeRateRepo, userID int64, base string) (*rateTable, error) {
+	rates, err := repo.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	t := &rateTable{base: base, rates: make(map[[2]string][]*ExchangeRate)}
+	for _, rate := range rates {
+		pair := [2]string{rate.From, rate.To}
+		t.rates[pair] = append(t.rates[pair], rate)
+	}
+	for _, pairRates := range t.rates {
+		sort.SliceStable(pairRates, func(i, j int) bool {
+			return pairRates[i].Date.Before(pairRates[j].Date)
+		})
+	}
+	return t, nil
+}
+
+// Convert implements Converter with the latest rate on or before the transaction date.
+// A pair without a direct rate uses the inverse rate, or goes through the DefaultCurrency.
+func (t *rateTable) Convert(amount Money, currency string, date time.Time) (Money, error) {
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	if currency == t.base {
+		return amount, nil
+	}
+	if rate, ok := t.rate(currency, t.base, date); ok {
+		return amount.Mul(rate), nil
+	}
+	if currency != DefaultCurrency && t.base != DefaultCurrency {
+		toDefault, ok1 := t.rate(currency, DefaultCurrency, date)
+		fromDefault, ok2 := t.rate(DefaultCurrency, t.base, date)
+		if ok1 && ok2 {
+			return amount.Mul(toDefault * fromDefault), nil
+		}
+	}
+	return 0, errors.NotFound(ErrExchangeRateNotFound.Reason,
+		fmt.Sprintf("no exchange rate from %s to %s on or before %s", currency, t.base, date.Format("2006-01-02")))
+}
+
+// rate finds the rate of a pair on a day, directly or from the inverse pair
+func (t *rateTable) rate(from, to string, date time.Time) (float64, bool) {
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	if rate := latestRate(t.rates[[2]string{from, to}], day); rate != nil {
+		return rate.Rate, true
+	}
+	if rate := latestRate(t.rates[[2]string{to, from}], day); rate != nil {
+		return 1 / rate.Rate, true
+	}
+	return 0, false
+}
+
+// latestRate returns the last of the rates ordered by date that applies on day
+func latestRate(rates []*ExchangeRate, day time.Time) *ExchangeRate {
+	i := sort.Search(len(rates), func(i int) bool {
+		return rates[i].Date.After(day)
+	})
+	if i == 0 {
+		return nil
+	}
+	return rates[i-1]
+}
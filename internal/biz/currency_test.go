@@ -0,0 +1,112 @@
+package biz_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	v1 "accounter_go/api/accounter/v1"
+	"accounter_go/internal/biz"
+	"accounter_go/internal/data"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+func TestNormalizeCurrency(t *testing.T) {
+	for in, want := range map[string]string{"": "CNY", "usd": "USD", " EUR ": "EUR"} {
+		if got, err := biz.NormalizeCurrency(in); err != nil || got != want {
+			t.Errorf("NormalizeCurrency(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"US", "USDT", "U$D", "人民币"} {
+		if _, err := biz.NormalizeCurrency(in); !errors.Is(err, biz.ErrInvalidCurrency) {
+			t.Errorf("NormalizeCurrency(%q) = %v, want ErrInvalidCurrency", in, err)
+		}
+	}
+}
+
+func TestStatsInBaseCurrency(t *testing.T) {
+	logger := log.NewStdLogger(io.Discard)
+	rates := data.NewExchangeRateMemoryRepo(logger)
+	uc := biz.NewAccounterUsecase(data.NewAccounterMemoryRepo(logger), data.NewCategoryMemoryRepo(logger), rates, data.NewAccountMemoryRepo(logger), logger)
+	rateUC := biz.NewExchangeRateUseCase(rates, logger)
+	ctx := context.Background()
+	day := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+
+	for _, rate := range []*biz.ExchangeRate{
+		{From: "usd", To: "CNY", Rate: 7, Date: day("2025-06-01")},
+		{From: "USD", To: "CNY", Rate: 7.2, Date: day("2025-07-01")},
+		{From: "CNY", To: "JPY", Rate: 20, Date: day("2025-06-01")},
+	} {
+		if _, err := rateUC.SetRate(ctx, 1, rate); err != nil {
+			t.Fatalf("SetRate: %v", err)
+		}
+	}
+	if _, err := rateUC.SetRate(ctx, 1, &biz.ExchangeRate{From: "USD", To: "USD", Rate: 1, Date: day("2025-06-01")}); err == nil {
+		t.Errorf("SetRate of a currency to itself should fail")
+	}
+
+	for _, a := range []*biz.Accounter{
+		{Type: v1.Type_Expense, Amount: money("10"), Currency: "usd", Date: day("2025-06-15")},
+		{Type: v1.Type_Expense, Amount: money("10"), Currency: "USD", Date: day("2025-07-15")},
+		{Type: v1.Type_Income, Amount: money("100"), Date: day("2025-07-15")},
+	} {
+		a.UserID = 1
+		if _, err := uc.CreateAccounter(ctx, a); err != nil {
+			t.Fatalf("CreateAccounter: %v", err)
+		}
+	}
+	if _, err := uc.CreateAccounter(ctx, &biz.Accounter{UserID: 1, Amount: money("1"), Currency: "dollar", Date: day("2025-07-15")}); !errors.Is(err, biz.ErrInvalidCurrency) {
+		t.Errorf("CreateAccounter with an invalid currency = %v, want ErrInvalidCurrency", err)
+	}
+
+	stats, err := uc.GetStats(ctx, &biz.StatsFilter{UserID: 1})
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+	if stats.BaseCurrency != "CNY" || stats.TotalExpense != money("142") || stats.TotalIncome != money("100") {
+		t.Errorf("GetStats in CNY = %s %v/%v", stats.BaseCurrency, stats.TotalIncome, stats.TotalExpense)
+	}
+	if len(stats.ByCurrency) != 2 || stats.ByCurrency[1].Currency != "USD" || stats.ByCurrency[1].Expense != money("20") {
+		t.Errorf("ByCurrency = %+v", stats.ByCurrency)
+	}
+
+	// The inverse of USD/CNY converts CNY into USD
+	stats, err = uc.GetStats(ctx, &biz.StatsFilter{UserID: 1, BaseCurrency: "usd"})
+	if err != nil {
+		t.Fatalf("GetStats in USD: %v", err)
+	}
+	if stats.BaseCurrency != "USD" || stats.TotalExpense != money("20") || stats.TotalIncome != money("13.89") {
+		t.Errorf("GetStats in USD = %s %v/%v", stats.BaseCurrency, stats.TotalIncome, stats.TotalExpense)
+	}
+
+	// USD goes through CNY into JPY
+	period, err := uc.GetPeriodStats(ctx, &biz.PeriodStatsFilter{UserID: 1, PeriodType: v1.PeriodType_MONTHLY, Year: 2025, Month: 7, BaseCurrency: "JPY"})
+	if err != nil {
+		t.Fatalf("GetPeriodStats in JPY: %v", err)
+	}
+	if period.BaseCurrency != "JPY" || period.TotalExpense != money("1440") || period.TotalIncome != money("2000") {
+		t.Errorf("GetPeriodStats in JPY = %+v", period)
+	}
+
+	if _, err := uc.GetStats(ctx, &biz.StatsFilter{UserID: 1, BaseCurrency: "EUR"}); !errors.Is(err, biz.ErrExchangeRateNotFound) {
+		t.Errorf("GetStats without a rate = %v, want ErrExchangeRateNotFound", err)
+	}
+	if _, err := uc.GetStats(ctx, &biz.StatsFilter{UserID: 2, BaseCurrency: "USD"}); err != nil {
+		t.Errorf("GetStats of a user without transactions = %v", err)
+	}
+}
+
+// money parses a decimal amount of the test fixture
+func money(s string) biz.Money {
+	m, err := biz.ParseMoney(s)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
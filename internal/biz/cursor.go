@@ -0,0 +1,112 @@
+package biz
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	v1 "accounter_go/api/accounter/v1"
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+var (
+	// ErrInvalidPageToken is a page token that is malformed or was issued for another sort order.
+	ErrInvalidPageToken = errors.BadRequest("INVALID_PAGE_TOKEN", "invalid page token, start again from the first page")
+)
+
+// ListCursor is the position of a transaction in the sort order of a ListFilter, a page that
+// starts after it doesn't shift when transactions are added or deleted before it.
+// Only the key of SortBy is set: Date, Amount or CreatedAt, none for the order of creation.
+type ListCursor struct {
+	SortBy     v1.SortField `json:"s,omitempty"`
+	Descending bool         `json:"d,omitempty"`
+	Date       time.Time    `json:"t,omitempty"`
+	Amount     Money        `json:"a,omitempty"`
+	CreatedAt  time.Time    `json:"c,omitempty"`
+	ID         int64        `json:"i"`
+}
+
+// AccounterPage is a page of transactions
+type AccounterPage struct {
+	Accounters    []*Accounter
+	Total         int32  // transactions matching the filter on all pages
+	NextPageToken string // empty on the last page
+}
+
+// CursorOf returns the position of a transaction in the sort order of filter
+func CursorOf(filter *ListFilter, a *Accounter) *ListCursor {
+	c := &ListCursor{SortBy: filter.SortBy, Descending: filter.Descending, ID: a.TransactionID}
+	switch filter.SortBy {
+	case v1.SortField_DATE:
+		c.Date = a.Date
+	case v1.SortField_AMOUNT:
+		c.Amount = a.Amount
+	case v1.SortField_CREATED_AT:
+		c.CreatedAt = a.CreatedAt
+	}
+	return c
+}
+
+func encodePageToken(c *ListCursor) string {
+	payload, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(payload)
+}
+
+// decodePageToken reads a page token, it has to be for the sort order of filter
+func decodePageToken(token string, filter *ListFilter) (*ListCursor, error) {
+	payload, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidPageToken
+	}
+	var c ListCursor
+	if err := json.Unmarshal(payload, &c); err != nil || c.ID <= 0 {
+		return nil, ErrInvalidPageToken
+	}
+	if c.SortBy != filter.SortBy || c.Descending != filter.Descending {
+		return nil, ErrInvalidPageToken
+	}
+	return &c, nil
+}
+
+// ListAccounterPage lists a page of transactions. With a pageToken from the previous page
+// it continues right after the last transaction of that page and ignores filter.Page,
+// without one it returns page filter.Page. Either way the reply has the token of the next page.
+func (uc *AccounterUseCase) ListAccounterPage(ctx context.Context, filter *ListFilter, pageToken string) (*AccounterPage, error) {
+	uc.Log.WithContext(ctx).Infof("ListAccounterPage")
+	if err := normalizeListFilter(filter); err != nil {
+		return nil, err
+	}
+
+	var (
+		accounters []*Accounter
+		total      int32
+		more       bool
+		err        error
+	)
+	if pageToken != "" {
+		if filter.After, err = decodePageToken(pageToken, filter); err != nil {
+			return nil, err
+		}
+		// One more than the page tells if there is a next page
+		next := *filter
+		next.Page, next.PageSize = 1, filter.PageSize+1
+		if accounters, total, err = uc.repo.ListWithFilters(ctx, &next); err != nil {
+			return nil, err
+		}
+		if more = len(accounters) > int(filter.PageSize); more {
+			accounters = accounters[:filter.PageSize]
+		}
+	} else {
+		if accounters, total, err = uc.repo.ListWithFilters(ctx, filter); err != nil {
+			return nil, err
+		}
+		more = len(accounters) > 0 && (filter.Page-1)*filter.PageSize+int32(len(accounters)) < total
+	}
+
+	page := &AccounterPage{Accounters: accounters, Total: total}
+	if more {
+		page.NextPageToken = encodePageToken(CursorOf(filter, accounters[len(accounters)-1]))
+	}
+	return page, nil
+}
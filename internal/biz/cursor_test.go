@@ -0,0 +1,84 @@
+package biz_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	v1 "accounter_go/api/accounter/v1"
+	"accounter_go/internal/biz"
+)
+
+func TestListAccounterPage(t *testing.T) {
+	uc, lunch := newTestUseCase(t)
+	ctx := context.Background()
+
+	for i, desc := range []string{"b", "c", "d", "e"} {
+		if _, err := uc.CreateAccounter(ctx, &biz.Accounter{
+			UserID: 1, Type: v1.Type_Expense, Desc: desc, Amount: money("1"), Date: time.Date(2025, 6, 2+i, 0, 0, 0, 0, time.UTC),
+		}); err != nil {
+			t.Fatalf("CreateAccounter: %v", err)
+		}
+	}
+	list := func(filter biz.ListFilter, token string) *biz.AccounterPage {
+		t.Helper()
+		filter.UserID = 1
+		page, err := uc.ListAccounterPage(ctx, &filter, token)
+		if err != nil {
+			t.Fatalf("ListAccounterPage(%q): %v", token, err)
+		}
+		return page
+	}
+	descs := func(page *biz.AccounterPage) string {
+		var s string
+		for _, a := range page.Accounters {
+			s += a.Desc + " "
+		}
+		return s
+	}
+
+	// The token of the first page leads through the others, the last one has none
+	byDate := biz.ListFilter{SortBy: v1.SortField_DATE, Descending: true, Page: 1, PageSize: 2}
+	page := list(byDate, "")
+	if got := descs(page); got != "e d " || page.Total != 5 || page.NextPageToken == "" {
+		t.Fatalf("first page = %s(total %d, token %q)", got, page.Total, page.NextPageToken)
+	}
+	// Deleting a transaction that was already listed doesn't shift the next page
+	if err := uc.DeleteAccounter(ctx, 1, page.Accounters[0].TransactionID); err != nil {
+		t.Fatalf("DeleteAccounter: %v", err)
+	}
+	page = list(byDate, page.NextPageToken)
+	if got := descs(page); got != "c b " || page.Total != 4 || page.NextPageToken == "" {
+		t.Fatalf("second page = %s(total %d, token %q)", got, page.Total, page.NextPageToken)
+	}
+	page = list(byDate, page.NextPageToken)
+	if got := descs(page); got != "lunch " || page.NextPageToken != "" {
+		t.Errorf("last page = %s(token %q)", got, page.NextPageToken)
+	}
+
+	// Offset pages still work and hand out tokens until the last page
+	if page := list(biz.ListFilter{Page: 2, PageSize: 3}, ""); descs(page) != "d " || page.NextPageToken != "" {
+		t.Errorf("last offset page = %s(token %q)", descs(page), page.NextPageToken)
+	}
+	if page := list(biz.ListFilter{Page: 1, PageSize: 4}, ""); page.NextPageToken != "" {
+		t.Errorf("a page with every transaction has token %q", page.NextPageToken)
+	}
+
+	token := list(biz.ListFilter{Page: 1, PageSize: 1}, "").NextPageToken
+	for name, tc := range map[string]struct {
+		filter biz.ListFilter
+		token  string
+	}{
+		"another sort order": {byDate, token},
+		"a malformed token":  {biz.ListFilter{Page: 1, PageSize: 1}, "not a token"},
+	} {
+		tc.filter.UserID = 1
+		if _, err := uc.ListAccounterPage(ctx, &tc.filter, tc.token); !errors.Is(err, biz.ErrInvalidPageToken) {
+			t.Errorf("ListAccounterPage with %s = %v, want ErrInvalidPageToken", name, err)
+		}
+	}
+	if page := list(biz.ListFilter{Page: 1, PageSize: 1}, token); len(page.Accounters) != 1 || page.Accounters[0].TransactionID == lunch.TransactionID {
+		t.Errorf("page after the first transaction = %s", descs(page))
+	}
+}
@@ -0,0 +1,319 @@
+package biz
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	v1 "accounter_go/api/accounter/v1"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+// Export formats
+const (
+	ExportCSV  = "csv"
+	ExportXLSX = "xlsx"
+	ExportJSON = "json"
+)
+
+// ErrInvalidExport is an unknown export format or filter.
+var ErrInvalidExport = errors.BadRequest("INVALID_EXPORT", "invalid export")
+
+// exportContentTypes are the media types of the export formats
+var exportContentTypes = map[string]string{
+	ExportCSV:       "text/csv; charset=utf-8",
+	ExportXLSX:      "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	ExportJSON:      "application/json; charset=utf-8",
+	ExportBeancount: "text/plain; charset=utf-8",
+	ExportLedger:    "text/plain; charset=utf-8",
+}
+
+// ExportContentType returns the media type of an export format, ErrInvalidExport for unknown formats
+func ExportContentType(format string) (string, error) {
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		return "", errors.BadRequest(ErrInvalidExport.Reason, fmt.Sprintf("unknown format %q, want csv, xlsx, json, beancount or ledger", format))
+	}
+	return contentType, nil
+}
+
+// exportRow is one exported transaction, with the names of its category and accounts
+type exportRow struct {
+	ID          int64    `json:"id"`
+	Date        string   `json:"date"`
+	Type        v1.Type  `json:"-"`
+	TypeName    string   `json:"type"`
+	CategoryID  int64    `json:"categoryId"`
+	Category    string   `json:"category"`
+	Amount      string   `json:"amount"`
+	Currency    string   `json:"currency"`
+	AccountID   int64    `json:"accountId,omitempty"`
+	Account     string   `json:"account,omitempty"`
+	ToAccountID int64    `json:"toAccountId,omitempty"`
+	ToAccount   string   `json:"toAccount,omitempty"`
+	ToAmount    string   `json:"toAmount,omitempty"`
+	ToCurrency  string   `json:"toCurrency,omitempty"`
+	Fee         string   `json:"fee,omitempty"`
+	Desc        string   `json:"desc"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+// exportTypeNames are the types in JSON exports, exportTypeLabels the ones in spreadsheets
+var (
+	exportTypeNames  = map[v1.Type]string{v1.Type_Income: "income", v1.Type_Expense: "expense", v1.Type_Transfer: "transfer"}
+	exportTypeLabels = map[v1.Type]string{v1.Type_Income: "收入", v1.Type_Expense: "支出", v1.Type_Transfer: "转账"}
+)
+
+// exportHeader is the header of spreadsheet exports, see exportRow.cells
+var exportHeader = []string{"ID", "日期", "类型", "分类", "金额", "币种", "账户", "转入账户", "转入金额", "转入币种", "手续费", "备注", "标签"}
+
+// cells returns the row as spreadsheet cells, numbers reports which of them are numbers
+func (r *exportRow) cells() (cells []string, numbers []bool) {
+	cells = []string{fmt.Sprint(r.ID), r.Date, exportTypeLabels[r.Type], r.Category, r.Amount, r.Currency,
+		r.Account, r.ToAccount, r.ToAmount, r.ToCurrency, r.Fee, r.Desc, strings.Join(r.Tags, ",")}
+	numbers = []bool{true, false, false, false, true, false, false, false, r.ToAmount != "", false, r.Fee != "", false, false}
+	return cells, numbers
+}
+
+// exportWriter writes the rows of an export in one format
+type exportWriter interface {
+	Write(*exportRow) error
+	Close() error
+}
+
+// Export writes the transactions that match filter in the given format, ignoring the pagination of filter.
+// Spreadsheets and JSON are written as the repo reads the transactions, journals are sorted
+// by date first, with the accounts of mapping. Nothing is written when the names of the categories
+// and accounts can't be loaded.
+func (uc *AccounterUseCase) Export(ctx context.Context, filter *ListFilter, format string, mapping *JournalMapping, w io.Writer) error {
+	if _, err := ExportContentType(format); err != nil {
+		return err
+	}
+	if err := normalizeListFilter(filter); err != nil {
+		return err
+	}
+	if format == ExportBeancount || format == ExportLedger {
+		return uc.exportJournal(ctx, filter, format, mapping, w)
+	}
+	categories, err := uc.categories.ListByUserID(ctx, filter.UserID)
+	if err != nil {
+		return err
+	}
+	byID := make(map[int64]*Category, len(categories))
+	for _, c := range categories {
+		byID[c.ID] = c
+	}
+	accounts, err := uc.accounts.ListByUserID(ctx, filter.UserID)
+	if err != nil {
+		return err
+	}
+	accountNames := make(map[int64]string, len(accounts))
+	for _, a := range accounts {
+		accountNames[a.ID] = a.Name
+	}
+
+	var out exportWriter
+	switch format {
+	case ExportCSV:
+		out, err = newCSVExportWriter(w)
+	case ExportXLSX:
+		out, err = newXLSXExportWriter(w)
+	case ExportJSON:
+		out, err = newJSONExportWriter(w)
+	}
+	if err != nil {
+		return err
+	}
+	if err := uc.eachAccounter(ctx, filter, func(a *Accounter) error {
+		return out.Write(toExportRow(a, byID, accountNames))
+	}); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// eachAccounter calls fn with every transaction that matches filter without reading them into
+// one slice, so that large exports don't hold every transaction in memory
+func (uc *AccounterUseCase) eachAccounter(ctx context.Context, filter *ListFilter, fn func(*Accounter) error) error {
+	all := *filter
+	all.Page, all.PageSize, all.After = 0, 0, nil
+	return uc.repo.EachWithFilters(ctx, &all, fn)
+}
+
+// toExportRow converts a transaction to an exported row
+func toExportRow(a *Accounter, byID map[int64]*Category, accountNames map[int64]string) *exportRow {
+	row := &exportRow{
+		ID:         a.TransactionID,
+		Date:       a.Date.Format("2006-01-02"),
+		Type:       a.Type,
+		TypeName:   exportTypeNames[a.Type],
+		CategoryID: a.CategoryID,
+		Category:   categoryName(byID, a.CategoryID),
+		Amount:     a.Amount.Format(a.Currency),
+		Currency:   a.Currency,
+		AccountID:  a.AccountID,
+		Account:    accountNames[a.AccountID],
+		Desc:       a.Desc,
+		Tags:       a.Tags,
+	}
+	if a.Type == v1.Type_Transfer {
+		row.ToAccountID = a.ToAccountID
+		row.ToAccount = accountNames[a.ToAccountID]
+		row.ToAmount = a.ToAmount.Format(a.ToCurrency)
+		row.ToCurrency = a.ToCurrency
+		row.Fee = a.Fee.Format(a.Currency)
+		if a.CategoryID == 0 {
+			row.Category = "" // transfers don't need a category
+		}
+	}
+	return row
+}
+
+// csvExportWriter writes UTF-8 CSV with a byte order mark, so that Excel reads the Chinese text correctly
+type csvExportWriter struct {
+	w *csv.Writer
+}
+
+func newCSVExportWriter(w io.Writer) (*csvExportWriter, error) {
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return nil, err
+	}
+	out := &csvExportWriter{w: csv.NewWriter(w)}
+	if err := out.w.Write(exportHeader); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *csvExportWriter) Write(row *exportRow) error {
+	cells, _ := row.cells()
+	return c.w.Write(cells)
+}
+
+func (c *csvExportWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// jsonExportWriter writes a JSON array, one transaction per line
+type jsonExportWriter struct {
+	w     *bufio.Writer
+	count int
+}
+
+func newJSONExportWriter(w io.Writer) (*jsonExportWriter, error) {
+	out := &jsonExportWriter{w: bufio.NewWriter(w)}
+	_, err := out.w.WriteString("[")
+	return out, err
+}
+
+func (j *jsonExportWriter) Write(row *exportRow) error {
+	data, err := json.Marshal(row)
+	if err != nil {
+		return err
+	}
+	sep := ",\n"
+	if j.count == 0 {
+		sep = "\n"
+	}
+	j.count++
+	if _, err := j.w.WriteString(sep); err != nil {
+		return err
+	}
+	_, err = j.w.Write(data)
+	return err
+}
+
+func (j *jsonExportWriter) Close() error {
+	if _, err := j.w.WriteString("\n]\n"); err != nil {
+		return err
+	}
+	return j.w.Flush()
+}
+
+// xlsxParts are the parts of a workbook besides its only worksheet
+var xlsxParts = []struct{ name, content string }{
+	{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/workbook.xml", xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="交易" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+	{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
+
+// xlsxExportWriter writes a workbook with one worksheet. The worksheet is streamed into the zip
+// with inline strings, so there is no shared string table to hold in memory.
+type xlsxExportWriter struct {
+	zip   *zip.Writer
+	sheet *bufio.Writer
+}
+
+func newXLSXExportWriter(w io.Writer) (*xlsxExportWriter, error) {
+	z := zip.NewWriter(w)
+	for _, part := range xlsxParts {
+		f, err := z.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return nil, err
+		}
+	}
+	f, err := z.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	out := &xlsxExportWriter{zip: z, sheet: bufio.NewWriter(f)}
+	out.sheet.WriteString(xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	if err := out.writeRow(exportHeader, nil); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (x *xlsxExportWriter) Write(row *exportRow) error {
+	return x.writeRow(row.cells())
+}
+
+// writeRow writes a row of cells, the numbers as numbers and the rest as inline strings
+func (x *xlsxExportWriter) writeRow(cells []string, numbers []bool) error {
+	x.sheet.WriteString("<row>")
+	for i, cell := range cells {
+		if i < len(numbers) && numbers[i] {
+			x.sheet.WriteString("<c><v>")
+			xml.EscapeText(x.sheet, []byte(cell))
+			x.sheet.WriteString("</v></c>")
+			continue
+		}
+		x.sheet.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+		xml.EscapeText(x.sheet, []byte(cell))
+		x.sheet.WriteString("</t></is></c>")
+	}
+	_, err := x.sheet.WriteString("</row>")
+	return err
+}
+
+func (x *xlsxExportWriter) Close() error {
+	if _, err := x.sheet.WriteString("</sheetData></worksheet>"); err != nil {
+		return err
+	}
+	if err := x.sheet.Flush(); err != nil {
+		return err
+	}
+	return x.zip.Close()
+}
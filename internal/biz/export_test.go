@@ -0,0 +1,148 @@
+package biz_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "accounter_go/api/accounter/v1"
+	"accounter_go/internal/biz"
+	"accounter_go/internal/data"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+func TestExport(t *testing.T) {
+	logger := log.NewStdLogger(io.Discard)
+	accounters := data.NewAccounterMemoryRepo(logger)
+	accounts := data.NewAccountMemoryRepo(logger)
+	uc := biz.NewAccounterUsecase(accounters, data.NewCategoryMemoryRepo(logger), data.NewExchangeRateMemoryRepo(logger), accounts, logger)
+	accountUC := biz.NewAccountUseCase(accounts, accounters, logger)
+	ctx := context.Background()
+	date := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	card, _ := accountUC.CreateAccount(ctx, 1, &biz.Account{Name: "招商银行", Type: v1.AccountType_DEBIT_CARD})
+	alipay, _ := accountUC.CreateAccount(ctx, 1, &biz.Account{Name: "支付宝", Type: v1.AccountType_ALIPAY})
+	for _, a := range []*biz.Accounter{
+		{Type: v1.Type_Expense, CategoryID: int64(v1.Category_Food), Desc: "lunch", Amount: money("25"), AccountID: card.ID, Tags: []string{"work", "team"}},
+		{Type: v1.Type_Transfer, Amount: money("200"), Fee: money("0.5"), AccountID: card.ID, ToAccountID: alipay.ID},
+		{Type: v1.Type_Income, CategoryID: int64(v1.Category_Salary), Desc: `July, "net"`, Amount: money("12000.5")},
+	} {
+		a.UserID, a.Date = 1, date
+		if _, err := uc.CreateAccounter(ctx, a); err != nil {
+			t.Fatalf("CreateAccounter: %v", err)
+		}
+	}
+	export := func(filter *biz.ListFilter, format string) []byte {
+		t.Helper()
+		var buf bytes.Buffer
+		if err := uc.Export(ctx, filter, format, nil, &buf); err != nil {
+			t.Fatalf("Export %s: %v", format, err)
+		}
+		return buf.Bytes()
+	}
+
+	out := export(&biz.ListFilter{UserID: 1, Page: 2, PageSize: 1}, biz.ExportCSV)
+	if !bytes.HasPrefix(out, []byte("\ufeff")) {
+		t.Errorf("CSV export doesn't start with a byte order mark")
+	}
+	records, err := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(out, []byte("\ufeff")))).ReadAll()
+	if err != nil {
+		t.Fatalf("reading the CSV export: %v", err)
+	}
+	var got []string
+	for _, record := range records {
+		got = append(got, strings.Join(record, "|"))
+	}
+	want := []string{
+		"ID|日期|类型|分类|金额|币种|账户|转入账户|转入金额|转入币种|手续费|备注|标签",
+		"1|2025-07-01|支出|餐饮|25.00|CNY|招商银行|||||lunch|team,work",
+		"2|2025-07-01|转账||200.00|CNY|招商银行|支付宝|200.00|CNY|0.50||",
+		`3|2025-07-01|收入|工资|12000.50|CNY||||||July, "net"|`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("CSV export ignoring the pagination =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	var rows []map[string]interface{}
+	if err := json.Unmarshal(export(&biz.ListFilter{UserID: 1, Types: []v1.Type{v1.Type_Expense}}, biz.ExportJSON), &rows); err != nil {
+		t.Fatalf("reading the JSON export: %v", err)
+	}
+	if len(rows) != 1 || rows[0]["type"] != "expense" || rows[0]["category"] != "餐饮" || rows[0]["amount"] != "25.00" || rows[0]["account"] != "招商银行" {
+		t.Errorf("JSON export of expenses = %v", rows)
+	}
+	if err := json.Unmarshal(export(&biz.ListFilter{UserID: 1, Tags: []string{"#Work"}}, biz.ExportJSON), &rows); err != nil || len(rows) != 1 || rows[0]["desc"] != "lunch" {
+		t.Errorf("JSON export of a tag = %v, %v", rows, err)
+	}
+	if err := json.Unmarshal(export(&biz.ListFilter{UserID: 2}, biz.ExportJSON), &rows); err != nil || len(rows) != 0 {
+		t.Errorf("JSON export without transactions = %v, %v", rows, err)
+	}
+
+	out = export(&biz.ListFilter{UserID: 1}, biz.ExportXLSX)
+	z, err := zip.NewReader(bytes.NewReader(out), int64(len(out)))
+	if err != nil {
+		t.Fatalf("reading the XLSX export: %v", err)
+	}
+	var sheet []byte
+	for _, f := range z.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatalf("opening %s: %v", f.Name, err)
+		}
+		content, _ := io.ReadAll(r)
+		r.Close()
+		if err := xml.Unmarshal(content, new(struct{})); err != nil {
+			t.Errorf("%s is not well-formed XML: %v", f.Name, err)
+		}
+		if f.Name == "xl/worksheets/sheet1.xml" {
+			sheet = content
+		}
+	}
+	for _, cell := range []string{"<c><v>12000.50</v></c>", `<t xml:space="preserve">July, &#34;net&#34;</t>`, `<t xml:space="preserve">支付宝</t>`} {
+		if !bytes.Contains(sheet, []byte(cell)) {
+			t.Errorf("XLSX worksheet lacks %s:\n%s", cell, sheet)
+		}
+	}
+
+	if err := uc.Export(ctx, &biz.ListFilter{UserID: 1}, "pdf", nil, io.Discard); !errors.Is(err, biz.ErrInvalidExport) {
+		t.Errorf("Export pdf = %v, want ErrInvalidExport", err)
+	}
+}
+
+func TestExportReadsAllPages(t *testing.T) {
+	uc, _ := newTestUseCase(t)
+	ctx := context.Background()
+
+	const n = 1200
+	for i := 1; i < n; i++ {
+		if _, err := uc.CreateAccounter(ctx, &biz.Accounter{UserID: 1, Type: v1.Type_Expense, Amount: money("1"), Date: time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC)}); err != nil {
+			t.Fatalf("CreateAccounter: %v", err)
+		}
+	}
+	var buf bytes.Buffer
+	if err := uc.Export(ctx, &biz.ListFilter{UserID: 1}, biz.ExportJSON, nil, &buf); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	var rows []struct {
+		ID int64 `json:"id"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &rows); err != nil {
+		t.Fatalf("reading the JSON export: %v", err)
+	}
+	if len(rows) != n {
+		t.Fatalf("Export of %d transactions returned %d", n, len(rows))
+	}
+	for i, row := range rows {
+		if row.ID != int64(i+1) {
+			t.Fatalf("row %d is transaction %d, want every transaction once in order", i, row.ID)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package biz
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	v1 "accounter_go/api/accounter/v1"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+// ErrInvalidImport is an import file or mapping that can't be read at all, errors of single rows are reported per row.
+var ErrInvalidImport = errors.BadRequest("INVALID_IMPORT", "invalid import")
+
+// ImportRow is one row of an imported file and the transaction it becomes
+type ImportRow struct {
+	Line      int        // line of the row in the file, starting at 1
+	Accounter *Accounter // nil when the row couldn't be parsed
+	// Category is category text that the mapping didn't resolve, Import matches it against
+	// the names of the categories of the user and clears it when a category has that name
+	Category  string
+	Duplicate bool   // an existing transaction has the same date, amount and description
+	Skip      string // why a valid row isn't a transaction, such as a refund or a transfer between own accounts
+	Error     string // why the row can't be imported, empty for valid rows
+}
+
+// ImportOptions controls how Import stores the parsed rows
+type ImportOptions struct {
+	DryRun bool // only check the rows and report what would be imported
+	// SkipInvalid imports the valid rows of a file with invalid rows,
+	// otherwise nothing is imported until every row is fixed
+	SkipInvalid bool
+}
+
+// ImportResult reports every row of an import. Rows are only stored when Imported is above 0.
+type ImportResult struct {
+	Rows       []*ImportRow
+	Imported   int
+	Duplicates int
+	Skipped    int
+	Invalid    int
+}
+
+// importKey identifies duplicate transactions
+type importKey struct {
+	day    string
+	amount Money
+	desc   string
+}
+
+func newImportKey(a *Accounter) importKey {
+	return importKey{day: a.Date.Format("2006-01-02"), amount: a.Amount, desc: strings.TrimSpace(a.Desc)}
+}
+
+// externalKey identifies a transaction by its ID at the source, banks only keep FITIDs unique per account
+type externalKey struct {
+	accountID  int64
+	externalID string
+}
+
+// Import checks parsed rows as transactions of userID and stores the valid ones that don't
+// duplicate an existing transaction, all in one batch. A row duplicates a transaction with the same date,
+// amount and description, a file with the same transaction twice imports it only when it isn't stored yet.
+// Rows with an ExternalID only duplicate a transaction or an earlier row with that ID in the same account,
+// so that the same purchase twice on a day is imported twice while overlapping statements add nothing.
+func (uc *AccounterUseCase) Import(ctx context.Context, userID int64, rows []*ImportRow, opts ImportOptions) (*ImportResult, error) {
+	uc.Log.WithContext(ctx).Infof("Import: %d rows, dry run %v", len(rows), opts.DryRun)
+	categories, err := uc.categories.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]int64, len(categories))
+	for _, c := range categories {
+		// Categories of the user come after the built-in ones and win on equal names
+		byName[strings.ToLower(strings.TrimSpace(c.Name))] = c.ID
+	}
+
+	checked := make(map[int64]error)
+	for _, row := range rows {
+		if row.Error != "" || row.Skip != "" || row.Accounter == nil {
+			continue
+		}
+		if id, ok := byName[strings.ToLower(strings.TrimSpace(row.Category))]; ok && row.Category != "" {
+			row.Accounter.CategoryID = id
+			row.Category = ""
+		}
+		row.Accounter.UserID = userID
+		if err := uc.checkImported(ctx, row.Accounter, checked); err != nil {
+			row.Error = errorMessage(err)
+		}
+	}
+
+	existing, err := uc.repo.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	// Each existing transaction covers one imported row, so repeated rows of a file are only skipped when they are stored as often
+	stored := make(map[importKey]int, len(existing))
+	external := make(map[externalKey]bool)
+	for _, a := range existing {
+		stored[newImportKey(a)]++
+		if a.ExternalID != "" {
+			external[externalKey{accountID: a.AccountID, externalID: a.ExternalID}] = true
+		}
+	}
+
+	result := &ImportResult{Rows: rows}
+	var batch []*Accounter
+	var batchRows []*ImportRow
+	for _, row := range rows {
+		if row.Error == "" && row.Skip != "" {
+			result.Skipped++
+			continue
+		}
+		if row.Error != "" || row.Accounter == nil {
+			if row.Error == "" {
+				row.Error = "the row has no transaction"
+			}
+			result.Invalid++
+			continue
+		}
+		if id := row.Accounter.ExternalID; id != "" {
+			key := externalKey{accountID: row.Accounter.AccountID, externalID: id}
+			row.Duplicate = external[key]
+			external[key] = true
+		} else if key := newImportKey(row.Accounter); stored[key] > 0 {
+			stored[key]--
+			row.Duplicate = true
+		}
+		if row.Duplicate {
+			result.Duplicates++
+			continue
+		}
+		batch = append(batch, row.Accounter)
+		batchRows = append(batchRows, row)
+	}
+
+	if opts.DryRun || len(batch) == 0 || result.Invalid > 0 && !opts.SkipInvalid {
+		return result, nil
+	}
+	saved, err := uc.repo.SaveBatch(ctx, batch)
+	if err != nil {
+		return nil, err
+	}
+	for i, a := range saved {
+		batchRows[i].Accounter = a
+	}
+	result.Imported = len(saved)
+	uc.Log.WithContext(ctx).Infof("Imported %d transactions, skipped %d duplicates", result.Imported, result.Duplicates)
+	return result, nil
+}
+
+// checkImported checks the rules that only apply to imported transactions, then checks them
+// like CreateAccounter does, checked remembers the categories that were looked up already
+func (uc *AccounterUseCase) checkImported(ctx context.Context, a *Accounter, checked map[int64]error) error {
+	if a.Type != v1.Type_Income && a.Type != v1.Type_Expense && a.Type != v1.Type_Transfer {
+		return invalidImport("imported transactions are income, expense or transfers")
+	}
+	if a.Date.IsZero() {
+		return invalidImport("the row has no date")
+	}
+	return uc.checkNew(ctx, a, checked)
+}
+
+// errorMessage returns the message of an error without the code and reason of kratos errors
+func errorMessage(err error) string {
+	if e := errors.FromError(err); e != nil && e.Message != "" {
+		return e.Message
+	}
+	return err.Error()
+}
+
+// invalidImport returns an ErrInvalidImport with a message
+func invalidImport(format string, a ...interface{}) error {
+	return errors.BadRequest(ErrInvalidImport.Reason, fmt.Sprintf(format, a...))
+}
+
+// importDay moves a date to midnight UTC, imported transactions are whole days
+func importDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,218 @@
+package biz_test
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	v1 "accounter_go/api/accounter/v1"
+	"accounter_go/internal/biz"
+)
+
+// rowString summarizes a parsed row as date type amount currency category desc, or its error
+func rowString(row *biz.ImportRow) string {
+	if row.Error != "" {
+		return fmt.Sprintf("%d: error %s", row.Line, row.Error)
+	}
+	a := row.Accounter
+	kind := "Expense"
+	switch a.Type {
+	case v1.Type_Income:
+		kind = "Income"
+	case v1.Type_None:
+		kind = "None"
+	}
+	s := fmt.Sprintf("%d: %s %s %s %s %d %s", row.Line, a.Date.Format("2006-01-02"), kind, a.Amount.Format(a.Currency), a.Currency, a.CategoryID, a.Desc)
+	if row.Category != "" {
+		s += " [" + row.Category + "]"
+	}
+	if row.Duplicate {
+		s += " duplicate"
+	}
+	return s
+}
+
+func TestParseCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     string
+		mapping biz.CSVMapping
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "signed amounts",
+			csv: "\ufeffDate,Amount,Description,Category\n" +
+				"2025-06-01,\"-1,234.50\",Rent,House\n" +
+				"2025/06/02 08:30,¥25.00,Refund,\n" +
+				"\n" +
+				"2025-06-03,(30),Lunch,餐饮\n" +
+				"yesterday,10,Bad date,\n" +
+				"2025-06-04,ten,Bad amount,\n",
+			mapping: biz.CSVMapping{
+				DateColumn: "Date", AmountColumn: "Amount", DescColumns: []string{"Description"}, CategoryColumn: "Category",
+				Categories: map[string]int64{"House": int64(v1.Category_House)}, Currency: "cny",
+			},
+			want: []string{
+				"2: 2025-06-01 Expense 1234.50 cny 15 Rent",
+				"3: 2025-06-02 Income 25.00 cny 0 Refund",
+				"5: 2025-06-03 Expense 30.00 cny 0 Lunch [餐饮]",
+				`6: error "yesterday" is not a date`,
+				`7: error "ten" is not an amount`,
+			},
+		},
+		{
+			name: "credit card statement without a header",
+			csv:  "03/07/2025;12.5;Coffee;Shop\n04/07/2025;-100;Payment;\n",
+			mapping: biz.CSVMapping{
+				Delimiter: ";", NoHeader: true, DateColumn: "1", DateFormat: "DD/MM/YYYY", AmountColumn: "2",
+				AmountSign: biz.AmountSignPositiveExpense, DescColumns: []string{"3", "4"}, DefaultCategoryID: int64(v1.Category_Other),
+			},
+			want: []string{
+				"1: 2025-07-03 Expense 12.50  7 Coffee Shop",
+				"2: 2025-07-04 Income 100.00  7 Payment",
+			},
+		},
+		{
+			name: "income and expense columns",
+			csv:  "title line\n日期\t收入\t支出\t备注\n2025年6月1日\t\t18\t咖啡\n2025年6月2日\t500\t\t奖金\n2025年6月3日\t1\t2\t两个金额\n",
+			mapping: biz.CSVMapping{
+				Delimiter: `\t`, SkipLines: 1, DateColumn: "日期", IncomeColumn: "收入", ExpenseColumn: "支出", DescColumns: []string{"备注"},
+			},
+			want: []string{
+				"3: 2025-06-01 Expense 18.00  0 咖啡",
+				"4: 2025-06-02 Income 500.00  0 奖金",
+				"5: error the row needs an amount in either the income or the expense column",
+			},
+		},
+		{
+			name: "type column",
+			csv:  "time,kind,amount,currency\n2025-06-01 12:00:00,收入,-8,usd\n2025-06-02 12:00:00,支出,9,\n",
+			mapping: biz.CSVMapping{
+				DateColumn: "time", DateFormat: "YYYY-MM-DD HH:mm:ss", AmountColumn: "amount", CurrencyColumn: "currency",
+				TypeColumn: "kind", IncomeValues: []string{"收入"},
+			},
+			want: []string{
+				"2: 2025-06-01 Income 8.00 usd 0 ",
+				"3: 2025-06-02 Expense 9.00  0 ",
+			},
+		},
+		{
+			name:    "unknown column",
+			csv:     "Date,Amount\n",
+			mapping: biz.CSVMapping{DateColumn: "Date", AmountColumn: "Value"},
+			wantErr: true,
+		},
+		{
+			name:    "no amount column",
+			csv:     "Date,Amount\n",
+			mapping: biz.CSVMapping{DateColumn: "Date"},
+			wantErr: true,
+		},
+		{
+			name:    "date format without a day",
+			csv:     "Date,Amount\n",
+			mapping: biz.CSVMapping{DateColumn: "Date", AmountColumn: "Amount", DateFormat: "YYYY-MM"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := biz.ParseCSV(strings.NewReader(tt.csv), &tt.mapping)
+			if tt.wantErr {
+				if !biz.ErrInvalidImport.Is(err) {
+					t.Errorf("ParseCSV = %v, want ErrInvalidImport", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseCSV: %v", err)
+			}
+			got := make([]string, 0, len(rows))
+			for _, row := range rows {
+				got = append(got, rowString(row))
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("ParseCSV got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestImportCSV(t *testing.T) {
+	uc, _ := newTestUseCase(t) // user 1 has lunch 25 on 2025-06-01
+	ctx := context.Background()
+	mapping := &biz.CSVMapping{DateColumn: "date", AmountColumn: "amount", DescColumns: []string{"desc"}, CategoryColumn: "category"}
+	file := "date,amount,desc,category\n" +
+		"2025-06-01,-25,lunch,餐饮\n" +
+		"2025-06-01,-25,lunch,餐饮\n" +
+		"2025-06-02,3000,salary,工资\n" +
+		"2025-06-03,-12,snack,nope\n"
+	listDescs := func() string {
+		list, _, err := uc.ListAccounters(ctx, &biz.ListFilter{UserID: 1, Page: 1, PageSize: 100})
+		if err != nil {
+			t.Fatalf("ListAccounters: %v", err)
+		}
+		descs := make([]string, 0, len(list))
+		for _, a := range list {
+			descs = append(descs, a.Desc)
+		}
+		sort.Strings(descs)
+		return strings.Join(descs, ",")
+	}
+
+	// The first lunch is already recorded, the second one in the file is another lunch
+	preview, err := uc.ImportCSV(ctx, 1, strings.NewReader(file), mapping, biz.ImportOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("ImportCSV dry run: %v", err)
+	}
+	if preview.Imported != 0 || preview.Duplicates != 1 || preview.Invalid != 0 || len(preview.Rows) != 4 {
+		t.Fatalf("dry run = %+v", preview)
+	}
+	if !preview.Rows[0].Duplicate || preview.Rows[1].Duplicate || preview.Rows[1].Accounter.CategoryID != int64(v1.Category_Food) ||
+		preview.Rows[2].Accounter.CategoryID != int64(v1.Category_Salary) || preview.Rows[3].Accounter.CategoryID != 0 {
+		t.Errorf("dry run rows = %v, %v, %v, %v", rowString(preview.Rows[0]), rowString(preview.Rows[1]), rowString(preview.Rows[2]), rowString(preview.Rows[3]))
+	}
+	if got := listDescs(); got != "lunch" {
+		t.Errorf("after dry run got %s", got)
+	}
+
+	// An invalid row stops the whole file unless invalid rows are skipped
+	bad := file + "2025-06-04,-5,bus,\n2025-06-05,0,nothing,\n"
+	result, err := uc.ImportCSV(ctx, 1, strings.NewReader(bad), mapping, biz.ImportOptions{})
+	if err != nil || result.Imported != 0 || result.Invalid != 1 || result.Rows[5].Error == "" {
+		t.Fatalf("import with an invalid row = %+v, %v", result, err)
+	}
+	if got := listDescs(); got != "lunch" {
+		t.Errorf("after a failed import got %s", got)
+	}
+	result, err = uc.ImportCSV(ctx, 1, strings.NewReader(bad), mapping, biz.ImportOptions{SkipInvalid: true})
+	if err != nil || result.Imported != 4 || result.Duplicates != 1 || result.Invalid != 1 {
+		t.Fatalf("import skipping invalid rows = %+v, %v", result, err)
+	}
+	if result.Rows[2].Accounter.TransactionID == 0 || result.Rows[2].Accounter.UserID != 1 {
+		t.Errorf("imported row = %+v", result.Rows[2].Accounter)
+	}
+	if got := listDescs(); got != "bus,lunch,lunch,salary,snack" {
+		t.Errorf("after import got %s", got)
+	}
+
+	// Importing the same file again only finds duplicates
+	result, err = uc.ImportCSV(ctx, 1, strings.NewReader(file), mapping, biz.ImportOptions{})
+	if err != nil || result.Imported != 0 || result.Duplicates != 4 {
+		t.Errorf("second import = %+v, %v", result, err)
+	}
+	// Another user has no duplicates
+	result, err = uc.ImportCSV(ctx, 2, strings.NewReader(file), mapping, biz.ImportOptions{DryRun: true})
+	if err != nil || result.Duplicates != 0 || result.Invalid != 0 {
+		t.Errorf("dry run of another user = %+v, %v", result, err)
+	}
+
+	// Rows with an account of another user are invalid
+	result, err = uc.ImportCSV(ctx, 1, strings.NewReader(file), &biz.CSVMapping{DateColumn: "date", AmountColumn: "amount", AccountID: 42}, biz.ImportOptions{DryRun: true})
+	if err != nil || result.Invalid != 4 {
+		t.Errorf("import into a missing account = %+v, %v", result, err)
+	}
+}
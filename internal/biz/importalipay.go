@@ -0,0 +1,144 @@
+package biz
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	v1 "accounter_go/api/accounter/v1"
+)
+
+// alipayCategories maps the 交易分类 of Alipay expenses to built-in categories,
+// the other expenses are guessed from the merchant
+var alipayCategories = map[string]v1.Category{
+	"餐饮美食": v1.Category_Food,
+	"交通出行": v1.Category_Transport,
+	"爱车养车": v1.Category_Transport,
+	"酒店旅游": v1.Category_Travel,
+	"日用百货": v1.Category_Shopping,
+	"服饰装扮": v1.Category_Shopping,
+	"数码电器": v1.Category_Shopping,
+	"家居家装": v1.Category_Shopping,
+	"母婴亲子": v1.Category_Shopping,
+	"美容美发": v1.Category_Shopping,
+	"充值缴费": v1.Category_Utility,
+	"住房物业": v1.Category_House,
+	"文化休闲": v1.Category_Entertainment,
+	"运动户外": v1.Category_Entertainment,
+	"教育培训": v1.Category_Education,
+	"医疗健康": v1.Category_Health,
+	"投资理财": v1.Category_Investment,
+	"信用借还": v1.Category_Loan,
+	"转账红包": v1.Category_Gift,
+}
+
+// ImportAlipay imports an Alipay bill statement into an account, 0 for none, see ParseAlipay and Import
+func (uc *AccounterUseCase) ImportAlipay(ctx context.Context, userID int64, r io.Reader, accountID int64, opts ImportOptions) (*ImportResult, error) {
+	rows, err := ParseAlipay(r, accountID)
+	if err != nil {
+		return nil, err
+	}
+	return uc.Import(ctx, userID, rows, opts)
+}
+
+// ParseAlipay parses the CSV bill statement (交易明细) exported by the Alipay app, in GBK or UTF-8.
+// Rows marked 不计收支, such as moving money to 余额宝 or paying off a credit card, are skipped as transfers
+// between own accounts, and so are closed orders, which were not paid or were refunded in full.
+// A refund is deducted from its purchase when the purchase is in the statement.
+func ParseAlipay(r io.Reader, accountID int64) ([]*ImportRow, error) {
+	t, err := readBill(r, "Alipay", "交易时间")
+	if err != nil {
+		return nil, err
+	}
+	if err := t.require("Alipay", []string{"交易时间"}, []string{"收/支"}, []string{"金额", "金额（元）"}, []string{"交易状态"}); err != nil {
+		return nil, err
+	}
+
+	var rows, refunds []*ImportRow
+	orders := make(map[*ImportRow][2]string) // 交易订单号 and 商家订单号 of each row
+	for _, record := range t.rows {
+		row := &ImportRow{Line: record.line}
+		rows = append(rows, row)
+		if record.err != "" {
+			row.Error = record.err
+			continue
+		}
+		date, err := parseImportDate(t.field(record, "交易时间"), defaultDateLayouts)
+		if err != nil {
+			row.Error = errorMessage(err)
+			continue
+		}
+		amount, err := parseImportAmount(t.field(record, "金额", "金额（元）"))
+		if err != nil {
+			row.Error = errorMessage(err)
+			continue
+		}
+		if amount < 0 {
+			amount = -amount
+		}
+
+		kind := t.field(record, "交易分类")
+		counterparty := t.field(record, "交易对方")
+		product := t.field(record, "商品说明", "商品名称")
+		status := t.field(record, "交易状态")
+		a := &Accounter{Date: date, Amount: amount, Currency: DefaultCurrency, AccountID: accountID, Desc: billDesc(counterparty, product)}
+		row.Accounter = a
+		orders[row] = [2]string{t.field(record, "交易订单号", "交易号"), t.field(record, "商家订单号", "商户订单号")}
+
+		switch t.field(record, "收/支") {
+		case "支出":
+			a.Type = v1.Type_Expense
+		case "收入":
+			a.Type = v1.Type_Income
+		}
+		switch {
+		case status == "退款成功" || kind == "退款" || strings.HasPrefix(product, "退款"):
+			a.Type = v1.Type_Income
+			refunds = append(refunds, row)
+		case strings.Contains(status, "关闭") || strings.Contains(status, "失败"):
+			row.Skip = "closed order, not paid or refunded in full"
+		case a.Type == v1.Type_None:
+			row.Skip = "neither income nor expense, such as a transfer between own accounts"
+		}
+
+		if category, ok := alipayCategories[kind]; ok && a.Type == v1.Type_Expense {
+			a.CategoryID = int64(category)
+		} else {
+			a.CategoryID = GuessCategory(a.Type, kind, counterparty, product)
+		}
+	}
+
+	for _, refund := range refunds {
+		purchase := alipayPurchase(rows, orders, refund)
+		switch {
+		case purchase == nil:
+			refund.Skip = "refund of a purchase that isn't in the statement"
+		case purchase.Skip != "":
+			refund.Skip = fmt.Sprintf("refund of the order on line %d, which isn't imported", purchase.Line)
+		default:
+			purchase.Accounter.Amount -= refund.Accounter.Amount
+			if purchase.Accounter.Amount <= 0 {
+				purchase.Skip = "refunded in full"
+			}
+			refund.Skip = fmt.Sprintf("refund, deducted from the purchase on line %d", purchase.Line)
+		}
+	}
+	return rows, nil
+}
+
+// alipayPurchase finds the purchase of a refund by its merchant order number, or by its order number
+// that the order number of the refund starts with
+func alipayPurchase(rows []*ImportRow, orders map[*ImportRow][2]string, refund *ImportRow) *ImportRow {
+	order, merchantOrder := orders[refund][0], orders[refund][1]
+	for _, row := range rows {
+		if row == refund || row.Accounter == nil || row.Accounter.Type != v1.Type_Expense {
+			continue
+		}
+		o := orders[row]
+		if merchantOrder != "" && o[1] == merchantOrder || o[0] != "" && o[0] != order && strings.HasPrefix(order, o[0]) {
+			return row
+		}
+	}
+	return nil
+}
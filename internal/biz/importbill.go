@@ -0,0 +1,184 @@
+package biz
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	v1 "accounter_go/api/accounter/v1"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// billTable holds the rows of a bill statement exported by a payment app. Statements start with a summary,
+// followed by a header line, the rows and sometimes a footer line of dashes.
+type billTable struct {
+	columns map[string]int
+	rows    []billRecord
+}
+
+type billRecord struct {
+	line   int
+	fields []string
+	err    string // the line isn't valid CSV
+}
+
+// readBill reads a statement in UTF-8 or GBK, the encoding of Alipay exports.
+// The header is the first line that starts with first, name is the app for error messages.
+func readBill(r io.Reader, name, first string) (*billTable, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, invalidImport("reading the file: %v", err)
+	}
+	if !utf8.Valid(data) {
+		if data, err = simplifiedchinese.GBK.NewDecoder().Bytes(data); err != nil {
+			return nil, invalidImport("the file is neither UTF-8 nor GBK")
+		}
+	}
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+
+	lines := strings.Split(string(data), "\n")
+	header := -1
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), first) {
+			header = i
+			break
+		}
+	}
+	if header < 0 {
+		return nil, invalidImport("not a %s bill, no line starts with %s", name, first)
+	}
+
+	reader := csv.NewReader(strings.NewReader(strings.Join(lines[header:], "\n")))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	names, err := reader.Read()
+	if err != nil {
+		return nil, invalidImport("reading the header: %v", err)
+	}
+	t := &billTable{columns: make(map[string]int, len(names))}
+	for i, name := range names {
+		t.columns[strings.TrimSpace(name)] = i
+	}
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, invalidImport("reading the file: %v", err)
+			}
+			t.rows = append(t.rows, billRecord{line: header + parseErr.Line, err: parseErr.Err.Error()})
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+		if strings.HasPrefix(strings.TrimSpace(fields[0]), "---") {
+			break
+		}
+		if blankRecord(fields) {
+			continue
+		}
+		t.rows = append(t.rows, billRecord{line: header + line, fields: fields})
+	}
+	return t, nil
+}
+
+// field returns the value of the first of the named columns that the statement has,
+// exports of different years name some columns differently
+func (t *billTable) field(record billRecord, names ...string) string {
+	for _, name := range names {
+		if i, ok := t.columns[name]; ok {
+			if i >= len(record.fields) {
+				return ""
+			}
+			return strings.TrimSpace(record.fields[i])
+		}
+	}
+	return ""
+}
+
+// require checks that a statement has the named columns
+func (t *billTable) require(name string, columns ...[]string) error {
+	for _, names := range columns {
+		found := false
+		for _, n := range names {
+			if _, ok := t.columns[n]; ok {
+				found = true
+			}
+		}
+		if !found {
+			return invalidImport("not a %s bill, the column %s is missing", name, names[0])
+		}
+	}
+	return nil
+}
+
+// billDesc joins the counterparty and the product of a bill row, leaving out placeholders and repeats
+func billDesc(parts ...string) string {
+	var desc []string
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" || part == "/" || part == "-" {
+			continue
+		}
+		if len(desc) > 0 && strings.Contains(desc[len(desc)-1], part) {
+			continue
+		}
+		desc = append(desc, part)
+	}
+	return strings.Join(desc, " ")
+}
+
+// merchantCategories are the keywords of merchants and products that GuessCategory looks for,
+// the first category with a matching keyword wins
+var merchantCategories = []struct {
+	category v1.Category
+	keywords []string
+}{
+	{v1.Category_Utility, []string{"电费", "水费", "燃气", "国家电网", "话费", "中国移动", "中国联通", "中国电信", "宽带", "充值缴费"}},
+	{v1.Category_House, []string{"房租", "租金", "物业", "自如", "链家", "贝壳"}},
+	{v1.Category_Snacks, []string{"零食", "奶茶", "喜茶", "蜜雪冰城", "茶百道", "良品铺子", "三只松鼠"}},
+	{v1.Category_Food, []string{"餐", "饭", "面馆", "麦当劳", "肯德基", "必胜客", "星巴克", "瑞幸", "咖啡", "饿了么", "美团外卖", "外卖", "食堂", "烧烤", "火锅"}},
+	{v1.Category_Travel, []string{"携程", "去哪儿", "飞猪", "酒店", "航空", "机票", "民宿"}},
+	{v1.Category_Transport, []string{"滴滴", "地铁", "公交", "打车", "出行", "加油", "停车", "铁路", "12306", "哈啰", "高速"}},
+	{v1.Category_Game, []string{"游戏", "steam", "米哈游", "网易游戏", "腾讯游戏"}},
+	{v1.Category_App, []string{"app store", "apple", "爱奇艺", "腾讯视频", "优酷", "网易云音乐", "qq音乐", "哔哩哔哩", "bilibili", "会员"}},
+	{v1.Category_Entertainment, []string{"电影", "影城", "影院", "猫眼", "大麦", "演出", "ktv", "健身"}},
+	{v1.Category_Health, []string{"医院", "药房", "药店", "大药房", "诊所", "体检", "医疗"}},
+	{v1.Category_Education, []string{"学费", "课程", "培训", "书店", "教育", "得到", "知乎"}},
+	{v1.Category_Gift, []string{"红包", "礼物", "鲜花"}},
+	{v1.Category_Investment, []string{"基金", "理财", "股票", "证券"}},
+	{v1.Category_Loan, []string{"借款", "还款", "花呗", "借呗", "白条"}},
+	{v1.Category_Shopping, []string{"淘宝", "天猫", "京东", "拼多多", "超市", "商场", "盒马", "唯品会", "便利店", "优衣库", "宜家"}},
+}
+
+// incomeCategories are the keywords that GuessCategory looks for in income
+var incomeCategories = []struct {
+	category v1.Category
+	keywords []string
+}{
+	{v1.Category_Salary, []string{"工资", "薪资", "奖金"}},
+}
+
+// GuessCategory guesses the built-in category of a transaction from its counterparty and description,
+// income falls back to OtherIncome and expenses to Other
+func GuessCategory(t v1.Type, text ...string) int64 {
+	s := strings.ToLower(strings.Join(text, " "))
+	table, fallback := merchantCategories, v1.Category_Other
+	if t == v1.Type_Income {
+		table, fallback = incomeCategories, v1.Category_OtherIncome
+	}
+	for _, c := range table {
+		for _, keyword := range c.keywords {
+			if strings.Contains(s, keyword) {
+				return int64(c.category)
+			}
+		}
+	}
+	return int64(fallback)
+}
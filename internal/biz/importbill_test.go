@@ -0,0 +1,132 @@
+package biz_test
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	v1 "accounter_go/api/accounter/v1"
+	"accounter_go/internal/biz"
+)
+
+// billRowString is rowString with the reason a row is skipped
+func billRowString(row *biz.ImportRow) string {
+	if row.Skip != "" {
+		return rowString(row) + " skip: " + row.Skip
+	}
+	return rowString(row)
+}
+
+func TestParseBills(t *testing.T) {
+	tests := []struct {
+		name  string
+		file  string
+		parse func(f *os.File) ([]*biz.ImportRow, error)
+		want  []string
+	}{
+		{
+			name:  "alipay",
+			file:  "testdata/alipay.csv",
+			parse: func(f *os.File) ([]*biz.ImportRow, error) { return biz.ParseAlipay(f, 3) },
+			want: []string{
+				"18: 2025-06-28 Income 99.00 CNY 13 某某数码专营店 退款-蓝牙耳机 skip: refund, deducted from the purchase on line 24",
+				"19: 2025-06-25 Expense 35.50 CNY 2 某某餐厅 午餐",
+				"20: 2025-06-24 None 1000.00 CNY 10 余额宝 余额宝-自动转入 skip: neither income nor expense, such as a transfer between own accounts",
+				"21: 2025-06-22 Expense 88.20 CNY 6 某某超市 超市购物",
+				"22: 2025-06-21 Income 59.00 CNY 13 某某数码专营店 退款-手机壳 skip: refund of the order on line 23, which isn't imported",
+				"23: 2025-06-21 Expense 59.00 CNY 6 某某数码专营店 手机壳 skip: closed order, not paid or refunded in full",
+				"24: 2025-06-20 Expense 200.00 CNY 6 某某数码专营店 蓝牙耳机",
+				"25: 2025-06-15 Income 8000.00 CNY 12 某某科技有限公司 6月工资",
+				"26: 2025-06-12 Expense 23.00 CNY 8 滴滴出行 快车订单",
+				"27: 2025-06-10 None 99.00 CNY 11 花呗 花呗主动还款-2025年05月账单 skip: neither income nor expense, such as a transfer between own accounts",
+				"28: 2025-06-08 Expense 28.00 CNY 18 某某奶茶店 奶茶两杯",
+			},
+		},
+		{
+			name:  "wechat",
+			file:  "testdata/wechat.csv",
+			parse: func(f *os.File) ([]*biz.ImportRow, error) { return biz.ParseWeChat(f, 0) },
+			want: []string{
+				"17: 2025-06-29 Income 15.00 CNY 13 某某外卖 skip: refund, deducted from the purchase",
+				"18: 2025-06-29 Expense 30.00 CNY 2 某某外卖 外卖订单",
+				"19: 2025-06-27 Expense 18.00 CNY 2 某某咖啡 拿铁",
+				"20: 2025-06-26 Expense 66.00 CNY 17 某某",
+				"21: 2025-06-25 Income 88.00 CNY 13 某某",
+				"22: 2025-06-24 None 500.00 CNY 7 招商银行(0000) skip: neither income nor expense, such as a transfer between own accounts",
+				"23: 2025-06-20 Income 85.00 CNY 13 某某影城 skip: refund, deducted from the purchase",
+				"24: 2025-06-20 Expense 85.00 CNY 9 某某影城 电影票 skip: refunded in full",
+				"25: 2025-06-18 Expense 3000.00 CNY 15 某某 转账备注:六月房租",
+				"26: 2025-06-15 Expense 12.50 CNY 6 某某便利店 skip: failed or closed payment",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := os.Open(tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			rows, err := tt.parse(f)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			got := make([]string, 0, len(rows))
+			for _, row := range rows {
+				got = append(got, billRowString(row))
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestImportAlipay(t *testing.T) {
+	uc, _ := newTestUseCase(t)
+	ctx := context.Background()
+	f, err := os.Open("testdata/alipay.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	result, err := uc.ImportAlipay(ctx, 1, f, 0, biz.ImportOptions{})
+	if err != nil || result.Imported != 6 || result.Skipped != 5 || result.Invalid != 0 || result.Duplicates != 0 {
+		t.Fatalf("ImportAlipay = %+v, %v", result, err)
+	}
+
+	// A WeChat statement isn't an Alipay statement
+	wechat, err := os.Open("testdata/wechat.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wechat.Close()
+	if _, err := uc.ImportAlipay(ctx, 1, wechat, 0, biz.ImportOptions{DryRun: true}); !biz.ErrInvalidImport.Is(err) {
+		t.Errorf("ImportAlipay of a WeChat statement = %v, want ErrInvalidImport", err)
+	}
+	if _, err := biz.ParseWeChat(strings.NewReader("日期,金额\n2025-06-01,1\n"), 0); !biz.ErrInvalidImport.Is(err) {
+		t.Errorf("ParseWeChat of another file = %v, want ErrInvalidImport", err)
+	}
+}
+
+func TestGuessCategory(t *testing.T) {
+	tests := []struct {
+		kind v1.Type
+		text []string
+		want v1.Category
+	}{
+		{v1.Type_Expense, []string{"美团外卖", "午餐"}, v1.Category_Food},
+		{v1.Type_Expense, []string{"Apple", "iCloud 50GB"}, v1.Category_App},
+		{v1.Type_Expense, []string{"国家电网", "电费"}, v1.Category_Utility},
+		{v1.Type_Expense, []string{"某某", "/"}, v1.Category_Other},
+		{v1.Type_Income, []string{"某某公司", "五月工资"}, v1.Category_Salary},
+		{v1.Type_Income, []string{"某某餐厅", "退款"}, v1.Category_OtherIncome},
+	}
+	for _, tt := range tests {
+		if got := biz.GuessCategory(tt.kind, tt.text...); got != int64(tt.want) {
+			t.Errorf("GuessCategory(%v, %v) = %d, want %d", tt.kind, tt.text, got, tt.want)
+		}
+	}
+}
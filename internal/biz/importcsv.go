@@ -0,0 +1,398 @@
+package biz
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	v1 "accounter_go/api/accounter/v1"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+// Amount sign conventions of CSVMapping.AmountSign
+const (
+	AmountSignNegativeExpense = "negative_expense" // negative amounts are expenses, the default
+	AmountSignPositiveExpense = "positive_expense" // positive amounts are expenses, as on credit card statements
+	AmountSignExpense         = "expense"          // every row is an expense, the sign is ignored
+	AmountSignIncome          = "income"           // every row is income, the sign is ignored
+)
+
+// CSVMapping says how the columns of a CSV file become transactions.
+// Columns are header names, or column numbers starting at 1 for files without a header.
+//
+// The type of a row comes from TypeColumn when it is set, otherwise from the sign of AmountColumn
+// following AmountSign, or from which of IncomeColumn and ExpenseColumn holds the amount.
+type CSVMapping struct {
+	Delimiter string // a single character, "," by default
+	NoHeader  bool
+	SkipLines int // lines before the header or the first row, such as the title of a statement
+
+	DateColumn string
+	// DateFormat is a layout such as YYYY-MM-DD, DD/MM/YYYY or YYYY-MM-DD HH:mm:ss,
+	// common date and date time formats are recognized when it is empty
+	DateFormat string
+
+	AmountColumn  string
+	AmountSign    string
+	IncomeColumn  string
+	ExpenseColumn string
+
+	TypeColumn    string
+	IncomeValues  []string // values of TypeColumn for income
+	ExpenseValues []string // values of TypeColumn for expenses, every other value when empty
+
+	DescColumns []string // joined with a space when there are several
+
+	CategoryColumn string
+	// Categories maps text of CategoryColumn to category IDs, text that isn't mapped matches
+	// the category of the user with that name, or falls back to DefaultCategoryID
+	Categories        map[string]int64
+	DefaultCategoryID int64
+
+	CurrencyColumn string
+	Currency       string // currency of all rows without CurrencyColumn, the currency of the account when empty
+	AccountID      int64
+}
+
+// ImportCSV parses a CSV file with a mapping and imports its rows, see Import
+func (uc *AccounterUseCase) ImportCSV(ctx context.Context, userID int64, r io.Reader, m *CSVMapping, opts ImportOptions) (*ImportResult, error) {
+	rows, err := ParseCSV(r, m)
+	if err != nil {
+		return nil, err
+	}
+	return uc.Import(ctx, userID, rows, opts)
+}
+
+// csvColumns holds the positions of the mapped columns, -1 for columns that aren't mapped
+type csvColumns struct {
+	date, amount, income, expense, kind, category, currency int
+	desc                                                    []int
+}
+
+// ParseCSV parses the rows of a CSV file, rows that can't be parsed are returned with an error.
+// It fails with ErrInvalidImport when the file or the mapping can't be used at all.
+func ParseCSV(r io.Reader, m *CSVMapping) ([]*ImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	if m.Delimiter != "" {
+		delimiter, size := utf8.DecodeRuneInString(m.Delimiter)
+		if m.Delimiter == `\t` {
+			delimiter, size = '\t', len(m.Delimiter)
+		}
+		if size != len(m.Delimiter) {
+			return nil, invalidImport("the delimiter must be a single character")
+		}
+		reader.Comma = delimiter
+	}
+	// Trimming a tab delimited file would also drop its empty fields, fields are trimmed after reading anyway
+	reader.TrimLeadingSpace = !unicode.IsSpace(reader.Comma)
+
+	for i := 0; i < m.SkipLines; i++ {
+		if _, err := reader.Read(); err != nil {
+			return nil, invalidImport("the file has fewer than %d lines: %v", m.SkipLines, err)
+		}
+	}
+	var header []string
+	if !m.NoHeader {
+		var err error
+		if header, err = reader.Read(); err != nil {
+			return nil, invalidImport("the file has no header: %v", err)
+		}
+		if len(header) > 0 {
+			header[0] = strings.TrimPrefix(header[0], "\ufeff")
+		}
+	}
+	columns, err := m.columns(header)
+	if err != nil {
+		return nil, err
+	}
+	layouts, err := dateLayouts(m.DateFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []*ImportRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, invalidImport("reading the file: %v", err)
+			}
+			rows = append(rows, &ImportRow{Line: parseErr.Line, Error: parseErr.Err.Error()})
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+		if blankRecord(record) {
+			continue
+		}
+		row := &ImportRow{Line: line}
+		row.Accounter, row.Category, err = m.parseRecord(record, columns, layouts)
+		if err != nil {
+			row.Accounter = nil
+			row.Error = errorMessage(err)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// columns finds the mapped columns in the header
+func (m *CSVMapping) columns(header []string) (*csvColumns, error) {
+	find := func(name string) (int, error) {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return -1, nil
+		}
+		for i, h := range header {
+			if strings.TrimSpace(h) == name {
+				return i, nil
+			}
+		}
+		if n, err := strconv.Atoi(name); err == nil && n >= 1 {
+			return n - 1, nil
+		}
+		if header == nil {
+			return 0, invalidImport("column %q must be a number, the file has no header", name)
+		}
+		return 0, invalidImport("column %q is not in the header", name)
+	}
+
+	c := &csvColumns{}
+	var err error
+	for _, col := range []struct {
+		name string
+		pos  *int
+	}{
+		{m.DateColumn, &c.date}, {m.AmountColumn, &c.amount}, {m.IncomeColumn, &c.income}, {m.ExpenseColumn, &c.expense},
+		{m.TypeColumn, &c.kind}, {m.CategoryColumn, &c.category}, {m.CurrencyColumn, &c.currency},
+	} {
+		if *col.pos, err = find(col.name); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range m.DescColumns {
+		pos, err := find(name)
+		if err != nil {
+			return nil, err
+		}
+		if pos >= 0 {
+			c.desc = append(c.desc, pos)
+		}
+	}
+
+	switch {
+	case c.date < 0:
+		return nil, invalidImport("the mapping needs a date column")
+	case c.amount < 0 && c.income < 0 && c.expense < 0:
+		return nil, invalidImport("the mapping needs an amount column, or income and expense columns")
+	case c.amount >= 0 && (c.income >= 0 || c.expense >= 0):
+		return nil, invalidImport("the mapping has an amount column and income or expense columns, use one or the other")
+	}
+	switch m.AmountSign {
+	case "", AmountSignNegativeExpense, AmountSignPositiveExpense, AmountSignExpense, AmountSignIncome:
+	default:
+		return nil, invalidImport("unknown amount sign %q", m.AmountSign)
+	}
+	return c, nil
+}
+
+// parseRecord converts a CSV record to a transaction and the category text that the mapping didn't resolve
+func (m *CSVMapping) parseRecord(record []string, c *csvColumns, layouts []string) (*Accounter, string, error) {
+	field := func(pos int) string {
+		if pos < 0 || pos >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[pos])
+	}
+	if c.date >= len(record) {
+		return nil, "", invalidImport("the row has %d columns", len(record))
+	}
+
+	date, err := parseImportDate(field(c.date), layouts)
+	if err != nil {
+		return nil, "", err
+	}
+	a := &Accounter{Date: date, Currency: m.Currency, AccountID: m.AccountID, CategoryID: m.DefaultCategoryID}
+
+	var amount Money
+	if c.amount >= 0 {
+		if amount, err = parseImportAmount(field(c.amount)); err != nil {
+			return nil, "", err
+		}
+		switch {
+		case m.AmountSign == AmountSignExpense:
+			a.Type = v1.Type_Expense
+		case m.AmountSign == AmountSignIncome:
+			a.Type = v1.Type_Income
+		case (amount < 0) == (m.AmountSign == AmountSignPositiveExpense):
+			a.Type = v1.Type_Income
+		default:
+			a.Type = v1.Type_Expense
+		}
+	} else {
+		income, expense := field(c.income), field(c.expense)
+		if (income == "") == (expense == "") {
+			return nil, "", invalidImport("the row needs an amount in either the income or the expense column")
+		}
+		a.Type = v1.Type_Income
+		if expense != "" {
+			a.Type = v1.Type_Expense
+			income = expense
+		}
+		if amount, err = parseImportAmount(income); err != nil {
+			return nil, "", err
+		}
+	}
+	if amount < 0 {
+		amount = -amount
+	}
+	a.Amount = amount
+
+	if c.kind >= 0 {
+		if a.Type, err = m.parseType(field(c.kind)); err != nil {
+			return nil, "", err
+		}
+	}
+
+	descs := make([]string, 0, len(c.desc))
+	for _, pos := range c.desc {
+		if d := field(pos); d != "" {
+			descs = append(descs, d)
+		}
+	}
+	a.Desc = strings.Join(descs, " ")
+
+	if code := field(c.currency); code != "" {
+		a.Currency = code
+	}
+
+	category := field(c.category)
+	if id, ok := m.Categories[category]; ok && category != "" {
+		a.CategoryID = id
+		category = ""
+	}
+	return a, category, nil
+}
+
+// parseType maps a value of TypeColumn to income or expense
+func (m *CSVMapping) parseType(value string) (v1.Type, error) {
+	for _, v := range m.IncomeValues {
+		if strings.EqualFold(strings.TrimSpace(v), value) {
+			return v1.Type_Income, nil
+		}
+	}
+	if len(m.ExpenseValues) == 0 {
+		return v1.Type_Expense, nil
+	}
+	for _, v := range m.ExpenseValues {
+		if strings.EqualFold(strings.TrimSpace(v), value) {
+			return v1.Type_Expense, nil
+		}
+	}
+	return 0, invalidImport("type %q is neither an income nor an expense value", value)
+}
+
+func blankRecord(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// defaultDateLayouts are tried in order when a mapping has no date format
+var defaultDateLayouts = func() []string {
+	var layouts []string
+	for _, date := range []string{"2006-01-02", "2006/01/02", "2006.01.02", "2006-1-2", "2006/1/2", "2006年1月2日", "20060102"} {
+		for _, clock := range []string{"", " 15:04:05", " 15:04"} {
+			layouts = append(layouts, date+clock)
+		}
+	}
+	return append(layouts, time.RFC3339)
+}()
+
+// dateFormatTokens translates the tokens of CSVMapping.DateFormat to Go layouts, longer tokens first
+var dateFormatTokens = []struct{ token, layout string }{
+	{"YYYY", "2006"}, {"YY", "06"}, {"MM", "01"}, {"M", "1"}, {"DD", "02"}, {"D", "2"},
+	{"HH", "15"}, {"mm", "04"}, {"ss", "05"},
+}
+
+// dateLayouts returns the Go layouts of a date format such as DD/MM/YYYY
+func dateLayouts(format string) ([]string, error) {
+	if format == "" {
+		return defaultDateLayouts, nil
+	}
+	var layout strings.Builder
+	hasYear, hasMonth, hasDay := false, false, false
+	for rest := format; rest != ""; {
+		matched := false
+		for _, t := range dateFormatTokens {
+			if strings.HasPrefix(rest, t.token) {
+				layout.WriteString(t.layout)
+				rest = rest[len(t.token):]
+				hasYear = hasYear || t.token[0] == 'Y'
+				hasMonth = hasMonth || t.token[0] == 'M'
+				hasDay = hasDay || t.token[0] == 'D'
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			r, size := utf8.DecodeRuneInString(rest)
+			if strings.ContainsRune("0123456789", r) {
+				return nil, invalidImport("date format %q has a digit, use YYYY, MM, DD, HH, mm and ss", format)
+			}
+			layout.WriteString(rest[:size])
+			rest = rest[size:]
+		}
+	}
+	if !hasYear || !hasMonth || !hasDay {
+		return nil, invalidImport("date format %q needs a year, a month and a day", format)
+	}
+	return []string{layout.String()}, nil
+}
+
+// parseImportDate parses a date with the first layout that fits, the time of day is dropped
+func parseImportDate(s string, layouts []string) (time.Time, error) {
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return importDay(t), nil
+		}
+	}
+	return time.Time{}, invalidImport("%q is not a date", s)
+}
+
+// amountNoise is the text around amounts in exported statements
+var amountNoise = strings.NewReplacer(",", "", " ", "", "¥", "", "￥", "", "$", "", "€", "", "£", "", "元", "")
+
+// parseImportAmount parses an amount such as -1,234.50, ¥12.00 or (30.00) for -30
+func parseImportAmount(s string) (Money, error) {
+	clean := amountNoise.Replace(strings.TrimSpace(s))
+	negative := strings.HasPrefix(clean, "(") && strings.HasSuffix(clean, ")")
+	if negative {
+		clean = clean[1 : len(clean)-1]
+	}
+	if clean == "" {
+		return 0, invalidImport("the row has no amount")
+	}
+	amount, err := ParseMoney(clean)
+	if err != nil {
+		return 0, invalidImport("%q is not an amount", s)
+	}
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
@@ -0,0 +1,390 @@
+package biz
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strings"
+	"time"
+	"unicode"
+
+	v1 "accounter_go/api/accounter/v1"
+)
+
+// journalDirectives are the dated beancount entries that aren't transactions
+var journalDirectives = map[string]bool{
+	"open": true, "close": true, "commodity": true, "balance": true, "pad": true, "note": true,
+	"document": true, "price": true, "event": true, "query": true, "custom": true,
+}
+
+// journalSymbols are the currency symbols of ledger amounts such as ¥25.00
+var journalSymbols = []struct{ symbol, currency string }{
+	{"¥", "CNY"}, {"￥", "CNY"}, {"$", "USD"}, {"€", "EUR"}, {"£", "GBP"},
+}
+
+// journalEntry is a transaction of a journal being parsed
+type journalEntry struct {
+	line     int
+	date     string
+	desc     string
+	postings []*journalLine
+	err      string
+}
+
+// journalLine is a posting of a journal transaction, without an amount the posting balances the others
+type journalLine struct {
+	account   string
+	amount    Money
+	currency  string
+	hasAmount bool
+}
+
+// ImportJournal imports the transactions of a beancount or ledger journal, see parseJournal and Import.
+// The journal accounts are mapped back to categories and accounts like Export maps them with mapping.
+func (uc *AccounterUseCase) ImportJournal(ctx context.Context, userID int64, r io.Reader, mapping *JournalMapping, opts ImportOptions) (*ImportResult, error) {
+	categories, err := uc.categories.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := uc.accounts.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	j, err := newJournalAccounts(categories, accounts, mapping)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := parseJournal(r, j)
+	if err != nil {
+		return nil, err
+	}
+	return uc.Import(ctx, userID, rows, opts)
+}
+
+// parseJournal reads the transactions of a beancount or ledger journal, other directives are ignored.
+// A transaction between an account and Expenses or Income accounts is an expense or income per category,
+// one between two accounts is a transfer with the fee posted to Expenses:Fees.
+// Entries against Equity, such as opening balances, are skipped.
+func parseJournal(r io.Reader, j *journalAccounts) ([]*ImportRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var rows []*ImportRow
+	var entry *journalEntry
+	finish := func() {
+		if entry != nil {
+			rows = append(rows, j.rows(entry)...)
+			entry = nil
+		}
+	}
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if line == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+		switch {
+		case strings.TrimSpace(text) == "":
+			finish()
+		case text[0] == ' ' || text[0] == '\t':
+			if entry != nil && entry.err == "" {
+				entry.addPosting(strings.TrimSpace(text))
+			}
+		default:
+			finish()
+			entry = parseJournalHeader(line, text)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, invalidImport("reading the journal: %v", err)
+	}
+	finish()
+	return rows, nil
+}
+
+// parseJournalHeader parses the first line of a transaction, it returns nil for other lines
+func parseJournalHeader(line int, text string) *journalEntry {
+	if text[0] < '0' || text[0] > '9' {
+		return nil // options, comments, account declarations, prices and periodic transactions
+	}
+	date, rest := text, ""
+	if i := strings.IndexFunc(text, unicode.IsSpace); i >= 0 {
+		date, rest = text[:i], strings.TrimSpace(text[i:])
+	}
+	keyword, _, _ := strings.Cut(rest, " ")
+	if journalDirectives[keyword] {
+		return nil
+	}
+	entry := &journalEntry{line: line}
+	date, _, _ = strings.Cut(date, "=") // the auxiliary date of ledger
+	day, err := parseImportDate(date, []string{"2006-01-02", "2006/01/02"})
+	if err != nil {
+		entry.err = errorMessage(err)
+		return entry
+	}
+	entry.date = day.Format("2006-01-02")
+
+	if keyword == "txn" || keyword == "*" || keyword == "!" {
+		rest = strings.TrimSpace(strings.TrimPrefix(rest, keyword))
+	} else if strings.HasPrefix(rest, "*") || strings.HasPrefix(rest, "!") {
+		rest = strings.TrimSpace(rest[1:])
+	}
+	if strings.HasPrefix(rest, `"`) {
+		// beancount: the payee and the narration, followed by tags and links
+		var parts []string
+		for strings.HasPrefix(rest, `"`) {
+			var part string
+			part, rest = readJournalString(rest)
+			if part != "" {
+				parts = append(parts, part)
+			}
+			rest = strings.TrimSpace(rest)
+		}
+		entry.desc = strings.Join(parts, " ")
+		return entry
+	}
+	// ledger: an optional (code), the payee and a note after two spaces or a tab
+	if strings.HasPrefix(rest, "(") {
+		if i := strings.Index(rest, ")"); i >= 0 {
+			rest = rest[i+1:]
+		}
+	}
+	if i := journalNote(rest); i >= 0 {
+		rest = rest[:i]
+	}
+	entry.desc = strings.TrimSpace(rest)
+	return entry
+}
+
+// readJournalString reads a beancount string at the start of s, it returns the string and the rest of s
+func readJournalString(s string) (string, string) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				b.WriteByte(s[i])
+			}
+		case '"':
+			return b.String(), s[i+1:]
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), ""
+}
+
+// journalNote returns the index of the note of a ledger line, a semicolon after two spaces or a tab
+func journalNote(s string) int {
+	for i := 1; i < len(s); i++ {
+		if s[i] == ';' && (s[i-1] == '\t' || i >= 2 && s[i-2:i] == "  ") {
+			return i
+		}
+	}
+	return -1
+}
+
+// addPosting parses an indented line of a transaction, which is a posting, metadata or a comment
+func (e *journalEntry) addPosting(text string) {
+	text, _, _ = strings.Cut(text, ";")
+	if len(text) > 1 && (text[0] == '*' || text[0] == '!') && text[1] == ' ' {
+		text = strings.TrimSpace(text[1:]) // the flag of a beancount posting
+	}
+	if text == "" || text[0] == '#' {
+		return
+	}
+	// ledger accounts may contain single spaces, their amounts follow two spaces or a tab,
+	// beancount accounts are followed by any space
+	account, amount := text, ""
+	i := strings.IndexByte(text, '\t')
+	if k := strings.Index(text, "  "); k >= 0 && (i < 0 || k < i) {
+		i = k
+	}
+	if i < 0 {
+		i = strings.IndexFunc(text, unicode.IsSpace)
+	}
+	if i >= 0 {
+		account, amount = text[:i], strings.TrimSpace(text[i:])
+	}
+	account = strings.Trim(account, "()[]") // virtual postings of ledger
+	if !hasJournalRoot(account) {
+		if first, _, _ := strings.Cut(account, ":"); first != "" && unicode.IsLower(rune(first[0])) {
+			return // beancount metadata such as id: "12"
+		}
+		e.err = "unknown account " + account + ", accounts start with Assets, Liabilities, Equity, Income or Expenses"
+		return
+	}
+
+	p := &journalLine{account: account}
+	e.postings = append(e.postings, p)
+	// the price or cost of the amount and ledger balance assertions follow it
+	if i := strings.IndexAny(amount, "@{="); i >= 0 {
+		amount = strings.TrimSpace(amount[:i])
+	}
+	if amount == "" {
+		return
+	}
+	var number []string
+	for _, field := range strings.Fields(amount) {
+		if isJournalCommodity(field) {
+			p.currency = field
+		} else {
+			number = append(number, field)
+		}
+	}
+	for _, s := range journalSymbols {
+		if strings.Contains(amount, s.symbol) {
+			p.currency = s.currency
+		}
+	}
+	m, err := parseImportAmount(strings.Join(number, ""))
+	if err != nil {
+		e.err = errorMessage(err)
+		return
+	}
+	p.amount, p.hasAmount = m, true
+}
+
+// isJournalCommodity reports whether s is a commodity such as CNY
+func isJournalCommodity(s string) bool {
+	if s == "" || s[0] < 'A' || s[0] > 'Z' {
+		return false
+	}
+	for _, r := range s {
+		if !(r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("'._-", r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// rows turns a journal transaction into the rows of its transactions
+func (j *journalAccounts) rows(e *journalEntry) []*ImportRow {
+	invalid := func(msg string) []*ImportRow {
+		return []*ImportRow{{Line: e.line, Error: msg}}
+	}
+	if e.err != "" {
+		return invalid(e.err)
+	}
+	date, _ := parseImportDate(e.date, []string{"2006-01-02"})
+
+	// a posting without an amount balances the others
+	var elided *journalLine
+	balance := make(map[string]Money)
+	for _, p := range e.postings {
+		if !p.hasAmount {
+			if elided != nil {
+				return invalid("more than one posting has no amount")
+			}
+			elided = p
+			continue
+		}
+		balance[p.currency] += p.amount
+	}
+	if elided != nil {
+		if len(balance) != 1 {
+			return invalid("a posting without an amount can only balance postings of one currency")
+		}
+		for currency, sum := range balance {
+			elided.amount, elided.currency, elided.hasAmount = -sum, currency, true
+		}
+	}
+
+	var flows, fees []*journalLine
+	money := make(map[string][]*journalLine)
+	var order []string // accounts of money in the order of the postings
+	for _, p := range e.postings {
+		root, _, _ := strings.Cut(p.account, ":")
+		switch {
+		case root == "Equity":
+			return []*ImportRow{{Line: e.line, Skip: "opening balance or another entry against equity"}}
+		case p.account == journalFeesAccount:
+			fees = append(fees, p)
+		case root == "Expenses" || root == "Income":
+			flows = append(flows, p)
+		default:
+			if _, ok := money[p.account]; !ok {
+				order = append(order, p.account)
+			}
+			money[p.account] = append(money[p.account], p)
+		}
+	}
+
+	if len(flows) == 0 && len(money) == 2 {
+		return j.transferRows(e, date, money, order, fees)
+	}
+	flows = append(flows, fees...)
+	if len(flows) == 0 || len(money) != 1 {
+		return invalid("a transaction is between one account and categories, or a transfer between two accounts")
+	}
+	accountID, ok := j.accountIDs[order[0]]
+	if !ok {
+		return invalid("unknown account " + order[0] + ", map it to one of your accounts")
+	}
+
+	var rows []*ImportRow
+	for _, p := range flows {
+		row := &ImportRow{Line: e.line}
+		rows = append(rows, row)
+		a := &Accounter{Date: date, Desc: e.desc, Currency: p.currency, AccountID: accountID}
+		switch root, _, _ := strings.Cut(p.account, ":"); {
+		case root == "Expenses" && p.amount > 0:
+			a.Type, a.Amount = v1.Type_Expense, p.amount
+		case root == "Income" && p.amount < 0:
+			a.Type, a.Amount = v1.Type_Income, -p.amount
+		default:
+			row.Error = "a posting that reverses " + p.account + ", such as a refund, can't be imported"
+			continue
+		}
+		if id, ok := j.categoryIDs[p.account]; ok {
+			a.CategoryID = id
+		} else {
+			row.Category = p.account[strings.LastIndex(p.account, ":")+1:]
+		}
+		row.Accounter = a
+	}
+	return rows
+}
+
+// transferRows turns a transaction between two accounts into a transfer
+func (j *journalAccounts) transferRows(e *journalEntry, date time.Time, money map[string][]*journalLine, order []string, fees []*journalLine) []*ImportRow {
+	invalid := []*ImportRow{{Line: e.line, Error: "a transfer takes money from one account and adds it to another"}}
+	sums := make(map[string]Money)
+	for account, postings := range money {
+		for _, p := range postings {
+			if p.currency != postings[0].currency {
+				return invalid
+			}
+			sums[account] += p.amount
+		}
+	}
+	from, to := order[0], order[1]
+	if sums[from] > 0 {
+		from, to = to, from
+	}
+	if sums[from] >= 0 || sums[to] <= 0 {
+		return invalid
+	}
+	a := &Accounter{
+		Type:       v1.Type_Transfer,
+		Date:       date,
+		Desc:       e.desc,
+		Amount:     -sums[from],
+		Currency:   money[from][0].currency,
+		ToAmount:   sums[to],
+		ToCurrency: money[to][0].currency,
+	}
+	for _, p := range fees {
+		if p.currency != a.Currency {
+			return []*ImportRow{{Line: e.line, Error: "the fee of a transfer is in the currency of the account it comes from"}}
+		}
+		a.Fee += p.amount
+	}
+	a.Amount -= a.Fee
+	for _, account := range []string{from, to} {
+		if _, ok := j.accountIDs[account]; !ok {
+			return []*ImportRow{{Line: e.line, Error: "unknown account " + account + ", map it to one of your accounts"}}
+		}
+	}
+	a.AccountID, a.ToAccountID = j.accountIDs[from], j.accountIDs[to]
+	return []*ImportRow{{Line: e.line, Accounter: a}}
+}
@@ -0,0 +1,170 @@
+package biz
+
+import (
+	"bytes"
+	"context"
+	"html"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	v1 "accounter_go/api/accounter/v1"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// ImportOFX imports an OFX bank or credit card statement into an account, 0 for none, see ParseOFX and Import
+func (uc *AccounterUseCase) ImportOFX(ctx context.Context, userID int64, r io.Reader, accountID int64, opts ImportOptions) (*ImportResult, error) {
+	rows, err := ParseOFX(r, accountID)
+	if err != nil {
+		return nil, err
+	}
+	return uc.Import(ctx, userID, rows, opts)
+}
+
+// ParseOFX parses the transactions (STMTTRN) of an OFX statement, both the SGML of OFX 1 and the XML of OFX 2.
+// Credits are income and debits expenses, with categories guessed from the payee and the memo.
+// The FITID of a transaction becomes its ExternalID, so importing an overlapping statement
+// only adds the transactions that aren't stored yet.
+func ParseOFX(r io.Reader, accountID int64) ([]*ImportRow, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, invalidImport("reading the file: %v", err)
+	}
+	text, err := decodeStatement(data)
+	if err != nil {
+		return nil, err
+	}
+	start := strings.Index(strings.ToUpper(text), "<OFX>")
+	if start < 0 {
+		return nil, invalidImport("not an OFX statement, there is no <OFX> element")
+	}
+
+	// SGML leaves have no end tags, so only elements without a value, the aggregates, go on the stack
+	var stack []string
+	var rows []*ImportRow
+	var trn map[string]string // fields of the transaction being read
+	currency, line, trnLine := "", 1+strings.Count(text[:start], "\n"), 0
+	finish := func() {
+		if trn != nil {
+			rows = append(rows, ofxRow(trnLine, trn, currency, accountID))
+			trn = nil
+		}
+	}
+	for pos := start; pos < len(text); {
+		open := strings.IndexByte(text[pos:], '<')
+		if open < 0 {
+			break
+		}
+		line += strings.Count(text[pos:pos+open], "\n")
+		pos += open
+		end := strings.IndexByte(text[pos:], '>')
+		if end < 0 {
+			break
+		}
+		tag := strings.ToUpper(strings.TrimSpace(text[pos+1 : pos+end]))
+		pos += end + 1
+		next := strings.IndexByte(text[pos:], '<')
+		if next < 0 {
+			next = len(text) - pos
+		}
+		value := strings.TrimSpace(html.UnescapeString(text[pos : pos+next]))
+
+		switch {
+		case tag == "" || tag[0] == '?' || tag[0] == '!':
+			// XML declarations, processing instructions and comments
+		case tag[0] == '/':
+			name := tag[1:]
+			for i := len(stack) - 1; i >= 0; i-- {
+				if stack[i] == name {
+					stack = stack[:i]
+					if name == "STMTTRN" {
+						finish()
+					}
+					break
+				}
+			}
+		case value == "":
+			if tag == "STMTTRN" {
+				finish()
+				trn, trnLine = make(map[string]string), line
+			}
+			stack = append(stack, tag)
+		case tag == "CURDEF":
+			currency = value
+		case trn != nil:
+			if tag == "CURSYM" && len(stack) > 0 {
+				tag = stack[len(stack)-1] // the currency of the amount or, for ORIGCURRENCY, the one it was converted from
+			}
+			if _, ok := trn[tag]; !ok {
+				trn[tag] = value
+			}
+		}
+	}
+	finish()
+	if len(rows) == 0 {
+		return nil, invalidImport("the OFX statement has no transactions")
+	}
+	return rows, nil
+}
+
+// decodeStatement returns the text of a bank statement in UTF-8. Statements that aren't UTF-8
+// are GBK, unless the header of an OFX 1 statement declares Windows-1252.
+func decodeStatement(data []byte) (string, error) {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	if utf8.Valid(data) {
+		return string(data), nil
+	}
+	header := data
+	if i := bytes.IndexByte(data, '<'); i >= 0 {
+		header = data[:i]
+	}
+	decoder := simplifiedchinese.GBK.NewDecoder()
+	if charset := strings.ToUpper(string(header)); strings.Contains(charset, "CHARSET:1252") || strings.Contains(charset, "CHARSET:ISO-8859-1") {
+		decoder = charmap.Windows1252.NewDecoder()
+	}
+	text, err := decoder.Bytes(data)
+	if err != nil {
+		return "", invalidImport("the file is neither UTF-8 nor in the charset of its header")
+	}
+	return string(text), nil
+}
+
+// ofxRow turns the fields of a STMTTRN into a row
+func ofxRow(line int, fields map[string]string, currency string, accountID int64) *ImportRow {
+	row := &ImportRow{Line: line}
+	posted := fields["DTPOSTED"]
+	if posted == "" {
+		posted = fields["DTUSER"]
+	}
+	if len(posted) > 8 {
+		posted = posted[:8] // the time and the time zone
+	}
+	date, err := parseImportDate(posted, []string{"20060102"})
+	if err != nil {
+		row.Error = errorMessage(err)
+		return row
+	}
+	amount, err := parseImportAmount(fields["TRNAMT"])
+	if err != nil {
+		row.Error = errorMessage(err)
+		return row
+	}
+	if c := fields["CURRENCY"]; c != "" {
+		currency = c
+	}
+
+	a := &Accounter{Date: date, Amount: amount, Currency: currency, AccountID: accountID, ExternalID: fields["FITID"], Desc: billDesc(fields["NAME"], fields["MEMO"])}
+	row.Accounter = a
+	switch {
+	case amount > 0:
+		a.Type = v1.Type_Income
+	case amount < 0:
+		a.Type, a.Amount = v1.Type_Expense, -amount
+	default:
+		row.Skip = "zero amount"
+	}
+	a.CategoryID = GuessCategory(a.Type, a.Desc)
+	return row
+}
@@ -0,0 +1,167 @@
+package biz
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"strings"
+
+	v1 "accounter_go/api/accounter/v1"
+)
+
+// qifTypes are the QIF account types whose transactions are imported,
+// investment accounts and lists such as !Type:Cat are skipped
+var qifTypes = map[string]bool{"bank": true, "cash": true, "ccard": true, "oth a": true, "oth l": true}
+
+// qifRecord is a transaction of a QIF file, the fields by their code letter
+type qifRecord struct {
+	line   int
+	fields map[byte]string
+}
+
+// ImportQIF imports a QIF statement into an account, 0 for none, see ParseQIF and Import
+func (uc *AccounterUseCase) ImportQIF(ctx context.Context, userID int64, r io.Reader, accountID int64, opts ImportOptions) (*ImportResult, error) {
+	rows, err := ParseQIF(r, accountID)
+	if err != nil {
+		return nil, err
+	}
+	return uc.Import(ctx, userID, rows, opts)
+}
+
+// ParseQIF parses the transactions of the bank, cash, credit card and other asset or liability
+// accounts of a QIF file. Dates are month first, as Quicken writes them, unless a date of the file
+// only reads day first. Transfers, whose category is an account in brackets, are skipped and other
+// categories are matched by name. QIF has no transaction IDs, so duplicates are found by date,
+// amount and description, and only the total of a split transaction is imported.
+func ParseQIF(r io.Reader, accountID int64) ([]*ImportRow, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, invalidImport("reading the file: %v", err)
+	}
+	text, err := decodeStatement(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []*qifRecord
+	var record *qifRecord
+	found, section := false, false
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if line[0] == '!' {
+			header := strings.ToLower(strings.TrimSpace(line[1:]))
+			if kind, ok := strings.CutPrefix(header, "type:"); ok {
+				found = true
+				section = qifTypes[strings.TrimSpace(kind)]
+			} else if header == "account" {
+				section = false // the account list or the account of the next !Type section
+			}
+			record = nil
+			continue
+		}
+		if !section {
+			continue
+		}
+		if line[0] == '^' {
+			if record != nil {
+				records = append(records, record)
+			}
+			record = nil
+			continue
+		}
+		if record == nil {
+			record = &qifRecord{line: i + 1, fields: make(map[byte]string)}
+		}
+		if _, ok := record.fields[line[0]]; !ok {
+			record.fields[line[0]] = strings.TrimSpace(line[1:])
+		}
+	}
+	if record != nil {
+		records = append(records, record) // the last record may lack its ^
+	}
+	if !found {
+		return nil, invalidImport("not a QIF file, there is no !Type line")
+	}
+	if len(records) == 0 {
+		return nil, invalidImport("the QIF file has no transactions of bank, cash or card accounts")
+	}
+
+	dayFirst := false
+	for _, record := range records {
+		dayFirst = dayFirst || qifDayFirst(record.fields['D'])
+	}
+	layouts := []string{"1/2/2006", "1/2/06", "2006/1/2"}
+	if dayFirst {
+		layouts = []string{"2/1/2006", "2/1/06", "2006/1/2"}
+	}
+
+	rows := make([]*ImportRow, 0, len(records))
+	for _, record := range records {
+		rows = append(rows, qifRow(record, layouts, accountID))
+	}
+	return rows, nil
+}
+
+// qifDate normalizes the separators of a QIF date, such as 6/30'25 or 30.06.2025, to slashes
+func qifDate(s string) string {
+	s = strings.ReplaceAll(s, " ", "")
+	return strings.NewReplacer("'", "/", "-", "/", ".", "/").Replace(s)
+}
+
+// qifDayFirst reports whether a date only reads day first, such as 30/06/2025
+func qifDayFirst(s string) bool {
+	parts := strings.Split(qifDate(s), "/")
+	if len(parts) != 3 || len(parts[0]) > 2 {
+		return false
+	}
+	first, err := strconv.Atoi(parts[0])
+	return err == nil && first > 12
+}
+
+// qifRow turns a QIF record into a row
+func qifRow(record *qifRecord, layouts []string, accountID int64) *ImportRow {
+	row := &ImportRow{Line: record.line}
+	date, err := parseImportDate(qifDate(record.fields['D']), layouts)
+	if err != nil {
+		row.Error = errorMessage(err)
+		return row
+	}
+	total := record.fields['T']
+	if total == "" {
+		total = record.fields['U']
+	}
+	amount, err := parseImportAmount(total)
+	if err != nil {
+		row.Error = errorMessage(err)
+		return row
+	}
+
+	a := &Accounter{Date: date, Amount: amount, AccountID: accountID, Desc: billDesc(record.fields['P'], record.fields['M'])}
+	row.Accounter = a
+	switch {
+	case amount > 0:
+		a.Type = v1.Type_Income
+	case amount < 0:
+		a.Type, a.Amount = v1.Type_Expense, -amount
+	default:
+		row.Skip = "zero amount"
+	}
+	a.CategoryID = GuessCategory(a.Type, a.Desc)
+
+	// categories are Parent:Child, optionally followed by /Class
+	category, _, _ := strings.Cut(record.fields['L'], "/")
+	category = strings.TrimSpace(category)
+	if strings.HasPrefix(category, "[") {
+		if row.Skip == "" {
+			row.Skip = "transfer to or from " + strings.Trim(category, "[]") + ", one of your own accounts"
+		}
+		return row
+	}
+	if category != "" {
+		row.Category = category[strings.LastIndex(category, ":")+1:]
+	}
+	return row
+}
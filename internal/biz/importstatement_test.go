@@ -0,0 +1,153 @@
+package biz_test
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"accounter_go/internal/biz"
+)
+
+// statementRowString is billRowString with the ID of the transaction at the bank
+func statementRowString(row *biz.ImportRow) string {
+	if row.Accounter != nil && row.Accounter.ExternalID != "" {
+		return billRowString(row) + " #" + row.Accounter.ExternalID
+	}
+	return billRowString(row)
+}
+
+func TestParseStatements(t *testing.T) {
+	tests := []struct {
+		name  string
+		file  string
+		parse func(f *os.File) ([]*biz.ImportRow, error)
+		want  []string
+	}{
+		{
+			name:  "ofx",
+			file:  "testdata/statement.ofx",
+			parse: func(f *os.File) ([]*biz.ImportRow, error) { return biz.ParseOFX(f, 2) },
+			want: []string{
+				"39: 2025-06-01 Income 3200.00 USD 13 ACME CORP PAYROLL June salary #202506010001",
+				"47: 2025-06-03 Expense 4.75 USD 7 STARBUCKS #1234 #202506030001",
+				"54: 2025-06-03 Expense 4.75 USD 7 STARBUCKS #1234 #202506030002",
+				"61: 2025-06-15 Expense 1250.00 USD 7 Rent & parking #202506150001",
+				"76: 2025-06-20 Expense 30.00 EUR 7 HOTEL DU NORD PARIS #202506200001",
+				"87: 2025-06-25 None 0.00 USD 7 CARD REPLACED skip: zero amount #202506250001",
+				`94: error "20250631" is not a date`,
+			},
+		},
+		{
+			name:  "qif",
+			file:  "testdata/statement.qif",
+			parse: func(f *os.File) ([]*biz.ImportRow, error) { return biz.ParseQIF(f, 2) },
+			want: []string{
+				"6: 2025-06-01 Income 3200.00  13 ACME CORP June salary [Salary]",
+				"12: 2025-06-03 Expense 4.75  7 STARBUCKS [Coffee]",
+				"17: 2025-06-15 Expense 1250.00  7 Landlord [Rent]",
+				"27: 2025-06-20 Expense 500.00  7 Transfer to savings skip: transfer to or from Savings, one of your own accounts",
+				`32: error "06/31/2025" is not a date`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := os.Open(tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			rows, err := tt.parse(f)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			got := make([]string, 0, len(rows))
+			for _, row := range rows {
+				got = append(got, statementRowString(row))
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestParseStatementVariants(t *testing.T) {
+	// OFX 2 is XML, with end tags and entities
+	ofx := `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+<OFX><CREDITCARDMSGSRSV1><CCSTMTTRNRS><CCSTMTRS><CURDEF>CNY</CURDEF>
+<BANKTRANLIST><STMTTRN><TRNTYPE>DEBIT</TRNTYPE><DTPOSTED>20250702083000</DTPOSTED><TRNAMT>-18.00</TRNAMT>
+<FITID>CC-1</FITID><NAME>瑞幸咖啡</NAME><MEMO></MEMO></STMTTRN></BANKTRANLIST></CCSTMTRS></CCSTMTTRNRS></CREDITCARDMSGSRSV1></OFX>`
+	rows, err := biz.ParseOFX(strings.NewReader(ofx), 0)
+	var got []string
+	for _, row := range rows {
+		got = append(got, statementRowString(row))
+	}
+	if err != nil || strings.Join(got, "\n") != "4: 2025-07-02 Expense 18.00 CNY 2 瑞幸咖啡 #CC-1" {
+		t.Errorf("ParseOFX of OFX 2 = %v, %v", got, err)
+	}
+
+	// dates that only read day first make the whole file day first
+	qif := "!Type:CCard\r\nD01/07/2025\r\nT-10.00\r\nPTaxi\r\n^\r\nD30.06.2025\r\nT-12.00\r\nPTaxi\r\n^\r\n"
+	rows, err = biz.ParseQIF(strings.NewReader(qif), 0)
+	got = nil
+	for _, row := range rows {
+		got = append(got, statementRowString(row))
+	}
+	want := []string{"2: 2025-07-01 Expense 10.00  7 Taxi", "6: 2025-06-30 Expense 12.00  7 Taxi"}
+	if err != nil || strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("ParseQIF of day first dates = %v, %v", got, err)
+	}
+
+	for name, parse := range map[string]func() error{
+		"ParseOFX of a CSV file": func() error { _, err := biz.ParseOFX(strings.NewReader("date,amount\n"), 0); return err },
+		"ParseOFX without STMTTRN": func() error {
+			_, err := biz.ParseOFX(strings.NewReader("<OFX><SIGNONMSGSRSV1></SIGNONMSGSRSV1></OFX>"), 0)
+			return err
+		},
+		"ParseQIF of a CSV file": func() error { _, err := biz.ParseQIF(strings.NewReader("date,amount\n"), 0); return err },
+		"ParseQIF of investments only": func() error {
+			_, err := biz.ParseQIF(strings.NewReader("!Type:Invst\nD6/5/2025\nT-50\n^\n"), 0)
+			return err
+		},
+	} {
+		if err := parse(); !biz.ErrInvalidImport.Is(err) {
+			t.Errorf("%s = %v, want ErrInvalidImport", name, err)
+		}
+	}
+}
+
+func TestImportOFXOverlapping(t *testing.T) {
+	uc, _ := newTestUseCase(t)
+	ctx := context.Background()
+	f, err := os.Open("testdata/statement.ofx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// both coffees are imported, the FITIDs tell them apart
+	result, err := uc.ImportOFX(ctx, 1, f, 0, biz.ImportOptions{SkipInvalid: true})
+	if err != nil || result.Imported != 5 || result.Skipped != 1 || result.Invalid != 1 || result.Duplicates != 0 {
+		t.Fatalf("ImportOFX = %+v, %v", result, err)
+	}
+
+	// the next statement overlaps the last one, only its new transaction is imported
+	next := `<OFX><BANKMSGSRSV1><STMTTRNRS><STMTRS><CURDEF>USD
+<BANKTRANLIST>
+<STMTTRN><TRNTYPE>POS<DTPOSTED>20250620<TRNAMT>-30.00<FITID>202506200001<NAME>HOTEL DU NORD PARIS
+<CURRENCY><CURRATE>1.08<CURSYM>EUR</CURRENCY></STMTTRN>
+<STMTTRN><TRNTYPE>POS<DTPOSTED>20250703<TRNAMT>-4.75<FITID>202507030001<NAME>STARBUCKS #1234</STMTTRN>
+<STMTTRN><TRNTYPE>POS<DTPOSTED>20250603<TRNAMT>-4.75<FITID>202506030002<NAME>STARBUCKS #1234</STMTTRN>
+</BANKTRANLIST></STMTRS></STMTTRNRS></BANKMSGSRSV1></OFX>`
+	result, err = uc.ImportOFX(ctx, 1, strings.NewReader(next), 0, biz.ImportOptions{})
+	if err != nil || result.Imported != 1 || result.Duplicates != 2 {
+		t.Fatalf("ImportOFX of an overlapping statement = %+v, %v", result, err)
+	}
+	list, _, err := uc.ListAccounters(ctx, &biz.ListFilter{UserID: 1, Page: 1, PageSize: 100})
+	if err != nil || len(list) != 7 {
+		t.Errorf("transactions after both imports = %d, %v", len(list), err)
+	}
+}
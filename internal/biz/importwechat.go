@@ -0,0 +1,92 @@
+package biz
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	v1 "accounter_go/api/accounter/v1"
+)
+
+// ImportWeChat imports a WeChat Pay bill statement into an account, 0 for none, see ParseWeChat and Import
+func (uc *AccounterUseCase) ImportWeChat(ctx context.Context, userID int64, r io.Reader, accountID int64, opts ImportOptions) (*ImportResult, error) {
+	rows, err := ParseWeChat(r, accountID)
+	if err != nil {
+		return nil, err
+	}
+	return uc.Import(ctx, userID, rows, opts)
+}
+
+// ParseWeChat parses the CSV bill statement (微信支付账单明细) exported by WeChat Pay.
+// Rows with / as 收/支, such as topping up or withdrawing the balance, are skipped as transfers
+// between own accounts. WeChat marks refunded purchases with their refunded amount, which is deducted,
+// so the refund rows themselves are skipped.
+func ParseWeChat(r io.Reader, accountID int64) ([]*ImportRow, error) {
+	t, err := readBill(r, "WeChat Pay", "交易时间")
+	if err != nil {
+		return nil, err
+	}
+	if err := t.require("WeChat Pay", []string{"交易时间"}, []string{"收/支"}, []string{"金额(元)", "金额（元）"}, []string{"当前状态"}); err != nil {
+		return nil, err
+	}
+
+	var rows []*ImportRow
+	for _, record := range t.rows {
+		row := &ImportRow{Line: record.line}
+		rows = append(rows, row)
+		if record.err != "" {
+			row.Error = record.err
+			continue
+		}
+		date, err := parseImportDate(t.field(record, "交易时间"), defaultDateLayouts)
+		if err != nil {
+			row.Error = errorMessage(err)
+			continue
+		}
+		amount, err := parseImportAmount(t.field(record, "金额(元)", "金额（元）"))
+		if err != nil {
+			row.Error = errorMessage(err)
+			continue
+		}
+		if amount < 0 {
+			amount = -amount
+		}
+
+		kind := t.field(record, "交易类型")
+		counterparty := t.field(record, "交易对方")
+		product := t.field(record, "商品")
+		status := t.field(record, "当前状态")
+		a := &Accounter{Date: date, Amount: amount, Currency: DefaultCurrency, AccountID: accountID, Desc: billDesc(counterparty, product)}
+		row.Accounter = a
+
+		switch t.field(record, "收/支") {
+		case "支出":
+			a.Type = v1.Type_Expense
+		case "收入":
+			a.Type = v1.Type_Income
+		}
+		switch {
+		case a.Type == v1.Type_Income && (strings.Contains(kind, "退款") || strings.Contains(status, "退款")):
+			row.Skip = "refund, deducted from the purchase"
+		case strings.Contains(status, "失败") || strings.Contains(status, "关闭"):
+			row.Skip = "failed or closed payment"
+		case strings.Contains(status, "全额退款"):
+			row.Skip = "refunded in full"
+		case strings.Contains(status, "已退款"):
+			// 已退款(￥10.00) is a partial refund of the purchase
+			_, refunded, _ := strings.Cut(status, "已退款")
+			refund, err := parseImportAmount(strings.Trim(refunded, "()（） "))
+			if err != nil {
+				row.Error = "unknown refund status " + status
+				continue
+			}
+			if a.Amount -= refund; a.Amount <= 0 {
+				row.Skip = "refunded in full"
+			}
+		case a.Type == v1.Type_None:
+			row.Skip = "neither income nor expense, such as a transfer between own accounts"
+		}
+		a.CategoryID = GuessCategory(a.Type, kind, counterparty, product)
+	}
+	return rows, nil
+}
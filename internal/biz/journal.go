@@ -0,0 +1,389 @@
+package biz
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	v1 "accounter_go/api/accounter/v1"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+// Journal export formats of plain-text accounting tools
+const (
+	ExportBeancount = "beancount"
+	ExportLedger    = "ledger"
+)
+
+// ErrInvalidJournalMapping is a mapping to something that isn't a journal account.
+var ErrInvalidJournalMapping = errors.BadRequest("INVALID_JOURNAL_MAPPING", "journal accounts look like Expenses:Food, components without spaces separated by colons")
+
+// JournalMapping maps categories and accounts to the accounts of a beancount or ledger journal,
+// e.g. "餐饮": "Expenses:Food" or "招商银行": "Assets:Bank:CMB". Keys are names or IDs. A category mapped
+// without a root such as Expenses is below the root of the transaction, Expenses or Income.
+// Categories and accounts that aren't mapped get accounts derived from their names,
+// with the English names of the built-in categories.
+type JournalMapping struct {
+	Categories map[string]string
+	Accounts   map[string]string
+}
+
+const (
+	journalFeesAccount       = "Expenses:Fees"
+	journalOpeningAccount    = "Equity:Opening-Balances"
+	journalUnassignedAccount = "Assets:Unassigned" // transactions without an account
+	journalUncategorized     = "Uncategorized"
+)
+
+// journalRoots are the top-level accounts of beancount, ledger uses the same by convention
+var journalRoots = []string{"Assets", "Liabilities", "Equity", "Income", "Expenses"}
+
+// journalAccountTypes are the parents of the accounts of each type
+var journalAccountTypes = map[v1.AccountType]string{
+	v1.AccountType_CASH:        "Assets:Cash",
+	v1.AccountType_DEBIT_CARD:  "Assets:Bank",
+	v1.AccountType_CREDIT_CARD: "Liabilities:CreditCard",
+	v1.AccountType_ALIPAY:      "Assets:Alipay",
+	v1.AccountType_WECHAT_PAY:  "Assets:WeChat",
+	v1.AccountType_INVESTMENT:  "Assets:Investment",
+}
+
+// journalAccounts maps the categories and accounts of a user to journal accounts and back
+type journalAccounts struct {
+	categories map[int64]string // full accounts, or accounts below the root of the transaction
+	accounts   map[int64]string
+	currencies map[string]string // currency of each journal account of an account
+	// the reverse maps, the first category or account of a journal account wins
+	categoryIDs map[string]int64
+	accountIDs  map[string]int64
+}
+
+func newJournalAccounts(categories []*Category, accounts []*Account, m *JournalMapping) (*journalAccounts, error) {
+	if m == nil {
+		m = &JournalMapping{}
+	}
+	for _, names := range []map[string]string{m.Categories, m.Accounts} {
+		for _, account := range names {
+			if !validJournalAccount(account) {
+				return nil, errors.BadRequest(ErrInvalidJournalMapping.Reason, fmt.Sprintf("%q isn't a journal account, they look like Expenses:Food", account))
+			}
+		}
+	}
+
+	j := &journalAccounts{
+		categories:  make(map[int64]string, len(categories)),
+		accounts:    make(map[int64]string, len(accounts)),
+		currencies:  make(map[string]string, len(accounts)),
+		categoryIDs: map[string]int64{"Expenses:" + journalUncategorized: 0, "Income:" + journalUncategorized: 0},
+		accountIDs:  map[string]int64{journalUnassignedAccount: 0},
+	}
+	byID := make(map[int64]*Category, len(categories))
+	for _, c := range categories {
+		byID[c.ID] = c
+	}
+	used := make(map[string]bool)
+	var name func(c *Category, depth int) string
+	name = func(c *Category, depth int) string {
+		if account, ok := j.categories[c.ID]; ok {
+			return account
+		}
+		account := journalLookup(m.Categories, c.ID, c.Name)
+		if account == "" {
+			account = journalComponent(c.Name, fmt.Sprintf("Category%d", c.ID))
+			if c.BuiltIn() {
+				account = v1.Category(c.ID).String()
+			}
+			if parent, ok := byID[c.ParentID]; ok && depth < maxCategoryDepth {
+				account = name(parent, depth+1) + ":" + account
+			}
+			if used[account] {
+				account += fmt.Sprintf("-%d", c.ID)
+			}
+			used[account] = true
+		}
+		j.categories[c.ID] = account
+		return account
+	}
+	for _, c := range categories {
+		account := name(c, 0)
+		keys := []string{account}
+		if !hasJournalRoot(account) {
+			keys = []string{"Expenses:" + account, "Income:" + account}
+		}
+		for _, key := range keys {
+			if _, ok := j.categoryIDs[key]; !ok {
+				j.categoryIDs[key] = c.ID
+			}
+		}
+	}
+
+	for _, a := range accounts {
+		account := journalLookup(m.Accounts, a.ID, a.Name)
+		switch {
+		case account == "":
+			parent, ok := journalAccountTypes[a.Type]
+			if !ok {
+				parent = "Assets:Other"
+			}
+			account = parent + ":" + journalComponent(a.Name, fmt.Sprintf("Account%d", a.ID))
+			if used[account] {
+				account += fmt.Sprintf("-%d", a.ID)
+			}
+			used[account] = true
+		case !hasJournalRoot(account):
+			account = "Assets:" + account
+		}
+		j.accounts[a.ID] = account
+		j.currencies[account] = a.Currency
+		if _, ok := j.accountIDs[account]; !ok {
+			j.accountIDs[account] = a.ID
+		}
+	}
+	return j, nil
+}
+
+// journalLookup returns the mapping of an ID or, when there is none, of a name ignoring case
+func journalLookup(m map[string]string, id int64, name string) string {
+	if account, ok := m[strconv.FormatInt(id, 10)]; ok {
+		return account
+	}
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys) // keys that differ in case only map deterministically
+	for _, key := range keys {
+		if strings.EqualFold(strings.TrimSpace(key), strings.TrimSpace(name)) {
+			return m[key]
+		}
+	}
+	return ""
+}
+
+// journalComponent turns a name into an account component of letters, digits and dashes
+// that starts with a capital, e.g. "my card" becomes My-card
+func journalComponent(name, fallback string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			dash = true
+			continue
+		}
+		if dash && b.Len() > 0 {
+			b.WriteByte('-')
+		}
+		dash = false
+		b.WriteRune(r)
+	}
+	s := b.String()
+	if s == "" {
+		return fallback
+	}
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[size:]
+}
+
+// validJournalAccount reports whether s is made of components separated by colons that beancount accepts
+func validJournalAccount(s string) bool {
+	for _, c := range strings.Split(s, ":") {
+		r, _ := utf8.DecodeRuneInString(c)
+		if c == "" || r < utf8.RuneSelf && !unicode.IsUpper(r) && !unicode.IsDigit(r) {
+			return false
+		}
+		if strings.IndexFunc(c, func(r rune) bool { return unicode.IsSpace(r) || strings.ContainsRune(`";@{}()[]`, r) }) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// hasJournalRoot reports whether an account starts with one of the journalRoots
+func hasJournalRoot(account string) bool {
+	root, _, _ := strings.Cut(account, ":")
+	for _, r := range journalRoots {
+		if root == r {
+			return true
+		}
+	}
+	return false
+}
+
+// category returns the journal account of a category for an income or expense
+func (j *journalAccounts) category(t v1.Type, id int64) string {
+	account, ok := j.categories[id]
+	if !ok || id == 0 {
+		account = journalUncategorized
+	}
+	if hasJournalRoot(account) {
+		return account
+	}
+	if t == v1.Type_Income {
+		return "Income:" + account
+	}
+	return "Expenses:" + account
+}
+
+// account returns the journal account of an account, journalUnassignedAccount for none
+func (j *journalAccounts) account(id int64) string {
+	if account, ok := j.accounts[id]; ok {
+		return account
+	}
+	return journalUnassignedAccount
+}
+
+// journalPosting is one line of a journal transaction
+type journalPosting struct {
+	account string
+	amount  string // e.g. -25.00 CNY, with the price of transfers between currencies
+}
+
+func journalAmount(m Money, currency string) string {
+	return m.Format(currency) + " " + currency
+}
+
+// postings returns the postings of a transaction, they always balance: the money of an expense
+// comes from its account, a transfer moves it between two accounts and pays its fee to journalFeesAccount
+func (j *journalAccounts) postings(a *Accounter) []journalPosting {
+	switch a.Type {
+	case v1.Type_Transfer:
+		from, to := j.account(a.AccountID), j.account(a.ToAccountID)
+		toAmount := a.ToAmount
+		if toAmount == 0 && a.ToCurrency == a.Currency {
+			toAmount = a.Amount
+		}
+		postings := []journalPosting{{to, journalAmount(toAmount, a.ToCurrency)}}
+		if a.ToCurrency == a.Currency || a.ToCurrency == "" {
+			if a.Fee != 0 {
+				postings = append(postings, journalPosting{journalFeesAccount, journalAmount(a.Fee, a.Currency)})
+			}
+			return append(postings, journalPosting{from, journalAmount(-(a.Amount + a.Fee), a.Currency)})
+		}
+		postings = append(postings, journalPosting{from, journalAmount(-a.Amount, a.Currency) + " @@ " + journalAmount(toAmount, a.ToCurrency)})
+		if a.Fee != 0 {
+			postings = append(postings, journalPosting{journalFeesAccount, journalAmount(a.Fee, a.Currency)}, journalPosting{from, journalAmount(-a.Fee, a.Currency)})
+		}
+		return postings
+	case v1.Type_Income:
+		return []journalPosting{
+			{j.account(a.AccountID), journalAmount(a.Amount, a.Currency)},
+			{j.category(a.Type, a.CategoryID), journalAmount(-a.Amount, a.Currency)},
+		}
+	default:
+		return []journalPosting{
+			{j.category(a.Type, a.CategoryID), journalAmount(a.Amount, a.Currency)},
+			{j.account(a.AccountID), journalAmount(-a.Amount, a.Currency)},
+		}
+	}
+}
+
+// ledgerDesc and beancountDesc make descriptions one line, beancount strings also escape quotes
+var (
+	ledgerDesc    = strings.NewReplacer("\r", " ", "\n", " ", "\t", " ")
+	beancountDesc = strings.NewReplacer("\r", " ", "\n", " ", `\`, `\\`, `"`, `\"`)
+)
+
+// exportJournal writes the transactions as a journal sorted by date and ID, so that exports of the
+// same transactions are identical. The transactions are held in memory to sort them.
+// Every account used is opened on the first date, and without a start date the opening balances
+// of the accounts are recorded against journalOpeningAccount so that the balances match.
+func (uc *AccounterUseCase) exportJournal(ctx context.Context, filter *ListFilter, format string, mapping *JournalMapping, w io.Writer) error {
+	categories, err := uc.categories.ListByUserID(ctx, filter.UserID)
+	if err != nil {
+		return err
+	}
+	accounts, err := uc.accounts.ListByUserID(ctx, filter.UserID)
+	if err != nil {
+		return err
+	}
+	j, err := newJournalAccounts(categories, accounts, mapping)
+	if err != nil {
+		return err
+	}
+
+	var accounters []*Accounter
+	if err := uc.eachAccounter(ctx, filter, func(a *Accounter) error {
+		accounters = append(accounters, a)
+		return nil
+	}); err != nil {
+		return err
+	}
+	sort.SliceStable(accounters, func(x, y int) bool {
+		if !accounters[x].Date.Equal(accounters[y].Date) {
+			return accounters[x].Date.Before(accounters[y].Date)
+		}
+		return accounters[x].TransactionID < accounters[y].TransactionID
+	})
+
+	out := bufio.NewWriter(w)
+	if len(accounters) == 0 {
+		return out.Flush()
+	}
+	first := accounters[0].Date.Format("2006-01-02")
+
+	type entry struct {
+		date     string
+		desc     string
+		postings []journalPosting
+	}
+	var entries []entry
+	opened := make(map[string]bool)
+	for _, a := range accounters {
+		postings := j.postings(a)
+		for _, p := range postings {
+			opened[p.account] = true
+		}
+		entries = append(entries, entry{a.Date.Format("2006-01-02"), a.Desc, postings})
+	}
+	if filter.StartDate == nil {
+		var openings []entry
+		for _, a := range accounts {
+			if account := j.accounts[a.ID]; opened[account] && a.OpeningBalance != 0 {
+				openings = append(openings, entry{first, "Opening balance", []journalPosting{
+					{account, journalAmount(a.OpeningBalance, a.Currency)},
+					{journalOpeningAccount, journalAmount(-a.OpeningBalance, a.Currency)},
+				}})
+				opened[journalOpeningAccount] = true
+			}
+		}
+		entries = append(openings, entries...)
+	}
+
+	names := make([]string, 0, len(opened))
+	for account := range opened {
+		names = append(names, account)
+	}
+	sort.Strings(names)
+	for _, account := range names {
+		if format == ExportLedger {
+			fmt.Fprintf(out, "account %s\n", account)
+		} else if currency := j.currencies[account]; currency != "" {
+			fmt.Fprintf(out, "%s open %s %s\n", first, account, currency)
+		} else {
+			fmt.Fprintf(out, "%s open %s\n", first, account)
+		}
+	}
+
+	for _, e := range entries {
+		if format == ExportLedger {
+			fmt.Fprintf(out, "\n%s * %s\n", strings.ReplaceAll(e.date, "-", "/"), strings.Join(strings.Fields(ledgerDesc.Replace(e.desc)), " "))
+		} else {
+			fmt.Fprintf(out, "\n%s * \"%s\"\n", e.date, beancountDesc.Replace(e.desc))
+		}
+		for _, p := range e.postings {
+			indent := "  "
+			if format == ExportLedger {
+				indent = "    "
+			}
+			fmt.Fprintf(out, "%s%-40s  %s\n", indent, p.account, p.amount)
+		}
+	}
+	return out.Flush()
+}
@@ -0,0 +1,223 @@
+package biz_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "accounter_go/api/accounter/v1"
+	"accounter_go/internal/biz"
+	"accounter_go/internal/data"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// newJournalUseCase returns a use case with two accounts, a subcategory and a transaction of each kind,
+// recorded out of date order
+func newJournalUseCase(t *testing.T) *biz.AccounterUseCase {
+	t.Helper()
+	logger := log.NewStdLogger(io.Discard)
+	accounters := data.NewAccounterMemoryRepo(logger)
+	accounts := data.NewAccountMemoryRepo(logger)
+	categories := data.NewCategoryMemoryRepo(logger)
+	uc := biz.NewAccounterUsecase(accounters, categories, data.NewExchangeRateMemoryRepo(logger), accounts, logger)
+	accountUC := biz.NewAccountUseCase(accounts, accounters, logger)
+	ctx := context.Background()
+
+	card, _ := accountUC.CreateAccount(ctx, 1, &biz.Account{Name: "招商银行", Type: v1.AccountType_DEBIT_CARD, OpeningBalance: money("1000")})
+	wise, _ := accountUC.CreateAccount(ctx, 1, &biz.Account{Name: "wise usd", Type: v1.AccountType_OTHER, Currency: "USD"})
+	coffee, err := biz.NewCategoryUseCase(categories, accounters, logger).CreateCategory(ctx, 1, &biz.Category{Name: "咖啡", ParentID: int64(v1.Category_Food)})
+	if err != nil {
+		t.Fatalf("CreateCategory: %v", err)
+	}
+	for _, a := range []*biz.Accounter{
+		{Type: v1.Type_Income, CategoryID: int64(v1.Category_Salary), Desc: `July "net"`, Amount: money("12000"), AccountID: card.ID, Date: time.Date(2025, 7, 5, 0, 0, 0, 0, time.UTC)},
+		{Type: v1.Type_Expense, CategoryID: coffee.ID, Desc: "latte", Amount: money("32"), AccountID: card.ID, Date: time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{Type: v1.Type_Transfer, Amount: money("100"), ToAmount: money("13.9"), Fee: money("1.5"), AccountID: card.ID, ToAccountID: wise.ID, Desc: "exchange", Date: time.Date(2025, 7, 3, 0, 0, 0, 0, time.UTC)},
+		{Type: v1.Type_Expense, CategoryID: int64(v1.Category_Transport), Desc: "taxi", Amount: money("45.5"), Date: time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)},
+	} {
+		a.UserID = 1
+		if _, err := uc.CreateAccounter(ctx, a); err != nil {
+			t.Fatalf("CreateAccounter %s: %v", a.Desc, err)
+		}
+	}
+	return uc
+}
+
+// journalRowString is rowString with transfers and skipped entries
+func journalRowString(row *biz.ImportRow) string {
+	if row.Error == "" && row.Accounter == nil {
+		return fmt.Sprintf("%d: skip %s", row.Line, row.Skip)
+	}
+	if row.Error == "" && row.Accounter.Type == v1.Type_Transfer {
+		a := row.Accounter
+		s := fmt.Sprintf("%d: %s Transfer %s %s from %d to %d %s %s fee %s %s", row.Line, a.Date.Format("2006-01-02"),
+			a.Amount.Format(a.Currency), a.Currency, a.AccountID, a.ToAccountID, a.ToAmount.Format(a.ToCurrency), a.ToCurrency, a.Fee.Format(a.Currency), a.Desc)
+		if row.Duplicate {
+			s += " duplicate"
+		}
+		return s
+	}
+	return rowString(row)
+}
+
+func TestExportJournal(t *testing.T) {
+	uc := newJournalUseCase(t)
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	if err := uc.Export(ctx, &biz.ListFilter{UserID: 1}, biz.ExportBeancount, nil, &buf); err != nil {
+		t.Fatalf("Export beancount: %v", err)
+	}
+	want := `2025-07-01 open Assets:Bank:招商银行 CNY
+2025-07-01 open Assets:Other:Wise-usd USD
+2025-07-01 open Assets:Unassigned
+2025-07-01 open Equity:Opening-Balances
+2025-07-01 open Expenses:Fees
+2025-07-01 open Expenses:Food:咖啡
+2025-07-01 open Expenses:Transport
+2025-07-01 open Income:Salary
+
+2025-07-01 * "Opening balance"
+  Assets:Bank:招商银行                          1000.00 CNY
+  Equity:Opening-Balances                   -1000.00 CNY
+
+2025-07-01 * "latte"
+  Expenses:Food:咖啡                          32.00 CNY
+  Assets:Bank:招商银行                          -32.00 CNY
+
+2025-07-01 * "taxi"
+  Expenses:Transport                        45.50 CNY
+  Assets:Unassigned                         -45.50 CNY
+
+2025-07-03 * "exchange"
+  Assets:Other:Wise-usd                     13.90 USD
+  Assets:Bank:招商银行                          -100.00 CNY @@ 13.90 USD
+  Expenses:Fees                             1.50 CNY
+  Assets:Bank:招商银行                          -1.50 CNY
+
+2025-07-05 * "July \"net\""
+  Assets:Bank:招商银行                          12000.00 CNY
+  Income:Salary                             -12000.00 CNY
+`
+	if buf.String() != want {
+		t.Errorf("beancount export =\n%s\nwant\n%s", buf.String(), want)
+	}
+	first := buf.String()
+	buf.Reset()
+	if err := uc.Export(ctx, &biz.ListFilter{UserID: 1}, biz.ExportBeancount, nil, &buf); err != nil || buf.String() != first {
+		t.Errorf("a second beancount export differs, %v", err)
+	}
+
+	buf.Reset()
+	start := time.Date(2025, 7, 2, 0, 0, 0, 0, time.UTC)
+	mapping := &biz.JournalMapping{Categories: map[string]string{"工资": "Income:Job", "2": "Dining"}, Accounts: map[string]string{"招商银行": "Assets:CMB"}}
+	if err := uc.Export(ctx, &biz.ListFilter{UserID: 1, StartDate: &start}, biz.ExportLedger, mapping, &buf); err != nil {
+		t.Fatalf("Export ledger: %v", err)
+	}
+	want = `account Assets:CMB
+account Assets:Other:Wise-usd
+account Expenses:Fees
+account Income:Job
+
+2025/07/03 * exchange
+    Assets:Other:Wise-usd                     13.90 USD
+    Assets:CMB                                -100.00 CNY @@ 13.90 USD
+    Expenses:Fees                             1.50 CNY
+    Assets:CMB                                -1.50 CNY
+
+2025/07/05 * July "net"
+    Assets:CMB                                12000.00 CNY
+    Income:Job                                -12000.00 CNY
+`
+	if buf.String() != want {
+		t.Errorf("ledger export from July 2nd =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	mapping = &biz.JournalMapping{Categories: map[string]string{"餐饮": "expenses food"}}
+	if err := uc.Export(ctx, &biz.ListFilter{UserID: 1}, biz.ExportLedger, mapping, io.Discard); !errors.Is(err, biz.ErrInvalidJournalMapping) {
+		t.Errorf("Export with a mapping to an invalid account = %v, want ErrInvalidJournalMapping", err)
+	}
+}
+
+func TestImportJournal(t *testing.T) {
+	uc := newJournalUseCase(t)
+	ctx := context.Background()
+
+	// an export imports back as duplicates only
+	var buf bytes.Buffer
+	if err := uc.Export(ctx, &biz.ListFilter{UserID: 1}, biz.ExportBeancount, nil, &buf); err != nil {
+		t.Fatalf("Export beancount: %v", err)
+	}
+	result, err := uc.ImportJournal(ctx, 1, &buf, nil, biz.ImportOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("ImportJournal: %v", err)
+	}
+	var got []string
+	for _, row := range result.Rows {
+		got = append(got, journalRowString(row))
+	}
+	if result.Duplicates != 4 || result.Skipped != 1 || result.Invalid != 0 {
+		t.Errorf("ImportJournal of an export = %+v\n%s", result, strings.Join(got, "\n"))
+	}
+
+	journal := `; hand-written ledger journal
+account Assets:CMB
+2025/08/01 * (42) Starbucks  ; coffee with Li
+    Expenses:Food                    ¥38.00
+    Expenses:Snacks                  12 CNY
+    Assets:CMB
+
+2025-08-02 txn "Employer" "Bonus" #work
+  id: "7"
+  Income:Salary   -5000.00 CNY
+  * Assets:CMB
+
+2025/08/03 Top up
+    Assets:Other:Wise-usd    20.00 USD
+    Assets:CMB              -145.00 CNY @ 0.1379 USD
+
+2025/08/04 Refund
+    Expenses:Food    -10 CNY
+    Assets:CMB
+
+2025/08/05 Unknown bank
+    Expenses:Food    10 CNY
+    Assets:Bank:HSBC
+
+2025/08/06 Gym
+    Expenses:Sports  99 CNY
+    Assets:CMB
+
+2025/13/01 Bad date
+    Expenses:Food    10 CNY
+    Assets:CMB
+`
+	mapping := &biz.JournalMapping{Accounts: map[string]string{"招商银行": "Assets:CMB"}}
+	result, err = uc.ImportJournal(ctx, 1, strings.NewReader(journal), mapping, biz.ImportOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("ImportJournal: %v", err)
+	}
+	got = nil
+	for _, row := range result.Rows {
+		got = append(got, journalRowString(row))
+	}
+	want := []string{
+		"3: 2025-08-01 Expense 38.00 CNY 2 Starbucks",
+		"3: 2025-08-01 Expense 12.00 CNY 18 Starbucks",
+		"8: 2025-08-02 Income 5000.00 CNY 12 Employer Bonus",
+		"13: 2025-08-03 Transfer 145.00 CNY from 1 to 2 20.00 USD fee 0.00 Top up",
+		"17: error a posting that reverses Expenses:Food, such as a refund, can't be imported",
+		"21: error unknown account Assets:Bank:HSBC, map it to one of your accounts",
+		"25: 2025-08-06 Expense 99.00 CNY 0 Gym [Sports]",
+		`29: error "2025/13/01" is not a date`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("ImportJournal rows =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,178 @@
+package biz
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+// ErrInvalidAmount is an amount that isn't a decimal number.
+var ErrInvalidAmount = errors.BadRequest("INVALID_AMOUNT", "amount must be a decimal number such as 25.50")
+
+const (
+	// moneyDecimals is the number of decimals Money keeps, enough for every currency
+	moneyDecimals = 4
+	moneyScale    = 10000
+	// maxMoneyDigits bounds the integer digits of a parsed amount so it fits into Money
+	maxMoneyDigits = 14
+)
+
+// Money is an exact amount of money in ten-thousandths of a currency unit,
+// so Money(1) is 0.0001; build amounts with ParseMoney or MoneyFromFloat.
+// Amounts are summed as integers, so totals don't pick up float rounding errors.
+type Money int64
+
+// currencyDecimals lists the ISO 4217 currencies whose minor unit isn't a hundredth
+var currencyDecimals = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0,
+	"PYG": 0, "RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+}
+
+// CurrencyDecimals returns the number of decimals of the minor unit of a currency
+func CurrencyDecimals(currency string) int {
+	if d, ok := currencyDecimals[currency]; ok {
+		return d
+	}
+	return 2
+}
+
+// ParseMoney parses a decimal string such as "-25.50".
+// Digits beyond the precision of Money are rounded half away from zero.
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+	intPart, fracPart, _ := strings.Cut(s, ".")
+	if intPart == "" && fracPart == "" || len(intPart) > maxMoneyDigits || !isDigits(intPart) || !isDigits(fracPart) {
+		return 0, ErrInvalidAmount
+	}
+
+	var m int64
+	for _, c := range intPart {
+		m = m*10 + int64(c-'0')
+	}
+	for i := 0; i < moneyDecimals; i++ {
+		m *= 10
+		if i < len(fracPart) {
+			m += int64(fracPart[i] - '0')
+		}
+	}
+	if len(fracPart) > moneyDecimals && fracPart[moneyDecimals] >= '5' {
+		m++
+	}
+	if negative {
+		m = -m
+	}
+	return Money(m), nil
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// MoneyFromFloat converts a float amount, e.g. from the API fields that predate exact amounts,
+// rounding it to the precision of Money. Floats beyond the range of ParseMoney give ErrInvalidAmount,
+// converting them to an integer would give garbage.
+func MoneyFromFloat(f float64) (Money, error) {
+	if math.IsNaN(f) || math.Abs(f) >= math.Pow10(maxMoneyDigits) {
+		return 0, ErrInvalidAmount
+	}
+	return Money(math.Round(f * moneyScale)), nil
+}
+
+// Float64 returns m as a float, for the API fields that predate exact amounts
+func (m Money) Float64() float64 {
+	return float64(m) / moneyScale
+}
+
+// Mul multiplies m by a rate, such as an exchange rate, keeping the precision of Money
+func (m Money) Mul(rate float64) Money {
+	return Money(math.Round(float64(m) * rate))
+}
+
+// Round rounds m half away from zero to the minor unit of a currency
+func (m Money) Round(currency string) Money {
+	unit := Money(math.Pow10(moneyDecimals - CurrencyDecimals(currency)))
+	rem := m % unit
+	m -= rem
+	if rem*2 >= unit {
+		m += unit
+	} else if rem*2 <= -unit {
+		m -= unit
+	}
+	return m
+}
+
+// Format returns m rounded to a currency with exactly the decimals of its minor unit, e.g. "25.50"
+func (m Money) Format(currency string) string {
+	return m.Round(currency).format(CurrencyDecimals(currency))
+}
+
+// String returns m with as few decimals as needed, e.g. "25.5"
+func (m Money) String() string {
+	s := m.format(moneyDecimals)
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return s
+}
+
+// format returns m with the given number of decimals, dropping the ones beyond them
+func (m Money) format(decimals int) string {
+	sign := ""
+	v := int64(m)
+	if v < 0 {
+		sign, v = "-", -v
+	}
+	digits := strconv.FormatInt(v, 10)
+	if len(digits) <= moneyDecimals {
+		digits = strings.Repeat("0", moneyDecimals-len(digits)+1) + digits
+	}
+	point := len(digits) - moneyDecimals
+	if decimals == 0 {
+		return sign + digits[:point]
+	}
+	return sign + digits[:point] + "." + digits[point:point+decimals]
+}
+
+// MarshalJSON encodes m as a decimal string so it survives JSON without rounding
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.String())
+}
+
+// UnmarshalJSON accepts a decimal string, or a JSON number as written before amounts were exact
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if string(data) == "null" {
+		return nil
+	}
+	s := string(data)
+	if len(data) > 0 && data[0] == '"' {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+	}
+	parsed, err := ParseMoney(s)
+	if err != nil {
+		// Numbers in exponent notation such as 1e+3 that encoding/json writes for some floats
+		f, ferr := strconv.ParseFloat(s, 64)
+		if ferr != nil {
+			return err
+		}
+		if parsed, err = MoneyFromFloat(f); err != nil {
+			return err
+		}
+	}
+	*m = parsed
+	return nil
+}
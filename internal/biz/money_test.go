@@ -0,0 +1,102 @@
+package biz_test
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+
+	"accounter_go/internal/biz"
+)
+
+func TestParseMoney(t *testing.T) {
+	for in, want := range map[string]string{
+		"25.50": "25.5", "-0.01": "-0.01", "+3": "3", ".5": "0.5", "7.": "7",
+		"0.00005": "0.0001", "-0.00005": "-0.0001", "1.23444": "1.2344",
+	} {
+		got, err := biz.ParseMoney(in)
+		if err != nil || got.String() != want {
+			t.Errorf("ParseMoney(%q) = %s, %v, want %s", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", ".", "-", "1,5", "1e3", "abc", "123456789012345"} {
+		if _, err := biz.ParseMoney(in); !errors.Is(err, biz.ErrInvalidAmount) {
+			t.Errorf("ParseMoney(%q) = %v, want ErrInvalidAmount", in, err)
+		}
+	}
+}
+
+func TestMoneyRoundAndFormat(t *testing.T) {
+	cases := []struct {
+		amount, currency, want string
+	}{
+		{"10.005", "CNY", "10.01"},
+		{"-10.005", "CNY", "-10.01"},
+		{"10.0049", "USD", "10.00"},
+		{"1234.5", "JPY", "1235"},
+		{"-0.5", "JPY", "-1"},
+		{"1.2345", "KWD", "1.235"},
+		{"0.04", "CNY", "0.04"},
+	}
+	for _, c := range cases {
+		m, err := biz.ParseMoney(c.amount)
+		if err != nil {
+			t.Fatalf("ParseMoney(%q): %v", c.amount, err)
+		}
+		if got := m.Format(c.currency); got != c.want {
+			t.Errorf("%s in %s = %s, want %s", c.amount, c.currency, got, c.want)
+		}
+	}
+}
+
+func TestMoneySumIsExact(t *testing.T) {
+	var sum biz.Money
+	cent := money("0.01")
+	for i := 0; i < 1096555; i++ {
+		sum += cent
+	}
+	if got := sum.Format("CNY"); got != "10965.55" {
+		t.Errorf("sum = %s, want 10965.55", got)
+	}
+	a, _ := biz.MoneyFromFloat(0.1)
+	b, _ := biz.MoneyFromFloat(0.2)
+	if a+b != money("0.3") {
+		t.Errorf("0.1 + 0.2 != 0.3")
+	}
+}
+
+func TestMoneyFromFloatRejectsOutOfRange(t *testing.T) {
+	for _, f := range []float64{math.NaN(), math.Inf(1), math.Inf(-1), 1e30, -1e14} {
+		if m, err := biz.MoneyFromFloat(f); !errors.Is(err, biz.ErrInvalidAmount) {
+			t.Errorf("MoneyFromFloat(%v) = %s, %v, want ErrInvalidAmount", f, m, err)
+		}
+	}
+	if m, err := biz.MoneyFromFloat(-123456789.01); err != nil || m != money("-123456789.01") {
+		t.Errorf("MoneyFromFloat(-123456789.01) = %s, %v", m, err)
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	var v struct{ Amount biz.Money }
+	for in, want := range map[string]string{
+		`{"Amount": "25.50"}`:            "25.5",
+		`{"Amount": 10965.550000000001}`: "10965.55",
+		`{"Amount": 1e+3}`:               "1000",
+	} {
+		v.Amount = 0
+		if err := json.Unmarshal([]byte(in), &v); err != nil || v.Amount.String() != want {
+			t.Errorf("Unmarshal(%s) = %s, %v, want %s", in, v.Amount, err, want)
+		}
+	}
+	// Amounts too large for Money are errors instead of garbage
+	for _, in := range []string{`{"Amount": 1e21}`, `{"Amount": -1e14}`, `{"Amount": "1e21"}`} {
+		if err := json.Unmarshal([]byte(in), &v); !errors.Is(err, biz.ErrInvalidAmount) {
+			t.Errorf("Unmarshal(%s) = %s, %v, want ErrInvalidAmount", in, v.Amount, err)
+		}
+	}
+	v.Amount = money("1000")
+	out, err := json.Marshal(v)
+	if err != nil || string(out) != `{"Amount":"1000"}` {
+		t.Errorf("Marshal = %s, %v", out, err)
+	}
+}
@@ -0,0 +1,291 @@
+package biz
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	v1 "accounter_go/api/accounter/v1"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+var (
+	// ErrRecurringNotFound is recurring transaction not found, also returned for rules of other users.
+	ErrRecurringNotFound = errors.NotFound("RECURRING_NOT_FOUND", "recurring transaction not found")
+	// ErrInvalidRecurringType is a recurring transfer, recurring transactions are income or expense.
+	ErrInvalidRecurringType = errors.BadRequest("INVALID_RECURRING_TYPE", "recurring transactions are income or expense")
+)
+
+// Recurring is a transaction template that the scheduler records on every occurrence of RRule
+type Recurring struct {
+	ID         int64
+	UserID     int64
+	RRule      string    // see ParseRRule
+	StartDate  time.Time // midnight UTC of the first day the rule may occur on
+	Type       v1.Type
+	CategoryID int64
+	Desc       string
+	Amount     Money
+	Currency   string
+	AccountID  int64
+	LastDate   time.Time // last occurrence recorded as a transaction, zero before the first
+	NextDate   time.Time // next occurrence to record, zero once the rule has ended
+}
+
+// RecurringRepo is a Recurring repo.
+// FindByID returns ErrRecurringNotFound for unknown rules.
+type RecurringRepo interface {
+	Save(context.Context, *Recurring) (*Recurring, error)
+	Update(context.Context, *Recurring) (*Recurring, error)
+	FindByID(context.Context, int64) (*Recurring, error)
+	ListByUserID(context.Context, int64) ([]*Recurring, error)
+	// ListDue lists the rules of all users with a next occurrence on or before a time
+	ListDue(context.Context, time.Time) ([]*Recurring, error)
+	Delete(context.Context, int64) error
+}
+
+// RecurringPatch holds the fields of a partial update of a recurring transaction, nil fields are left unchanged.
+// Moving a rule to another account moves it to the currency of the account.
+type RecurringPatch struct {
+	RRule      *string
+	StartDate  *time.Time
+	CategoryID *int64
+	Desc       *string
+	Amount     *Money
+	AccountID  *int64
+}
+
+// RecurringUseCase is a Recurring usecase.
+type RecurringUseCase struct {
+	repo       RecurringRepo
+	accounters *AccounterUseCase
+	// mutex keeps RunDue and changes to the rules apart, so a rule never advances twice
+	mutex sync.Mutex
+	Log   *log.Helper
+}
+
+// NewRecurringUseCase new a Recurring usecase.
+func NewRecurringUseCase(repo RecurringRepo, accounters *AccounterUseCase, logger log.Logger) *RecurringUseCase {
+	return &RecurringUseCase{repo: repo, accounters: accounters, Log: log.NewHelper(logger)}
+}
+
+// ListRecurring lists the recurring transactions of userID
+func (uc *RecurringUseCase) ListRecurring(ctx context.Context, userID int64) ([]*Recurring, error) {
+	uc.Log.WithContext(ctx).Infof("ListRecurring")
+	return uc.repo.ListByUserID(ctx, userID)
+}
+
+// CreateRecurring creates a recurring transaction owned by userID, it starts today unless StartDate is set.
+// Occurrences from a start date in the past are recorded on the next run of the scheduler.
+func (uc *RecurringUseCase) CreateRecurring(ctx context.Context, userID int64, r *Recurring) (*Recurring, error) {
+	uc.Log.WithContext(ctx).Infof("CreateRecurring: %s", r.RRule)
+	uc.mutex.Lock()
+	defer uc.mutex.Unlock()
+
+	r.UserID = userID
+	// Without a currency the rule is in the currency of its account
+	adopt := r.Currency == ""
+	currency, err := NormalizeCurrency(r.Currency)
+	if err != nil {
+		return nil, err
+	}
+	r.Currency = currency
+	if r.StartDate.IsZero() {
+		r.StartDate = time.Now().UTC()
+	}
+	r.LastDate = time.Time{}
+	if err := uc.validate(ctx, r, adopt); err != nil {
+		return nil, err
+	}
+	if r.NextDate.IsZero() {
+		return nil, errors.BadRequest(ErrInvalidRRule.Reason, "the rule has no occurrence on or after its start date")
+	}
+	return uc.repo.Save(ctx, r)
+}
+
+// UpdateRecurring applies a partial update to a recurring transaction owned by userID.
+// Occurrences that were already recorded are not recorded again.
+func (uc *RecurringUseCase) UpdateRecurring(ctx context.Context, userID, id int64, patch *RecurringPatch) (*Recurring, error) {
+	uc.Log.WithContext(ctx).Infof("UpdateRecurring: %d", id)
+	uc.mutex.Lock()
+	defer uc.mutex.Unlock()
+
+	r, err := uc.ownRecurring(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if patch.RRule != nil {
+		r.RRule = *patch.RRule
+	}
+	if patch.StartDate != nil {
+		r.StartDate = *patch.StartDate
+	}
+	if patch.CategoryID != nil {
+		r.CategoryID = *patch.CategoryID
+	}
+	if patch.Desc != nil {
+		r.Desc = *patch.Desc
+	}
+	if patch.Amount != nil {
+		r.Amount = *patch.Amount
+	}
+	if patch.AccountID != nil {
+		r.AccountID = *patch.AccountID
+	}
+	if err := uc.validate(ctx, r, patch.AccountID != nil); err != nil {
+		return nil, err
+	}
+	return uc.repo.Update(ctx, r)
+}
+
+// DeleteRecurring deletes a recurring transaction owned by userID, the transactions it recorded are kept
+func (uc *RecurringUseCase) DeleteRecurring(ctx context.Context, userID, id int64) error {
+	uc.Log.WithContext(ctx).Infof("DeleteRecurring: %d", id)
+	uc.mutex.Lock()
+	defer uc.mutex.Unlock()
+
+	if _, err := uc.ownRecurring(ctx, userID, id); err != nil {
+		return err
+	}
+	return uc.repo.Delete(ctx, id)
+}
+
+// RunDue records every occurrence up to now of the recurring transactions of all users,
+// including the ones missed while the service was down, and returns the number of transactions created.
+// A rule that fails is left at its failing occurrence for the next run, the other rules still run.
+func (uc *RecurringUseCase) RunDue(ctx context.Context, now time.Time) (int, error) {
+	uc.mutex.Lock()
+	defer uc.mutex.Unlock()
+
+	rules, err := uc.repo.ListDue(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+	created := 0
+	var firstErr error
+	for _, r := range rules {
+		n, err := uc.catchUp(ctx, r, now)
+		created += n
+		if err != nil {
+			uc.Log.WithContext(ctx).Errorf("Failed to record recurring transaction %d on %s: %v", r.ID, r.NextDate.Format("2006-01-02"), err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	if created > 0 {
+		uc.Log.WithContext(ctx).Infof("Recorded %d recurring transactions", created)
+	}
+	return created, firstErr
+}
+
+// catchUp records the occurrences of a rule up to now, advancing the rule after each one
+func (uc *RecurringUseCase) catchUp(ctx context.Context, r *Recurring, now time.Time) (int, error) {
+	rule, err := ParseRRule(r.RRule)
+	if err != nil {
+		return 0, err
+	}
+	created := 0
+	for !r.NextDate.IsZero() && !r.NextDate.After(now) {
+		// A transaction recorded just before a crash, without advancing the rule, isn't recorded twice
+		recorded, err := uc.recorded(ctx, r, r.NextDate)
+		if err != nil {
+			return created, err
+		}
+		if !recorded {
+			if _, err := uc.accounters.CreateAccounter(ctx, r.transaction(r.NextDate)); err != nil {
+				return created, err
+			}
+			created++
+		}
+		r.LastDate = r.NextDate
+		r.NextDate = rule.Next(r.StartDate, r.LastDate)
+		if _, err := uc.repo.Update(ctx, r); err != nil {
+			return created, err
+		}
+	}
+	return created, nil
+}
+
+// recorded reports whether a rule already has a transaction on a day
+func (uc *RecurringUseCase) recorded(ctx context.Context, r *Recurring, day time.Time) (bool, error) {
+	filter := &ListFilter{UserID: r.UserID, StartDate: &day, EndDate: &day, Page: 1, PageSize: 100}
+	for {
+		accounters, total, err := uc.accounters.repo.ListWithFilters(ctx, filter)
+		if err != nil {
+			return false, err
+		}
+		for _, a := range accounters {
+			if a.RecurringID == r.ID {
+				return true, nil
+			}
+		}
+		if len(accounters) == 0 || filter.Page*filter.PageSize >= total {
+			return false, nil
+		}
+		filter.Page++
+	}
+}
+
+// transaction returns the transaction a rule records on a day
+func (r *Recurring) transaction(day time.Time) *Accounter {
+	return &Accounter{
+		UserID:      r.UserID,
+		Type:        r.Type,
+		CategoryID:  r.CategoryID,
+		Desc:        r.Desc,
+		Amount:      r.Amount,
+		Currency:    r.Currency,
+		AccountID:   r.AccountID,
+		RecurringID: r.ID,
+		Date:        day,
+	}
+}
+
+// validate checks the rule, type, category, account and amount of a recurring transaction owned by r.UserID.
+// It moves the start to midnight UTC, rounds the amount and sets NextDate to the first occurrence after LastDate,
+// with adopt the rule takes the currency of its account.
+func (uc *RecurringUseCase) validate(ctx context.Context, r *Recurring, adopt bool) error {
+	rule, err := ParseRRule(r.RRule)
+	if err != nil {
+		return err
+	}
+	if r.Type != v1.Type_Income && r.Type != v1.Type_Expense {
+		return ErrInvalidRecurringType
+	}
+	if err := uc.accounters.checkCategory(ctx, r.UserID, r.CategoryID); err != nil {
+		return err
+	}
+	a := r.transaction(r.StartDate)
+	if err := uc.accounters.checkAccount(ctx, a, adopt); err != nil {
+		return err
+	}
+	r.Currency = a.Currency
+	r.Amount = r.Amount.Round(r.Currency)
+	if r.Amount <= 0 {
+		return errors.BadRequest("INVALID_RECURRING_AMOUNT", "recurring amount must be positive")
+	}
+
+	r.StartDate = time.Date(r.StartDate.Year(), r.StartDate.Month(), r.StartDate.Day(), 0, 0, 0, 0, time.UTC)
+	after := r.LastDate
+	if after.IsZero() {
+		after = r.StartDate.Add(-time.Nanosecond)
+	}
+	r.NextDate = rule.Next(r.StartDate, after)
+	return nil
+}
+
+// ownRecurring loads a recurring transaction of userID
+func (uc *RecurringUseCase) ownRecurring(ctx context.Context, userID, id int64) (*Recurring, error) {
+	r, err := uc.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if r.UserID != userID {
+		return nil, ErrRecurringNotFound
+	}
+	return r, nil
+}
@@ -0,0 +1,124 @@
+package biz_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "accounter_go/api/accounter/v1"
+	"accounter_go/internal/biz"
+	"accounter_go/internal/data"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+func TestRecurringRunDue(t *testing.T) {
+	logger := log.NewStdLogger(io.Discard)
+	accounters := data.NewAccounterMemoryRepo(logger)
+	accounts := data.NewAccountMemoryRepo(logger)
+	uc := biz.NewAccounterUsecase(accounters, data.NewCategoryMemoryRepo(logger), data.NewExchangeRateMemoryRepo(logger), accounts, logger)
+	recurringUC := biz.NewRecurringUseCase(data.NewRecurringMemoryRepo(logger), uc, logger)
+	ctx := context.Background()
+	day := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	list := func(userID int64) []*biz.Accounter {
+		t.Helper()
+		list, _, err := uc.ListAccounters(ctx, &biz.ListFilter{UserID: userID, Page: 1, PageSize: 100})
+		if err != nil {
+			t.Fatalf("ListAccounters: %v", err)
+		}
+		return list
+	}
+
+	wise, _ := biz.NewAccountUseCase(accounts, accounters, logger).CreateAccount(ctx, 1, &biz.Account{Name: "Wise", Type: v1.AccountType_OTHER, Currency: "USD"})
+	rent, err := recurringUC.CreateRecurring(ctx, 1, &biz.Recurring{RRule: "FREQ=MONTHLY;BYMONTHDAY=1", StartDate: day("2025-05-01"), Type: v1.Type_Expense, CategoryID: int64(v1.Category_Utility), Desc: "房租", Amount: money("3000")})
+	if err != nil {
+		t.Fatalf("CreateRecurring: %v", err)
+	}
+	if !rent.NextDate.Equal(day("2025-05-01")) || rent.Currency != "CNY" {
+		t.Errorf("CreateRecurring = %+v", rent)
+	}
+	salary, err := recurringUC.CreateRecurring(ctx, 1, &biz.Recurring{RRule: "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1", StartDate: day("2025-05-10"), Type: v1.Type_Income, CategoryID: int64(v1.Category_Salary), Amount: money("2000.005"), AccountID: wise.ID})
+	if err != nil {
+		t.Fatalf("CreateRecurring: %v", err)
+	}
+	if salary.Currency != "USD" || salary.Amount != money("2000.01") || !salary.NextDate.Equal(day("2025-05-30")) {
+		t.Errorf("CreateRecurring in the currency of the account = %+v", salary)
+	}
+
+	for _, tc := range []struct {
+		name string
+		r    *biz.Recurring
+		want error
+	}{
+		{"transfer", &biz.Recurring{RRule: "FREQ=DAILY", Type: v1.Type_Transfer}, biz.ErrInvalidRecurringType},
+		{"with a bad rule", &biz.Recurring{RRule: "FREQ=HOURLY", Type: v1.Type_Expense}, biz.ErrInvalidRRule},
+		{"that never occurs", &biz.Recurring{RRule: "FREQ=DAILY;UNTIL=20250101", StartDate: day("2025-02-01"), Type: v1.Type_Expense}, biz.ErrInvalidRRule},
+		{"in another currency than its account", &biz.Recurring{RRule: "FREQ=DAILY", Type: v1.Type_Expense, Currency: "CNY", AccountID: wise.ID}, biz.ErrAccountCurrencyMismatch},
+	} {
+		if tc.r.Amount == 0 {
+			tc.r.Amount = money("1")
+		}
+		if _, err := recurringUC.CreateRecurring(ctx, 1, tc.r); !errors.Is(err, tc.want) {
+			t.Errorf("CreateRecurring %s = %v, want %v", tc.name, err, tc.want)
+		}
+	}
+	if _, err := recurringUC.CreateRecurring(ctx, 2, &biz.Recurring{RRule: "FREQ=DAILY", Type: v1.Type_Expense, Amount: money("1"), AccountID: wise.ID}); !errors.Is(err, biz.ErrAccountNotFound) {
+		t.Errorf("CreateRecurring on another user's account = %v, want ErrAccountNotFound", err)
+	}
+
+	// The first run catches up on everything since the start dates
+	created, err := recurringUC.RunDue(ctx, day("2025-07-10"))
+	if err != nil || created != 5 {
+		t.Fatalf("RunDue = %d, %v, want 5", created, err)
+	}
+	var dates []string
+	for _, a := range list(1) {
+		dates = append(dates, a.Date.Format("2006-01-02")+" "+a.Currency)
+		if a.RecurringID != rent.ID && a.RecurringID != salary.ID {
+			t.Errorf("transaction %+v wasn't recorded by a rule", a)
+		}
+	}
+	want := []string{"2025-05-01 CNY", "2025-06-01 CNY", "2025-07-01 CNY", "2025-05-30 USD", "2025-06-30 USD"}
+	if strings.Join(dates, ", ") != strings.Join(want, ", ") {
+		t.Errorf("recorded %v, want %v", dates, want)
+	}
+	if created, err := recurringUC.RunDue(ctx, day("2025-07-10")); err != nil || created != 0 {
+		t.Errorf("RunDue again = %d, %v, want nothing", created, err)
+	}
+
+	// A transaction recorded right before a crash, without advancing the rule, isn't recorded again,
+	// while the salary of Thursday July 31st is
+	if _, err := uc.CreateAccounter(ctx, &biz.Accounter{UserID: 1, Type: v1.Type_Expense, Amount: money("3000"), RecurringID: rent.ID, Date: day("2025-08-01")}); err != nil {
+		t.Fatalf("CreateAccounter: %v", err)
+	}
+	if created, err := recurringUC.RunDue(ctx, day("2025-08-01")); err != nil || created != 1 || len(list(1)) != 7 {
+		t.Errorf("RunDue after a crash = %d, %v, %d transactions", created, err, len(list(1)))
+	}
+
+	// Changing a rule keeps the occurrences that were already recorded
+	start, amount := day("2025-01-01"), money("3200")
+	rent, err = recurringUC.UpdateRecurring(ctx, 1, rent.ID, &biz.RecurringPatch{StartDate: &start, Amount: &amount})
+	if err != nil || !rent.LastDate.Equal(day("2025-08-01")) || !rent.NextDate.Equal(day("2025-09-01")) {
+		t.Errorf("UpdateRecurring = %+v, %v", rent, err)
+	}
+	if _, err := recurringUC.UpdateRecurring(ctx, 2, rent.ID, &biz.RecurringPatch{Amount: &amount}); !errors.Is(err, biz.ErrRecurringNotFound) {
+		t.Errorf("UpdateRecurring of another user = %v, want ErrRecurringNotFound", err)
+	}
+	if created, err := recurringUC.RunDue(ctx, day("2025-09-01")); err != nil || created != 2 {
+		t.Errorf("RunDue = %d, %v, want 2", created, err)
+	}
+
+	// Deleting a rule keeps its transactions
+	if err := recurringUC.DeleteRecurring(ctx, 1, rent.ID); err != nil {
+		t.Fatalf("DeleteRecurring: %v", err)
+	}
+	if rules, _ := recurringUC.ListRecurring(ctx, 1); len(rules) != 1 || len(list(1)) != 9 {
+		t.Errorf("after DeleteRecurring: %d rules, %d transactions", len(rules), len(list(1)))
+	}
+}
@@ -0,0 +1,247 @@
+package biz
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+// ErrInvalidRRule is a recurrence rule that can't be parsed or uses parts that aren't supported.
+var ErrInvalidRRule = errors.BadRequest("INVALID_RRULE", "invalid recurrence rule")
+
+// Recurrence frequencies of an RRule
+const (
+	FreqDaily   = "DAILY"
+	FreqWeekly  = "WEEKLY"
+	FreqMonthly = "MONTHLY"
+	FreqYearly  = "YEARLY"
+)
+
+// RRule is the subset of the iCalendar RRULE (RFC 5545) that recurring transactions use:
+// FREQ with INTERVAL, BYDAY, BYMONTHDAY, BYSETPOS and UNTIL. Occurrences are whole days,
+// the first one is on or after the start date of the rule.
+//
+//	FREQ=MONTHLY;BYMONTHDAY=10                  the 10th of every month
+//	FREQ=MONTHLY;BYMONTHDAY=-1                  the last day of every month
+//	FREQ=WEEKLY;BYDAY=MO,TH                     every Monday and Thursday
+//	FREQ=DAILY;INTERVAL=14                      every 14 days
+//	FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1  the last business day of every month
+type RRule struct {
+	Freq       string
+	Interval   int
+	ByDay      []time.Weekday // WEEKLY and MONTHLY
+	ByMonthDay []int          // MONTHLY, negative days count from the end of the month
+	BySetPos   int            // picks one of the days of a period, negative positions count from the end
+	Until      time.Time      // last possible occurrence, zero for no end
+}
+
+var rruleWeekdays = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+// maxRRuleSearch bounds the search for the next occurrence of rules that never occur again,
+// such as FREQ=YEARLY starting on a February 29th
+const maxRRuleSearch = 50 * 366 * 24 * time.Hour
+
+// ParseRRule parses a recurrence rule such as FREQ=MONTHLY;BYMONTHDAY=1, an RRULE: prefix is allowed
+func ParseRRule(s string) (*RRule, error) {
+	s = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(s)), "RRULE:")
+	r := &RRule{Interval: 1}
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return nil, invalidRRule("%q is not a NAME=VALUE part", part)
+		}
+		switch key {
+		case "FREQ":
+			switch value {
+			case FreqDaily, FreqWeekly, FreqMonthly, FreqYearly:
+				r.Freq = value
+			default:
+				return nil, invalidRRule("FREQ must be DAILY, WEEKLY, MONTHLY or YEARLY")
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > 1000 {
+				return nil, invalidRRule("INTERVAL must be a number from 1 to 1000")
+			}
+			r.Interval = n
+		case "BYDAY":
+			for _, day := range strings.Split(value, ",") {
+				wd, ok := rruleWeekdays[day]
+				if !ok {
+					return nil, invalidRRule("BYDAY takes days such as MO,TU, use BYSETPOS to pick one of them")
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		case "BYMONTHDAY":
+			for _, day := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(day)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return nil, invalidRRule("BYMONTHDAY takes days from 1 to 31 or -31 to -1")
+				}
+				r.ByMonthDay = append(r.ByMonthDay, n)
+			}
+		case "BYSETPOS":
+			n, err := strconv.Atoi(value)
+			if err != nil || n == 0 || n < -31 || n > 31 {
+				return nil, invalidRRule("BYSETPOS takes a single position from 1 to 31 or -31 to -1")
+			}
+			r.BySetPos = n
+		case "UNTIL":
+			until, err := time.Parse("20060102", value)
+			if err != nil {
+				if until, err = time.Parse("20060102T150405Z", value); err != nil {
+					return nil, invalidRRule("UNTIL must be a date such as 20251231")
+				}
+			}
+			r.Until = until
+		default:
+			return nil, invalidRRule("%s is not supported", key)
+		}
+	}
+
+	switch {
+	case r.Freq == "":
+		return nil, invalidRRule("FREQ is required")
+	case len(r.ByDay) > 0 && r.Freq != FreqWeekly && r.Freq != FreqMonthly:
+		return nil, invalidRRule("BYDAY needs FREQ=WEEKLY or FREQ=MONTHLY")
+	case len(r.ByMonthDay) > 0 && r.Freq != FreqMonthly:
+		return nil, invalidRRule("BYMONTHDAY needs FREQ=MONTHLY")
+	case r.BySetPos != 0 && len(r.ByDay) == 0 && len(r.ByMonthDay) == 0:
+		return nil, invalidRRule("BYSETPOS needs BYDAY or BYMONTHDAY")
+	}
+	return r, nil
+}
+
+func invalidRRule(format string, a ...interface{}) error {
+	return errors.BadRequest(ErrInvalidRRule.Reason, fmt.Sprintf(format, a...))
+}
+
+// Next returns the first occurrence of a rule starting on start that is after t,
+// zero when the rule doesn't occur again. start is midnight UTC of the start date.
+func (r *RRule) Next(start, t time.Time) time.Time {
+	from := t
+	if from.Before(start) {
+		from = start
+	}
+	for k := 0; ; k++ {
+		period := r.period(start, k)
+		if !r.Until.IsZero() && period.After(r.Until) {
+			return time.Time{}
+		}
+		if period.After(from) && period.Sub(from) > maxRRuleSearch {
+			return time.Time{}
+		}
+		for _, day := range r.days(start, period) {
+			if day.Before(start) || !day.After(t) {
+				continue
+			}
+			if !r.Until.IsZero() && day.After(r.Until) {
+				return time.Time{}
+			}
+			return day
+		}
+	}
+}
+
+// period returns the first day of the k-th period of a rule starting on start
+func (r *RRule) period(start time.Time, k int) time.Time {
+	switch r.Freq {
+	case FreqWeekly:
+		monday := start.AddDate(0, 0, -weekdayIndex(start.Weekday()))
+		return monday.AddDate(0, 0, 7*k*r.Interval)
+	case FreqMonthly:
+		return time.Date(start.Year(), start.Month()+time.Month(k*r.Interval), 1, 0, 0, 0, 0, time.UTC)
+	case FreqYearly:
+		return time.Date(start.Year()+k*r.Interval, 1, 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return start.AddDate(0, 0, k*r.Interval)
+	}
+}
+
+// days returns the occurrences within the period starting at period, in order
+func (r *RRule) days(start, period time.Time) []time.Time {
+	var days []time.Time
+	switch r.Freq {
+	case FreqWeekly:
+		weekdays := r.ByDay
+		if len(weekdays) == 0 {
+			weekdays = []time.Weekday{start.Weekday()}
+		}
+		for _, wd := range weekdays {
+			days = append(days, period.AddDate(0, 0, weekdayIndex(wd)))
+		}
+	case FreqMonthly:
+		last := period.AddDate(0, 1, -1).Day()
+		switch {
+		case len(r.ByMonthDay) > 0:
+			for _, d := range r.ByMonthDay {
+				if d < 0 {
+					d += last + 1
+				}
+				if d >= 1 && d <= last {
+					days = append(days, period.AddDate(0, 0, d-1))
+				}
+			}
+		case len(r.ByDay) > 0:
+			for d := 0; d < last; d++ {
+				day := period.AddDate(0, 0, d)
+				for _, wd := range r.ByDay {
+					if day.Weekday() == wd {
+						days = append(days, day)
+						break
+					}
+				}
+			}
+		case start.Day() <= last:
+			// Months without the day of the start date are skipped, as in RFC 5545
+			days = append(days, period.AddDate(0, 0, start.Day()-1))
+		}
+	case FreqYearly:
+		day := time.Date(period.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+		if day.Month() == start.Month() {
+			days = append(days, day)
+		}
+	default:
+		days = append(days, period)
+	}
+
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	days = uniqueDays(days)
+	if r.BySetPos != 0 {
+		i := r.BySetPos - 1
+		if r.BySetPos < 0 {
+			i = len(days) + r.BySetPos
+		}
+		if i < 0 || i >= len(days) {
+			return nil
+		}
+		return days[i : i+1]
+	}
+	return days
+}
+
+// uniqueDays drops repeated days from sorted days
+func uniqueDays(days []time.Time) []time.Time {
+	unique := days[:0]
+	for _, day := range days {
+		if len(unique) == 0 || !day.Equal(unique[len(unique)-1]) {
+			unique = append(unique, day)
+		}
+	}
+	return unique
+}
+
+// weekdayIndex counts weekdays from Monday, as weeks start on Monday in RFC 5545 by default
+func weekdayIndex(wd time.Weekday) int {
+	return (int(wd) + 6) % 7
+}
@@ -0,0 +1,75 @@
+package biz_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"accounter_go/internal/biz"
+)
+
+func TestRRuleNext(t *testing.T) {
+	day := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	for _, tc := range []struct {
+		rule  string
+		start string
+		want  []string // the occurrences from start on, "" once the rule has ended
+	}{
+		{"FREQ=MONTHLY;BYMONTHDAY=10", "2025-01-15", []string{"2025-02-10", "2025-03-10", "2025-04-10"}},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1", "2025-01-01", []string{"2025-01-31", "2025-02-28", "2025-03-31"}},
+		{"FREQ=MONTHLY", "2025-01-31", []string{"2025-01-31", "2025-03-31", "2025-05-31"}},
+		{"FREQ=MONTHLY;BYMONTHDAY=1,15", "2025-01-10", []string{"2025-01-15", "2025-02-01", "2025-02-15"}},
+		{"FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1", "2025-05-01", []string{"2025-05-30", "2025-06-30", "2025-07-31"}},
+		{"FREQ=WEEKLY;BYDAY=MO,TH", "2025-07-02", []string{"2025-07-03", "2025-07-07", "2025-07-10"}},
+		{"FREQ=WEEKLY;INTERVAL=2", "2025-07-02", []string{"2025-07-02", "2025-07-16", "2025-07-30"}},
+		{"FREQ=DAILY;INTERVAL=14", "2025-07-01", []string{"2025-07-01", "2025-07-15", "2025-07-29"}},
+		{"FREQ=DAILY;UNTIL=20250702", "2025-07-01", []string{"2025-07-01", "2025-07-02", ""}},
+		{"FREQ=YEARLY", "2024-02-29", []string{"2024-02-29", "2028-02-29"}},
+		{"RRULE:freq=monthly;interval=3;bymonthday=1", "2025-01-01", []string{"2025-01-01", "2025-04-01", "2025-07-01"}},
+		{"FREQ=MONTHLY;INTERVAL=12;BYMONTHDAY=30", "2025-02-01", []string{""}},
+	} {
+		rule, err := biz.ParseRRule(tc.rule)
+		if err != nil {
+			t.Errorf("ParseRRule(%q): %v", tc.rule, err)
+			continue
+		}
+		start := day(tc.start)
+		after := start.Add(-time.Nanosecond)
+		for _, want := range tc.want {
+			next := rule.Next(start, after)
+			got := ""
+			if !next.IsZero() {
+				got = next.Format("2006-01-02")
+			}
+			if got != want {
+				t.Errorf("%s from %s: got %q, want %q", tc.rule, tc.start, got, want)
+				break
+			}
+			after = next
+		}
+	}
+}
+
+func TestParseRRuleErrors(t *testing.T) {
+	for _, rule := range []string{
+		"",
+		"BYMONTHDAY=1",
+		"FREQ=HOURLY",
+		"FREQ=MONTHLY;COUNT=3",
+		"FREQ=MONTHLY;INTERVAL=0",
+		"FREQ=MONTHLY;BYDAY=-1FR",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=MONTHLY;BYSETPOS=-1",
+		"FREQ=DAILY;BYDAY=MO",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=DAILY;UNTIL=2025-01-01",
+		"FREQ",
+	} {
+		if _, err := biz.ParseRRule(rule); !errors.Is(err, biz.ErrInvalidRRule) {
+			t.Errorf("ParseRRule(%q) = %v, want ErrInvalidRRule", rule, err)
+		}
+	}
+}
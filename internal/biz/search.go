@@ -0,0 +1,188 @@
+package biz
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/mozillazg/go-pinyin"
+)
+
+var (
+	// ErrInvalidSearch is a search query that is too long.
+	ErrInvalidSearch = errors.BadRequest("INVALID_SEARCH", "invalid search")
+)
+
+// maxSearchLength caps a search query, in characters
+const maxSearchLength = 100
+
+// SearchTerms splits a search query into its lower case words, without duplicates.
+// A transaction matches a query when each word is part of its description or of one of its tags,
+// where Chinese also matches its pinyin, in full or by initials: "wucan" and "wc" both find 午餐.
+func SearchTerms(q string) ([]string, error) {
+	if utf8.RuneCountInString(q) > maxSearchLength {
+		return nil, errors.BadRequest(ErrInvalidSearch.Reason, fmt.Sprintf("the search is longer than %d characters", maxSearchLength))
+	}
+	var terms []string
+	seen := make(map[string]bool)
+	for _, term := range strings.Fields(strings.ToLower(q)) {
+		if !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+	return terms, nil
+}
+
+// SearchText is what SearchTerms are looked up in: one line for the description and each tag
+// in lower case, followed by lines with their pinyin and pinyin initials when they contain Chinese.
+// A word matches when it is part of a line.
+func SearchText(desc string, tags []string) string {
+	var lines []string
+	for _, field := range append([]string{desc}, tags...) {
+		if field == "" {
+			continue
+		}
+		forms := searchForms(field)
+		lines = append(lines, string(forms[0].text))
+		if forms[1].han {
+			lines = append(lines, string(forms[1].text), string(forms[2].text))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// SearchHighlight is where a search query matched a transaction
+type SearchHighlight struct {
+	Desc []SearchRange // ordered parts of the description that matched, without overlaps
+	Tags []string      // tags that matched
+}
+
+// SearchRange is the part [Start, End) of a text that matched, in characters
+type SearchRange struct {
+	Start int
+	End   int
+}
+
+// HighlightSearch finds the words of a query in a transaction, including the Chinese
+// that matched the pinyin of a word
+func HighlightSearch(a *Accounter, terms []string) *SearchHighlight {
+	h := &SearchHighlight{}
+	if len(terms) == 0 {
+		return h
+	}
+	var ranges []SearchRange
+	if a.Desc != "" {
+		for _, form := range searchForms(a.Desc) {
+			for _, term := range terms {
+				ranges = append(ranges, form.find([]rune(term))...)
+			}
+		}
+	}
+	h.Desc = mergeSearchRanges(ranges)
+	for _, tag := range a.Tags {
+		for _, form := range searchForms(tag) {
+			if searchMatches(form, terms) {
+				h.Tags = append(h.Tags, tag)
+				break
+			}
+		}
+	}
+	return h
+}
+
+// searchForm is a text in lower case, in pinyin or in pinyin initials.
+// source maps each rune of text to the rune of the original text it came from.
+type searchForm struct {
+	text   []rune
+	source []int
+	han    bool // the text has Chinese with pinyin
+}
+
+// searchForms returns the lower case, pinyin and pinyin initials forms of a text,
+// characters without pinyin are kept in lower case
+func searchForms(s string) [3]searchForm {
+	var forms [3]searchForm
+	args := pinyin.NewArgs()
+	for i, r := range []rune(s) {
+		lower := unicode.ToLower(r)
+		forms[0].text = append(forms[0].text, lower)
+		forms[0].source = append(forms[0].source, i)
+
+		var syllable []rune
+		if unicode.Is(unicode.Han, r) {
+			if p := pinyin.SinglePinyin(r, args); len(p) > 0 && p[0] != "" {
+				syllable = []rune(p[0])
+			}
+		}
+		if syllable == nil {
+			forms[1].text = append(forms[1].text, lower)
+			forms[1].source = append(forms[1].source, i)
+			forms[2].text = append(forms[2].text, lower)
+			forms[2].source = append(forms[2].source, i)
+			continue
+		}
+		forms[1].han, forms[2].han = true, true
+		for range syllable {
+			forms[1].source = append(forms[1].source, i)
+		}
+		forms[1].text = append(forms[1].text, syllable...)
+		forms[2].text = append(forms[2].text, syllable[0])
+		forms[2].source = append(forms[2].source, i)
+	}
+	return forms
+}
+
+// find returns the ranges of the original text where term occurs in the form
+func (f searchForm) find(term []rune) []SearchRange {
+	var ranges []SearchRange
+	for i := 0; i+len(term) <= len(f.text); i++ {
+		if runesEqual(f.text[i:i+len(term)], term) {
+			ranges = append(ranges, SearchRange{Start: f.source[i], End: f.source[i+len(term)-1] + 1})
+		}
+	}
+	return ranges
+}
+
+// searchMatches reports whether a form contains any of terms
+func searchMatches(f searchForm, terms []string) bool {
+	text := string(f.text)
+	for _, term := range terms {
+		if strings.Contains(text, term) {
+			return true
+		}
+	}
+	return false
+}
+
+func runesEqual(a, b []rune) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeSearchRanges orders ranges and joins the ones that overlap or touch
+func mergeSearchRanges(ranges []SearchRange) []SearchRange {
+	if len(ranges) == 0 {
+		return nil
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Start < ranges[j].Start })
+	merged := []SearchRange{ranges[0]}
+	for _, r := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if r.Start <= last.End {
+			if r.End > last.End {
+				last.End = r.End
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
@@ -0,0 +1,87 @@
+package biz_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "accounter_go/api/accounter/v1"
+	"accounter_go/internal/biz"
+)
+
+func TestSearchTerms(t *testing.T) {
+	got, err := biz.SearchTerms("  Coffee 午餐  coffee\tWC ")
+	if err != nil || strings.Join(got, ",") != "coffee,午餐,wc" {
+		t.Errorf("SearchTerms = %v, %v", got, err)
+	}
+	if got, err := biz.SearchTerms(" "); err != nil || got != nil {
+		t.Errorf("SearchTerms of spaces = %v, %v", got, err)
+	}
+	if _, err := biz.SearchTerms(strings.Repeat("a", 101)); !errors.Is(err, biz.ErrInvalidSearch) {
+		t.Errorf("SearchTerms of a long query = %v, want ErrInvalidSearch", err)
+	}
+}
+
+func TestSearchText(t *testing.T) {
+	got := biz.SearchText("午餐 KFC", []string{"work", "报销"})
+	want := "午餐 kfc\nwucan kfc\nwc kfc\nwork\n报销\nbaoxiao\nbx"
+	if got != want {
+		t.Errorf("SearchText = %q, want %q", got, want)
+	}
+}
+
+func TestHighlightSearch(t *testing.T) {
+	a := &biz.Accounter{Desc: "公司午餐 Coffee", Tags: []string{"报销", "work"}}
+	for _, tc := range []struct {
+		query string
+		desc  string
+		tags  string
+	}{
+		{"coffee", "[5 11)", ""},
+		{"wucan", "[2 4)", ""},
+		{"wc", "[2 4)", ""},
+		{"wuc", "[2 4)", ""}, // a syllable that only partly matches highlights its whole character
+		{"si wu", "[1 3)", ""},
+		{"bx work", "", "报销,work"},
+		{"tea", "", ""},
+	} {
+		terms, _ := biz.SearchTerms(tc.query)
+		h := biz.HighlightSearch(a, terms)
+		var desc []string
+		for _, r := range h.Desc {
+			desc = append(desc, fmt.Sprintf("[%d %d)", r.Start, r.End))
+		}
+		if got := strings.Join(desc, ","); got != tc.desc {
+			t.Errorf("HighlightSearch(%q) desc = %s, want %s", tc.query, got, tc.desc)
+		}
+		if got := strings.Join(h.Tags, ","); got != tc.tags {
+			t.Errorf("HighlightSearch(%q) tags = %s, want %s", tc.query, got, tc.tags)
+		}
+	}
+}
+
+func TestSearchAccounters(t *testing.T) {
+	uc, _ := newTestUseCase(t)
+	ctx := context.Background()
+
+	if _, err := uc.CreateAccounter(ctx, &biz.Accounter{
+		UserID: 1, Type: v1.Type_Expense, Desc: "晚餐", Amount: money("60"), Tags: []string{"Family"}, Date: time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC),
+	}); err != nil {
+		t.Fatalf("CreateAccounter: %v", err)
+	}
+	// The query is matched without regard to case
+	filter := &biz.ListFilter{UserID: 1, Query: " WanCan  FAMILY ", Page: 1, PageSize: 10}
+	list, _, err := uc.ListAccounters(ctx, filter)
+	if err != nil || len(list) != 1 || list[0].Desc != "晚餐" {
+		t.Errorf("ListAccounters of wancan family = %v, %v", list, err)
+	}
+	if filter.Query != "wancan family" {
+		t.Errorf("normalized query = %q", filter.Query)
+	}
+	if _, _, err := uc.ListAccounters(ctx, &biz.ListFilter{UserID: 1, Query: strings.Repeat("餐", 101), Page: 1, PageSize: 10}); !errors.Is(err, biz.ErrInvalidSearch) {
+		t.Errorf("ListAccounters of a long query = %v, want ErrInvalidSearch", err)
+	}
+}
@@ -0,0 +1,124 @@
+package biz
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+var (
+	// ErrInvalidTag is a tag that is empty, too long or contains a comma, or too many tags on a transaction.
+	ErrInvalidTag = errors.BadRequest("INVALID_TAG", "invalid tag")
+)
+
+const (
+	maxTags         = 10 // per transaction
+	maxTagLength    = 32 // in characters
+	defaultTagLimit = 10 // suggestions when the limit isn't given
+)
+
+// Tag is a tag in use, with the number of transactions that have it.
+type Tag struct {
+	Name  string
+	Count int32
+}
+
+// TagStat represents statistics for a tag, a transaction with several tags counts for each of them
+type TagStat struct {
+	Tag    string
+	Amount Money
+	Count  int32
+}
+
+// NormalizeTags returns the tags of a transaction in canonical form: trimmed, without a leading '#',
+// in lower case and with runs of spaces turned into '-', sorted and without duplicates.
+func NormalizeTags(tags []string) ([]string, error) {
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "#")
+		tag = strings.ToLower(strings.Join(strings.FieldsFunc(tag, unicode.IsSpace), "-"))
+		if tag == "" {
+			return nil, invalidTag("tags can't be empty")
+		}
+		if strings.ContainsRune(tag, ',') {
+			return nil, invalidTag("tags can't contain commas")
+		}
+		if utf8.RuneCountInString(tag) > maxTagLength {
+			return nil, invalidTag("tag %q is longer than %d characters", tag, maxTagLength)
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	if len(normalized) > maxTags {
+		return nil, invalidTag("a transaction has at most %d tags", maxTags)
+	}
+	sort.Strings(normalized)
+	if len(normalized) == 0 {
+		return nil, nil
+	}
+	return normalized, nil
+}
+
+// invalidTag is ErrInvalidTag with a message
+func invalidTag(format string, a ...interface{}) error {
+	return errors.BadRequest(ErrInvalidTag.Reason, fmt.Sprintf(format, a...))
+}
+
+// HasTags reports whether tags, in canonical form, include every one of want
+func HasTags(tags, want []string) bool {
+	for _, w := range want {
+		i := sort.SearchStrings(tags, w)
+		if i == len(tags) || tags[i] != w {
+			return false
+		}
+	}
+	return true
+}
+
+// ListTags lists the tags userID has in use, ordered by name
+func (uc *AccounterUseCase) ListTags(ctx context.Context, userID int64) ([]*Tag, error) {
+	uc.Log.WithContext(ctx).Infof("ListTags")
+	return uc.repo.ListTags(ctx, userID)
+}
+
+// SuggestTags completes a tag being typed: the tags of userID starting with prefix,
+// then those containing it, the most used first. An empty prefix suggests the most used tags.
+func (uc *AccounterUseCase) SuggestTags(ctx context.Context, userID int64, prefix string, limit int) ([]*Tag, error) {
+	uc.Log.WithContext(ctx).Infof("SuggestTags: %s", prefix)
+	tags, err := uc.repo.ListTags(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = defaultTagLimit
+	}
+	prefix = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(prefix), "#"))
+
+	var starting, containing []*Tag
+	for _, tag := range tags {
+		switch {
+		case strings.HasPrefix(tag.Name, prefix):
+			starting = append(starting, tag)
+		case strings.Contains(tag.Name, prefix):
+			containing = append(containing, tag)
+		}
+	}
+	byUse := func(tags []*Tag) {
+		sort.SliceStable(tags, func(i, j int) bool { return tags[i].Count > tags[j].Count })
+	}
+	byUse(starting)
+	byUse(containing)
+	suggestions := append(starting, containing...)
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions, nil
+}
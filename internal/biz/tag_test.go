@@ -0,0 +1,94 @@
+package biz_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "accounter_go/api/accounter/v1"
+	"accounter_go/internal/biz"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	got, err := biz.NormalizeTags([]string{" Travel ", "#business", "travel", "road  trip", "报销"})
+	if err != nil || strings.Join(got, ",") != "business,road-trip,travel,报销" {
+		t.Errorf("NormalizeTags = %v, %v", got, err)
+	}
+	if got, err := biz.NormalizeTags(nil); err != nil || got != nil {
+		t.Errorf("NormalizeTags(nil) = %v, %v", got, err)
+	}
+	for name, tags := range map[string][]string{
+		"empty":    {"travel", " # "},
+		"comma":    {"a,b"},
+		"too long": {strings.Repeat("长", 33)},
+		"too many": {"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k"},
+	} {
+		if _, err := biz.NormalizeTags(tags); !errors.Is(err, biz.ErrInvalidTag) {
+			t.Errorf("NormalizeTags of %s tags = %v, want ErrInvalidTag", name, err)
+		}
+	}
+}
+
+func TestTags(t *testing.T) {
+	uc, lunch := newTestUseCase(t)
+	ctx := context.Background()
+
+	for _, a := range []*biz.Accounter{
+		{Desc: "hotel", Amount: money("300"), Tags: []string{"Travel", "business"}},
+		{Desc: "train", Amount: money("120"), Tags: []string{"#travel"}},
+		{Desc: "taxi", Amount: money("40"), Tags: []string{"trip-2025", "travel"}},
+	} {
+		a.UserID, a.Type, a.Date = 1, v1.Type_Expense, time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC)
+		if _, err := uc.CreateAccounter(ctx, a); err != nil {
+			t.Fatalf("CreateAccounter(%s): %v", a.Desc, err)
+		}
+	}
+	tags := []string{"Lunch Break"}
+	if _, err := uc.UpdateAccounter(ctx, 1, lunch.TransactionID, &biz.AccounterPatch{Tags: &tags}); err != nil {
+		t.Fatalf("UpdateAccounter: %v", err)
+	}
+
+	// Filters are normalized like the tags they match
+	list, _, err := uc.ListAccounters(ctx, &biz.ListFilter{UserID: 1, Tags: []string{"TRAVEL", "Business"}, Page: 1, PageSize: 10})
+	if err != nil || len(list) != 1 || list[0].Desc != "hotel" {
+		t.Errorf("ListAccounters of travel and business = %v, %v", list, err)
+	}
+	stats, err := uc.GetStats(ctx, &biz.StatsFilter{UserID: 1, Tags: []string{"travel"}})
+	if err != nil || stats.TotalExpense != money("460") {
+		t.Fatalf("GetStats of travel = %+v, %v", stats, err)
+	}
+	var byTag []string
+	for _, stat := range stats.ExpenseByTag {
+		byTag = append(byTag, stat.Tag+" "+stat.Amount.Format("CNY"))
+	}
+	if got := strings.Join(byTag, ", "); got != "business 300.00, travel 460.00, trip-2025 40.00" {
+		t.Errorf("expense by tag = %s", got)
+	}
+
+	suggest := func(prefix string, limit int) string {
+		tags, err := uc.SuggestTags(ctx, 1, prefix, limit)
+		if err != nil {
+			t.Fatalf("SuggestTags(%q): %v", prefix, err)
+		}
+		names := make([]string, len(tags))
+		for i, tag := range tags {
+			names[i] = tag.Name
+		}
+		return strings.Join(names, ",")
+	}
+	// Tags starting with the prefix come first, the most used first
+	if got := suggest("TR", 0); got != "travel,trip-2025" {
+		t.Errorf("SuggestTags(TR) = %s", got)
+	}
+	if got := suggest("b", 0); got != "business,lunch-break" {
+		t.Errorf("SuggestTags(b) = %s", got)
+	}
+	if got := suggest("", 1); got != "travel" {
+		t.Errorf("SuggestTags with limit 1 = %s", got)
+	}
+	if other, err := uc.ListTags(ctx, 2); err != nil || len(other) != 0 {
+		t.Errorf("ListTags of another user = %v, %v", other, err)
+	}
+}
@@ -0,0 +1,57 @@
+package biz
+
+import (
+	"context"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+var (
+	// ErrTransferAccountsRequired is a transfer without a source or destination account.
+	ErrTransferAccountsRequired = errors.BadRequest("TRANSFER_ACCOUNTS_REQUIRED", "a transfer needs two different accounts")
+	// ErrTransferAmountRequired is a transfer between currencies without the amount that arrives.
+	ErrTransferAmountRequired = errors.BadRequest("TRANSFER_AMOUNT_REQUIRED", "a transfer between currencies needs the amount that arrives")
+	// ErrInvalidTransferAmount is a negative transfer amount or fee, or a same-currency transfer that changes the amount.
+	ErrInvalidTransferAmount = errors.BadRequest("INVALID_TRANSFER_AMOUNT", "transfer amounts and fees must not be negative, and a transfer within a currency arrives in full")
+)
+
+// checkTransfer checks both accounts of a transfer and fills in its currencies.
+// The transfer is in the currency of its source account, with adopt it takes that currency
+// instead of being checked against it. Within a currency ToAmount defaults to Amount.
+func (uc *AccounterUseCase) checkTransfer(ctx context.Context, a *Accounter, adopt bool) error {
+	if a.AccountID == 0 || a.ToAccountID == 0 || a.AccountID == a.ToAccountID {
+		return ErrTransferAccountsRequired
+	}
+	if err := uc.checkAccount(ctx, a, adopt); err != nil {
+		return err
+	}
+	to, err := ownAccount(ctx, uc.accounts, a.UserID, a.ToAccountID)
+	if err != nil {
+		return err
+	}
+
+	a.ToCurrency = to.Currency
+	a.Amount = a.Amount.Round(a.Currency)
+	a.Fee = a.Fee.Round(a.Currency)
+	a.ToAmount = a.ToAmount.Round(a.ToCurrency)
+	if a.Amount < 0 || a.Fee < 0 || a.ToAmount < 0 {
+		return ErrInvalidTransferAmount
+	}
+	switch {
+	case a.ToCurrency != a.Currency && a.ToAmount == 0:
+		return ErrTransferAmountRequired
+	case a.ToCurrency == a.Currency && a.ToAmount == 0:
+		a.ToAmount = a.Amount
+	case a.ToCurrency == a.Currency && a.ToAmount != a.Amount:
+		return ErrInvalidTransferAmount
+	}
+	return nil
+}
+
+// clearTransfer drops the transfer fields of a transaction that isn't a transfer
+func clearTransfer(a *Accounter) {
+	a.ToAccountID = 0
+	a.ToAmount = 0
+	a.ToCurrency = ""
+	a.Fee = 0
+}
@@ -0,0 +1,106 @@
+package biz_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	v1 "accounter_go/api/accounter/v1"
+	"accounter_go/internal/biz"
+	"accounter_go/internal/data"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+func TestTransfers(t *testing.T) {
+	logger := log.NewStdLogger(io.Discard)
+	accounters := data.NewAccounterMemoryRepo(logger)
+	accounts := data.NewAccountMemoryRepo(logger)
+	uc := biz.NewAccounterUsecase(accounters, data.NewCategoryMemoryRepo(logger), data.NewExchangeRateMemoryRepo(logger), accounts, logger)
+	accountUC := biz.NewAccountUseCase(accounts, accounters, logger)
+	ctx := context.Background()
+	date := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	card, _ := accountUC.CreateAccount(ctx, 1, &biz.Account{Name: "招商银行", Type: v1.AccountType_DEBIT_CARD, OpeningBalance: money("1000")})
+	alipay, _ := accountUC.CreateAccount(ctx, 1, &biz.Account{Name: "支付宝", Type: v1.AccountType_ALIPAY})
+	wise, _ := accountUC.CreateAccount(ctx, 1, &biz.Account{Name: "Wise", Type: v1.AccountType_OTHER, Currency: "USD"})
+	other, _ := accountUC.CreateAccount(ctx, 2, &biz.Account{Name: "现金", Type: v1.AccountType_CASH})
+
+	// Within a currency the whole amount arrives
+	topUp, err := uc.CreateAccounter(ctx, &biz.Accounter{UserID: 1, Type: v1.Type_Transfer, CategoryID: int64(v1.Category_Food), Amount: money("200"), AccountID: card.ID, ToAccountID: alipay.ID, Date: date})
+	if err != nil {
+		t.Fatalf("CreateAccounter: %v", err)
+	}
+	if topUp.Currency != "CNY" || topUp.ToCurrency != "CNY" || topUp.ToAmount != money("200") {
+		t.Errorf("transfer within a currency = %+v", topUp)
+	}
+	// Across currencies the arriving amount is required, the fee stays in the source currency
+	exchange := &biz.Accounter{UserID: 1, Type: v1.Type_Transfer, Amount: money("100"), Fee: money("1.234"), AccountID: card.ID, ToAccountID: wise.ID, Date: date}
+	if _, err := uc.CreateAccounter(ctx, exchange); !errors.Is(err, biz.ErrTransferAmountRequired) {
+		t.Errorf("transfer between currencies without the arriving amount = %v, want ErrTransferAmountRequired", err)
+	}
+	exchange.ToAmount = money("13.9")
+	if exchange, err = uc.CreateAccounter(ctx, exchange); err != nil || exchange.ToCurrency != "USD" || exchange.Fee != money("1.23") {
+		t.Errorf("transfer between currencies = %+v, %v", exchange, err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		a    *biz.Accounter
+		want error
+	}{
+		{"without a destination", &biz.Accounter{AccountID: card.ID}, biz.ErrTransferAccountsRequired},
+		{"to the same account", &biz.Accounter{AccountID: card.ID, ToAccountID: card.ID}, biz.ErrTransferAccountsRequired},
+		{"to another user's account", &biz.Accounter{AccountID: card.ID, ToAccountID: other.ID}, biz.ErrAccountNotFound},
+		{"with a negative fee", &biz.Accounter{AccountID: card.ID, ToAccountID: alipay.ID, Fee: money("-1")}, biz.ErrInvalidTransferAmount},
+		{"that loses money within a currency", &biz.Accounter{AccountID: card.ID, ToAccountID: alipay.ID, ToAmount: money("99")}, biz.ErrInvalidTransferAmount},
+	} {
+		tc.a.UserID, tc.a.Type, tc.a.Amount, tc.a.Date = 1, v1.Type_Transfer, money("100"), date
+		if _, err := uc.CreateAccounter(ctx, tc.a); !errors.Is(err, tc.want) {
+			t.Errorf("transfer %s = %v, want %v", tc.name, err, tc.want)
+		}
+	}
+
+	// Changing the amount of a transfer within a currency changes what arrives
+	amount := money("250")
+	if topUp, err = uc.UpdateAccounter(ctx, 1, topUp.TransactionID, &biz.AccounterPatch{Amount: &amount}); err != nil || topUp.ToAmount != money("250") {
+		t.Errorf("UpdateAccounter amount of a transfer = %+v, %v", topUp, err)
+	}
+
+	stats, err := uc.GetStats(ctx, &biz.StatsFilter{UserID: 1})
+	if err != nil || stats.TotalIncome != 0 || stats.TotalExpense != 0 {
+		t.Errorf("GetStats with only transfers = %+v, %v", stats, err)
+	}
+	balances, err := accountUC.ListBalances(ctx, 1, nil)
+	if err != nil {
+		t.Fatalf("ListBalances: %v", err)
+	}
+	if got := balances[0]; got.Balance != money("648.77") || got.TransferOut != money("351.23") || got.Count != 2 {
+		t.Errorf("balance of the card = %+v", got)
+	}
+	if got := balances[1]; got.Balance != money("250") || got.TransferIn != money("250") {
+		t.Errorf("balance of Alipay = %+v", got)
+	}
+	if got := balances[2]; got.Balance != money("13.9") || got.Count != 1 {
+		t.Errorf("balance of Wise = %+v", got)
+	}
+
+	// Deleting the single record removes both sides of a transfer
+	if err := accountUC.DeleteAccount(ctx, 1, wise.ID); !errors.Is(err, biz.ErrAccountInUse) {
+		t.Errorf("deleting the destination of a transfer = %v, want ErrAccountInUse", err)
+	}
+	if err := uc.DeleteAccounter(ctx, 1, exchange.TransactionID); err != nil {
+		t.Fatalf("DeleteAccounter: %v", err)
+	}
+	if err := accountUC.DeleteAccount(ctx, 1, wise.ID); err != nil {
+		t.Errorf("DeleteAccount after deleting its transfer: %v", err)
+	}
+
+	// A transfer that becomes an expense drops its destination
+	expense := v1.Type_Expense
+	if got, err := uc.UpdateAccounter(ctx, 1, topUp.TransactionID, &biz.AccounterPatch{Type: &expense}); err != nil || got.ToAccountID != 0 || got.ToAmount != 0 {
+		t.Errorf("UpdateAccounter transfer to expense = %+v, %v", got, err)
+	}
+}
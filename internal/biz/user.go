@@ -0,0 +1,30 @@
+package biz
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+var (
+	// ErrUsernameTaken is username already registered.
+	ErrUsernameTaken = errors.Conflict("USERNAME_TAKEN", "username is already taken")
+)
+
+// User is a User model.
+type User struct {
+	UserID       int64
+	Username     string
+	PasswordHash string
+	CreatedAt    time.Time
+}
+
+// UserRepo is a User repo.
+// FindByID and FindByUsername return ErrUserNotFound for unknown users,
+// Save returns ErrUsernameTaken when the username already exists.
+type UserRepo interface {
+	Save(context.Context, *User) (*User, error)
+	FindByID(context.Context, int64) (*User, error)
+	FindByUsername(context.Context, string) (*User, error)
+}
@@ -0,0 +1,116 @@
+package data
+
+import (
+	"context"
+	"errors"
+
+	v1 "accounter_go/api/accounter/v1"
+	"accounter_go/internal/biz"
+	"accounter_go/internal/data/model"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"gorm.io/gorm"
+)
+
+type accountDbRepo struct {
+	data *Data
+	log  *log.Helper
+}
+
+// NewAccountDbRepo creates a new database-based AccountRepo backed by the accounts table
+func NewAccountDbRepo(data *Data, logger log.Logger) biz.AccountRepo {
+	return &accountDbRepo{
+		data: data,
+		log:  log.NewHelper(logger),
+	}
+}
+
+func (r *accountDbRepo) Save(ctx context.Context, account *biz.Account) (*biz.Account, error) {
+	record := toModelAccount(account)
+	record.AccountID = 0
+	if err := r.data.db.WithContext(ctx).Create(record).Error; err != nil {
+		r.log.WithContext(ctx).Errorf("Failed to save account: %v", err)
+		return nil, err
+	}
+
+	return toBizAccount(record), nil
+}
+
+func (r *accountDbRepo) Update(ctx context.Context, account *biz.Account) (*biz.Account, error) {
+	record := toModelAccount(account)
+	result := r.data.db.WithContext(ctx).
+		Model(&model.Account{}).
+		Where("account_id = ?", account.ID).
+		Updates(map[string]interface{}{
+			"account_name":       record.AccountName,
+			"account_type":       record.AccountType,
+			"opening_balance_e4": record.OpeningBalance,
+		})
+	if result.Error != nil {
+		r.log.WithContext(ctx).Errorf("Failed to update account: %v", result.Error)
+		return nil, result.Error
+	}
+
+	return r.FindByID(ctx, account.ID)
+}
+
+func (r *accountDbRepo) FindByID(ctx context.Context, id int64) (*biz.Account, error) {
+	var record model.Account
+	if err := r.data.db.WithContext(ctx).First(&record, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, biz.ErrAccountNotFound
+		}
+		r.log.WithContext(ctx).Errorf("Failed to find account %d: %v", id, err)
+		return nil, err
+	}
+
+	return toBizAccount(&record), nil
+}
+
+func (r *accountDbRepo) ListByUserID(ctx context.Context, userID int64) ([]*biz.Account, error) {
+	var records []model.Account
+	if err := r.data.db.WithContext(ctx).Where("user_id = ?", userID).Order("account_id").Find(&records).Error; err != nil {
+		r.log.WithContext(ctx).Errorf("Failed to list accounts of user %d: %v", userID, err)
+		return nil, err
+	}
+
+	accounts := make([]*biz.Account, len(records))
+	for i := range records {
+		accounts[i] = toBizAccount(&records[i])
+	}
+	return accounts, nil
+}
+
+func (r *accountDbRepo) Delete(ctx context.Context, id int64) error {
+	result := r.data.db.WithContext(ctx).Delete(&model.Account{}, id)
+	if result.Error != nil {
+		r.log.WithContext(ctx).Errorf("Failed to delete account %d: %v", id, result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return biz.ErrAccountNotFound
+	}
+	return nil
+}
+
+func toModelAccount(a *biz.Account) *model.Account {
+	return &model.Account{
+		AccountID:      a.ID,
+		UserID:         a.UserID,
+		AccountName:    a.Name,
+		AccountType:    int8(a.Type),
+		CurrencyCode:   a.Currency,
+		OpeningBalance: int64(a.OpeningBalance),
+	}
+}
+
+func toBizAccount(a *model.Account) *biz.Account {
+	return &biz.Account{
+		ID:             a.AccountID,
+		UserID:         a.UserID,
+		Name:           a.AccountName,
+		Type:           v1.AccountType(a.AccountType),
+		Currency:       a.CurrencyCode,
+		OpeningBalance: biz.Money(a.OpeningBalance),
+	}
+}
@@ -0,0 +1,126 @@
+package data
+
+import (
+	"context"
+
+	v1 "accounter_go/api/accounter/v1"
+	"accounter_go/internal/biz"
+	"accounter_go/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// FileAccountData represents the structure stored in the accounts JSON file
+type FileAccountData struct {
+	ID             int64     `json:"id"`
+	UserID         int64     `json:"user_id"`
+	Name           string    `json:"name"`
+	Type           int32     `json:"type"`
+	Currency       string    `json:"currency"`
+	OpeningBalance biz.Money `json:"opening_balance"`
+}
+
+func (a FileAccountData) recordID() int64 { return a.ID }
+
+// accountFileRepo keeps the accounts in accounts.json next to the accounter data
+type accountFileRepo struct {
+	*jsonCollection[FileAccountData]
+}
+
+// NewAccountFileRepo creates a new file-based AccountRepo
+func NewAccountFileRepo(c *conf.Data, logger log.Logger) (biz.AccountRepo, error) {
+	accounts, err := openJSONCollection[FileAccountData](c, "accounts.json", "accounts", logger)
+	if err != nil {
+		return nil, err
+	}
+	return &accountFileRepo{accounts}, nil
+}
+
+// NewAccountMemoryRepo creates a AccountRepo that keeps everything in memory
+func NewAccountMemoryRepo(logger log.Logger) biz.AccountRepo {
+	return &accountFileRepo{newJSONCollection[FileAccountData]("", "accounts", logger)}
+}
+
+func (r *accountFileRepo) Save(ctx context.Context, account *biz.Account) (*biz.Account, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	record := toFileAccount(account)
+	record.ID = r.nextID
+	if err := r.insertLocked(ctx, record); err != nil {
+		return nil, err
+	}
+
+	return toBizAccountFile(&record), nil
+}
+
+func (r *accountFileRepo) Update(ctx context.Context, account *biz.Account) (*biz.Account, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	i := r.indexOf(account.ID)
+	if i < 0 {
+		return nil, biz.ErrAccountNotFound
+	}
+	if err := r.replaceLocked(ctx, i, toFileAccount(account)); err != nil {
+		return nil, err
+	}
+
+	return toBizAccountFile(&r.records[i]), nil
+}
+
+func (r *accountFileRepo) FindByID(ctx context.Context, id int64) (*biz.Account, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if i := r.indexOf(id); i >= 0 {
+		return toBizAccountFile(&r.records[i]), nil
+	}
+	return nil, biz.ErrAccountNotFound
+}
+
+func (r *accountFileRepo) ListByUserID(ctx context.Context, userID int64) ([]*biz.Account, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	accounts := make([]*biz.Account, 0)
+	for i := range r.records {
+		if r.records[i].UserID == userID {
+			accounts = append(accounts, toBizAccountFile(&r.records[i]))
+		}
+	}
+	return accounts, nil
+}
+
+func (r *accountFileRepo) Delete(ctx context.Context, id int64) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	i := r.indexOf(id)
+	if i < 0 {
+		return biz.ErrAccountNotFound
+	}
+	return r.removeLocked(ctx, i)
+}
+
+func toFileAccount(a *biz.Account) FileAccountData {
+	return FileAccountData{
+		ID:             a.ID,
+		UserID:         a.UserID,
+		Name:           a.Name,
+		Type:           int32(a.Type),
+		Currency:       a.Currency,
+		OpeningBalance: a.OpeningBalance,
+	}
+}
+
+func toBizAccountFile(a *FileAccountData) *biz.Account {
+	return &biz.Account{
+		ID:             a.ID,
+		UserID:         a.UserID,
+		Name:           a.Name,
+		Type:           v1.AccountType(a.Type),
+		Currency:       a.Currency,
+		OpeningBalance: a.OpeningBalance,
+	}
+}
@@ -0,0 +1,75 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	v1 "accounter_go/api/accounter/v1"
+	"accounter_go/internal/biz"
+)
+
+func runAccountRepoContract(t *testing.T, repo biz.AccountRepo) {
+	ctx := context.Background()
+
+	saved, err := repo.Save(ctx, &biz.Account{
+		UserID: 1, Name: "招商银行", Type: v1.AccountType_DEBIT_CARD, Currency: "CNY", OpeningBalance: money("1234.56"),
+	})
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	second, err := repo.Save(ctx, &biz.Account{UserID: 1, Name: "Wise", Type: v1.AccountType_OTHER, Currency: "USD"})
+	if err != nil || second.ID == saved.ID {
+		t.Fatalf("Save = %+v, %v", second, err)
+	}
+
+	got, err := repo.FindByID(ctx, saved.ID)
+	if err != nil || got.UserID != 1 || got.Name != "招商银行" || got.Type != v1.AccountType_DEBIT_CARD || got.Currency != "CNY" || got.OpeningBalance != money("1234.56") {
+		t.Errorf("FindByID = %+v, %v", got, err)
+	}
+	if _, err := repo.FindByID(ctx, 99999); !errors.Is(err, biz.ErrAccountNotFound) {
+		t.Errorf("FindByID of an unknown account = %v, want ErrAccountNotFound", err)
+	}
+
+	list, err := repo.ListByUserID(ctx, 1)
+	if err != nil || len(list) != 2 || list[0].ID != saved.ID || list[1].ID != second.ID {
+		t.Errorf("ListByUserID = %+v, %v", list, err)
+	}
+	if others, _ := repo.ListByUserID(ctx, 2); len(others) != 0 {
+		t.Errorf("accounts leaked to another user: %d", len(others))
+	}
+
+	got.Name = "招行储蓄卡"
+	got.OpeningBalance = money("-20")
+	updated, err := repo.Update(ctx, got)
+	if err != nil || updated.Name != "招行储蓄卡" || updated.OpeningBalance != money("-20") || updated.Currency != "CNY" {
+		t.Errorf("Update = %+v, %v", updated, err)
+	}
+
+	if err := repo.Delete(ctx, saved.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := repo.FindByID(ctx, saved.ID); !errors.Is(err, biz.ErrAccountNotFound) {
+		t.Errorf("deleted account is still found: %v", err)
+	}
+	if err := repo.Delete(ctx, saved.ID); !errors.Is(err, biz.ErrAccountNotFound) {
+		t.Errorf("Delete of a deleted account = %v, want ErrAccountNotFound", err)
+	}
+}
+
+func TestAccountRepo(t *testing.T) {
+	runRepoBackends(t, NewAccountFileRepo, NewAccountDbRepo, NewAccountMemoryRepo, runAccountRepoContract,
+		func(t *testing.T, repo biz.AccountRepo, reopen func() biz.AccountRepo) {
+			kept, err := repo.Save(context.Background(), &biz.Account{UserID: 1, Name: "现金", Type: v1.AccountType_CASH, Currency: "CNY", OpeningBalance: money("88.8")})
+			if err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+			reopened := reopen()
+			if got, err := reopened.FindByID(context.Background(), kept.ID); err != nil || got.Name != "现金" || got.OpeningBalance != money("88.8") {
+				t.Errorf("account not persisted: %+v, %v", got, err)
+			}
+			if next, err := reopened.Save(context.Background(), &biz.Account{UserID: 1, Name: "支付宝", Type: v1.AccountType_ALIPAY, Currency: "CNY"}); err != nil || next.ID != kept.ID+1 {
+				t.Errorf("Save after reopen = %+v, %v", next, err)
+			}
+		})
+}
@@ -1,21 +1,24 @@
-// This file contains the database-based implementation of AccounterRepo
-// It's kept for future use when switching back to database storage
-// To use this implementation:
-// 1. Uncomment NewAccounterDbRepo in data.go ProviderSet
-// 2. Comment out NewAccounterFileRepo in data.go ProviderSet
-// 3. Ensure database configuration is properly set up
+// This file contains the database-based implementation of AccounterRepo.
+// Filtering, pagination and statistics are all pushed down to SQL so it can be
+// used as a drop-in replacement for accounterFileRepo.
+// It serves both the mysql and the sqlite storage backends, select one with
+// data.storage.backend in the config file.
 
 package data
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	v1 "accounter_go/api/accounter/v1"
 	"accounter_go/internal/biz"
 	"accounter_go/internal/data/model"
 
 	"github.com/go-kratos/kratos/v2/log"
+	"gorm.io/gorm"
 )
 
 type accounterDbRepo struct {
@@ -24,7 +27,6 @@ type accounterDbRepo struct {
 }
 
 // NewAccounterDbRepo creates a new database-based AccounterRepo
-// This is kept for future use when switching back to database storage
 func NewAccounterDbRepo(data *Data, logger log.Logger) biz.AccounterRepo {
 	return &accounterDbRepo{
 		data: data,
@@ -32,51 +34,315 @@ func NewAccounterDbRepo(data *Data, logger log.Logger) biz.AccounterRepo {
 	}
 }
 
-func (r *accounterDbRepo) Save(ctx context.Context, accounter *biz.Accounter) (*biz.Accounter, error) {
-	// Convert biz.Accounter to model.AccounterTransaction
+// toBizAccounter converts a model.AccounterTransaction to biz.Accounter, codes maps currency IDs to codes
+func toBizAccounter(transaction *model.AccounterTransaction, codes map[int]string) *biz.Accounter {
+	note := ""
+	if transaction.Note != nil {
+		note = *transaction.Note
+	}
+	toCurrency := ""
+	if transaction.ToCurrencyID != 0 {
+		toCurrency = codes[transaction.ToCurrencyID]
+	}
+	return &biz.Accounter{
+		TransactionID: transaction.TransactionID,
+		UserID:        transaction.UserID,
+		Type:          v1.Type(transaction.TransactionType),
+		CategoryID:    int64(transaction.CategoryID),
+		Desc:          note,
+		Amount:        biz.Money(transaction.Amount),
+		Currency:      currencyOrDefault(codes[transaction.CurrencyID]),
+		AccountID:     transaction.AccountID,
+		ToAccountID:   transaction.ToAccountID,
+		ToAmount:      biz.Money(transaction.ToAmount),
+		ToCurrency:    toCurrency,
+		Fee:           biz.Money(transaction.Fee),
+		RecurringID:   transaction.RecurringID,
+		ExternalID:    transaction.ExternalID,
+		Date:          transaction.TransactionDate,
+		CreatedAt:     transaction.CreatedAt,
+	}
+}
+
+func toBizAccounters(transactions []model.AccounterTransaction, codes map[int]string) []*biz.Accounter {
+	results := make([]*biz.Accounter, 0, len(transactions))
+	for i := range transactions {
+		results = append(results, toBizAccounter(&transactions[i], codes))
+	}
+	return results
+}
+
+// defaultCurrencies are seeded into the currencies table, CNY keeps ID 1 that
+// transactions recorded before they had a currency refer to
+var defaultCurrencies = []model.Currency{
+	{CurrencyID: 1, CurrencyCode: "CNY", CurrencyName: "人民币", CurrencySymbol: ptrTo("¥")},
+	{CurrencyID: 2, CurrencyCode: "USD", CurrencyName: "美元", CurrencySymbol: ptrTo("$")},
+	{CurrencyID: 3, CurrencyCode: "EUR", CurrencyName: "欧元", CurrencySymbol: ptrTo("€")},
+	{CurrencyID: 4, CurrencyCode: "JPY", CurrencyName: "日元", CurrencySymbol: ptrTo("¥")},
+	{CurrencyID: 5, CurrencyCode: "HKD", CurrencyName: "港币", CurrencySymbol: ptrTo("HK$")},
+	{CurrencyID: 6, CurrencyCode: "GBP", CurrencyName: "英镑", CurrencySymbol: ptrTo("£")},
+}
+
+func ptrTo(s string) *string {
+	return &s
+}
+
+// seedCurrencies inserts the default currencies that are missing, existing rows are left alone
+func seedCurrencies(db *gorm.DB) error {
+	for _, currency := range defaultCurrencies {
+		if err := db.Where("currency_code = ?", currency.CurrencyCode).FirstOrCreate(&currency).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// currencyCodes maps the currency IDs of transactions to currency codes
+func (r *accounterDbRepo) currencyCodes(ctx context.Context) (map[int]string, error) {
+	var currencies []model.Currency
+	if err := r.data.db.WithContext(ctx).Find(&currencies).Error; err != nil {
+		r.log.WithContext(ctx).Errorf("Failed to load currencies: %v", err)
+		return nil, err
+	}
+	codes := make(map[int]string, len(currencies))
+	for _, currency := range currencies {
+		codes[currency.CurrencyID] = currency.CurrencyCode
+	}
+	return codes, nil
+}
+
+// currencyID returns the ID of a currency code, codes that aren't seeded are added to the currencies table
+func (r *accounterDbRepo) currencyID(ctx context.Context, code string) (int, error) {
+	code = currencyOrDefault(code)
+	currency := model.Currency{CurrencyCode: code, CurrencyName: code}
+	if err := r.data.db.WithContext(ctx).Where("currency_code = ?", code).FirstOrCreate(&currency).Error; err != nil {
+		r.log.WithContext(ctx).Errorf("Failed to find currency %s: %v", code, err)
+		return 0, err
+	}
+	return currency.CurrencyID, nil
+}
+
+// toCurrencyID returns the ID of the currency a transfer arrives in, 0 for the other types
+func (r *accounterDbRepo) toCurrencyID(ctx context.Context, accounter *biz.Accounter) (int, error) {
+	if accounter.ToCurrency == "" {
+		return 0, nil
+	}
+	return r.currencyID(ctx, accounter.ToCurrency)
+}
+
+// dateExprs holds the dialect specific SQL expressions that extract parts of transaction_date
+type dateExprs struct {
+	day         string // yyyy-mm-dd
+	year        string
+	month       string
+	isoYearWeek string // ISO year * 100 + ISO week
+}
+
+func transactionDateExprs(dialect string) dateExprs {
+	switch dialect {
+	case "sqlite":
+		// SQLite has no ISO week function: the Thursday of a week decides its ISO year and week number
+		thursday := "transaction_date, '-3 days', 'weekday 4'"
+		return dateExprs{
+			day:         "date(transaction_date)",
+			year:        "CAST(strftime('%Y', transaction_date) AS INTEGER)",
+			month:       "CAST(strftime('%m', transaction_date) AS INTEGER)",
+			isoYearWeek: "CAST(strftime('%Y', " + thursday + ") AS INTEGER) * 100 + (CAST(strftime('%j', " + thursday + ") AS INTEGER) - 1) / 7 + 1",
+		}
+	default:
+		return dateExprs{
+			day:         "DATE_FORMAT(transaction_date, '%Y-%m-%d')",
+			year:        "YEAR(transaction_date)",
+			month:       "MONTH(transaction_date)",
+			isoYearWeek: "YEARWEEK(transaction_date, 3)",
+		}
+	}
+}
+
+func (r *accounterDbRepo) dateExprs() dateExprs {
+	return transactionDateExprs(r.data.db.Dialector.Name())
+}
+
+// createdAtColumn is the expression that sorts and pages by created_at. SQLite keeps times as text in the
+// format of whoever wrote them, CURRENT_TIMESTAMP for older rows and the driver for newer ones, strftime
+// gives every row the same format with milliseconds so that text comparisons follow the time.
+func createdAtColumn(dialect string) string {
+	if dialect == "sqlite" {
+		return "strftime('%Y-%m-%d %H:%M:%f', created_at)"
+	}
+	return "created_at"
+}
+
+// createdAtKey is a creation time as createdAtColumn compares it
+func createdAtKey(dialect string, t time.Time) interface{} {
+	if dialect == "sqlite" {
+		return t.UTC().Format("2006-01-02 15:04:05.000")
+	}
+	return t
+}
+
+// creationTime is the current time at the precision created_at keeps: the milliseconds of createdAtColumn
+// in SQLite and the whole seconds of a MySQL timestamp. A cursor made from the time Save returns
+// then compares equal to the stored one.
+func (r *accounterDbRepo) creationTime() time.Time {
+	if r.data.db.Dialector.Name() == "sqlite" {
+		return time.Now().UTC().Truncate(time.Millisecond)
+	}
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+// newTransaction converts a new biz.Accounter to a model.AccounterTransaction, adding its currencies
+// to the currencies table when needed. codes maps the IDs of those currencies to their codes.
+func (r *accounterDbRepo) newTransaction(ctx context.Context, accounter *biz.Accounter) (*model.AccounterTransaction, map[int]string, error) {
+	currencyID, err := r.currencyID(ctx, accounter.Currency)
+	if err != nil {
+		return nil, nil, err
+	}
+	toCurrencyID, err := r.toCurrencyID(ctx, accounter)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	transaction := &model.AccounterTransaction{
 		UserID:          accounter.UserID,
-		CategoryID:      int(accounter.Category),
-		CurrencyID:      1, // Default to CNY
+		CategoryID:      int(accounter.CategoryID),
+		CurrencyID:      currencyID,
+		AccountID:       accounter.AccountID,
+		ToAccountID:     accounter.ToAccountID,
+		ToCurrencyID:    toCurrencyID,
+		ToAmount:        int64(accounter.ToAmount),
+		Fee:             int64(accounter.Fee),
+		RecurringID:     accounter.RecurringID,
+		ExternalID:      accounter.ExternalID,
 		TransactionType: int8(accounter.Type),
-		Amount:          accounter.Amount,
+		Amount:          int64(accounter.Amount),
 		TransactionDate: accounter.Date,
 		Note:            &accounter.Desc,
+		CreatedAt:       r.creationTime(),
+		SearchText:      ptrTo(biz.SearchText(accounter.Desc, accounter.Tags)),
 	}
+	return transaction, map[int]string{currencyID: accounter.Currency, toCurrencyID: accounter.ToCurrency}, nil
+}
 
-	if err := r.data.db.WithContext(ctx).Create(transaction).Error; err != nil {
-		r.log.WithContext(ctx).Errorf("Failed to save accounter: %v", err)
+func (r *accounterDbRepo) Save(ctx context.Context, accounter *biz.Accounter) (*biz.Accounter, error) {
+	transaction, codes, err := r.newTransaction(ctx, accounter)
+	if err != nil {
 		return nil, err
 	}
 
-	// Convert back to biz.Accounter
-	result := &biz.Accounter{
-		TransactionID: transaction.TransactionID,
-		UserID:        transaction.UserID,
-		Type:          v1.Type(transaction.TransactionType),
-		Category:      v1.Category(transaction.CategoryID),
-		Desc:          *transaction.Note,
-		Amount:        transaction.Amount,
-		Date:          transaction.TransactionDate,
+	err = r.data.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(transaction).Error; err != nil {
+			return err
+		}
+		return addTags(tx, accounter.UserID, transaction.TransactionID, accounter.Tags)
+	})
+	if err != nil {
+		r.log.WithContext(ctx).Errorf("Failed to save accounter: %v", err)
+		return nil, err
 	}
 
+	result := toBizAccounter(transaction, codes)
+	result.Tags = accounter.Tags
 	return result, nil
 }
 
+// SaveBatch inserts all transactions in one database transaction
+func (r *accounterDbRepo) SaveBatch(ctx context.Context, accounters []*biz.Accounter) ([]*biz.Accounter, error) {
+	transactions := make([]*model.AccounterTransaction, 0, len(accounters))
+	codes := make(map[int]string)
+	for _, accounter := range accounters {
+		transaction, transactionCodes, err := r.newTransaction(ctx, accounter)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, transaction)
+		for id, code := range transactionCodes {
+			codes[id] = code
+		}
+	}
+	if len(transactions) == 0 {
+		return []*biz.Accounter{}, nil
+	}
+
+	err := r.data.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.CreateInBatches(transactions, 100).Error; err != nil {
+			return err
+		}
+		for i, transaction := range transactions {
+			if err := addTags(tx, transaction.UserID, transaction.TransactionID, accounters[i].Tags); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		r.log.WithContext(ctx).Errorf("Failed to save %d accounters: %v", len(transactions), err)
+		return nil, err
+	}
+
+	results := make([]*biz.Accounter, 0, len(transactions))
+	for i, transaction := range transactions {
+		result := toBizAccounter(transaction, codes)
+		result.Tags = accounters[i].Tags
+		results = append(results, result)
+	}
+	r.log.WithContext(ctx).Infof("Saved %d accounters", len(results))
+	return results, nil
+}
+
 func (r *accounterDbRepo) Update(ctx context.Context, accounter *biz.Accounter) (*biz.Accounter, error) {
-	transaction := &model.AccounterTransaction{
-		TransactionID:   accounter.TransactionID,
-		UserID:          accounter.UserID,
-		CategoryID:      int(accounter.Category),
-		CurrencyID:      1, // Default to CNY
-		TransactionType: int8(accounter.Type),
-		Amount:          accounter.Amount,
-		TransactionDate: accounter.Date,
-		Note:            &accounter.Desc,
+	currencyID, err := r.currencyID(ctx, accounter.Currency)
+	if err != nil {
+		return nil, err
 	}
+	toCurrencyID, err := r.toCurrencyID(ctx, accounter)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.data.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.AccounterTransaction{}).
+			Where("transaction_id = ?", accounter.TransactionID).
+			Updates(map[string]interface{}{
+				"user_id":          accounter.UserID,
+				"category_id":      int(accounter.CategoryID),
+				"currency_id":      currencyID,
+				"account_id":       accounter.AccountID,
+				"to_account_id":    accounter.ToAccountID,
+				"to_currency_id":   toCurrencyID,
+				"to_amount_e4":     int64(accounter.ToAmount),
+				"fee_e4":           int64(accounter.Fee),
+				"transaction_type": int8(accounter.Type),
+				"amount_e4":        int64(accounter.Amount),
+				"transaction_date": accounter.Date,
+				"note":             accounter.Desc,
+				"search_text":      biz.SearchText(accounter.Desc, accounter.Tags),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
 
-	if err := r.data.db.WithContext(ctx).Save(transaction).Error; err != nil {
-		r.log.WithContext(ctx).Errorf("Failed to update accounter: %v", err)
+		if result.RowsAffected == 0 {
+			// MySQL reports zero affected rows when nothing changed, so confirm the record exists
+			var count int64
+			if err := tx.Model(&model.AccounterTransaction{}).
+				Where("transaction_id = ?", accounter.TransactionID).Count(&count).Error; err != nil {
+				return err
+			}
+			if count == 0 {
+				return biz.ErrAccounterNotFound
+			}
+		}
+
+		if err := tx.Where("transaction_id = ?", accounter.TransactionID).Delete(&model.AccounterTransactionTag{}).Error; err != nil {
+			return err
+		}
+		return addTags(tx, accounter.UserID, accounter.TransactionID, accounter.Tags)
+	})
+	if err != nil {
+		if !errors.Is(err, biz.ErrAccounterNotFound) {
+			r.log.WithContext(ctx).Errorf("Failed to update accounter: %v", err)
+		}
 		return nil, err
 	}
 
@@ -86,99 +352,686 @@ func (r *accounterDbRepo) Update(ctx context.Context, accounter *biz.Accounter)
 func (r *accounterDbRepo) FindByID(ctx context.Context, id int64) (*biz.Accounter, error) {
 	var transaction model.AccounterTransaction
 	if err := r.data.db.WithContext(ctx).First(&transaction, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, biz.ErrAccounterNotFound
+		}
 		r.log.WithContext(ctx).Errorf("Failed to find accounter by id %d: %v", id, err)
 		return nil, err
 	}
-
-	result := &biz.Accounter{
-		TransactionID: transaction.TransactionID,
-		UserID:        transaction.UserID,
-		Type:          v1.Type(transaction.TransactionType),
-		Category:      v1.Category(transaction.CategoryID),
-		Desc:          *transaction.Note,
-		Amount:        transaction.Amount,
-		Date:          transaction.TransactionDate,
+	codes, err := r.currencyCodes(ctx)
+	if err != nil {
+		return nil, err
 	}
 
+	result := toBizAccounter(&transaction, codes)
+	if err := r.loadTags(ctx, []*biz.Accounter{result}); err != nil {
+		return nil, err
+	}
 	return result, nil
 }
 
 func (r *accounterDbRepo) ListByUserID(ctx context.Context, userID int64) ([]*biz.Accounter, error) {
 	var transactions []model.AccounterTransaction
-	if err := r.data.db.WithContext(ctx).Where("user_id = ?", userID).Find(&transactions).Error; err != nil {
+	if err := r.data.db.WithContext(ctx).Where("user_id = ?", userID).Order("transaction_id").Find(&transactions).Error; err != nil {
 		r.log.WithContext(ctx).Errorf("Failed to list accounters by user id %d: %v", userID, err)
 		return nil, err
 	}
-
-	var results []*biz.Accounter
-	for _, transaction := range transactions {
-		note := ""
-		if transaction.Note != nil {
-			note = *transaction.Note
-		}
-		result := &biz.Accounter{
-			TransactionID: transaction.TransactionID,
-			UserID:        transaction.UserID,
-			Type:          v1.Type(transaction.TransactionType),
-			Category:      v1.Category(transaction.CategoryID),
-			Desc:          note,
-			Amount:        transaction.Amount,
-			Date:          transaction.TransactionDate,
-		}
-		results = append(results, result)
+	codes, err := r.currencyCodes(ctx)
+	if err != nil {
+		return nil, err
 	}
 
+	results := toBizAccounters(transactions, codes)
+	if err := r.loadTags(ctx, results); err != nil {
+		return nil, err
+	}
 	return results, nil
 }
 
 func (r *accounterDbRepo) ListAll(ctx context.Context) ([]*biz.Accounter, error) {
 	var transactions []model.AccounterTransaction
-	if err := r.data.db.WithContext(ctx).Find(&transactions).Error; err != nil {
+	if err := r.data.db.WithContext(ctx).Order("transaction_id").Find(&transactions).Error; err != nil {
 		r.log.WithContext(ctx).Errorf("Failed to list all accounters: %v", err)
 		return nil, err
 	}
+	codes, err := r.currencyCodes(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	var results []*biz.Accounter
-	for _, transaction := range transactions {
-		note := ""
-		if transaction.Note != nil {
-			note = *transaction.Note
-		}
-		result := &biz.Accounter{
-			TransactionID: transaction.TransactionID,
-			UserID:        transaction.UserID,
-			Type:          v1.Type(transaction.TransactionType),
-			Category:      v1.Category(transaction.CategoryID),
-			Desc:          note,
-			Amount:        transaction.Amount,
-			Date:          transaction.TransactionDate,
+	results := toBizAccounters(transactions, codes)
+	if err := r.loadTags(ctx, results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// listQuery builds the filtered query shared by ListWithFilters and EachWithFilters
+func (r *accounterDbRepo) listQuery(ctx context.Context, filter *biz.ListFilter) *gorm.DB {
+	db := r.data.db.WithContext(ctx).Model(&model.AccounterTransaction{})
+	if filter.UserID != 0 {
+		db = db.Where("user_id = ?", filter.UserID)
+	}
+	if len(filter.Types) > 0 {
+		types := make([]int8, len(filter.Types))
+		for i, t := range filter.Types {
+			types[i] = int8(t)
 		}
-		results = append(results, result)
+		db = db.Where("transaction_type IN ?", types)
+	}
+	if len(filter.CategoryIDs) > 0 {
+		db = db.Where("category_id IN ?", filter.CategoryIDs)
+	}
+	if filter.AccountID != nil {
+		// Transfers belong to both of their accounts
+		db = db.Where("(account_id = ? OR (transaction_type = ? AND to_account_id = ?))", *filter.AccountID, int8(v1.Type_Transfer), *filter.AccountID)
+	}
+	if filter.MinAmount != nil {
+		db = db.Where("amount_e4 >= ?", int64(*filter.MinAmount))
+	}
+	if filter.MaxAmount != nil {
+		db = db.Where("amount_e4 <= ?", int64(*filter.MaxAmount))
+	}
+	db = r.withTags(db, filter.Tags)
+	db = withSearch(db, filter.Query)
+	if filter.StartDate != nil {
+		db = db.Where("transaction_date >= ?", *filter.StartDate)
 	}
+	if filter.EndDate != nil {
+		db = db.Where("transaction_date <= ?", *filter.EndDate)
+	}
+	return db
+}
 
-	return results, nil
+// listOrder is the ORDER BY of a sort field, ties are ordered by ID in the same direction
+func listOrder(dialect string, sortBy v1.SortField, descending bool) string {
+	direction := ""
+	if descending {
+		direction = " DESC"
+	}
+	order := ""
+	switch sortBy {
+	case v1.SortField_DATE:
+		order = "transaction_date" + direction + ", "
+	case v1.SortField_AMOUNT:
+		order = "amount_e4" + direction + ", "
+	case v1.SortField_CREATED_AT:
+		order = createdAtColumn(dialect) + direction + ", "
+	}
+	return order + "transaction_id" + direction
+}
+
+// afterCursor narrows a query of transactions to those after a cursor in the order of listOrder
+func afterCursor(db *gorm.DB, c *biz.ListCursor) *gorm.DB {
+	op := ">"
+	if c.Descending {
+		op = "<"
+	}
+	keyset := func(column string, key interface{}) *gorm.DB {
+		return db.Where("("+column+" "+op+" ? OR ("+column+" = ? AND transaction_id "+op+" ?))", key, key, c.ID)
+	}
+	switch c.SortBy {
+	case v1.SortField_DATE:
+		return keyset("transaction_date", c.Date)
+	case v1.SortField_AMOUNT:
+		return keyset("amount_e4", int64(c.Amount))
+	case v1.SortField_CREATED_AT:
+		dialect := db.Dialector.Name()
+		return keyset(createdAtColumn(dialect), createdAtKey(dialect, c.CreatedAt))
+	default:
+		return db.Where("transaction_id "+op+" ?", c.ID)
+	}
 }
 
-// ListWithFilters - Database implementation (placeholder for future use)
 func (r *accounterDbRepo) ListWithFilters(ctx context.Context, filter *biz.ListFilter) ([]*biz.Accounter, int32, error) {
-	// TODO: Implement database version with proper SQL queries
-	return nil, 0, fmt.Errorf("database implementation not yet available")
+	var total int64
+	if err := r.listQuery(ctx, filter).Count(&total).Error; err != nil {
+		r.log.WithContext(ctx).Errorf("Failed to count accounters: %v", err)
+		return nil, 0, err
+	}
+
+	page := r.listQuery(ctx, filter)
+	offset := int((filter.Page - 1) * filter.PageSize)
+	if filter.After != nil {
+		page, offset = afterCursor(page, filter.After), 0
+	}
+	if int64(offset) > total {
+		return []*biz.Accounter{}, int32(total), nil
+	}
+
+	var transactions []model.AccounterTransaction
+	if err := page.
+		Order(listOrder(r.data.db.Dialector.Name(), filter.SortBy, filter.Descending)).
+		Offset(offset).
+		Limit(int(filter.PageSize)).
+		Find(&transactions).Error; err != nil {
+		r.log.WithContext(ctx).Errorf("Failed to list accounters with filters: %v", err)
+		return nil, 0, err
+	}
+	codes, err := r.currencyCodes(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	results := toBizAccounters(transactions, codes)
+	if err := r.loadTags(ctx, results); err != nil {
+		return nil, 0, err
+	}
+	return results, int32(total), nil
+}
+
+// eachBatchSize is the number of transactions EachWithFilters reads at a time
+const eachBatchSize = 500
+
+// EachWithFilters reads the transactions in batches, each continuing after the last transaction of
+// the previous one, so that no rows are held open while fn runs and transactions added or deleted
+// meanwhile don't make it skip or repeat others
+func (r *accounterDbRepo) EachWithFilters(ctx context.Context, filter *biz.ListFilter, fn func(*biz.Accounter) error) error {
+	codes, err := r.currencyCodes(ctx)
+	if err != nil {
+		return err
+	}
+	after := filter.After
+	for {
+		batch := r.listQuery(ctx, filter)
+		if after != nil {
+			batch = afterCursor(batch, after)
+		}
+		var transactions []model.AccounterTransaction
+		if err := batch.
+			Order(listOrder(r.data.db.Dialector.Name(), filter.SortBy, filter.Descending)).
+			Limit(eachBatchSize).
+			Find(&transactions).Error; err != nil {
+			r.log.WithContext(ctx).Errorf("Failed to list accounters with filters: %v", err)
+			return err
+		}
+		results := toBizAccounters(transactions, codes)
+		if err := r.loadTags(ctx, results); err != nil {
+			return err
+		}
+		for _, a := range results {
+			if err := fn(a); err != nil {
+				return err
+			}
+		}
+		if len(results) < eachBatchSize {
+			return nil
+		}
+		after = biz.CursorOf(filter, results[len(results)-1])
+	}
 }
 
-// Delete - Database implementation (placeholder for future use)
 func (r *accounterDbRepo) Delete(ctx context.Context, id int64) error {
-	// TODO: Implement database version
-	return fmt.Errorf("database implementation not yet available")
+	err := r.data.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&model.AccounterTransaction{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return biz.ErrAccounterNotFound
+		}
+		return tx.Where("transaction_id = ?", id).Delete(&model.AccounterTransactionTag{}).Error
+	})
+	if err != nil {
+		if !errors.Is(err, biz.ErrAccounterNotFound) {
+			r.log.WithContext(ctx).Errorf("Failed to delete accounter %d: %v", id, err)
+		}
+		return err
+	}
+
+	r.log.WithContext(ctx).Infof("Deleted accounter with ID: %d", id)
+	return nil
+}
+
+// DeleteBatch deletes all transactions and their tags in one database transaction
+func (r *accounterDbRepo) DeleteBatch(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	err := r.data.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("transaction_id IN ?", ids).Delete(&model.AccounterTransaction{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != int64(len(ids)) {
+			return biz.ErrAccounterNotFound
+		}
+		return tx.Where("transaction_id IN ?", ids).Delete(&model.AccounterTransactionTag{}).Error
+	})
+	if err != nil {
+		if !errors.Is(err, biz.ErrAccounterNotFound) {
+			r.log.WithContext(ctx).Errorf("Failed to delete %d accounters: %v", len(ids), err)
+		}
+		return err
+	}
+
+	r.log.WithContext(ctx).Infof("Deleted %d accounters", len(ids))
+	return nil
+}
+
+// categoryStatRow is one row of the GROUP BY query behind GetStats.
+// Rows are per currency and day so that amounts can be converted with the rate of their day.
+type categoryStatRow struct {
+	TransactionType int8
+	CategoryID      int
+	CurrencyID      int
+	Day             string
+	Amount          int64
+	Count           int32
+}
+
+// convertRow converts the amount of a stats row into the base currency and adds it to the per-currency totals
+func convertRow(converter biz.Converter, byCurrency currencyTotals, transactionType int8, currency, day string, amount biz.Money, count int32) (biz.Money, error) {
+	byCurrency.add(currency, v1.Type(transactionType), amount, count)
+	if converter == nil {
+		return amount, nil
+	}
+	// MySQL and SQLite both return yyyy-mm-dd, keep only the date in case a driver appends a time
+	if len(day) > 10 {
+		day = day[:10]
+	}
+	date, err := time.Parse("2006-01-02", day)
+	if err != nil {
+		return 0, fmt.Errorf("invalid transaction day %q: %v", day, err)
+	}
+	return converter.Convert(amount, currency, date)
+}
+
+// tagStatRow is one row of the GROUP BY query behind the per-tag statistics of GetStats
+type tagStatRow struct {
+	TransactionType int8
+	Tag             string
+	CurrencyID      int
+	Day             string
+	Amount          int64
+	Count           int32
 }
 
-// GetStats - Database implementation (placeholder for future use)
 func (r *accounterDbRepo) GetStats(ctx context.Context, filter *biz.StatsFilter) (*biz.Stats, error) {
-	// TODO: Implement database version with proper SQL queries
-	return nil, fmt.Errorf("database implementation not yet available")
+	query := func() *gorm.DB {
+		db := r.data.db.WithContext(ctx).Model(&model.AccounterTransaction{}).
+			Where("transaction_type IN ?", []int8{int8(v1.Type_Income), int8(v1.Type_Expense)})
+		if filter.UserID != 0 {
+			db = db.Where("user_id = ?", filter.UserID)
+		}
+		if filter.AccountID != nil {
+			db = db.Where("account_id = ?", *filter.AccountID)
+		}
+		if filter.StartDate != nil {
+			db = db.Where("transaction_date >= ?", *filter.StartDate)
+		}
+		if filter.EndDate != nil {
+			db = db.Where("transaction_date <= ?", *filter.EndDate)
+		}
+		return r.withTags(db, filter.Tags)
+	}
+
+	var rows []categoryStatRow
+	if err := query().Select("transaction_type, category_id, currency_id, " + r.dateExprs().day + " AS day, SUM(amount_e4) AS amount, COUNT(*) AS count").
+		Group("transaction_type, category_id, currency_id, day").
+		Order("category_id").
+		Scan(&rows).Error; err != nil {
+		r.log.WithContext(ctx).Errorf("Failed to get stats: %v", err)
+		return nil, err
+	}
+	// A transaction counts once for each of its tags
+	var tagRows []tagStatRow
+	if err := r.data.db.WithContext(ctx).
+		Table("(?) AS tr", query().Select("transaction_id, transaction_type, currency_id, "+r.dateExprs().day+" AS day, amount_e4 AS amount")).
+		Joins("JOIN accounter_transaction_tags tt ON tt.transaction_id = tr.transaction_id").
+		Joins("JOIN accounter_tags t ON t.tag_id = tt.tag_id").
+		Select("tr.transaction_type, t.name AS tag, tr.currency_id, tr.day, SUM(tr.amount) AS amount, COUNT(*) AS count").
+		Group("tr.transaction_type, t.name, tr.currency_id, tr.day").
+		Scan(&tagRows).Error; err != nil {
+		r.log.WithContext(ctx).Errorf("Failed to get tag stats: %v", err)
+		return nil, err
+	}
+	codes, err := r.currencyCodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &biz.Stats{}
+	byCurrency := make(currencyTotals)
+	categories := make(map[[2]int]*biz.CategoryStat)
+	for _, row := range rows {
+		amount, err := convertRow(filter.Converter, byCurrency, row.TransactionType, currencyOrDefault(codes[row.CurrencyID]), row.Day, biz.Money(row.Amount), row.Count)
+		if err != nil {
+			return nil, err
+		}
+
+		key := [2]int{int(row.TransactionType), row.CategoryID}
+		stat, exists := categories[key]
+		if !exists {
+			stat = &biz.CategoryStat{CategoryID: int64(row.CategoryID)}
+			categories[key] = stat
+			if v1.Type(row.TransactionType) == v1.Type_Income {
+				stats.IncomeByCategory = append(stats.IncomeByCategory, stat)
+			} else {
+				stats.ExpenseByCategory = append(stats.ExpenseByCategory, stat)
+			}
+		}
+		stat.Amount += amount
+		stat.Count += row.Count
+		if v1.Type(row.TransactionType) == v1.Type_Income {
+			stats.TotalIncome += amount
+		} else {
+			stats.TotalExpense += amount
+		}
+	}
+	stats.Balance = stats.TotalIncome - stats.TotalExpense
+	stats.ByCurrency = byCurrency.stats()
+
+	byTag := make(tagTotals)
+	for _, row := range tagRows {
+		// The category rows already added these transactions to the per-currency totals
+		amount, err := convertRow(filter.Converter, make(currencyTotals), row.TransactionType, currencyOrDefault(codes[row.CurrencyID]), row.Day, biz.Money(row.Amount), row.Count)
+		if err != nil {
+			return nil, err
+		}
+		byTag.add(row.Tag, v1.Type(row.TransactionType), amount, row.Count)
+	}
+	stats.IncomeByTag = byTag.stats(v1.Type_Income)
+	stats.ExpenseByTag = byTag.stats(v1.Type_Expense)
+
+	return stats, nil
+}
+
+// periodStatRow is one row of the GROUP BY query behind GetPeriodStats
+type periodStatRow struct {
+	PeriodKey       int
+	TransactionType int8
+	CurrencyID      int
+	Day             string
+	Amount          int64
+	Count           int32
 }
 
-// GetPeriodStats - Database implementation (placeholder for future use)
 func (r *accounterDbRepo) GetPeriodStats(ctx context.Context, filter *biz.PeriodStatsFilter) (*biz.PeriodStats, error) {
-	// TODO: Implement database version with proper SQL queries
-	return nil, fmt.Errorf("database implementation not yet available")
+	exprs := r.dateExprs()
+	// Transfers are neither income nor expense
+	db := r.data.db.WithContext(ctx).Model(&model.AccounterTransaction{}).
+		Where("transaction_type <> ?", int8(v1.Type_Transfer))
+	if filter.UserID != 0 {
+		db = db.Where("user_id = ?", filter.UserID)
+	}
+	if filter.AccountID != nil {
+		db = db.Where("account_id = ?", *filter.AccountID)
+	}
+	if filter.Year != 0 {
+		db = db.Where(exprs.year+" = ?", filter.Year)
+	}
+
+	// 根据时间段类型选择分组键
+	var periodKey string
+	switch filter.PeriodType {
+	case v1.PeriodType_MONTHLY:
+		if filter.Month != 0 {
+			db = db.Where(exprs.month+" = ?", filter.Month)
+		}
+		periodKey = exprs.year + " * 100 + " + exprs.month
+	case v1.PeriodType_YEARLY:
+		periodKey = exprs.year
+	case v1.PeriodType_WEEKLY:
+		if filter.Week != 0 {
+			// 计算指定年份的第N周
+			yearStart := time.Date(int(filter.Year), 1, 1, 0, 0, 0, 0, time.UTC)
+			weekStart := yearStart.AddDate(0, 0, (int(filter.Week)-1)*7)
+			weekEnd := weekStart.AddDate(0, 0, 6)
+			db = db.Where("transaction_date >= ? AND transaction_date <= ?", weekStart, weekEnd)
+		}
+		periodKey = exprs.isoYearWeek
+	default:
+		return &biz.PeriodStats{}, nil
+	}
+
+	var rows []periodStatRow
+	if err := db.Select(periodKey + " AS period_key, transaction_type, currency_id, " + exprs.day + " AS day, SUM(amount_e4) AS amount, COUNT(*) AS count").
+		Group("period_key, transaction_type, currency_id, day").
+		Order("period_key").
+		Scan(&rows).Error; err != nil {
+		r.log.WithContext(ctx).Errorf("Failed to get period stats: %v", err)
+		return nil, err
+	}
+	codes, err := r.currencyCodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &biz.PeriodStats{}
+	byCurrency := make(currencyTotals)
+	periods := make(map[int]*biz.PeriodData)
+	for _, row := range rows {
+		period, exists := periods[row.PeriodKey]
+		if !exists {
+			period = &biz.PeriodData{PeriodName: periodDisplayName(filter.PeriodType, row.PeriodKey)}
+			periods[row.PeriodKey] = period
+			stats.Periods = append(stats.Periods, period)
+		}
+		period.TransactionCount += row.Count
+
+		if t := v1.Type(row.TransactionType); t != v1.Type_Income && t != v1.Type_Expense {
+			continue
+		}
+		amount, err := convertRow(filter.Converter, byCurrency, row.TransactionType, currencyOrDefault(codes[row.CurrencyID]), row.Day, biz.Money(row.Amount), row.Count)
+		if err != nil {
+			return nil, err
+		}
+		if v1.Type(row.TransactionType) == v1.Type_Income {
+			period.Income += amount
+			stats.TotalIncome += amount
+		} else {
+			period.Expense += amount
+			stats.TotalExpense += amount
+		}
+	}
+	stats.ByCurrency = byCurrency.stats()
+
+	for _, period := range stats.Periods {
+		period.Balance = period.Income - period.Expense
+	}
+	stats.TotalBalance = stats.TotalIncome - stats.TotalExpense
+
+	return stats, nil
+}
+
+// accountStatRow is one row of the GROUP BY queries behind GetAccountStats
+type accountStatRow struct {
+	AccountID       int64
+	TransactionType int8
+	Amount          int64
+	Fee             int64
+	Count           int32
+}
+
+func (r *accounterDbRepo) GetAccountStats(ctx context.Context, filter *biz.StatsFilter) ([]*biz.AccountStat, error) {
+	query := func() *gorm.DB {
+		db := r.data.db.WithContext(ctx).Model(&model.AccounterTransaction{})
+		if filter.UserID != 0 {
+			db = db.Where("user_id = ?", filter.UserID)
+		}
+		if filter.StartDate != nil {
+			db = db.Where("transaction_date >= ?", *filter.StartDate)
+		}
+		if filter.EndDate != nil {
+			db = db.Where("transaction_date <= ?", *filter.EndDate)
+		}
+		return db
+	}
+
+	var rows []accountStatRow
+	if err := query().Select("account_id, transaction_type, SUM(amount_e4) AS amount, SUM(fee_e4) AS fee, COUNT(*) AS count").
+		Group("account_id, transaction_type").
+		Scan(&rows).Error; err != nil {
+		r.log.WithContext(ctx).Errorf("Failed to get account stats: %v", err)
+		return nil, err
+	}
+	// The arriving side of the transfers, in the currencies of the destination accounts
+	var incoming []accountStatRow
+	if err := query().Where("transaction_type = ?", int8(v1.Type_Transfer)).
+		Select("to_account_id AS account_id, SUM(to_amount_e4) AS amount, COUNT(*) AS count").
+		Group("to_account_id").
+		Scan(&incoming).Error; err != nil {
+		r.log.WithContext(ctx).Errorf("Failed to get incoming transfers: %v", err)
+		return nil, err
+	}
+
+	byAccount := make(map[int64]*biz.AccountStat)
+	for _, row := range rows {
+		stat := accountStat(byAccount, row.AccountID)
+		switch v1.Type(row.TransactionType) {
+		case v1.Type_Income:
+			stat.Income += biz.Money(row.Amount)
+		case v1.Type_Expense:
+			stat.Expense += biz.Money(row.Amount)
+		case v1.Type_Transfer:
+			stat.TransferOut += biz.Money(row.Amount) + biz.Money(row.Fee)
+		}
+		stat.Count += row.Count
+	}
+	for _, row := range incoming {
+		stat := accountStat(byAccount, row.AccountID)
+		stat.TransferIn += biz.Money(row.Amount)
+		stat.Count += row.Count
+	}
+
+	return sortAccountStats(byAccount), nil
+}
+
+// tagRow is one row of the GROUP BY query behind ListTags
+type tagRow struct {
+	Name  string
+	Count int32
+}
+
+func (r *accounterDbRepo) ListTags(ctx context.Context, userID int64) ([]*biz.Tag, error) {
+	var rows []tagRow
+	if err := r.data.db.WithContext(ctx).Table("accounter_tags AS t").
+		Joins("JOIN accounter_transaction_tags tt ON tt.tag_id = t.tag_id").
+		Where("t.user_id = ?", userID).
+		Select("t.name, COUNT(*) AS count").
+		Group("t.name").
+		Order("t.name").
+		Scan(&rows).Error; err != nil {
+		r.log.WithContext(ctx).Errorf("Failed to list tags of user %d: %v", userID, err)
+		return nil, err
+	}
+
+	tags := make([]*biz.Tag, 0, len(rows))
+	for _, row := range rows {
+		tags = append(tags, &biz.Tag{Name: row.Name, Count: row.Count})
+	}
+	return tags, nil
+}
+
+// addTags links a transaction to its tags, adding the tags the user doesn't have yet
+func addTags(tx *gorm.DB, userID, transactionID int64, tags []string) error {
+	for _, name := range tags {
+		tag := model.AccounterTag{UserID: userID, Name: name}
+		if err := tx.Where("user_id = ? AND name = ?", userID, name).FirstOrCreate(&tag).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&model.AccounterTransactionTag{TransactionID: transactionID, TagID: tag.TagID}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tagQueryChunk caps the transaction IDs of one loadTags query, below the bound parameter limit of SQLite
+const tagQueryChunk = 500
+
+// loadTags fills in the tags of transactions read from the database
+func (r *accounterDbRepo) loadTags(ctx context.Context, accounters []*biz.Accounter) error {
+	if err := fillTags(r.data.db.WithContext(ctx), accounters); err != nil {
+		r.log.WithContext(ctx).Errorf("Failed to load tags: %v", err)
+		return err
+	}
+	return nil
+}
+
+// fillTags sets the tags of accounters from the tag tables
+func fillTags(db *gorm.DB, accounters []*biz.Accounter) error {
+	byID := make(map[int64]*biz.Accounter, len(accounters))
+	ids := make([]int64, 0, len(accounters))
+	for _, accounter := range accounters {
+		byID[accounter.TransactionID] = accounter
+		ids = append(ids, accounter.TransactionID)
+	}
+	for len(ids) > 0 {
+		chunk := ids
+		if len(chunk) > tagQueryChunk {
+			chunk = chunk[:tagQueryChunk]
+		}
+		ids = ids[len(chunk):]
+
+		var rows []struct {
+			TransactionID int64
+			Name          string
+		}
+		if err := db.Table("accounter_transaction_tags AS tt").
+			Joins("JOIN accounter_tags t ON t.tag_id = tt.tag_id").
+			Where("tt.transaction_id IN ?", chunk).
+			Select("tt.transaction_id, t.name").
+			Order("t.name").
+			Scan(&rows).Error; err != nil {
+			return err
+		}
+		for _, row := range rows {
+			accounter := byID[row.TransactionID]
+			accounter.Tags = append(accounter.Tags, row.Name)
+		}
+	}
+	return nil
+}
+
+// withTags narrows a query of transactions to those that have all of tags
+func (r *accounterDbRepo) withTags(db *gorm.DB, tags []string) *gorm.DB {
+	for _, tag := range tags {
+		db = db.Where("transaction_id IN (?)", r.data.db.Table("accounter_transaction_tags AS tt").
+			Joins("JOIN accounter_tags t ON t.tag_id = tt.tag_id").
+			Where("t.name = ?", tag).
+			Select("tt.transaction_id"))
+	}
+	return db
+}
+
+// searchEscaper escapes the LIKE wildcards of a search word, with '!' as the escape character
+var searchEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// withSearch narrows a query of transactions to those whose search_text contains every word of query,
+// see biz.SearchText
+func withSearch(db *gorm.DB, query string) *gorm.DB {
+	for _, term := range strings.Fields(query) {
+		db = db.Where("search_text LIKE ? ESCAPE '!'", "%"+searchEscaper.Replace(term)+"%")
+	}
+	return db
+}
+
+// backfillSearchText fills in the search_text of the transactions saved before it existed
+func backfillSearchText(db *gorm.DB) error {
+	for {
+		var transactions []model.AccounterTransaction
+		if err := db.Where("search_text IS NULL").
+			Select("transaction_id", "note").
+			Limit(tagQueryChunk).
+			Find(&transactions).Error; err != nil {
+			return err
+		}
+		if len(transactions) == 0 {
+			return nil
+		}
+
+		accounters := toBizAccounters(transactions, nil)
+		if err := fillTags(db, accounters); err != nil {
+			return err
+		}
+		for _, accounter := range accounters {
+			if err := db.Model(&model.AccounterTransaction{}).
+				Where("transaction_id = ?", accounter.TransactionID).
+				Update("search_text", biz.SearchText(accounter.Desc, accounter.Tags)).Error; err != nil {
+				return err
+			}
+		}
+	}
 }
@@ -0,0 +1,430 @@
+package data
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// The file storage persists data as a snapshot plus an append-only journal:
+//
+//   - accounters.json holds a snapshot of all records and the sequence number of
+//     the last journal record it contains.
+//   - accounters.json.journal holds one line per mutation, "<crc32> <json>\n",
+//     each appended and fsynced before the mutation becomes visible.
+//
+// A background compaction writes a new snapshot to a temporary file, renames it
+// over the old one and drops the journal records it covers. Startup loads the
+// snapshot and replays the newer journal records; a torn or corrupt record ends
+// the replay and is cut off so later writes start from a clean tail.
+//
+// Snapshots older than fileFormatVersion are rewritten by a compaction right
+// after startup; records they contain are still read as they were written.
+
+const (
+	journalOpSave   = "save"
+	journalOpUpdate = "update"
+	journalOpDelete = "delete"
+
+	// compactThreshold is the number of journal records that triggers a background compaction
+	compactThreshold = 1000
+
+	// fileFormatVersion is the version of the snapshot format written by compact.
+	// Version 2 stores amounts as exact decimal strings instead of JSON numbers.
+	fileFormatVersion = 2
+)
+
+// journalOp is a single mutation of the file storage
+type journalOp struct {
+	Op     string             `json:"op"`
+	Record *FileAccounterData `json:"record,omitempty"`
+	ID     int64              `json:"id,omitempty"`
+}
+
+// journalRecord is one line of the journal, all its ops are applied atomically
+type journalRecord struct {
+	Seq int64       `json:"seq"`
+	Ops []journalOp `json:"ops"`
+}
+
+// fileSnapshot is the content of the snapshot file
+type fileSnapshot struct {
+	Version int                 `json:"version,omitempty"`
+	Seq     int64               `json:"seq"`
+	NextID  int64               `json:"next_id"`
+	Records []FileAccounterData `json:"records"`
+}
+
+func (s *FileAccounterStorage) journalPath() string {
+	return s.filePath + ".journal"
+}
+
+// open loads the snapshot, replays the journal and starts the background compaction
+func (s *FileAccounterStorage) open() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.loadSnapshot(); err != nil {
+		return err
+	}
+	s.search = newSearchIndex(s.data)
+	replayed, err := s.replayJournal()
+	if err != nil {
+		return err
+	}
+
+	journal, err := os.OpenFile(s.journalPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open journal %s: %v", s.journalPath(), err)
+	}
+	s.journal = journal
+
+	s.compactCh = make(chan struct{}, 1)
+	s.done = make(chan struct{})
+	s.wg.Add(1)
+	go s.compactLoop()
+
+	s.log.Infof("Loaded %d records from file (%d journal records replayed), next ID: %d", len(s.data), replayed, s.nextID)
+	if replayed > 0 || (s.version < fileFormatVersion && len(s.data) > 0) {
+		s.requestCompaction()
+	}
+	return nil
+}
+
+func (s *FileAccounterStorage) loadSnapshot() error {
+	content, err := os.ReadFile(s.filePath)
+	if os.IsNotExist(err) {
+		// File doesn't exist, start with empty data
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read file %s: %v", s.filePath, err)
+	}
+
+	content = bytes.TrimSpace(content)
+	if len(content) == 0 {
+		return nil
+	}
+
+	if content[0] == '[' {
+		// Files written before the journal existed are a plain array of records
+		if err := json.Unmarshal(content, &s.data); err != nil {
+			return fmt.Errorf("failed to unmarshal data from file %s: %v", s.filePath, err)
+		}
+	} else {
+		var snapshot fileSnapshot
+		if err := json.Unmarshal(content, &snapshot); err != nil {
+			return fmt.Errorf("failed to unmarshal data from file %s: %v", s.filePath, err)
+		}
+		s.data = snapshot.Records
+		s.version = snapshot.Version
+		s.seq = snapshot.Seq
+		s.nextID = snapshot.NextID
+	}
+	if s.data == nil {
+		s.data = make([]FileAccounterData, 0)
+	}
+
+	// Find the next ID
+	for _, item := range s.data {
+		if item.TransactionID >= s.nextID {
+			s.nextID = item.TransactionID + 1
+		}
+	}
+	return nil
+}
+
+// decodeJournalLine parses "<crc32> <json>" and verifies the checksum
+func decodeJournalLine(line []byte) (*journalRecord, error) {
+	if len(line) < 10 || line[8] != ' ' {
+		return nil, fmt.Errorf("malformed journal record")
+	}
+	sum, err := strconv.ParseUint(string(line[:8]), 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("malformed journal checksum: %v", err)
+	}
+	payload := line[9:]
+	if crc32.ChecksumIEEE(payload) != uint32(sum) {
+		return nil, fmt.Errorf("journal checksum mismatch")
+	}
+	var record journalRecord
+	if err := json.Unmarshal(payload, &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func encodeJournalLine(record *journalRecord) ([]byte, error) {
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	line := make([]byte, 0, len(payload)+10)
+	line = append(line, fmt.Sprintf("%08x ", crc32.ChecksumIEEE(payload))...)
+	line = append(line, payload...)
+	return append(line, '\n'), nil
+}
+
+// replayJournal applies the journal records newer than the snapshot and cuts off
+// a torn or corrupt tail. It returns the number of records applied.
+func (s *FileAccounterStorage) replayJournal() (int, error) {
+	content, err := os.ReadFile(s.journalPath())
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read journal %s: %v", s.journalPath(), err)
+	}
+
+	replayed := 0
+	offset := 0
+	for offset < len(content) {
+		end := bytes.IndexByte(content[offset:], '\n')
+		var record *journalRecord
+		if end < 0 {
+			err = fmt.Errorf("journal record is not terminated")
+		} else {
+			record, err = decodeJournalLine(content[offset : offset+end])
+		}
+		if err != nil {
+			if end >= 0 && offset+end+1 < len(content) {
+				// Valid looking data follows, keep a copy of the journal before cutting it
+				backup := fmt.Sprintf("%s.corrupt-%d", s.journalPath(), time.Now().Unix())
+				if werr := os.WriteFile(backup, content, 0644); werr != nil {
+					return replayed, fmt.Errorf("failed to back up corrupt journal: %v", werr)
+				}
+				s.log.Errorf("Corrupt journal record at offset %d (%v), dropped the rest of the journal, backup at %s", offset, err, backup)
+			} else {
+				s.log.Warnf("Dropped torn journal record at offset %d: %v", offset, err)
+			}
+			if err := os.Truncate(s.journalPath(), int64(offset)); err != nil {
+				return replayed, fmt.Errorf("failed to truncate journal: %v", err)
+			}
+			break
+		}
+
+		offset += end + 1
+		s.journalSize = int64(offset)
+		s.journalRecords++
+		if record.Seq <= s.seq {
+			// Already part of the snapshot
+			continue
+		}
+		s.apply(record)
+		replayed++
+	}
+	return replayed, nil
+}
+
+// apply applies a journal record to the in-memory data. The caller must hold s.mutex.
+func (s *FileAccounterStorage) apply(record *journalRecord) {
+	s.seq = record.Seq
+	for _, op := range record.Ops {
+		switch op.Op {
+		case journalOpSave:
+			s.data = append(s.data, *op.Record)
+			s.search.add(op.Record)
+			if op.Record.TransactionID >= s.nextID {
+				s.nextID = op.Record.TransactionID + 1
+			}
+		case journalOpUpdate:
+			if i := s.indexOf(op.Record.TransactionID); i >= 0 {
+				s.data[i] = *op.Record
+				s.search.add(op.Record)
+			}
+		case journalOpDelete:
+			if i := s.indexOf(op.ID); i >= 0 {
+				s.data = append(s.data[:i], s.data[i+1:]...)
+				s.search.remove(op.ID)
+			}
+		}
+	}
+}
+
+// indexOf returns the position of a record in s.data or -1. The caller must hold s.mutex.
+func (s *FileAccounterStorage) indexOf(id int64) int {
+	for i, item := range s.data {
+		if item.TransactionID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// commit appends the ops to the journal as one fsynced record and then applies
+// them in memory. The caller must hold s.mutex.
+func (s *FileAccounterStorage) commit(ops ...journalOp) error {
+	record := &journalRecord{Seq: s.seq + 1, Ops: ops}
+	if s.filePath == "" {
+		// Memory only storage
+		s.apply(record)
+		return nil
+	}
+
+	line, err := encodeJournalLine(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal journal record: %v", err)
+	}
+	if _, err := s.journal.Write(line); err != nil {
+		// Cut off whatever part of the record reached the file
+		_ = s.journal.Truncate(s.journalSize)
+		return fmt.Errorf("failed to write journal %s: %v", s.journalPath(), err)
+	}
+	if err := s.journal.Sync(); err != nil {
+		_ = s.journal.Truncate(s.journalSize)
+		return fmt.Errorf("failed to sync journal %s: %v", s.journalPath(), err)
+	}
+
+	s.journalSize += int64(len(line))
+	s.journalRecords++
+	s.apply(record)
+
+	if s.journalRecords >= compactThreshold {
+		s.requestCompaction()
+	}
+	return nil
+}
+
+func (s *FileAccounterStorage) requestCompaction() {
+	select {
+	case s.compactCh <- struct{}{}:
+	default:
+	}
+}
+
+func (s *FileAccounterStorage) compactLoop() {
+	defer s.wg.Done()
+	for {
+		select {
+		case <-s.compactCh:
+			if err := s.compact(); err != nil {
+				s.log.Errorf("Failed to compact journal: %v", err)
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// compact writes a new snapshot and removes the journal records it covers.
+// Writes keep going while the snapshot is written, only the journal swap blocks them.
+func (s *FileAccounterStorage) compact() error {
+	s.compactMutex.Lock()
+	defer s.compactMutex.Unlock()
+
+	s.mutex.RLock()
+	snapshot := fileSnapshot{
+		Version: fileFormatVersion,
+		Seq:     s.seq,
+		NextID:  s.nextID,
+		Records: append(make([]FileAccounterData, 0, len(s.data)), s.data...),
+	}
+	covered := s.journalSize
+	s.mutex.RUnlock()
+
+	content, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot: %v", err)
+	}
+	if err := writeFileAtomic(s.filePath, content); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// Keep the records appended while the snapshot was being written
+	tail := make([]byte, s.journalSize-covered)
+	if len(tail) > 0 {
+		journal, err := os.Open(s.journalPath())
+		if err != nil {
+			return fmt.Errorf("failed to read journal tail: %v", err)
+		}
+		_, err = journal.ReadAt(tail, covered)
+		journal.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read journal tail: %v", err)
+		}
+	}
+	if err := writeFileAtomic(s.journalPath(), tail); err != nil {
+		return err
+	}
+
+	journal, err := os.OpenFile(s.journalPath(), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to reopen journal %s: %v", s.journalPath(), err)
+	}
+	s.journal.Close()
+	s.journal = journal
+	s.version = fileFormatVersion
+	s.journalSize = int64(len(tail))
+	s.journalRecords = bytes.Count(tail, []byte{'\n'})
+
+	s.log.Infof("Compacted %d records into snapshot %s", len(snapshot.Records), s.filePath)
+	return nil
+}
+
+// close stops the background compaction, compacts a final time and closes the journal
+func (s *FileAccounterStorage) close() {
+	if s.journal == nil {
+		return
+	}
+	close(s.done)
+	s.wg.Wait()
+
+	// The compaction of an old snapshot format may not have run yet
+	if s.journalRecords > 0 || (s.version < fileFormatVersion && len(s.data) > 0) {
+		if err := s.compact(); err != nil {
+			s.log.Errorf("Failed to compact journal on close: %v", err)
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := s.journal.Close(); err != nil {
+		s.log.Errorf("Failed to close journal: %v", err)
+	}
+	s.journal = nil
+}
+
+// writeFileAtomic writes content to a temporary file, fsyncs it and renames it over path
+func writeFileAtomic(path string, content []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file for %s: %v", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	if _, err := w.Write(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %v", tmp.Name(), err)
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %v", tmp.Name(), err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync %s: %v", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %v", tmp.Name(), err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to rename %s to %s: %v", tmp.Name(), path, err)
+	}
+
+	// Persist the rename itself
+	if dir, err := os.Open(filepath.Dir(path)); err == nil {
+		_ = dir.Sync()
+		dir.Close()
+	}
+	return nil
+}
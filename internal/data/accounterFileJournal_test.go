@@ -0,0 +1,228 @@
+package data
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	v1 "accounter_go/api/accounter/v1"
+	"accounter_go/internal/biz"
+	"accounter_go/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+func openTestFileRepo(t *testing.T, dir string) (*accounterFileRepo, func()) {
+	t.Helper()
+	repo, cleanup, err := NewAccounterFileRepo(&conf.Data{
+		FileStorage: &conf.Data_FileStorage{DataDir: dir, AccounterFile: "accounters.json"},
+	}, log.NewStdLogger(io.Discard))
+	if err != nil {
+		t.Fatalf("NewAccounterFileRepo: %v", err)
+	}
+	return repo.(*accounterFileRepo), cleanup
+}
+
+func listAllDescs(t *testing.T, repo biz.AccounterRepo) []string {
+	t.Helper()
+	all, err := repo.ListAll(context.Background())
+	if err != nil {
+		t.Fatalf("ListAll: %v", err)
+	}
+	return descs(all)
+}
+
+func saveDescs(t *testing.T, repo biz.AccounterRepo, names ...string) []*biz.Accounter {
+	t.Helper()
+	var saved []*biz.Accounter
+	for _, name := range names {
+		a, err := repo.Save(context.Background(), &biz.Accounter{
+			UserID: 1, Type: v1.Type_Expense, CategoryID: int64(v1.Category_Food), Desc: name, Amount: money("1"), Date: date("2025-06-01"),
+		})
+		if err != nil {
+			t.Fatalf("Save(%s): %v", name, err)
+		}
+		saved = append(saved, a)
+	}
+	return saved
+}
+
+func TestFileJournalReplayWithoutCompaction(t *testing.T) {
+	dir := t.TempDir()
+	repo, _ := openTestFileRepo(t, dir)
+	saved := saveDescs(t, repo, "a", "b", "c")
+	if err := repo.Delete(context.Background(), saved[1].TransactionID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	// Simulate a crash: the snapshot was never written, only the journal exists
+	if _, err := os.Stat(filepath.Join(dir, "accounters.json")); !os.IsNotExist(err) {
+		t.Fatalf("snapshot should not exist before compaction, stat err = %v", err)
+	}
+
+	reopened, cleanup := openTestFileRepo(t, dir)
+	defer cleanup()
+	if got := listAllDescs(t, reopened); !equalStrings(got, []string{"a", "c"}) {
+		t.Errorf("after replay got %v", got)
+	}
+	next := saveDescs(t, reopened, "d")
+	if next[0].TransactionID != 4 {
+		t.Errorf("next ID after replay = %d, want 4", next[0].TransactionID)
+	}
+	// The search index is rebuilt from the replayed records
+	list, _, err := reopened.ListWithFilters(context.Background(), &biz.ListFilter{UserID: 1, Query: "c", Page: 1, PageSize: 10})
+	if err != nil || !equalStrings(descs(list), []string{"c"}) {
+		t.Errorf("search after replay = %v, %v", descs(list), err)
+	}
+}
+
+func TestFileJournalDropsTornRecord(t *testing.T) {
+	dir := t.TempDir()
+	repo, _ := openTestFileRepo(t, dir)
+	saveDescs(t, repo, "a", "b")
+
+	// A crash in the middle of an append leaves a partial last line
+	journalPath := filepath.Join(dir, "accounters.json.journal")
+	f, err := os.OpenFile(journalPath, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(`0badc0de {"seq":3,"ops":[{"op":"save","rec`); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	reopened, cleanup := openTestFileRepo(t, dir)
+	if got := listAllDescs(t, reopened); !equalStrings(got, []string{"a", "b"}) {
+		t.Errorf("after torn record got %v", got)
+	}
+	saveDescs(t, reopened, "c")
+	cleanup()
+
+	final, cleanup := openTestFileRepo(t, dir)
+	defer cleanup()
+	if got := listAllDescs(t, final); !equalStrings(got, []string{"a", "b", "c"}) {
+		t.Errorf("after restart got %v", got)
+	}
+}
+
+func TestFileJournalCompaction(t *testing.T) {
+	dir := t.TempDir()
+	repo, cleanup := openTestFileRepo(t, dir)
+	saved := saveDescs(t, repo, "a", "b", "c")
+
+	if err := repo.storage.compact(); err != nil {
+		t.Fatalf("compact: %v", err)
+	}
+	journal, err := os.ReadFile(filepath.Join(dir, "accounters.json.journal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(journal) != 0 {
+		t.Errorf("journal should be empty after compaction, got %q", journal)
+	}
+
+	// Writes after the snapshot only live in the journal until the next compaction
+	if err := repo.Delete(context.Background(), saved[0].TransactionID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	cleanup()
+
+	snapshot, err := os.ReadFile(filepath.Join(dir, "accounters.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(snapshot), `"desc": "a"`) {
+		t.Errorf("close should compact the delete into the snapshot")
+	}
+
+	reopened, cleanup := openTestFileRepo(t, dir)
+	defer cleanup()
+	if got := listAllDescs(t, reopened); !equalStrings(got, []string{"b", "c"}) {
+		t.Errorf("after compaction got %v", got)
+	}
+}
+
+func TestFileStorageLoadsLegacyArray(t *testing.T) {
+	dir := t.TempDir()
+	legacy := `[{"transaction_id": 7, "user_id": 1, "type": 2, "category": 2, "desc": "old", "amount": 3.5, "date": "2025-01-01T00:00:00Z", "created_at": "2025-01-01T00:00:00Z"}]`
+	if err := os.WriteFile(filepath.Join(dir, "accounters.json"), []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	repo, cleanup := openTestFileRepo(t, dir)
+	defer cleanup()
+	if got := listAllDescs(t, repo); !equalStrings(got, []string{"old"}) {
+		t.Errorf("legacy load got %v", got)
+	}
+	if next := saveDescs(t, repo, "new"); next[0].TransactionID != 8 {
+		t.Errorf("next ID = %d, want 8", next[0].TransactionID)
+	}
+}
+
+func TestFileStorageMigratesFloatAmounts(t *testing.T) {
+	dir := t.TempDir()
+	legacy := `{"seq": 0, "next_id": 3, "records": [
+		{"transaction_id": 1, "user_id": 1, "type": 2, "category": 2, "desc": "tea", "amount": 10965.550000000001, "date": "2025-01-01T00:00:00Z", "created_at": "2025-01-01T00:00:00Z"},
+		{"transaction_id": 2, "user_id": 1, "type": 2, "category": 2, "desc": "gum", "amount": 0.1, "date": "2025-01-02T00:00:00Z", "created_at": "2025-01-02T00:00:00Z"}]}`
+	if err := os.WriteFile(filepath.Join(dir, "accounters.json"), []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	repo, cleanup := openTestFileRepo(t, dir)
+	stats, err := repo.GetStats(context.Background(), &biz.StatsFilter{UserID: 1})
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+	if stats.TotalExpense != money("10965.65") {
+		t.Errorf("TotalExpense = %s, want 10965.65", stats.TotalExpense)
+	}
+	cleanup()
+
+	// The old snapshot is rewritten with decimal strings
+	snapshot, err := os.ReadFile(filepath.Join(dir, "accounters.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"version": 2`, `"amount": "10965.55"`, `"amount": "0.1"`} {
+		if !strings.Contains(string(snapshot), want) {
+			t.Errorf("migrated snapshot lacks %s:\n%s", want, snapshot)
+		}
+	}
+
+	reopened, cleanup := openTestFileRepo(t, dir)
+	defer cleanup()
+	if got, err := reopened.FindByID(context.Background(), 1); err != nil || got.Amount != money("10965.55") {
+		t.Errorf("FindByID after migration = %+v, %v", got, err)
+	}
+}
+
+func TestFileJournalBatchIsOneRecord(t *testing.T) {
+	dir := t.TempDir()
+	repo, _ := openTestFileRepo(t, dir)
+	saveDescs(t, repo, "a")
+	batch := []*biz.Accounter{
+		{UserID: 1, Type: v1.Type_Expense, Desc: "b", Amount: money("1"), Date: date("2025-06-01")},
+		{UserID: 1, Type: v1.Type_Expense, Desc: "c", Amount: money("2"), Date: date("2025-06-01")},
+	}
+	if _, err := repo.SaveBatch(context.Background(), batch); err != nil {
+		t.Fatalf("SaveBatch: %v", err)
+	}
+
+	journal, err := os.ReadFile(filepath.Join(dir, "accounters.json.journal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(journal), "\n"); lines != 2 {
+		t.Errorf("journal has %d records, want 2", lines)
+	}
+
+	reopened, cleanup := openTestFileRepo(t, dir)
+	defer cleanup()
+	if got := listAllDescs(t, reopened); !equalStrings(got, []string{"a", "b", "c"}) {
+		t.Errorf("after replay got %v", got)
+	}
+}
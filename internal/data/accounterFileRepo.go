@@ -2,11 +2,11 @@ package data
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -22,13 +22,45 @@ type FileAccounterData struct {
 	TransactionID int64     `json:"transaction_id"`
 	UserID        int64     `json:"user_id"`
 	Type          int32     `json:"type"`
-	Category      int32     `json:"category"`
+	CategoryID    int64     `json:"category"`
 	Desc          string    `json:"desc"`
-	Amount        float64   `json:"amount"`
+	Amount        biz.Money `json:"amount"`
+	Currency      string    `json:"currency,omitempty"`
+	AccountID     int64     `json:"account_id,omitempty"`
+	ToAccountID   int64     `json:"to_account_id,omitempty"`
+	ToAmount      biz.Money `json:"to_amount,omitempty"`
+	ToCurrency    string    `json:"to_currency,omitempty"`
+	Fee           biz.Money `json:"fee,omitempty"`
+	RecurringID   int64     `json:"recurring_id,omitempty"`
+	ExternalID    string    `json:"external_id,omitempty"`
+	Tags          []string  `json:"tags,omitempty"`
 	Date          time.Time `json:"date"`
 	CreatedAt     time.Time `json:"created_at"`
 }
 
+// toBiz converts a stored record to a transaction
+func (d *FileAccounterData) toBiz() *biz.Accounter {
+	return &biz.Accounter{
+		TransactionID: d.TransactionID,
+		UserID:        d.UserID,
+		Type:          v1.Type(d.Type),
+		CategoryID:    d.CategoryID,
+		Desc:          d.Desc,
+		Amount:        d.Amount,
+		Currency:      currencyOrDefault(d.Currency),
+		AccountID:     d.AccountID,
+		ToAccountID:   d.ToAccountID,
+		ToAmount:      d.ToAmount,
+		ToCurrency:    d.ToCurrency,
+		Fee:           d.Fee,
+		RecurringID:   d.RecurringID,
+		ExternalID:    d.ExternalID,
+		Tags:          d.Tags,
+		Date:          d.Date,
+		CreatedAt:     d.CreatedAt,
+	}
+}
+
 // FileAccounterStorage manages the file storage operations
 type FileAccounterStorage struct {
 	filePath string
@@ -36,6 +68,18 @@ type FileAccounterStorage struct {
 	mutex    sync.RWMutex
 	nextID   int64
 	log      *log.Helper
+	search   *searchIndex // see accounterSearchIndex.go
+
+	// journal state, see accounterFileJournal.go
+	journal        *os.File
+	journalSize    int64
+	journalRecords int
+	seq            int64
+	version        int // format version of the snapshot on disk
+	compactMutex   sync.Mutex
+	compactCh      chan struct{}
+	done           chan struct{}
+	wg             sync.WaitGroup
 }
 
 type accounterFileRepo struct {
@@ -44,23 +88,17 @@ type accounterFileRepo struct {
 }
 
 // NewAccounterFileRepo creates a new file-based AccounterRepo
-func NewAccounterFileRepo(c *conf.Data, logger log.Logger) biz.AccounterRepo {
+func NewAccounterFileRepo(c *conf.Data, logger log.Logger) (biz.AccounterRepo, func(), error) {
 	// Get file storage config or use defaults
-	dataDir := "./data"
+	dataDir := fileStorageDir(c)
 	fileName := "accounters.json"
-	
-	if c.FileStorage != nil {
-		if c.FileStorage.DataDir != "" {
-			dataDir = c.FileStorage.DataDir
-		}
-		if c.FileStorage.AccounterFile != "" {
-			fileName = c.FileStorage.AccounterFile
-		}
+	if c.GetFileStorage().GetAccounterFile() != "" {
+		fileName = c.FileStorage.AccounterFile
 	}
 
 	// Create data directory if it doesn't exist
 	if err := os.MkdirAll(dataDir, 0755); err != nil {
-		log.NewHelper(logger).Errorf("Failed to create data directory: %v", err)
+		return nil, nil, fmt.Errorf("failed to create data directory: %v", err)
 	}
 
 	storage := &FileAccounterStorage{
@@ -71,140 +109,165 @@ func NewAccounterFileRepo(c *conf.Data, logger log.Logger) biz.AccounterRepo {
 	}
 
 	// Load existing data
-	storage.loadFromFile()
+	if err := storage.open(); err != nil {
+		log.NewHelper(logger).Errorf("Failed to load file storage: %v", err)
+		return nil, nil, err
+	}
 
 	log.NewHelper(logger).Infof("Initialized file storage at: %s", storage.filePath)
 
 	return &accounterFileRepo{
 		storage: storage,
 		log:     log.NewHelper(logger),
-	}
+	}, storage.close, nil
 }
 
-func (s *FileAccounterStorage) loadFromFile() {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
-
-	if _, err := os.Stat(s.filePath); os.IsNotExist(err) {
-		// File doesn't exist, start with empty data
-		return
-	}
-
-	content, err := ioutil.ReadFile(s.filePath)
-	if err != nil {
-		s.log.Errorf("Failed to read file %s: %v", s.filePath, err)
-		return
+// fileStorageDir returns the configured data directory or the default one
+func fileStorageDir(c *conf.Data) string {
+	if c.GetFileStorage().GetDataDir() != "" {
+		return c.FileStorage.DataDir
 	}
-
-	if len(content) == 0 {
-		return
-	}
-
-	if err := json.Unmarshal(content, &s.data); err != nil {
-		s.log.Errorf("Failed to unmarshal data from file %s: %v", s.filePath, err)
-		return
-	}
-
-	// Find the next ID
-	for _, item := range s.data {
-		if item.TransactionID >= s.nextID {
-			s.nextID = item.TransactionID + 1
-		}
-	}
-
-	s.log.Infof("Loaded %d records from file, next ID: %d", len(s.data), s.nextID)
+	return "./data"
 }
 
-func (s *FileAccounterStorage) saveToFile() error {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
-
-	content, err := json.MarshalIndent(s.data, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal data: %v", err)
-	}
-
-	if err := os.WriteFile(s.filePath, content, 0644); err != nil {
-		return fmt.Errorf("failed to write file %s: %v", s.filePath, err)
+// NewAccounterMemoryRepo creates an AccounterRepo that keeps everything in memory,
+// it shares the file repo implementation but never touches the disk
+func NewAccounterMemoryRepo(logger log.Logger) biz.AccounterRepo {
+	return &accounterFileRepo{
+		storage: &FileAccounterStorage{
+			data:   make([]FileAccounterData, 0),
+			nextID: 1,
+			log:    log.NewHelper(logger),
+			search: newSearchIndex(nil),
+		},
+		log: log.NewHelper(logger),
 	}
-
-	return nil
 }
 
 func (r *accounterFileRepo) Save(ctx context.Context, accounter *biz.Accounter) (*biz.Accounter, error) {
 	r.storage.mutex.Lock()
+	defer r.storage.mutex.Unlock()
 
 	// Generate new ID
 	newID := r.storage.nextID
-	r.storage.nextID++
 
 	// Create file data structure
 	fileData := FileAccounterData{
 		TransactionID: newID,
 		UserID:        accounter.UserID,
 		Type:          int32(accounter.Type),
-		Category:      int32(accounter.Category),
+		CategoryID:    accounter.CategoryID,
 		Desc:          accounter.Desc,
 		Amount:        accounter.Amount,
+		Currency:      accounter.Currency,
+		AccountID:     accounter.AccountID,
+		ToAccountID:   accounter.ToAccountID,
+		ToAmount:      accounter.ToAmount,
+		ToCurrency:    accounter.ToCurrency,
+		Fee:           accounter.Fee,
+		RecurringID:   accounter.RecurringID,
+		ExternalID:    accounter.ExternalID,
+		Tags:          accounter.Tags,
 		Date:          accounter.Date,
 		CreatedAt:     time.Now(),
 	}
 
-	// Add to in-memory data
-	r.storage.data = append(r.storage.data, fileData)
-	r.storage.mutex.Unlock()
-
-	// Save to file
-	if err := r.storage.saveToFile(); err != nil {
+	// Append to the journal and add to in-memory data
+	if err := r.storage.commit(journalOp{Op: journalOpSave, Record: &fileData}); err != nil {
 		r.log.WithContext(ctx).Errorf("Failed to save to file: %v", err)
 		return nil, err
 	}
 
 	// Return the saved accounter with new ID
-	result := &biz.Accounter{
-		TransactionID: newID,
-		UserID:        accounter.UserID,
-		Type:          accounter.Type,
-		Category:      accounter.Category,
-		Desc:          accounter.Desc,
-		Amount:        accounter.Amount,
-		Date:          accounter.Date,
-	}
+	result := fileData.toBiz()
 
 	r.log.WithContext(ctx).Infof("Saved accounter with ID: %d", newID)
 	return result, nil
 }
 
+// SaveBatch saves all accounters as one journal record, so either all of them are stored or none
+func (r *accounterFileRepo) SaveBatch(ctx context.Context, accounters []*biz.Accounter) ([]*biz.Accounter, error) {
+	r.storage.mutex.Lock()
+	defer r.storage.mutex.Unlock()
+
+	now := time.Now()
+	ops := make([]journalOp, 0, len(accounters))
+	results := make([]*biz.Accounter, 0, len(accounters))
+	for i, accounter := range accounters {
+		newID := r.storage.nextID + int64(i)
+		ops = append(ops, journalOp{Op: journalOpSave, Record: &FileAccounterData{
+			TransactionID: newID,
+			UserID:        accounter.UserID,
+			Type:          int32(accounter.Type),
+			CategoryID:    accounter.CategoryID,
+			Desc:          accounter.Desc,
+			Amount:        accounter.Amount,
+			Currency:      accounter.Currency,
+			AccountID:     accounter.AccountID,
+			ToAccountID:   accounter.ToAccountID,
+			ToAmount:      accounter.ToAmount,
+			ToCurrency:    accounter.ToCurrency,
+			Fee:           accounter.Fee,
+			RecurringID:   accounter.RecurringID,
+			ExternalID:    accounter.ExternalID,
+			Tags:          accounter.Tags,
+			Date:          accounter.Date,
+			CreatedAt:     now,
+		}})
+		result := *accounter
+		result.TransactionID = newID
+		result.CreatedAt = now
+		results = append(results, &result)
+	}
+	if len(ops) == 0 {
+		return results, nil
+	}
+
+	if err := r.storage.commit(ops...); err != nil {
+		r.log.WithContext(ctx).Errorf("Failed to save %d accounters to file: %v", len(ops), err)
+		return nil, err
+	}
+
+	r.log.WithContext(ctx).Infof("Saved %d accounters", len(results))
+	return results, nil
+}
+
 func (r *accounterFileRepo) Update(ctx context.Context, accounter *biz.Accounter) (*biz.Accounter, error) {
 	r.storage.mutex.Lock()
 	defer r.storage.mutex.Unlock()
 
 	// Find and update the record
-	for i, item := range r.storage.data {
-		if item.TransactionID == accounter.TransactionID {
-			r.storage.data[i] = FileAccounterData{
-				TransactionID: accounter.TransactionID,
-				UserID:        accounter.UserID,
-				Type:          int32(accounter.Type),
-				Category:      int32(accounter.Category),
-				Desc:          accounter.Desc,
-				Amount:        accounter.Amount,
-				Date:          accounter.Date,
-				CreatedAt:     item.CreatedAt, // Keep original creation time
-			}
-
-			// Save to file
-			if err := r.storage.saveToFile(); err != nil {
-				r.log.WithContext(ctx).Errorf("Failed to save to file after update: %v", err)
-				return nil, err
-			}
+	i := r.storage.indexOf(accounter.TransactionID)
+	if i < 0 {
+		return nil, biz.ErrAccounterNotFound
+	}
 
-			r.log.WithContext(ctx).Infof("Updated accounter with ID: %d", accounter.TransactionID)
-			return accounter, nil
-		}
+	fileData := FileAccounterData{
+		TransactionID: accounter.TransactionID,
+		UserID:        accounter.UserID,
+		Type:          int32(accounter.Type),
+		CategoryID:    accounter.CategoryID,
+		Desc:          accounter.Desc,
+		Amount:        accounter.Amount,
+		Currency:      accounter.Currency,
+		AccountID:     accounter.AccountID,
+		ToAccountID:   accounter.ToAccountID,
+		ToAmount:      accounter.ToAmount,
+		ToCurrency:    accounter.ToCurrency,
+		Fee:           accounter.Fee,
+		RecurringID:   r.storage.data[i].RecurringID, // Keep the rule that created it
+		ExternalID:    r.storage.data[i].ExternalID,  // Keep the statement it was imported from
+		Tags:          accounter.Tags,
+		Date:          accounter.Date,
+		CreatedAt:     r.storage.data[i].CreatedAt, // Keep original creation time
+	}
+	if err := r.storage.commit(journalOp{Op: journalOpUpdate, Record: &fileData}); err != nil {
+		r.log.WithContext(ctx).Errorf("Failed to save to file after update: %v", err)
+		return nil, err
 	}
 
-	return nil, fmt.Errorf("accounter with ID %d not found", accounter.TransactionID)
+	r.log.WithContext(ctx).Infof("Updated accounter with ID: %d", accounter.TransactionID)
+	return accounter, nil
 }
 
 func (r *accounterFileRepo) FindByID(ctx context.Context, id int64) (*biz.Accounter, error) {
@@ -213,20 +276,11 @@ func (r *accounterFileRepo) FindByID(ctx context.Context, id int64) (*biz.Accoun
 
 	for _, item := range r.storage.data {
 		if item.TransactionID == id {
-			result := &biz.Accounter{
-				TransactionID: item.TransactionID,
-				UserID:        item.UserID,
-				Type:          v1.Type(item.Type),
-				Category:      v1.Category(item.Category),
-				Desc:          item.Desc,
-				Amount:        item.Amount,
-				Date:          item.Date,
-			}
-			return result, nil
+			return item.toBiz(), nil
 		}
 	}
 
-	return nil, fmt.Errorf("accounter with ID %d not found", id)
+	return nil, biz.ErrAccounterNotFound
 }
 
 func (r *accounterFileRepo) ListByUserID(ctx context.Context, userID int64) ([]*biz.Accounter, error) {
@@ -236,16 +290,7 @@ func (r *accounterFileRepo) ListByUserID(ctx context.Context, userID int64) ([]*
 	var results []*biz.Accounter
 	for _, item := range r.storage.data {
 		if item.UserID == userID {
-			result := &biz.Accounter{
-				TransactionID: item.TransactionID,
-				UserID:        item.UserID,
-				Type:          v1.Type(item.Type),
-				Category:      v1.Category(item.Category),
-				Desc:          item.Desc,
-				Amount:        item.Amount,
-				Date:          item.Date,
-			}
-			results = append(results, result)
+			results = append(results, item.toBiz())
 		}
 	}
 
@@ -258,16 +303,7 @@ func (r *accounterFileRepo) ListAll(ctx context.Context) ([]*biz.Accounter, erro
 
 	var results []*biz.Accounter
 	for _, item := range r.storage.data {
-		result := &biz.Accounter{
-			TransactionID: item.TransactionID,
-			UserID:        item.UserID,
-			Type:          v1.Type(item.Type),
-			Category:      v1.Category(item.Category),
-			Desc:          item.Desc,
-			Amount:        item.Amount,
-			Date:          item.Date,
-		}
-		results = append(results, result)
+		results = append(results, item.toBiz())
 	}
 
 	return results, nil
@@ -277,16 +313,89 @@ func (r *accounterFileRepo) ListWithFilters(ctx context.Context, filter *biz.Lis
 	r.storage.mutex.RLock()
 	defer r.storage.mutex.RUnlock()
 
-	var filtered []*biz.Accounter
-	for _, item := range r.storage.data {
+	filtered, less := r.matching(filter)
+	total := int32(len(filtered))
+
+	// Apply pagination
+	start := (filter.Page - 1) * filter.PageSize
+	if filter.After != nil {
+		start = int32(startAfter(filtered, less, filter.After))
+	}
+	end := start + filter.PageSize
+
+	if start > total {
+		return []*biz.Accounter{}, total, nil
+	}
+	if end > total {
+		end = total
+	}
+
+	results := make([]*biz.Accounter, 0, end-start)
+	for _, item := range filtered[start:end] {
+		results = append(results, item.toBiz())
+	}
+
+	return results, total, nil
+}
+
+// EachWithFilters filters and sorts the records once and calls fn with copies of them,
+// fn runs without the lock so a slow reader doesn't hold up writes
+func (r *accounterFileRepo) EachWithFilters(ctx context.Context, filter *biz.ListFilter, fn func(*biz.Accounter) error) error {
+	r.storage.mutex.RLock()
+	filtered, less := r.matching(filter)
+	start := 0
+	if filter.After != nil {
+		start = startAfter(filtered, less, filter.After)
+	}
+	records := make([]FileAccounterData, 0, len(filtered)-start)
+	for _, item := range filtered[start:] {
+		records = append(records, *item)
+	}
+	r.storage.mutex.RUnlock()
+
+	for i := range records {
+		if err := fn(records[i].toBiz()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matching returns the records that match filter in its sort order and the order itself,
+// the caller holds the read lock
+func (r *accounterFileRepo) matching(filter *biz.ListFilter) ([]*FileAccounterData, func(a, b *FileAccounterData) bool) {
+	terms := strings.Fields(filter.Query)
+	var matches map[int64]bool
+	if len(terms) > 0 {
+		matches = r.storage.search.match(terms)
+	}
+
+	var filtered []*FileAccounterData
+	for i := range r.storage.data {
+		item := &r.storage.data[i]
 		// Apply filters
 		if filter.UserID != 0 && item.UserID != filter.UserID {
 			continue
 		}
-		if filter.Type != nil && int32(*filter.Type) != item.Type {
+		if len(filter.Types) > 0 && !containsType(filter.Types, v1.Type(item.Type)) {
+			continue
+		}
+		if len(filter.CategoryIDs) > 0 && !containsID(filter.CategoryIDs, item.CategoryID) {
+			continue
+		}
+		if filter.AccountID != nil && !item.inAccount(*filter.AccountID) {
+			continue
+		}
+		if filter.MinAmount != nil && item.Amount < *filter.MinAmount {
+			continue
+		}
+		if filter.MaxAmount != nil && item.Amount > *filter.MaxAmount {
+			continue
+		}
+		if !biz.HasTags(item.Tags, filter.Tags) {
 			continue
 		}
-		if filter.Category != nil && int32(*filter.Category) != item.Category {
+		if len(terms) > 0 && !matches[item.TransactionID] {
 			continue
 		}
 		if filter.StartDate != nil && item.Date.Before(*filter.StartDate) {
@@ -295,33 +404,59 @@ func (r *accounterFileRepo) ListWithFilters(ctx context.Context, filter *biz.Lis
 		if filter.EndDate != nil && item.Date.After(*filter.EndDate) {
 			continue
 		}
-
-		result := &biz.Accounter{
-			TransactionID: item.TransactionID,
-			UserID:        item.UserID,
-			Type:          v1.Type(item.Type),
-			Category:      v1.Category(item.Category),
-			Desc:          item.Desc,
-			Amount:        item.Amount,
-			Date:          item.Date,
-		}
-		filtered = append(filtered, result)
+		filtered = append(filtered, item)
 	}
+	less := fileAccounterLess(filter.SortBy, filter.Descending)
+	sort.Slice(filtered, func(i, j int) bool { return less(filtered[i], filtered[j]) })
+	return filtered, less
+}
 
-	total := int32(len(filtered))
-
-	// Apply pagination
-	start := (filter.Page - 1) * filter.PageSize
-	end := start + filter.PageSize
+// startAfter returns the index of the first record that follows the cursor c in the order less
+func startAfter(records []*FileAccounterData, less func(a, b *FileAccounterData) bool, c *biz.ListCursor) int {
+	after := &FileAccounterData{TransactionID: c.ID, Date: c.Date, Amount: c.Amount, CreatedAt: c.CreatedAt}
+	return sort.Search(len(records), func(i int) bool { return less(after, records[i]) })
+}
 
-	if start > total {
-		return []*biz.Accounter{}, total, nil
+// fileAccounterLess orders records by a sort field, records that tie are ordered by ID in the same direction
+func fileAccounterLess(sortBy v1.SortField, descending bool) func(a, b *FileAccounterData) bool {
+	return func(a, b *FileAccounterData) bool {
+		if descending {
+			a, b = b, a
+		}
+		switch sortBy {
+		case v1.SortField_DATE:
+			if !a.Date.Equal(b.Date) {
+				return a.Date.Before(b.Date)
+			}
+		case v1.SortField_AMOUNT:
+			if a.Amount != b.Amount {
+				return a.Amount < b.Amount
+			}
+		case v1.SortField_CREATED_AT:
+			if !a.CreatedAt.Equal(b.CreatedAt) {
+				return a.CreatedAt.Before(b.CreatedAt)
+			}
+		}
+		return a.TransactionID < b.TransactionID
 	}
-	if end > total {
-		end = total
+}
+
+func containsType(types []v1.Type, t v1.Type) bool {
+	for _, candidate := range types {
+		if candidate == t {
+			return true
+		}
 	}
+	return false
+}
 
-	return filtered[start:end], total, nil
+func containsID(ids []int64, id int64) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
 }
 
 func (r *accounterFileRepo) Delete(ctx context.Context, id int64) error {
@@ -329,22 +464,42 @@ func (r *accounterFileRepo) Delete(ctx context.Context, id int64) error {
 	defer r.storage.mutex.Unlock()
 
 	// Find and remove the record
-	for i, item := range r.storage.data {
-		if item.TransactionID == id {
-			r.storage.data = append(r.storage.data[:i], r.storage.data[i+1:]...)
-			
-			// Save to file
-			if err := r.storage.saveToFile(); err != nil {
-				r.log.WithContext(ctx).Errorf("Failed to save to file after delete: %v", err)
-				return err
-			}
+	if r.storage.indexOf(id) < 0 {
+		return biz.ErrAccounterNotFound
+	}
+
+	if err := r.storage.commit(journalOp{Op: journalOpDelete, ID: id}); err != nil {
+		r.log.WithContext(ctx).Errorf("Failed to save to file after delete: %v", err)
+		return err
+	}
+
+	r.log.WithContext(ctx).Infof("Deleted accounter with ID: %d", id)
+	return nil
+}
+
+// DeleteBatch deletes all accounters as one journal record, so either all of them are deleted or none
+func (r *accounterFileRepo) DeleteBatch(ctx context.Context, ids []int64) error {
+	r.storage.mutex.Lock()
+	defer r.storage.mutex.Unlock()
 
-			r.log.WithContext(ctx).Infof("Deleted accounter with ID: %d", id)
-			return nil
+	ops := make([]journalOp, 0, len(ids))
+	for _, id := range ids {
+		if r.storage.indexOf(id) < 0 {
+			return biz.ErrAccounterNotFound
 		}
+		ops = append(ops, journalOp{Op: journalOpDelete, ID: id})
+	}
+	if len(ops) == 0 {
+		return nil
+	}
+
+	if err := r.storage.commit(ops...); err != nil {
+		r.log.WithContext(ctx).Errorf("Failed to delete %d accounters from file: %v", len(ops), err)
+		return err
 	}
 
-	return fmt.Errorf("accounter with ID %d not found", id)
+	r.log.WithContext(ctx).Infof("Deleted %d accounters", len(ops))
+	return nil
 }
 
 func (r *accounterFileRepo) GetStats(ctx context.Context, filter *biz.StatsFilter) (*biz.Stats, error) {
@@ -352,40 +507,25 @@ func (r *accounterFileRepo) GetStats(ctx context.Context, filter *biz.StatsFilte
 	defer r.storage.mutex.RUnlock()
 
 	var (
-		totalIncome         float64
-		totalExpense        float64
-		incomeByCategory    = make(map[v1.Category]*biz.CategoryStat)
-		expenseByCategory   = make(map[v1.Category]*biz.CategoryStat)
+		totalIncome       biz.Money
+		totalExpense      biz.Money
+		incomeByCategory  = make(map[int64]*biz.CategoryStat)
+		expenseByCategory = make(map[int64]*biz.CategoryStat)
+		byTag             = make(tagTotals)
+		byCurrency        = make(currencyTotals)
 	)
 
-	// Category names mapping
-	categoryNames := map[v1.Category]string{
-		v1.Category_Default:     "默认",
-		v1.Category_Game:        "游戏",
-		v1.Category_Food:        "餐饮",
-		v1.Category_Travel:      "旅行",
-		v1.Category_Education:   "教育",
-		v1.Category_Health:      "健康",
-		v1.Category_Shopping:    "购物",
-		v1.Category_Other:       "其他",
-		v1.Category_Transport:   "交通",
-		v1.Category_Entertainment: "娱乐",
-		v1.Category_Investment:  "投资",
-		v1.Category_Loan:        "借款",
-		v1.Category_Salary:      "工资",
-		v1.Category_OtherIncome: "其他收入",
-		v1.Category_App:         "应用",
-		v1.Category_House:       "住房",
-		v1.Category_Utility:     "水电费",
-		v1.Category_Gift:        "礼物",
-		v1.Category_Snacks:      "零食",
-	}
-
 	for _, item := range r.storage.data {
 		// Apply filters
 		if filter.UserID != 0 && item.UserID != filter.UserID {
 			continue
 		}
+		if filter.AccountID != nil && *filter.AccountID != item.AccountID {
+			continue
+		}
+		if !biz.HasTags(item.Tags, filter.Tags) {
+			continue
+		}
 		if filter.StartDate != nil && item.Date.Before(*filter.StartDate) {
 			continue
 		}
@@ -393,51 +533,60 @@ func (r *accounterFileRepo) GetStats(ctx context.Context, filter *biz.StatsFilte
 			continue
 		}
 
-		category := v1.Category(item.Category)
-		categoryName := categoryNames[category]
-		if categoryName == "" {
-			categoryName = "未知"
+		category := item.CategoryID
+		if item.Type != int32(v1.Type_Income) && item.Type != int32(v1.Type_Expense) {
+			continue
+		}
+		currency := currencyOrDefault(item.Currency)
+		byCurrency.add(currency, v1.Type(item.Type), item.Amount, 1)
+		amount, err := convertAmount(filter.Converter, item.Amount, currency, item.Date)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, tag := range item.Tags {
+			byTag.add(tag, v1.Type(item.Type), amount, 1)
 		}
 
 		if item.Type == int32(v1.Type_Income) {
-			totalIncome += item.Amount
+			totalIncome += amount
 			if stat, exists := incomeByCategory[category]; exists {
-				stat.Amount += item.Amount
+				stat.Amount += amount
 				stat.Count++
 			} else {
 				incomeByCategory[category] = &biz.CategoryStat{
-					Category:     category,
-					CategoryName: categoryName,
-					Amount:       item.Amount,
-					Count:        1,
+					CategoryID: category,
+					Amount:     amount,
+					Count:      1,
 				}
 			}
-		} else if item.Type == int32(v1.Type_Expense) {
-			totalExpense += item.Amount
+		} else {
+			totalExpense += amount
 			if stat, exists := expenseByCategory[category]; exists {
-				stat.Amount += item.Amount
+				stat.Amount += amount
 				stat.Count++
 			} else {
 				expenseByCategory[category] = &biz.CategoryStat{
-					Category:     category,
-					CategoryName: categoryName,
-					Amount:       item.Amount,
-					Count:        1,
+					CategoryID: category,
+					Amount:     amount,
+					Count:      1,
 				}
 			}
 		}
 	}
 
-	// Convert maps to slices
+	// Convert maps to slices ordered by category
 	var incomeStats []*biz.CategoryStat
 	for _, stat := range incomeByCategory {
 		incomeStats = append(incomeStats, stat)
 	}
+	sortCategoryStats(incomeStats)
 
 	var expenseStats []*biz.CategoryStat
 	for _, stat := range expenseByCategory {
 		expenseStats = append(expenseStats, stat)
 	}
+	sortCategoryStats(expenseStats)
 
 	return &biz.Stats{
 		TotalIncome:       totalIncome,
@@ -445,6 +594,9 @@ func (r *accounterFileRepo) GetStats(ctx context.Context, filter *biz.StatsFilte
 		Balance:           totalIncome - totalExpense,
 		IncomeByCategory:  incomeStats,
 		ExpenseByCategory: expenseStats,
+		IncomeByTag:       byTag.stats(v1.Type_Income),
+		ExpenseByTag:      byTag.stats(v1.Type_Expense),
+		ByCurrency:        byCurrency.stats(),
 	}, nil
 }
 
@@ -453,17 +605,24 @@ func (r *accounterFileRepo) GetPeriodStats(ctx context.Context, filter *biz.Peri
 	defer r.storage.mutex.RUnlock()
 
 	// 按时间段分组统计数据
-	periodStats := make(map[string]*biz.PeriodData)
-	var totalIncome, totalExpense float64
+	periodStats := make(map[int]*biz.PeriodData)
+	byCurrency := make(currencyTotals)
+	var totalIncome, totalExpense biz.Money
 
 	for _, item := range r.storage.data {
-		// Apply user filter
+		// Apply user and account filters, transfers are neither income nor expense
 		if filter.UserID != 0 && item.UserID != filter.UserID {
 			continue
 		}
+		if filter.AccountID != nil && *filter.AccountID != item.AccountID {
+			continue
+		}
+		if item.Type == int32(v1.Type_Transfer) {
+			continue
+		}
 
-		// 根据时间段类型生成时间段名称
-		var periodName string
+		// 根据时间段类型生成时间段键
+		var periodKey int
 		switch filter.PeriodType {
 		case v1.PeriodType_MONTHLY:
 			// 按月统计
@@ -473,13 +632,13 @@ func (r *accounterFileRepo) GetPeriodStats(ctx context.Context, filter *biz.Peri
 			if filter.Month != 0 && item.Date.Month() != time.Month(filter.Month) {
 				continue
 			}
-			periodName = fmt.Sprintf("%d年%d月", item.Date.Year(), item.Date.Month())
+			periodKey = item.Date.Year()*100 + int(item.Date.Month())
 		case v1.PeriodType_YEARLY:
 			// 按年统计
 			if filter.Year != 0 && item.Date.Year() != int(filter.Year) {
 				continue
 			}
-			periodName = fmt.Sprintf("%d年", item.Date.Year())
+			periodKey = item.Date.Year()
 		case v1.PeriodType_WEEKLY:
 			// 按周统计
 			if filter.Year != 0 && item.Date.Year() != int(filter.Year) {
@@ -490,51 +649,123 @@ func (r *accounterFileRepo) GetPeriodStats(ctx context.Context, filter *biz.Peri
 				yearStart := time.Date(int(filter.Year), 1, 1, 0, 0, 0, 0, time.UTC)
 				weekStart := yearStart.AddDate(0, 0, (int(filter.Week)-1)*7)
 				weekEnd := weekStart.AddDate(0, 0, 6)
-				
+
 				if item.Date.Before(weekStart) || item.Date.After(weekEnd) {
 					continue
 				}
 			}
 			year, week := item.Date.ISOWeek()
-			periodName = fmt.Sprintf("%d年第%d周", year, week)
+			periodKey = year*100 + week
 		default:
 			continue
 		}
 
 		// 获取或创建时间段统计
-		if stat, exists := periodStats[periodName]; exists {
-			stat.TransactionCount++
-		} else {
-			periodStats[periodName] = &biz.PeriodData{
-				PeriodName:       periodName,
-				Income:           0,
-				Expense:          0,
-				Balance:          0,
-				TransactionCount: 0,
+		stat, exists := periodStats[periodKey]
+		if !exists {
+			stat = &biz.PeriodData{
+				PeriodName: periodDisplayName(filter.PeriodType, periodKey),
 			}
+			periodStats[periodKey] = stat
+		}
+		stat.TransactionCount++
+
+		if item.Type != int32(v1.Type_Income) && item.Type != int32(v1.Type_Expense) {
+			continue
+		}
+		// 按原币种累计，再换算成本位币累计
+		currency := currencyOrDefault(item.Currency)
+		byCurrency.add(currency, v1.Type(item.Type), item.Amount, 1)
+		amount, err := convertAmount(filter.Converter, item.Amount, currency, item.Date)
+		if err != nil {
+			return nil, err
 		}
 
 		// 累计统计数据
 		if item.Type == int32(v1.Type_Income) {
-			periodStats[periodName].Income += item.Amount
-			totalIncome += item.Amount
-		} else if item.Type == int32(v1.Type_Expense) {
-			periodStats[periodName].Expense += item.Amount
-			totalExpense += item.Amount
+			stat.Income += amount
+			totalIncome += amount
+		} else {
+			stat.Expense += amount
+			totalExpense += amount
 		}
 	}
 
-	// 计算每个时间段的余额并转换为切片
+	// 计算每个时间段的余额并按时间顺序转换为切片
+	periodKeys := make([]int, 0, len(periodStats))
+	for key := range periodStats {
+		periodKeys = append(periodKeys, key)
+	}
+	sort.Ints(periodKeys)
+
 	var periods []*biz.PeriodData
-	for _, stat := range periodStats {
+	for _, key := range periodKeys {
+		stat := periodStats[key]
 		stat.Balance = stat.Income - stat.Expense
 		periods = append(periods, stat)
 	}
 
 	return &biz.PeriodStats{
+		ByCurrency:   byCurrency.stats(),
 		Periods:      periods,
 		TotalIncome:  totalIncome,
 		TotalExpense: totalExpense,
 		TotalBalance: totalIncome - totalExpense,
 	}, nil
 }
+
+func (r *accounterFileRepo) GetAccountStats(ctx context.Context, filter *biz.StatsFilter) ([]*biz.AccountStat, error) {
+	r.storage.mutex.RLock()
+	defer r.storage.mutex.RUnlock()
+
+	byAccount := make(map[int64]*biz.AccountStat)
+	for _, item := range r.storage.data {
+		if filter.UserID != 0 && item.UserID != filter.UserID {
+			continue
+		}
+		if filter.StartDate != nil && item.Date.Before(*filter.StartDate) {
+			continue
+		}
+		if filter.EndDate != nil && item.Date.After(*filter.EndDate) {
+			continue
+		}
+
+		stat := accountStat(byAccount, item.AccountID)
+		switch v1.Type(item.Type) {
+		case v1.Type_Income:
+			stat.Income += item.Amount
+		case v1.Type_Expense:
+			stat.Expense += item.Amount
+		case v1.Type_Transfer:
+			stat.TransferOut += item.Amount + item.Fee
+			to := accountStat(byAccount, item.ToAccountID)
+			to.TransferIn += item.ToAmount
+			to.Count++
+		}
+		stat.Count++
+	}
+
+	return sortAccountStats(byAccount), nil
+}
+
+func (r *accounterFileRepo) ListTags(ctx context.Context, userID int64) ([]*biz.Tag, error) {
+	r.storage.mutex.RLock()
+	defer r.storage.mutex.RUnlock()
+
+	counts := make(map[string]int32)
+	for _, item := range r.storage.data {
+		if item.UserID != userID {
+			continue
+		}
+		for _, tag := range item.Tags {
+			counts[tag]++
+		}
+	}
+	return sortTags(counts), nil
+}
+
+// inAccount reports whether money of the record moves in or out of an account,
+// transfers belong to both of their accounts
+func (d *FileAccounterData) inAccount(accountID int64) bool {
+	return d.AccountID == accountID || (d.Type == int32(v1.Type_Transfer) && d.ToAccountID == accountID)
+}
@@ -0,0 +1,1143 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "accounter_go/api/accounter/v1"
+	"accounter_go/internal/biz"
+	"accounter_go/internal/conf"
+	"accounter_go/internal/data/model"
+
+	"github.com/glebarez/sqlite"
+	"github.com/go-kratos/kratos/v2/log"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// The contract tests below run the same behavior checks against every
+// biz.AccounterRepo implementation so the backends stay interchangeable.
+
+func newTestFileRepo(t *testing.T) biz.AccounterRepo {
+	repo, cleanup, err := NewAccounterFileRepo(&conf.Data{
+		FileStorage: &conf.Data_FileStorage{
+			DataDir:       t.TempDir(),
+			AccounterFile: "accounters.json",
+		},
+	}, log.NewStdLogger(io.Discard))
+	if err != nil {
+		t.Fatalf("NewAccounterFileRepo: %v", err)
+	}
+	t.Cleanup(cleanup)
+	return repo
+}
+
+// newTestMysqlRepo needs ACCOUNTER_TEST_MYSQL_DSN pointing at a disposable database,
+// e.g. root:pass@tcp(127.0.0.1:3306)/accounter_test?parseTime=True&loc=UTC
+// Nothing sets it by default, so go test skips the MySQL run unless it is pointed at a database by hand.
+// Every table is dropped first so that each run starts from an empty, freshly migrated schema.
+func newTestMysqlRepo(t *testing.T) biz.AccounterRepo {
+	dsn := os.Getenv("ACCOUNTER_TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("ACCOUNTER_TEST_MYSQL_DSN not set")
+	}
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open mysql: %v", err)
+	}
+	if err := db.Migrator().DropTable(schemaModels...); err != nil {
+		t.Fatalf("drop tables: %v", err)
+	}
+	if err := migrateSchema(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return NewAccounterDbRepo(&Data{db: db}, log.NewStdLogger(io.Discard))
+}
+
+func TestAccounterFileRepoContract(t *testing.T) {
+	runAccounterRepoContract(t, newTestFileRepo)
+}
+
+func TestAccounterMemoryRepoContract(t *testing.T) {
+	runAccounterRepoContract(t, func(t *testing.T) biz.AccounterRepo {
+		return NewAccounterMemoryRepo(log.NewStdLogger(io.Discard))
+	})
+}
+
+func TestAccounterSqliteRepoContract(t *testing.T) {
+	runAccounterRepoContract(t, func(t *testing.T) biz.AccounterRepo {
+		db, cleanup, err := NewSqliteDB(&conf.Data{
+			Sqlite: &conf.Data_Sqlite{Path: filepath.Join(t.TempDir(), "accounters.db")},
+		}, log.NewStdLogger(io.Discard))
+		if err != nil {
+			t.Fatalf("NewSqliteDB: %v", err)
+		}
+		t.Cleanup(cleanup)
+		return NewAccounterDbRepo(&Data{db: db}, log.NewStdLogger(io.Discard))
+	})
+}
+
+// Transactions saved before search existed get their search text when the schema is migrated
+func TestSqliteBackfillsSearchText(t *testing.T) {
+	db, cleanup, err := NewSqliteDB(&conf.Data{
+		Sqlite: &conf.Data_Sqlite{Path: filepath.Join(t.TempDir(), "accounters.db")},
+	}, log.NewStdLogger(io.Discard))
+	if err != nil {
+		t.Fatalf("NewSqliteDB: %v", err)
+	}
+	defer cleanup()
+	repo := NewAccounterDbRepo(&Data{db: db}, log.NewStdLogger(io.Discard))
+	ctx := context.Background()
+
+	lunch, err := repo.Save(ctx, &biz.Accounter{UserID: 1, Type: v1.Type_Expense, Desc: "午餐", Amount: money("25"), Currency: "CNY", Tags: []string{"work"}, Date: date("2025-06-01")})
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := db.Model(&model.AccounterTransaction{}).Where("1 = 1").Update("search_text", nil).Error; err != nil {
+		t.Fatalf("clear search_text: %v", err)
+	}
+	if err := migrateSchema(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	for _, query := range []string{"wucan", "work"} {
+		list, _, err := repo.ListWithFilters(ctx, &biz.ListFilter{UserID: 1, Query: query, Page: 1, PageSize: 10})
+		if err != nil || len(list) != 1 || list[0].TransactionID != lunch.TransactionID {
+			t.Errorf("search %q after backfill = %v, %v", query, descs(list), err)
+		}
+	}
+}
+
+// Amounts are summed as integers, float sums of many cents drift off the exact total
+func TestSqliteSumsAmountsExactly(t *testing.T) {
+	db, cleanup, err := NewSqliteDB(&conf.Data{
+		Sqlite: &conf.Data_Sqlite{Path: filepath.Join(t.TempDir(), "accounters.db")},
+	}, log.NewStdLogger(io.Discard))
+	if err != nil {
+		t.Fatalf("NewSqliteDB: %v", err)
+	}
+	defer cleanup()
+	repo := NewAccounterDbRepo(&Data{db: db}, log.NewStdLogger(io.Discard))
+	ctx := context.Background()
+
+	batch := make([]*biz.Accounter, 0, 1001)
+	for i := 0; i < 1000; i++ {
+		batch = append(batch, &biz.Accounter{UserID: 1, Type: v1.Type_Expense, Desc: "cent", Amount: money("0.01"), Currency: "CNY", Date: date("2025-06-01")})
+	}
+	batch = append(batch, &biz.Accounter{UserID: 1, Type: v1.Type_Expense, Desc: "tea", Amount: money("10965.55"), Currency: "CNY", Date: date("2025-06-02")})
+	if _, err := repo.SaveBatch(ctx, batch); err != nil {
+		t.Fatalf("SaveBatch: %v", err)
+	}
+
+	stats, err := repo.GetStats(ctx, &biz.StatsFilter{UserID: 1})
+	if err != nil || stats.TotalExpense != money("10975.55") {
+		t.Errorf("GetStats total expense = %v, %v, want 10975.55", stats.TotalExpense, err)
+	}
+	period, err := repo.GetPeriodStats(ctx, &biz.PeriodStatsFilter{UserID: 1, PeriodType: v1.PeriodType_MONTHLY, Year: 2025})
+	if err != nil || period.TotalExpense != money("10975.55") {
+		t.Errorf("GetPeriodStats total expense = %v, %v, want 10975.55", period.TotalExpense, err)
+	}
+}
+
+// Databases created before amounts were integers keep their amounts exactly
+func TestSqliteMigratesDecimalAmounts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "accounters.db")
+	legacy, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	for _, stmt := range []string{
+		// The table as AutoMigrate created it when amounts were decimals
+		"CREATE TABLE `accounter_transactions` (`transaction_id` integer PRIMARY KEY AUTOINCREMENT,`user_id` bigint NOT NULL," +
+			"`category_id` int NOT NULL,`currency_id` int NOT NULL,`transaction_type` tinyint NOT NULL,`amount` decimal(18,5) NOT NULL," +
+			"`to_amount` decimal(18,5) NOT NULL DEFAULT 0,`fee` decimal(18,5) NOT NULL DEFAULT 0,`transaction_date` datetime NOT NULL," +
+			"`note` varchar(255),`created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,`updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP)",
+		`INSERT INTO accounter_transactions (user_id, category_id, currency_id, transaction_type, amount, transaction_date, note)
+			VALUES (1, 2, 0, 2, 10965.55, '2025-06-01 00:00:00', 'tea'), (1, 2, 0, 2, 0.1, '2025-06-02 00:00:00', 'gum'),
+			(1, 0, 0, 3, 100.25, '2025-06-03 00:00:00', 'transfer')`,
+		`UPDATE accounter_transactions SET to_amount = 14.07, fee = 0.35 WHERE note = 'transfer'`,
+	} {
+		if err := legacy.Exec(stmt).Error; err != nil {
+			t.Fatalf("create the legacy schema: %v", err)
+		}
+	}
+	if sqlDB, err := legacy.DB(); err == nil {
+		sqlDB.Close()
+	}
+
+	db, cleanup, err := NewSqliteDB(&conf.Data{Sqlite: &conf.Data_Sqlite{Path: path}}, log.NewStdLogger(io.Discard))
+	if err != nil {
+		t.Fatalf("NewSqliteDB: %v", err)
+	}
+	defer cleanup()
+	for _, column := range []string{"amount", "to_amount", "fee"} {
+		if db.Migrator().HasColumn(&model.AccounterTransaction{}, column) {
+			t.Errorf("the legacy column %s is still there", column)
+		}
+	}
+	repo := NewAccounterDbRepo(&Data{db: db}, log.NewStdLogger(io.Discard))
+	ctx := context.Background()
+	transfer, err := repo.FindByID(ctx, 3)
+	if err != nil || transfer.Amount != money("100.25") || transfer.ToAmount != money("14.07") || transfer.Fee != money("0.35") {
+		t.Errorf("FindByID of the transfer = %+v, %v", transfer, err)
+	}
+	stats, err := repo.GetStats(ctx, &biz.StatsFilter{UserID: 1})
+	if err != nil || stats.TotalExpense != money("10965.65") {
+		t.Errorf("GetStats total expense = %v, %v, want 10965.65", stats.TotalExpense, err)
+	}
+}
+
+// Older rows keep created_at as CURRENT_TIMESTAMP wrote it, paging by creation compares all rows in one format
+func TestSqliteCursorComparesCreatedAtFormats(t *testing.T) {
+	db, cleanup, err := NewSqliteDB(&conf.Data{
+		Sqlite: &conf.Data_Sqlite{Path: filepath.Join(t.TempDir(), "accounters.db")},
+	}, log.NewStdLogger(io.Discard))
+	if err != nil {
+		t.Fatalf("NewSqliteDB: %v", err)
+	}
+	defer cleanup()
+	repo := NewAccounterDbRepo(&Data{db: db}, log.NewStdLogger(io.Discard))
+	ctx := context.Background()
+
+	saved := saveDescs(t, repo, "a", "b", "c", "d")
+	for i, createdAt := range []string{"2025-06-01 10:00:00.250+00:00", "2025-06-01 10:00:00", "2025-06-01 10:00:00.250", "2025-06-01T10:00:00.1Z"} {
+		if err := db.Exec("UPDATE accounter_transactions SET created_at = ? WHERE transaction_id = ?", createdAt, saved[i].TransactionID).Error; err != nil {
+			t.Fatalf("set created_at: %v", err)
+		}
+	}
+
+	filter := biz.ListFilter{UserID: 1, SortBy: v1.SortField_CREATED_AT, Page: 1, PageSize: 1}
+	var walked []string
+	for len(walked) <= len(saved) {
+		page, _, err := repo.ListWithFilters(ctx, &filter)
+		if err != nil {
+			t.Fatalf("ListWithFilters: %v", err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		walked = append(walked, page[0].Desc)
+		filter.After = cursorOf(filter.SortBy, false, page[0])
+	}
+	if !equalStrings(walked, []string{"b", "d", "a", "c"}) {
+		t.Errorf("pages by creation = %v, want b d a c", walked)
+	}
+}
+
+func TestAccounterDbRepoContract(t *testing.T) {
+	runAccounterRepoContract(t, newTestMysqlRepo)
+}
+
+func date(s string) time.Time {
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+var contractFixture = []*biz.Accounter{
+	{UserID: 1, Type: v1.Type_Income, CategoryID: int64(v1.Category_Salary), Desc: "salary", Amount: money("10000"), Date: date("2025-06-01")},
+	{UserID: 1, Type: v1.Type_Expense, CategoryID: int64(v1.Category_Food), Desc: "lunch", Amount: money("25.5"), Date: date("2025-06-02")},
+	{UserID: 1, Type: v1.Type_Expense, CategoryID: int64(v1.Category_Food), Desc: "dinner", Amount: money("30"), Date: date("2025-06-15")},
+	{UserID: 1, Type: v1.Type_Expense, CategoryID: int64(v1.Category_Transport), Desc: "metro", Amount: money("5"), Date: date("2025-07-01")},
+	{UserID: 1, Type: v1.Type_Income, CategoryID: int64(v1.Category_OtherIncome), Desc: "refund", Amount: money("200"), Date: date("2025-07-03")},
+	{UserID: 1, Type: v1.Type_Expense, CategoryID: int64(v1.Category_Shopping), Desc: "shoes", Amount: money("99.9"), Date: date("2024-12-30")},
+	{UserID: 2, Type: v1.Type_Expense, CategoryID: int64(v1.Category_Food), Desc: "other user", Amount: money("12"), Date: date("2025-06-02")},
+}
+
+func seed(t *testing.T, repo biz.AccounterRepo) []*biz.Accounter {
+	t.Helper()
+	saved := make([]*biz.Accounter, 0, len(contractFixture))
+	for _, a := range contractFixture {
+		in := *a
+		out, err := repo.Save(context.Background(), &in)
+		if err != nil {
+			t.Fatalf("Save(%s): %v", a.Desc, err)
+		}
+		saved = append(saved, out)
+	}
+	return saved
+}
+
+// cursorOf is the cursor of a transaction in a sort order
+func cursorOf(sortBy v1.SortField, descending bool, a *biz.Accounter) *biz.ListCursor {
+	return &biz.ListCursor{SortBy: sortBy, Descending: descending, Date: a.Date, Amount: a.Amount, CreatedAt: a.CreatedAt, ID: a.TransactionID}
+}
+
+func descs(accounters []*biz.Accounter) []string {
+	out := make([]string, 0, len(accounters))
+	for _, a := range accounters {
+		out = append(out, a.Desc)
+	}
+	return out
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// money parses a decimal amount of the test fixture
+func money(s string) biz.Money {
+	m, err := biz.ParseMoney(s)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func runAccounterRepoContract(t *testing.T, newRepo func(t *testing.T) biz.AccounterRepo) {
+	ctx := context.Background()
+
+	t.Run("SaveAndFind", func(t *testing.T) {
+		repo := newRepo(t)
+		saved := seed(t, repo)
+		for i, s := range saved {
+			if s.TransactionID == 0 {
+				t.Fatalf("saved record %d has no ID", i)
+			}
+			got, err := repo.FindByID(ctx, s.TransactionID)
+			if err != nil {
+				t.Fatalf("FindByID(%d): %v", s.TransactionID, err)
+			}
+			want := contractFixture[i]
+			if got.UserID != want.UserID || got.Type != want.Type || got.CategoryID != want.CategoryID ||
+				got.Desc != want.Desc || got.Amount != want.Amount || !got.Date.Equal(want.Date) {
+				t.Errorf("FindByID(%d) = %+v, want %+v", s.TransactionID, got, want)
+			}
+		}
+	})
+
+	t.Run("ListWithFilters", func(t *testing.T) {
+		repo := newRepo(t)
+		seed(t, repo)
+
+		cases := []struct {
+			name      string
+			filter    biz.ListFilter
+			wantDescs []string
+			wantTotal int32
+		}{
+			{"first page", biz.ListFilter{UserID: 1, Page: 1, PageSize: 4}, []string{"salary", "lunch", "dinner", "metro"}, 6},
+			{"last page", biz.ListFilter{UserID: 1, Page: 2, PageSize: 4}, []string{"refund", "shoes"}, 6},
+			{"past the end", biz.ListFilter{UserID: 1, Page: 3, PageSize: 4}, []string{}, 6},
+			{"by type", biz.ListFilter{UserID: 1, Types: []v1.Type{v1.Type_Expense}, Page: 1, PageSize: 10}, []string{"lunch", "dinner", "metro", "shoes"}, 4},
+			{"by types", biz.ListFilter{UserID: 1, Types: []v1.Type{v1.Type_Income, v1.Type_Transfer}, Page: 1, PageSize: 10}, []string{"salary", "refund"}, 2},
+			{"by category", biz.ListFilter{UserID: 1, CategoryIDs: []int64{int64(v1.Category_Food)}, Page: 1, PageSize: 10}, []string{"lunch", "dinner"}, 2},
+			{"by categories", biz.ListFilter{UserID: 1, CategoryIDs: []int64{int64(v1.Category_Food), int64(v1.Category_Transport)}, Page: 1, PageSize: 10}, []string{"lunch", "dinner", "metro"}, 3},
+			{"by amount range", biz.ListFilter{UserID: 1, MinAmount: ptr(money("25.5")), MaxAmount: ptr(money("200")), Page: 1, PageSize: 10}, []string{"lunch", "dinner", "refund", "shoes"}, 4},
+			{"by date range", biz.ListFilter{UserID: 1, StartDate: ptr(date("2025-06-02")), EndDate: ptr(date("2025-07-01")), Page: 1, PageSize: 10}, []string{"lunch", "dinner", "metro"}, 3},
+			{"other user", biz.ListFilter{UserID: 2, Page: 1, PageSize: 10}, []string{"other user"}, 1},
+			{"by date", biz.ListFilter{UserID: 1, SortBy: v1.SortField_DATE, Page: 1, PageSize: 3}, []string{"shoes", "salary", "lunch"}, 6},
+			{"by date descending", biz.ListFilter{UserID: 1, SortBy: v1.SortField_DATE, Descending: true, Page: 1, PageSize: 3}, []string{"refund", "metro", "dinner"}, 6},
+			{"by amount", biz.ListFilter{UserID: 1, SortBy: v1.SortField_AMOUNT, Page: 1, PageSize: 10}, []string{"metro", "lunch", "dinner", "shoes", "refund", "salary"}, 6},
+			{"by creation descending", biz.ListFilter{UserID: 1, SortBy: v1.SortField_CREATED_AT, Descending: true, Page: 1, PageSize: 10}, []string{"shoes", "refund", "metro", "dinner", "lunch", "salary"}, 6},
+		}
+		for _, c := range cases {
+			got, total, err := repo.ListWithFilters(ctx, &c.filter)
+			if err != nil {
+				t.Fatalf("%s: %v", c.name, err)
+			}
+			if total != c.wantTotal || !equalStrings(descs(got), c.wantDescs) {
+				t.Errorf("%s: got %v (total %d), want %v (total %d)", c.name, descs(got), total, c.wantDescs, c.wantTotal)
+			}
+		}
+	})
+
+	t.Run("Sort ties", func(t *testing.T) {
+		repo := newRepo(t)
+		saved := saveDescs(t, repo, "a", "b", "c", "d")
+
+		// Records that tie keep the order of their IDs, so every page continues where the last one ended
+		for _, descending := range []bool{false, true} {
+			var ids []int64
+			for page := int32(1); page <= 4; page++ {
+				list, _, err := repo.ListWithFilters(ctx, &biz.ListFilter{UserID: 1, SortBy: v1.SortField_DATE, Descending: descending, Page: page, PageSize: 1})
+				if err != nil || len(list) != 1 {
+					t.Fatalf("page %d = %v, %v", page, descs(list), err)
+				}
+				ids = append(ids, list[0].TransactionID)
+			}
+			for i := range ids {
+				want := saved[i].TransactionID
+				if descending {
+					want = saved[len(saved)-1-i].TransactionID
+				}
+				if ids[i] != want {
+					t.Errorf("descending %v: pages are transactions %v", descending, ids)
+					break
+				}
+			}
+		}
+	})
+
+	t.Run("Cursor", func(t *testing.T) {
+		repo := newRepo(t)
+		seed(t, repo)
+
+		// Walking the pages after the last transaction of each page gives every transaction once
+		for _, order := range []struct {
+			sortBy     v1.SortField
+			descending bool
+		}{
+			{v1.SortField_DEFAULT, false},
+			{v1.SortField_DATE, true},
+			{v1.SortField_AMOUNT, false},
+			{v1.SortField_CREATED_AT, true},
+		} {
+			filter := biz.ListFilter{UserID: 1, SortBy: order.sortBy, Descending: order.descending, Page: 1, PageSize: 100}
+			all, _, err := repo.ListWithFilters(ctx, &filter)
+			if err != nil {
+				t.Fatalf("ListWithFilters: %v", err)
+			}
+			var walked []string
+			filter.PageSize = 4
+			for {
+				page, total, err := repo.ListWithFilters(ctx, &filter)
+				if err != nil || total != 6 {
+					t.Fatalf("page after %+v = %v, total %d, %v", filter.After, descs(page), total, err)
+				}
+				walked = append(walked, descs(page)...)
+				if len(page) < int(filter.PageSize) || len(walked) > int(total) {
+					break
+				}
+				filter.After = cursorOf(order.sortBy, order.descending, page[len(page)-1])
+			}
+			if !equalStrings(walked, descs(all)) {
+				t.Errorf("sort %v descending %v: pages %v, want %v", order.sortBy, order.descending, walked, descs(all))
+			}
+		}
+
+		// Changes before the cursor don't shift the next page
+		filter := &biz.ListFilter{UserID: 1, SortBy: v1.SortField_DATE, Page: 1, PageSize: 2}
+		first, _, err := repo.ListWithFilters(ctx, filter)
+		if err != nil || !equalStrings(descs(first), []string{"shoes", "salary"}) {
+			t.Fatalf("first page = %v, %v", descs(first), err)
+		}
+		if err := repo.Delete(ctx, first[0].TransactionID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := repo.Save(ctx, &biz.Accounter{UserID: 1, Type: v1.Type_Expense, Desc: "coffee", Amount: money("3"), Currency: "CNY", Date: date("2025-05-01")}); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+		filter.After = cursorOf(filter.SortBy, false, first[1])
+		second, _, err := repo.ListWithFilters(ctx, filter)
+		if err != nil || !equalStrings(descs(second), []string{"lunch", "dinner"}) {
+			t.Errorf("second page = %v, %v", descs(second), err)
+		}
+	})
+
+	t.Run("Cursor ties", func(t *testing.T) {
+		repo := newRepo(t)
+		batch := make([]*biz.Accounter, 50)
+		for i := range batch {
+			batch[i] = &biz.Accounter{UserID: 1, Type: v1.Type_Expense, Desc: fmt.Sprint(i), Amount: money("0.1"), Currency: "CNY", Date: date("2025-06-01")}
+		}
+		// The batch is created within a second and every transaction has the same amount
+		saved, err := repo.SaveBatch(ctx, batch)
+		if err != nil {
+			t.Fatalf("SaveBatch: %v", err)
+		}
+
+		for _, sortBy := range []v1.SortField{v1.SortField_CREATED_AT, v1.SortField_AMOUNT} {
+			for _, descending := range []bool{false, true} {
+				filter := biz.ListFilter{UserID: 1, SortBy: sortBy, Descending: descending, Page: 1, PageSize: 7}
+				var ids []int64
+				for len(ids) <= len(saved) {
+					page, _, err := repo.ListWithFilters(ctx, &filter)
+					if err != nil {
+						t.Fatalf("ListWithFilters: %v", err)
+					}
+					for _, a := range page {
+						ids = append(ids, a.TransactionID)
+					}
+					if len(page) < int(filter.PageSize) {
+						break
+					}
+					filter.After = cursorOf(sortBy, descending, page[len(page)-1])
+				}
+				ok := len(ids) == len(saved)
+				for i := 0; ok && i < len(ids); i++ {
+					want := saved[i]
+					if descending {
+						want = saved[len(saved)-1-i]
+					}
+					ok = ids[i] == want.TransactionID
+				}
+				if !ok {
+					t.Errorf("sort %v descending %v: pages are transactions %v", sortBy, descending, ids)
+				}
+			}
+		}
+	})
+
+	t.Run("EachWithFilters", func(t *testing.T) {
+		repo := newRepo(t)
+		seed(t, repo)
+		each := func(filter *biz.ListFilter) []string {
+			t.Helper()
+			var walked []string
+			if err := repo.EachWithFilters(ctx, filter, func(a *biz.Accounter) error {
+				walked = append(walked, a.Desc)
+				return nil
+			}); err != nil {
+				t.Fatalf("EachWithFilters: %v", err)
+			}
+			return walked
+		}
+
+		filter := &biz.ListFilter{UserID: 1, Types: []v1.Type{v1.Type_Expense}, SortBy: v1.SortField_AMOUNT, Descending: true}
+		if got := each(filter); !equalStrings(got, []string{"shoes", "dinner", "lunch", "metro"}) {
+			t.Errorf("expenses by amount = %v", got)
+		}
+		all, _, err := repo.ListWithFilters(ctx, &biz.ListFilter{UserID: 1, SortBy: v1.SortField_DATE, Page: 1, PageSize: 2})
+		if err != nil {
+			t.Fatalf("ListWithFilters: %v", err)
+		}
+		filter = &biz.ListFilter{UserID: 1, SortBy: v1.SortField_DATE, After: cursorOf(v1.SortField_DATE, false, all[1])}
+		if got := each(filter); !equalStrings(got, []string{"lunch", "dinner", "metro", "refund"}) {
+			t.Errorf("after %v = %v", descs(all), got)
+		}
+
+		stop := errors.New("stop")
+		calls := 0
+		if err := repo.EachWithFilters(ctx, &biz.ListFilter{UserID: 1}, func(*biz.Accounter) error {
+			calls++
+			return stop
+		}); !errors.Is(err, stop) || calls != 1 {
+			t.Errorf("EachWithFilters stopped after %d calls with %v", calls, err)
+		}
+
+		// Transactions past a batch of the database repo are walked once
+		batch := make([]*biz.Accounter, eachBatchSize+1)
+		for i := range batch {
+			batch[i] = &biz.Accounter{UserID: 3, Type: v1.Type_Expense, Desc: fmt.Sprint(i), Amount: money("1"), Currency: "CNY", Date: date("2025-06-01")}
+		}
+		if _, err := repo.SaveBatch(ctx, batch); err != nil {
+			t.Fatalf("SaveBatch: %v", err)
+		}
+		got := each(&biz.ListFilter{UserID: 3, SortBy: v1.SortField_AMOUNT})
+		want := descs(batch)
+		if !equalStrings(got, want) {
+			t.Errorf("walked %d of %d transactions", len(got), len(want))
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		repo := newRepo(t)
+		saved := seed(t, repo)
+
+		changed := *saved[1]
+		changed.Desc = "brunch"
+		changed.Amount = money("40")
+		if _, err := repo.Update(ctx, &changed); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		got, err := repo.FindByID(ctx, changed.TransactionID)
+		if err != nil {
+			t.Fatalf("FindByID: %v", err)
+		}
+		if got.Desc != "brunch" || got.Amount != money("40") {
+			t.Errorf("after Update got %+v", got)
+		}
+
+		missing := changed
+		missing.TransactionID = 99999
+		if _, err := repo.Update(ctx, &missing); !errors.Is(err, biz.ErrAccounterNotFound) {
+			t.Errorf("Update of a missing record = %v, want ErrAccounterNotFound", err)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		repo := newRepo(t)
+		saved := seed(t, repo)
+
+		if err := repo.Delete(ctx, saved[0].TransactionID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := repo.FindByID(ctx, saved[0].TransactionID); !errors.Is(err, biz.ErrAccounterNotFound) {
+			t.Errorf("FindByID of a deleted record = %v, want ErrAccounterNotFound", err)
+		}
+		if err := repo.Delete(ctx, saved[0].TransactionID); !errors.Is(err, biz.ErrAccounterNotFound) {
+			t.Errorf("deleting twice = %v, want ErrAccounterNotFound", err)
+		}
+		_, total, err := repo.ListWithFilters(ctx, &biz.ListFilter{UserID: 1, Page: 1, PageSize: 10})
+		if err != nil {
+			t.Fatalf("ListWithFilters: %v", err)
+		}
+		if total != 5 {
+			t.Errorf("total after delete = %d, want 5", total)
+		}
+	})
+
+	t.Run("GetStats", func(t *testing.T) {
+		repo := newRepo(t)
+		seed(t, repo)
+
+		stats, err := repo.GetStats(ctx, &biz.StatsFilter{UserID: 1})
+		if err != nil {
+			t.Fatalf("GetStats: %v", err)
+		}
+		if stats.TotalIncome != money("10200") || stats.TotalExpense != money("160.4") || stats.Balance != money("10039.6") {
+			t.Errorf("totals = %v/%v/%v", stats.TotalIncome, stats.TotalExpense, stats.Balance)
+		}
+		assertCategoryStats(t, "income", stats.IncomeByCategory, []biz.CategoryStat{
+			{CategoryID: int64(v1.Category_Salary), Amount: money("10000"), Count: 1},
+			{CategoryID: int64(v1.Category_OtherIncome), Amount: money("200"), Count: 1},
+		})
+		assertCategoryStats(t, "expense", stats.ExpenseByCategory, []biz.CategoryStat{
+			{CategoryID: int64(v1.Category_Food), Amount: money("55.5"), Count: 2},
+			{CategoryID: int64(v1.Category_Shopping), Amount: money("99.9"), Count: 1},
+			{CategoryID: int64(v1.Category_Transport), Amount: money("5"), Count: 1},
+		})
+
+		ranged, err := repo.GetStats(ctx, &biz.StatsFilter{UserID: 1, StartDate: ptr(date("2025-06-01")), EndDate: ptr(date("2025-06-30"))})
+		if err != nil {
+			t.Fatalf("GetStats with range: %v", err)
+		}
+		if ranged.TotalIncome != money("10000") || ranged.TotalExpense != money("55.5") {
+			t.Errorf("ranged totals = %v/%v", ranged.TotalIncome, ranged.TotalExpense)
+		}
+	})
+
+	t.Run("GetStats in a base currency", func(t *testing.T) {
+		repo := newRepo(t)
+		seed(t, repo)
+		for _, a := range []*biz.Accounter{
+			{UserID: 1, Type: v1.Type_Expense, CategoryID: int64(v1.Category_Food), Desc: "burger", Amount: money("10"), Currency: "USD", Date: date("2025-06-20")},
+			{UserID: 1, Type: v1.Type_Income, CategoryID: int64(v1.Category_Salary), Desc: "bonus", Amount: money("100"), Currency: "USD", Date: date("2025-07-02")},
+		} {
+			if _, err := repo.Save(ctx, a); err != nil {
+				t.Fatalf("Save(%s): %v", a.Desc, err)
+			}
+		}
+
+		stats, err := repo.GetStats(ctx, &biz.StatsFilter{UserID: 1, StartDate: ptr(date("2025-06-01")), Converter: testConverter{}})
+		if err != nil {
+			t.Fatalf("GetStats: %v", err)
+		}
+		if stats.TotalIncome != money("10920") || stats.TotalExpense != money("130.5") {
+			t.Errorf("converted totals = %v/%v", stats.TotalIncome, stats.TotalExpense)
+		}
+		assertCategoryStats(t, "converted expense", stats.ExpenseByCategory, []biz.CategoryStat{
+			{CategoryID: int64(v1.Category_Food), Amount: money("125.5"), Count: 3},
+			{CategoryID: int64(v1.Category_Transport), Amount: money("5"), Count: 1},
+		})
+		if len(stats.ByCurrency) != 2 || stats.ByCurrency[0].Currency != "CNY" || stats.ByCurrency[1].Currency != "USD" ||
+			stats.ByCurrency[1].Income != money("100") || stats.ByCurrency[1].Expense != money("10") || stats.ByCurrency[1].Balance != money("90") ||
+			stats.ByCurrency[1].Count != 2 || stats.ByCurrency[0].Count != 5 {
+			t.Errorf("ByCurrency = %v", currencyStatsString(stats.ByCurrency))
+		}
+
+		period, err := repo.GetPeriodStats(ctx, &biz.PeriodStatsFilter{UserID: 1, PeriodType: v1.PeriodType_MONTHLY, Year: 2025, Month: 7, Converter: testConverter{}})
+		if err != nil {
+			t.Fatalf("GetPeriodStats: %v", err)
+		}
+		if len(period.Periods) != 1 || period.Periods[0].Income != money("920") || period.Periods[0].TransactionCount != 3 ||
+			len(period.ByCurrency) != 2 || period.ByCurrency[1].Income != money("100") {
+			t.Errorf("converted period stats = %+v, %v", period, currencyStatsString(period.ByCurrency))
+		}
+	})
+
+	t.Run("Accounts", func(t *testing.T) {
+		repo := newRepo(t)
+		seed(t, repo)
+		var saved []*biz.Accounter
+		for _, a := range []*biz.Accounter{
+			{UserID: 1, AccountID: 1, Type: v1.Type_Income, CategoryID: int64(v1.Category_OtherIncome), Desc: "interest", Amount: money("100"), Date: date("2025-06-05")},
+			{UserID: 1, AccountID: 1, Type: v1.Type_Expense, CategoryID: int64(v1.Category_Food), Desc: "groceries", Amount: money("40"), Date: date("2025-06-10")},
+			{UserID: 1, AccountID: 2, Type: v1.Type_Expense, CategoryID: int64(v1.Category_Transport), Desc: "taxi", Amount: money("7.5"), Date: date("2025-07-01")},
+		} {
+			out, err := repo.Save(ctx, a)
+			if err != nil {
+				t.Fatalf("Save(%s): %v", a.Desc, err)
+			}
+			saved = append(saved, out)
+		}
+		if got, err := repo.FindByID(ctx, saved[2].TransactionID); err != nil || got.AccountID != 2 {
+			t.Errorf("FindByID = %+v, %v", got, err)
+		}
+
+		list, total, err := repo.ListWithFilters(ctx, &biz.ListFilter{UserID: 1, AccountID: ptr(int64(1)), Page: 1, PageSize: 10})
+		if err != nil || total != 2 || !equalStrings(descs(list), []string{"interest", "groceries"}) {
+			t.Errorf("ListWithFilters by account = %v, %d, %v", descs(list), total, err)
+		}
+		if _, total, _ := repo.ListWithFilters(ctx, &biz.ListFilter{UserID: 1, AccountID: ptr(int64(0)), Page: 1, PageSize: 10}); total != 6 {
+			t.Errorf("transactions without an account = %d, want 6", total)
+		}
+
+		stats, err := repo.GetStats(ctx, &biz.StatsFilter{UserID: 1, AccountID: ptr(int64(1))})
+		if err != nil || stats.TotalIncome != money("100") || stats.TotalExpense != money("40") {
+			t.Errorf("GetStats by account = %+v, %v", stats, err)
+		}
+		period, err := repo.GetPeriodStats(ctx, &biz.PeriodStatsFilter{UserID: 1, AccountID: ptr(int64(2)), PeriodType: v1.PeriodType_MONTHLY, Year: 2025})
+		if err != nil || len(period.Periods) != 1 || period.Periods[0].PeriodName != "2025年7月" || period.TotalExpense != money("7.5") {
+			t.Errorf("GetPeriodStats by account = %+v, %v", period, err)
+		}
+
+		accounts, err := repo.GetAccountStats(ctx, &biz.StatsFilter{UserID: 1})
+		if err != nil {
+			t.Fatalf("GetAccountStats: %v", err)
+		}
+		want := []biz.AccountStat{
+			{AccountID: 0, Income: money("10200"), Expense: money("160.4"), Count: 6},
+			{AccountID: 1, Income: money("100"), Expense: money("40"), Count: 2},
+			{AccountID: 2, Expense: money("7.5"), Count: 1},
+		}
+		if len(accounts) != len(want) {
+			t.Fatalf("GetAccountStats = %d accounts, want %d", len(accounts), len(want))
+		}
+		for i := range want {
+			if *accounts[i] != want[i] {
+				t.Errorf("account stat %d = %+v, want %+v", i, *accounts[i], want[i])
+			}
+		}
+
+		accounts, err = repo.GetAccountStats(ctx, &biz.StatsFilter{UserID: 1, EndDate: ptr(date("2025-06-30"))})
+		if err != nil || len(accounts) != 2 || accounts[0].Expense != money("155.4") || accounts[0].Count != 4 || accounts[1].Income-accounts[1].Expense != money("60") {
+			t.Errorf("GetAccountStats up to June = %+v, %v", accounts, err)
+		}
+	})
+
+	t.Run("Transfers", func(t *testing.T) {
+		repo := newRepo(t)
+		seed(t, repo)
+		monthly := &biz.PeriodStatsFilter{UserID: 1, PeriodType: v1.PeriodType_MONTHLY, Year: 2025}
+		before, err := repo.GetPeriodStats(ctx, monthly)
+		if err != nil {
+			t.Fatalf("GetPeriodStats: %v", err)
+		}
+
+		transfer, err := repo.Save(ctx, &biz.Accounter{
+			UserID: 1, Type: v1.Type_Transfer, Desc: "换汇", Amount: money("100"), Currency: "CNY", AccountID: 1,
+			ToAccountID: 2, ToAmount: money("13.9"), ToCurrency: "USD", Fee: money("1.5"), Date: date("2025-06-20"),
+		})
+		if err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+		got, err := repo.FindByID(ctx, transfer.TransactionID)
+		if err != nil || got.ToAccountID != 2 || got.ToAmount != money("13.9") || got.ToCurrency != "USD" || got.Fee != money("1.5") {
+			t.Errorf("FindByID = %+v, %v", got, err)
+		}
+		if others, err := repo.FindByID(ctx, 1); err != nil || others.ToCurrency != "" || others.ToAccountID != 0 {
+			t.Errorf("FindByID of an expense = %+v, %v", others, err)
+		}
+
+		for _, account := range []int64{1, 2} {
+			list, total, err := repo.ListWithFilters(ctx, &biz.ListFilter{UserID: 1, AccountID: ptr(account), Page: 1, PageSize: 10})
+			if err != nil || total != 1 || list[0].TransactionID != transfer.TransactionID {
+				t.Errorf("ListWithFilters by account %d = %v, %d, %v", account, descs(list), total, err)
+			}
+		}
+
+		stats, err := repo.GetStats(ctx, &biz.StatsFilter{UserID: 1})
+		if err != nil || stats.TotalIncome != money("10200") || stats.TotalExpense != money("160.4") {
+			t.Errorf("GetStats with a transfer = %+v, %v", stats, err)
+		}
+		after, err := repo.GetPeriodStats(ctx, monthly)
+		if err != nil || after.TotalIncome != before.TotalIncome || after.TotalExpense != before.TotalExpense || len(after.Periods) != len(before.Periods) {
+			t.Fatalf("GetPeriodStats with a transfer = %+v, %v", after, err)
+		}
+		for i := range after.Periods {
+			if *after.Periods[i] != *before.Periods[i] {
+				t.Errorf("period %d = %+v, want %+v", i, *after.Periods[i], *before.Periods[i])
+			}
+		}
+
+		accounts, err := repo.GetAccountStats(ctx, &biz.StatsFilter{UserID: 1})
+		if err != nil || len(accounts) != 3 {
+			t.Fatalf("GetAccountStats = %+v, %v", accounts, err)
+		}
+		if want := (biz.AccountStat{AccountID: 1, TransferOut: money("101.5"), Count: 1}); *accounts[1] != want {
+			t.Errorf("source account stat = %+v, want %+v", *accounts[1], want)
+		}
+		if want := (biz.AccountStat{AccountID: 2, TransferIn: money("13.9"), Count: 1}); *accounts[2] != want {
+			t.Errorf("destination account stat = %+v, want %+v", *accounts[2], want)
+		}
+	})
+
+	t.Run("RecurringID", func(t *testing.T) {
+		repo := newRepo(t)
+		seed(t, repo)
+
+		rent, err := repo.Save(ctx, &biz.Accounter{UserID: 1, Type: v1.Type_Expense, Desc: "rent", Amount: money("3000"), Currency: "CNY", RecurringID: 7, Date: date("2025-07-01")})
+		if err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+		// The scheduler looks up the transactions of a rule on the day of an occurrence
+		list, total, err := repo.ListWithFilters(ctx, &biz.ListFilter{UserID: 1, StartDate: ptr(date("2025-07-01")), EndDate: ptr(date("2025-07-01")), Page: 1, PageSize: 10})
+		if err != nil || total != 2 || !equalStrings(descs(list), []string{"metro", "rent"}) || list[1].RecurringID != 7 || list[0].RecurringID != 0 {
+			t.Errorf("ListWithFilters on the day = %v, %d, %v", descs(list), total, err)
+		}
+
+		rent.Amount = money("3200")
+		rent.RecurringID = 0
+		if _, err := repo.Update(ctx, rent); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		if got, err := repo.FindByID(ctx, rent.TransactionID); err != nil || got.RecurringID != 7 || got.Amount != money("3200") {
+			t.Errorf("FindByID after Update = %+v, %v", got, err)
+		}
+	})
+
+	t.Run("ExternalID", func(t *testing.T) {
+		repo := newRepo(t)
+		seed(t, repo)
+
+		saved, err := repo.SaveBatch(ctx, []*biz.Accounter{
+			{UserID: 1, Type: v1.Type_Expense, Desc: "POS 7-ELEVEN", Amount: money("12.5"), Currency: "CNY", AccountID: 2, ExternalID: "20250701-001", Date: date("2025-07-01")},
+		})
+		if err != nil {
+			t.Fatalf("SaveBatch: %v", err)
+		}
+		// Imports look up the IDs of the statement among the transactions of the user
+		list, err := repo.ListByUserID(ctx, 1)
+		if err != nil {
+			t.Fatalf("ListByUserID: %v", err)
+		}
+		var ids []string
+		for _, a := range list {
+			if a.ExternalID != "" {
+				ids = append(ids, a.ExternalID)
+			}
+		}
+		if !equalStrings(ids, []string{"20250701-001"}) {
+			t.Errorf("external IDs of the transactions = %v", ids)
+		}
+
+		pos := saved[0]
+		pos.Desc = "7-Eleven"
+		pos.ExternalID = ""
+		if _, err := repo.Update(ctx, pos); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		if got, err := repo.FindByID(ctx, pos.TransactionID); err != nil || got.ExternalID != "20250701-001" || got.Desc != "7-Eleven" {
+			t.Errorf("FindByID after Update = %+v, %v", got, err)
+		}
+	})
+
+	t.Run("Tags", func(t *testing.T) {
+		repo := newRepo(t)
+		seed(t, repo)
+
+		trip, err := repo.Save(ctx, &biz.Accounter{UserID: 1, Type: v1.Type_Expense, Desc: "hotel", Amount: money("300"), Currency: "CNY", Tags: []string{"business", "travel"}, Date: date("2025-06-10")})
+		if err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+		saved, err := repo.SaveBatch(ctx, []*biz.Accounter{
+			{UserID: 1, Type: v1.Type_Expense, Desc: "train", Amount: money("120"), Currency: "CNY", Tags: []string{"travel"}, Date: date("2025-06-09")},
+			{UserID: 1, Type: v1.Type_Income, Desc: "per diem", Amount: money("50"), Currency: "CNY", Tags: []string{"business"}, Date: date("2025-06-11")},
+			{UserID: 2, Type: v1.Type_Expense, Desc: "other user", Amount: money("8"), Currency: "CNY", Tags: []string{"travel"}, Date: date("2025-06-11")},
+		})
+		if err != nil {
+			t.Fatalf("SaveBatch: %v", err)
+		}
+		if got, err := repo.FindByID(ctx, trip.TransactionID); err != nil || !equalStrings(got.Tags, []string{"business", "travel"}) {
+			t.Errorf("FindByID = %+v, %v", got, err)
+		}
+
+		list, _, err := repo.ListWithFilters(ctx, &biz.ListFilter{UserID: 1, Tags: []string{"travel"}, Page: 1, PageSize: 10})
+		if err != nil || !equalStrings(descs(list), []string{"hotel", "train"}) {
+			t.Errorf("ListWithFilters of travel = %v, %v", descs(list), err)
+		}
+		// Every tag of the filter has to match
+		list, _, err = repo.ListWithFilters(ctx, &biz.ListFilter{UserID: 1, Tags: []string{"business", "travel"}, Page: 1, PageSize: 10})
+		if err != nil || !equalStrings(descs(list), []string{"hotel"}) {
+			t.Errorf("ListWithFilters of business and travel = %v, %v", descs(list), err)
+		}
+
+		stats, err := repo.GetStats(ctx, &biz.StatsFilter{UserID: 1})
+		if err != nil {
+			t.Fatalf("GetStats: %v", err)
+		}
+		if got := tagStatsString(stats.ExpenseByTag); got != "business 300 1, travel 420 2" {
+			t.Errorf("expense by tag = %s", got)
+		}
+		if got := tagStatsString(stats.IncomeByTag); got != "business 50 1" {
+			t.Errorf("income by tag = %s", got)
+		}
+		// Tags don't count a transaction twice in the totals
+		if stats.TotalExpense != money("580.4") {
+			t.Errorf("total expense = %v", stats.TotalExpense)
+		}
+		travel, err := repo.GetStats(ctx, &biz.StatsFilter{UserID: 1, Tags: []string{"travel"}})
+		if err != nil || travel.TotalExpense != money("420") || travel.TotalIncome != 0 {
+			t.Errorf("GetStats of travel = %+v, %v", travel, err)
+		}
+
+		train := saved[0]
+		train.Tags = []string{"commute"}
+		if _, err := repo.Update(ctx, train); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		if err := repo.Delete(ctx, trip.TransactionID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		tags, err := repo.ListTags(ctx, 1)
+		if err != nil {
+			t.Fatalf("ListTags: %v", err)
+		}
+		var got []string
+		for _, tag := range tags {
+			got = append(got, fmt.Sprintf("%s %d", tag.Name, tag.Count))
+		}
+		if !equalStrings(got, []string{"business 1", "commute 1"}) {
+			t.Errorf("ListTags after Update and Delete = %v", got)
+		}
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		repo := newRepo(t)
+		seed(t, repo)
+
+		saved, err := repo.SaveBatch(ctx, []*biz.Accounter{
+			{UserID: 1, Type: v1.Type_Expense, Desc: "午餐 麦当劳", Amount: money("35"), Currency: "CNY", Tags: []string{"工作餐"}, Date: date("2025-06-12")},
+			{UserID: 1, Type: v1.Type_Expense, Desc: "Coffee at 50%_off", Amount: money("15"), Currency: "CNY", Tags: []string{"morning"}, Date: date("2025-06-12")},
+			{UserID: 2, Type: v1.Type_Expense, Desc: "coffee beans", Amount: money("80"), Currency: "CNY", Date: date("2025-06-12")},
+		})
+		if err != nil {
+			t.Fatalf("SaveBatch: %v", err)
+		}
+		search := func(query string) []string {
+			t.Helper()
+			list, total, err := repo.ListWithFilters(ctx, &biz.ListFilter{UserID: 1, Query: query, Page: 1, PageSize: 10})
+			if err != nil {
+				t.Fatalf("ListWithFilters(%q): %v", query, err)
+			}
+			if int(total) != len(list) {
+				t.Errorf("ListWithFilters(%q) total = %d for %v", query, total, descs(list))
+			}
+			return descs(list)
+		}
+		for _, tc := range []struct {
+			query string
+			want  []string
+		}{
+			{"coffee", []string{"Coffee at 50%_off"}},
+			{"午餐", []string{"午餐 麦当劳"}},
+			{"wucan", []string{"午餐 麦当劳"}},
+			{"mdl", []string{"午餐 麦当劳"}},
+			{"gzc", []string{"午餐 麦当劳"}}, // initials of the tag
+			{"coffee morning", []string{"Coffee at 50%_off"}},
+			{"coffee lunch", []string{}},
+			{"50%_", []string{"Coffee at 50%_off"}},
+			{"5%", []string{}}, // wildcards are matched literally
+			{"o_f", []string{}},
+		} {
+			if got := search(tc.query); !equalStrings(got, tc.want) {
+				t.Errorf("search %q = %v, want %v", tc.query, got, tc.want)
+			}
+		}
+
+		coffee := saved[1]
+		coffee.Desc = "tea"
+		if _, err := repo.Update(ctx, coffee); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		if err := repo.Delete(ctx, saved[0].TransactionID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if got := search("coffee"); len(got) != 0 {
+			t.Errorf("search of an updated description = %v", got)
+		}
+		if got := search("wucan"); len(got) != 0 {
+			t.Errorf("search of a deleted transaction = %v", got)
+		}
+		if got := search("tea morning"); !equalStrings(got, []string{"tea"}) {
+			t.Errorf("search after update = %v", got)
+		}
+	})
+
+	t.Run("SaveBatch", func(t *testing.T) {
+		repo := newRepo(t)
+		seed(t, repo)
+
+		batch := []*biz.Accounter{
+			{UserID: 1, Type: v1.Type_Expense, CategoryID: int64(v1.Category_Food), Desc: "coffee", Amount: money("18"), Currency: "CNY", Date: date("2025-08-01")},
+			{UserID: 1, Type: v1.Type_Expense, Desc: "hotel", Amount: money("120.5"), Currency: "EUR", Date: date("2025-08-02")},
+			{UserID: 1, Type: v1.Type_Income, Desc: "bonus", Amount: money("500"), Currency: "CNY", Date: date("2025-08-03")},
+		}
+		saved, err := repo.SaveBatch(ctx, batch)
+		if err != nil || len(saved) != len(batch) {
+			t.Fatalf("SaveBatch = %d, %v", len(saved), err)
+		}
+		ids := make(map[int64]bool)
+		for i, s := range saved {
+			if s.TransactionID == 0 || ids[s.TransactionID] {
+				t.Errorf("saved record %d has ID %d", i, s.TransactionID)
+			}
+			ids[s.TransactionID] = true
+			got, err := repo.FindByID(ctx, s.TransactionID)
+			if err != nil || got.Desc != batch[i].Desc || got.Amount != batch[i].Amount || got.Currency != batch[i].Currency || !got.Date.Equal(batch[i].Date) {
+				t.Errorf("FindByID(%d) = %+v, %v", s.TransactionID, got, err)
+			}
+		}
+
+		// IDs continue after the batch
+		next, err := repo.Save(ctx, &biz.Accounter{UserID: 1, Type: v1.Type_Expense, Desc: "taxi", Amount: money("40"), Currency: "CNY", Date: date("2025-08-04")})
+		if err != nil || ids[next.TransactionID] {
+			t.Errorf("Save after SaveBatch = %+v, %v", next, err)
+		}
+		list, err := repo.ListByUserID(ctx, 1)
+		if err != nil || len(list) != 10 {
+			t.Errorf("ListByUserID = %d records, %v", len(list), err)
+		}
+
+		if saved, err := repo.SaveBatch(ctx, nil); err != nil || len(saved) != 0 {
+			t.Errorf("SaveBatch(nil) = %v, %v", saved, err)
+		}
+	})
+
+	t.Run("DeleteBatch", func(t *testing.T) {
+		repo := newRepo(t)
+		saved := seed(t, repo)
+		lunch, dinner := saved[1].TransactionID, saved[2].TransactionID
+
+		// A missing record fails the whole batch
+		if err := repo.DeleteBatch(ctx, []int64{lunch, 999}); !errors.Is(err, biz.ErrAccounterNotFound) {
+			t.Errorf("DeleteBatch with a missing record = %v, want ErrAccounterNotFound", err)
+		}
+		if _, err := repo.FindByID(ctx, lunch); err != nil {
+			t.Errorf("FindByID after a failed DeleteBatch = %v", err)
+		}
+
+		if err := repo.DeleteBatch(ctx, []int64{lunch, dinner}); err != nil {
+			t.Fatalf("DeleteBatch: %v", err)
+		}
+		list, total, err := repo.ListWithFilters(ctx, &biz.ListFilter{UserID: 1, Page: 1, PageSize: 10})
+		if err != nil || total != 4 || !equalStrings(descs(list), []string{"salary", "metro", "refund", "shoes"}) {
+			t.Errorf("ListWithFilters after DeleteBatch = %v (total %d), %v", descs(list), total, err)
+		}
+		if err := repo.DeleteBatch(ctx, nil); err != nil {
+			t.Errorf("DeleteBatch(nil) = %v", err)
+		}
+	})
+
+	t.Run("GetPeriodStats", func(t *testing.T) {
+		repo := newRepo(t)
+		seed(t, repo)
+
+		cases := []struct {
+			name   string
+			filter biz.PeriodStatsFilter
+			want   []biz.PeriodData
+		}{
+			{"monthly", biz.PeriodStatsFilter{UserID: 1, PeriodType: v1.PeriodType_MONTHLY, Year: 2025}, []biz.PeriodData{
+				{PeriodName: "2025年6月", Income: money("10000"), Expense: money("55.5"), Balance: money("9944.5"), TransactionCount: 3},
+				{PeriodName: "2025年7月", Income: money("200"), Expense: money("5"), Balance: money("195"), TransactionCount: 2},
+			}},
+			{"single month", biz.PeriodStatsFilter{UserID: 1, PeriodType: v1.PeriodType_MONTHLY, Year: 2025, Month: 7}, []biz.PeriodData{
+				{PeriodName: "2025年7月", Income: money("200"), Expense: money("5"), Balance: money("195"), TransactionCount: 2},
+			}},
+			{"yearly", biz.PeriodStatsFilter{UserID: 1, PeriodType: v1.PeriodType_YEARLY}, []biz.PeriodData{
+				{PeriodName: "2024年", Expense: money("99.9"), Balance: money("-99.9"), TransactionCount: 1},
+				{PeriodName: "2025年", Income: money("10200"), Expense: money("60.5"), Balance: money("10139.5"), TransactionCount: 5},
+			}},
+			{"weekly", biz.PeriodStatsFilter{UserID: 1, PeriodType: v1.PeriodType_WEEKLY, Year: 2025}, []biz.PeriodData{
+				{PeriodName: "2025年第22周", Income: money("10000"), Balance: money("10000"), TransactionCount: 1},
+				{PeriodName: "2025年第23周", Expense: money("25.5"), Balance: money("-25.5"), TransactionCount: 1},
+				{PeriodName: "2025年第24周", Expense: money("30"), Balance: money("-30"), TransactionCount: 1},
+				{PeriodName: "2025年第27周", Income: money("200"), Expense: money("5"), Balance: money("195"), TransactionCount: 2},
+			}},
+			{"iso week of previous year", biz.PeriodStatsFilter{UserID: 1, PeriodType: v1.PeriodType_WEEKLY, Year: 2024}, []biz.PeriodData{
+				{PeriodName: "2025年第1周", Expense: money("99.9"), Balance: money("-99.9"), TransactionCount: 1},
+			}},
+			{"single week", biz.PeriodStatsFilter{UserID: 1, PeriodType: v1.PeriodType_WEEKLY, Year: 2025, Week: 22}, []biz.PeriodData{
+				{PeriodName: "2025年第22周", Income: money("10000"), Balance: money("10000"), TransactionCount: 1},
+				{PeriodName: "2025年第23周", Expense: money("25.5"), Balance: money("-25.5"), TransactionCount: 1},
+			}},
+		}
+		for _, c := range cases {
+			got, err := repo.GetPeriodStats(ctx, &c.filter)
+			if err != nil {
+				t.Fatalf("%s: %v", c.name, err)
+			}
+			if len(got.Periods) != len(c.want) {
+				t.Errorf("%s: got %d periods, want %d", c.name, len(got.Periods), len(c.want))
+				continue
+			}
+			var income, expense biz.Money
+			for i, p := range got.Periods {
+				w := c.want[i]
+				if p.PeriodName != w.PeriodName || p.Income != w.Income || p.Expense != w.Expense ||
+					p.Balance != w.Balance || p.TransactionCount != w.TransactionCount {
+					t.Errorf("%s: period %d = %+v, want %+v", c.name, i, *p, w)
+				}
+				income += w.Income
+				expense += w.Expense
+			}
+			if got.TotalIncome != income || got.TotalExpense != expense || got.TotalBalance != income-expense {
+				t.Errorf("%s: totals = %v/%v/%v", c.name, got.TotalIncome, got.TotalExpense, got.TotalBalance)
+			}
+		}
+	})
+}
+
+// testConverter converts USD at 7 before July 2025 and at 7.2 from then on
+type testConverter struct{}
+
+func (testConverter) Convert(amount biz.Money, currency string, date time.Time) (biz.Money, error) {
+	switch {
+	case currency == "CNY":
+		return amount, nil
+	case currency == "USD" && date.Before(time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)):
+		return amount.Mul(7), nil
+	case currency == "USD":
+		return amount.Mul(7.2), nil
+	}
+	return 0, biz.ErrExchangeRateNotFound
+}
+
+// tagStatsString formats tag statistics as "tag amount count" in order
+func tagStatsString(stats []*biz.TagStat) string {
+	parts := make([]string, 0, len(stats))
+	for _, stat := range stats {
+		parts = append(parts, fmt.Sprintf("%s %s %d", stat.Tag, stat.Amount, stat.Count))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func currencyStatsString(stats []*biz.CurrencyStat) []biz.CurrencyStat {
+	out := make([]biz.CurrencyStat, len(stats))
+	for i, s := range stats {
+		out[i] = *s
+	}
+	return out
+}
+
+func assertCategoryStats(t *testing.T, kind string, got []*biz.CategoryStat, want []biz.CategoryStat) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("%s: got %d categories, want %d", kind, len(got), len(want))
+		return
+	}
+	for i, g := range got {
+		w := want[i]
+		if g.CategoryID != w.CategoryID || g.Amount != w.Amount || g.Count != w.Count {
+			t.Errorf("%s: category %d = %+v, want %+v", kind, i, *g, w)
+		}
+	}
+}
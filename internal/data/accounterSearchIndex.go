@@ -0,0 +1,136 @@
+package data
+
+import (
+	"strings"
+
+	"accounter_go/internal/biz"
+)
+
+// searchIndex is an inverted index of the search texts of the file storage records, see biz.SearchText.
+// It maps every character and every pair of adjacent characters of a line to the records that have it,
+// a word can only be part of the records that have all of its pairs.
+type searchIndex struct {
+	texts    map[int64]string
+	postings map[string]map[int64]struct{}
+}
+
+func newSearchIndex(records []FileAccounterData) *searchIndex {
+	index := &searchIndex{
+		texts:    make(map[int64]string, len(records)),
+		postings: make(map[string]map[int64]struct{}),
+	}
+	for i := range records {
+		index.add(&records[i])
+	}
+	return index
+}
+
+// add indexes a record, replacing what was indexed for it before
+func (x *searchIndex) add(record *FileAccounterData) {
+	x.remove(record.TransactionID)
+	text := biz.SearchText(record.Desc, record.Tags)
+	x.texts[record.TransactionID] = text
+	for _, gram := range searchGrams(text) {
+		ids, ok := x.postings[gram]
+		if !ok {
+			ids = make(map[int64]struct{})
+			x.postings[gram] = ids
+		}
+		ids[record.TransactionID] = struct{}{}
+	}
+}
+
+func (x *searchIndex) remove(id int64) {
+	text, ok := x.texts[id]
+	if !ok {
+		return
+	}
+	delete(x.texts, id)
+	for _, gram := range searchGrams(text) {
+		if ids := x.postings[gram]; ids != nil {
+			delete(ids, id)
+			if len(ids) == 0 {
+				delete(x.postings, gram)
+			}
+		}
+	}
+}
+
+// match returns the records that contain every one of terms
+func (x *searchIndex) match(terms []string) map[int64]bool {
+	var candidates map[int64]struct{}
+	for i, term := range terms {
+		for j, gram := range termGrams(term) {
+			ids := x.postings[gram]
+			if i == 0 && j == 0 {
+				candidates = ids
+				continue
+			}
+			next := make(map[int64]struct{})
+			for id := range candidates {
+				if _, ok := ids[id]; ok {
+					next[id] = struct{}{}
+				}
+			}
+			candidates = next
+		}
+		if len(candidates) == 0 {
+			return nil
+		}
+	}
+
+	// Having the pairs of a word doesn't mean having them next to each other
+	matches := make(map[int64]bool, len(candidates))
+	for id := range candidates {
+		if containsAll(x.texts[id], terms) {
+			matches[id] = true
+		}
+	}
+	return matches
+}
+
+func containsAll(text string, terms []string) bool {
+	for _, term := range terms {
+		if !strings.Contains(text, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// searchGrams returns the distinct characters and pairs of adjacent characters of the lines of a search text
+func searchGrams(text string) []string {
+	seen := make(map[string]bool)
+	var grams []string
+	for _, line := range strings.Split(text, "\n") {
+		runes := []rune(line)
+		for i := range runes {
+			gram := string(runes[i])
+			if i+1 < len(runes) {
+				pair := string(runes[i : i+2])
+				if !seen[pair] {
+					seen[pair] = true
+					grams = append(grams, pair)
+				}
+			}
+			if !seen[gram] {
+				seen[gram] = true
+				grams = append(grams, gram)
+			}
+		}
+	}
+	return grams
+}
+
+// termGrams returns the grams a record has to be indexed under to contain term
+func termGrams(term string) []string {
+	runes := []rune(term)
+	if len(runes) == 1 {
+		return []string{term}
+	}
+	grams := make([]string, 0, len(runes)-1)
+	for i := 0; i+1 < len(runes); i++ {
+		grams = append(grams, string(runes[i:i+2]))
+	}
+	return grams
+}
@@ -0,0 +1,142 @@
+package data
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	v1 "accounter_go/api/accounter/v1"
+	"accounter_go/internal/biz"
+)
+
+// sortCategoryStats orders category statistics by category so every backend returns the same order
+func sortCategoryStats(stats []*biz.CategoryStat) {
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].CategoryID < stats[j].CategoryID
+	})
+}
+
+// sortAccountStats returns the account statistics ordered by account
+func sortAccountStats(byAccount map[int64]*biz.AccountStat) []*biz.AccountStat {
+	stats := make([]*biz.AccountStat, 0, len(byAccount))
+	for _, stat := range byAccount {
+		stats = append(stats, stat)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].AccountID < stats[j].AccountID
+	})
+	return stats
+}
+
+// accountStat returns the statistics of an account, adding them to byAccount when missing
+func accountStat(byAccount map[int64]*biz.AccountStat, accountID int64) *biz.AccountStat {
+	stat, ok := byAccount[accountID]
+	if !ok {
+		stat = &biz.AccountStat{AccountID: accountID}
+		byAccount[accountID] = stat
+	}
+	return stat
+}
+
+// currencyOrDefault fills in the currency of records saved before transactions had one
+func currencyOrDefault(currency string) string {
+	if currency == "" {
+		return biz.DefaultCurrency
+	}
+	return currency
+}
+
+// convertAmount converts an amount into the base currency of a statistics request
+func convertAmount(converter biz.Converter, amount biz.Money, currency string, date time.Time) (biz.Money, error) {
+	if converter == nil {
+		return amount, nil
+	}
+	return converter.Convert(amount, currency, date)
+}
+
+// currencyTotals accumulates the per-currency breakdown of the statistics, in each currency
+type currencyTotals map[string]*biz.CurrencyStat
+
+func (t currencyTotals) add(currency string, transactionType v1.Type, amount biz.Money, count int32) {
+	stat, ok := t[currency]
+	if !ok {
+		stat = &biz.CurrencyStat{Currency: currency}
+		t[currency] = stat
+	}
+	switch transactionType {
+	case v1.Type_Income:
+		stat.Income += amount
+	case v1.Type_Expense:
+		stat.Expense += amount
+	}
+	stat.Count += count
+}
+
+// stats returns the totals ordered by currency
+func (t currencyTotals) stats() []*biz.CurrencyStat {
+	stats := make([]*biz.CurrencyStat, 0, len(t))
+	for _, stat := range t {
+		stat.Balance = stat.Income - stat.Expense
+		stats = append(stats, stat)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Currency < stats[j].Currency
+	})
+	return stats
+}
+
+// tagTotals accumulates the per-tag breakdown of the statistics, in the base currency
+type tagTotals map[v1.Type]map[string]*biz.TagStat
+
+func (t tagTotals) add(tag string, transactionType v1.Type, amount biz.Money, count int32) {
+	byTag, ok := t[transactionType]
+	if !ok {
+		byTag = make(map[string]*biz.TagStat)
+		t[transactionType] = byTag
+	}
+	stat, ok := byTag[tag]
+	if !ok {
+		stat = &biz.TagStat{Tag: tag}
+		byTag[tag] = stat
+	}
+	stat.Amount += amount
+	stat.Count += count
+}
+
+// stats returns the totals of one transaction type ordered by tag
+func (t tagTotals) stats(transactionType v1.Type) []*biz.TagStat {
+	stats := make([]*biz.TagStat, 0, len(t[transactionType]))
+	for _, stat := range t[transactionType] {
+		stats = append(stats, stat)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Tag < stats[j].Tag
+	})
+	return stats
+}
+
+// sortTags returns the tags with their usage counts ordered by name
+func sortTags(counts map[string]int32) []*biz.Tag {
+	tags := make([]*biz.Tag, 0, len(counts))
+	for name, count := range counts {
+		tags = append(tags, &biz.Tag{Name: name, Count: count})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Name < tags[j].Name
+	})
+	return tags
+}
+
+// periodDisplayName formats a period key (yyyy for years, yyyymm for months, ISO yyyyww for weeks)
+func periodDisplayName(periodType v1.PeriodType, key int) string {
+	switch periodType {
+	case v1.PeriodType_MONTHLY:
+		return fmt.Sprintf("%d年%d月", key/100, key%100)
+	case v1.PeriodType_YEARLY:
+		return fmt.Sprintf("%d年", key)
+	case v1.PeriodType_WEEKLY:
+		return fmt.Sprintf("%d年第%d周", key/100, key%100)
+	default:
+		return ""
+	}
+}
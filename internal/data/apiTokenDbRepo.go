@@ -0,0 +1,102 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"accounter_go/internal/biz"
+	"accounter_go/internal/data/model"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"gorm.io/gorm"
+)
+
+type apiTokenDbRepo struct {
+	data *Data
+	log  *log.Helper
+}
+
+// NewAPITokenDbRepo creates a new database-based APITokenRepo backed by the api_tokens table
+func NewAPITokenDbRepo(data *Data, logger log.Logger) biz.APITokenRepo {
+	return &apiTokenDbRepo{
+		data: data,
+		log:  log.NewHelper(logger),
+	}
+}
+
+func (r *apiTokenDbRepo) Save(ctx context.Context, token *biz.APIToken) (*biz.APIToken, error) {
+	record := model.APIToken{
+		UserID:    token.UserID,
+		Name:      token.Name,
+		Prefix:    token.Prefix,
+		TokenHash: token.TokenHash,
+		Scopes:    strings.Join(token.Scopes, ","),
+		CreatedAt: token.CreatedAt,
+	}
+	if err := r.data.db.WithContext(ctx).Create(&record).Error; err != nil {
+		r.log.WithContext(ctx).Errorf("Failed to save api token: %v", err)
+		return nil, err
+	}
+
+	return toBizAPIToken(&record), nil
+}
+
+func (r *apiTokenDbRepo) FindByHash(ctx context.Context, hash string) (*biz.APIToken, error) {
+	var record model.APIToken
+	if err := r.data.db.WithContext(ctx).Where("token_hash = ?", hash).First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, biz.ErrAPITokenNotFound
+		}
+		r.log.WithContext(ctx).Errorf("Failed to find api token: %v", err)
+		return nil, err
+	}
+
+	return toBizAPIToken(&record), nil
+}
+
+func (r *apiTokenDbRepo) ListByUserID(ctx context.Context, userID int64) ([]*biz.APIToken, error) {
+	var records []model.APIToken
+	if err := r.data.db.WithContext(ctx).Where("user_id = ?", userID).Order("token_id").Find(&records).Error; err != nil {
+		r.log.WithContext(ctx).Errorf("Failed to list api tokens of user %d: %v", userID, err)
+		return nil, err
+	}
+
+	tokens := make([]*biz.APIToken, len(records))
+	for i := range records {
+		tokens[i] = toBizAPIToken(&records[i])
+	}
+	return tokens, nil
+}
+
+func (r *apiTokenDbRepo) Delete(ctx context.Context, userID, id int64) error {
+	result := r.data.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&model.APIToken{}, id)
+	if result.Error != nil {
+		r.log.WithContext(ctx).Errorf("Failed to delete api token %d: %v", id, result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return biz.ErrAPITokenNotFound
+	}
+	return nil
+}
+
+func (r *apiTokenDbRepo) UpdateLastUsed(ctx context.Context, id int64, at time.Time) error {
+	return r.data.db.WithContext(ctx).Model(&model.APIToken{}).
+		Where("token_id = ?", id).
+		Update("last_used_at", at).Error
+}
+
+func toBizAPIToken(t *model.APIToken) *biz.APIToken {
+	return &biz.APIToken{
+		ID:         t.TokenID,
+		UserID:     t.UserID,
+		Name:       t.Name,
+		Prefix:     t.Prefix,
+		TokenHash:  t.TokenHash,
+		Scopes:     strings.Split(t.Scopes, ","),
+		CreatedAt:  t.CreatedAt,
+		LastUsedAt: t.LastUsedAt,
+	}
+}
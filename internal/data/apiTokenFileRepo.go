@@ -0,0 +1,124 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"accounter_go/internal/biz"
+	"accounter_go/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// FileAPITokenData represents the structure stored in the API tokens JSON file
+type FileAPITokenData struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	TokenHash  string     `json:"token_hash"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+func (t FileAPITokenData) recordID() int64 { return t.ID }
+
+// apiTokenFileRepo keeps the personal API tokens in api_tokens.json next to the accounter data
+type apiTokenFileRepo struct {
+	*jsonCollection[FileAPITokenData]
+}
+
+// NewAPITokenFileRepo creates a new file-based APITokenRepo
+func NewAPITokenFileRepo(c *conf.Data, logger log.Logger) (biz.APITokenRepo, error) {
+	tokens, err := openJSONCollection[FileAPITokenData](c, "api_tokens.json", "api tokens", logger)
+	if err != nil {
+		return nil, err
+	}
+	return &apiTokenFileRepo{tokens}, nil
+}
+
+// NewAPITokenMemoryRepo creates an APITokenRepo that keeps everything in memory
+func NewAPITokenMemoryRepo(logger log.Logger) biz.APITokenRepo {
+	return &apiTokenFileRepo{newJSONCollection[FileAPITokenData]("", "api tokens", logger)}
+}
+
+func (r *apiTokenFileRepo) Save(ctx context.Context, token *biz.APIToken) (*biz.APIToken, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	record := FileAPITokenData{
+		ID:        r.nextID,
+		UserID:    token.UserID,
+		Name:      token.Name,
+		Prefix:    token.Prefix,
+		TokenHash: token.TokenHash,
+		Scopes:    token.Scopes,
+		CreatedAt: token.CreatedAt,
+	}
+	if err := r.insertLocked(ctx, record); err != nil {
+		return nil, err
+	}
+
+	return toBizAPITokenFile(&record), nil
+}
+
+func (r *apiTokenFileRepo) FindByHash(ctx context.Context, hash string) (*biz.APIToken, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for i := range r.records {
+		if r.records[i].TokenHash == hash {
+			return toBizAPITokenFile(&r.records[i]), nil
+		}
+	}
+	return nil, biz.ErrAPITokenNotFound
+}
+
+func (r *apiTokenFileRepo) ListByUserID(ctx context.Context, userID int64) ([]*biz.APIToken, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	tokens := make([]*biz.APIToken, 0)
+	for i := range r.records {
+		if r.records[i].UserID == userID {
+			tokens = append(tokens, toBizAPITokenFile(&r.records[i]))
+		}
+	}
+	return tokens, nil
+}
+
+func (r *apiTokenFileRepo) Delete(ctx context.Context, userID, id int64) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if i := r.indexOf(id); i >= 0 && r.records[i].UserID == userID {
+		return r.removeLocked(ctx, i)
+	}
+	return biz.ErrAPITokenNotFound
+}
+
+func (r *apiTokenFileRepo) UpdateLastUsed(ctx context.Context, id int64, at time.Time) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if i := r.indexOf(id); i >= 0 {
+		token := r.records[i]
+		token.LastUsedAt = &at
+		return r.replaceLocked(ctx, i, token)
+	}
+	return biz.ErrAPITokenNotFound
+}
+
+func toBizAPITokenFile(t *FileAPITokenData) *biz.APIToken {
+	return &biz.APIToken{
+		ID:         t.ID,
+		UserID:     t.UserID,
+		Name:       t.Name,
+		Prefix:     t.Prefix,
+		TokenHash:  t.TokenHash,
+		Scopes:     t.Scopes,
+		CreatedAt:  t.CreatedAt,
+		LastUsedAt: t.LastUsedAt,
+	}
+}
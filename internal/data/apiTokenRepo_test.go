@@ -0,0 +1,64 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"accounter_go/internal/biz"
+)
+
+func runAPITokenRepoContract(t *testing.T, repo biz.APITokenRepo) {
+	ctx := context.Background()
+
+	saved, err := repo.Save(ctx, &biz.APIToken{
+		UserID: 1, Name: "cron", Prefix: "acc_abcdef", TokenHash: "hash-1",
+		Scopes: []string{biz.ScopeRead, biz.ScopeWrite}, CreatedAt: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	got, err := repo.FindByHash(ctx, "hash-1")
+	if err != nil || got.ID != saved.ID || got.Name != "cron" || !equalStrings(got.Scopes, []string{"read", "write"}) {
+		t.Errorf("FindByHash = %+v, %v", got, err)
+	}
+	if got.LastUsedAt != nil {
+		t.Errorf("new token has a last-used time %v", got.LastUsedAt)
+	}
+	if _, err := repo.FindByHash(ctx, "unknown"); !errors.Is(err, biz.ErrAPITokenNotFound) {
+		t.Errorf("FindByHash of an unknown token = %v, want ErrAPITokenNotFound", err)
+	}
+
+	usedAt := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	if err := repo.UpdateLastUsed(ctx, saved.ID, usedAt); err != nil {
+		t.Fatalf("UpdateLastUsed: %v", err)
+	}
+	list, err := repo.ListByUserID(ctx, 1)
+	if err != nil || len(list) != 1 || list[0].LastUsedAt == nil || !list[0].LastUsedAt.Equal(usedAt) {
+		t.Errorf("ListByUserID after use = %+v, %v", list, err)
+	}
+
+	if err := repo.Delete(ctx, 2, saved.ID); !errors.Is(err, biz.ErrAPITokenNotFound) {
+		t.Errorf("Delete by another user = %v, want ErrAPITokenNotFound", err)
+	}
+	if err := repo.Delete(ctx, 1, saved.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := repo.FindByHash(ctx, "hash-1"); !errors.Is(err, biz.ErrAPITokenNotFound) {
+		t.Errorf("deleted token is still found: %v", err)
+	}
+}
+
+func TestAPITokenRepo(t *testing.T) {
+	runRepoBackends(t, NewAPITokenFileRepo, NewAPITokenDbRepo, NewAPITokenMemoryRepo, runAPITokenRepoContract,
+		func(t *testing.T, repo biz.APITokenRepo, reopen func() biz.APITokenRepo) {
+			if _, err := repo.Save(context.Background(), &biz.APIToken{UserID: 1, Name: "kept", TokenHash: "hash-2", Scopes: []string{biz.ScopeRead}}); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+			if got, err := reopen().FindByHash(context.Background(), "hash-2"); err != nil || got.ID != 2 {
+				t.Errorf("token not persisted: %+v, %v", got, err)
+			}
+		})
+}
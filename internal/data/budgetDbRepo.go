@@ -0,0 +1,120 @@
+package data
+
+import (
+	"context"
+	"errors"
+
+	v1 "accounter_go/api/accounter/v1"
+	"accounter_go/internal/biz"
+	"accounter_go/internal/data/model"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"gorm.io/gorm"
+)
+
+type budgetDbRepo struct {
+	data *Data
+	log  *log.Helper
+}
+
+// NewBudgetDbRepo creates a new database-based BudgetRepo backed by the budgets table
+func NewBudgetDbRepo(data *Data, logger log.Logger) biz.BudgetRepo {
+	return &budgetDbRepo{
+		data: data,
+		log:  log.NewHelper(logger),
+	}
+}
+
+func (r *budgetDbRepo) Save(ctx context.Context, budget *biz.Budget) (*biz.Budget, error) {
+	record := toModelBudget(budget)
+	record.BudgetID = 0
+	if err := r.data.db.WithContext(ctx).Create(record).Error; err != nil {
+		r.log.WithContext(ctx).Errorf("Failed to save budget: %v", err)
+		return nil, err
+	}
+
+	return toBizBudget(record), nil
+}
+
+func (r *budgetDbRepo) Update(ctx context.Context, budget *biz.Budget) (*biz.Budget, error) {
+	record := toModelBudget(budget)
+	result := r.data.db.WithContext(ctx).
+		Model(&model.Budget{}).
+		Where("budget_id = ?", budget.ID).
+		Updates(map[string]interface{}{
+			"amount_e4":  record.Amount,
+			"rollover":   record.Rollover,
+			"start_date": record.StartDate,
+		})
+	if result.Error != nil {
+		r.log.WithContext(ctx).Errorf("Failed to update budget: %v", result.Error)
+		return nil, result.Error
+	}
+
+	return r.FindByID(ctx, budget.ID)
+}
+
+func (r *budgetDbRepo) FindByID(ctx context.Context, id int64) (*biz.Budget, error) {
+	var record model.Budget
+	if err := r.data.db.WithContext(ctx).First(&record, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, biz.ErrBudgetNotFound
+		}
+		r.log.WithContext(ctx).Errorf("Failed to find budget %d: %v", id, err)
+		return nil, err
+	}
+
+	return toBizBudget(&record), nil
+}
+
+func (r *budgetDbRepo) ListByUserID(ctx context.Context, userID int64) ([]*biz.Budget, error) {
+	var records []model.Budget
+	if err := r.data.db.WithContext(ctx).Where("user_id = ?", userID).Order("budget_id").Find(&records).Error; err != nil {
+		r.log.WithContext(ctx).Errorf("Failed to list budgets of user %d: %v", userID, err)
+		return nil, err
+	}
+
+	budgets := make([]*biz.Budget, len(records))
+	for i := range records {
+		budgets[i] = toBizBudget(&records[i])
+	}
+	return budgets, nil
+}
+
+func (r *budgetDbRepo) Delete(ctx context.Context, id int64) error {
+	result := r.data.db.WithContext(ctx).Delete(&model.Budget{}, id)
+	if result.Error != nil {
+		r.log.WithContext(ctx).Errorf("Failed to delete budget %d: %v", id, result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return biz.ErrBudgetNotFound
+	}
+	return nil
+}
+
+func toModelBudget(b *biz.Budget) *model.Budget {
+	return &model.Budget{
+		BudgetID:     b.ID,
+		UserID:       b.UserID,
+		CategoryID:   b.CategoryID,
+		PeriodType:   int8(b.Period),
+		Amount:       int64(b.Amount),
+		CurrencyCode: b.Currency,
+		Rollover:     b.Rollover,
+		StartDate:    b.StartDate,
+	}
+}
+
+func toBizBudget(b *model.Budget) *biz.Budget {
+	return &biz.Budget{
+		ID:         b.BudgetID,
+		UserID:     b.UserID,
+		CategoryID: b.CategoryID,
+		Period:     v1.PeriodType(b.PeriodType),
+		Amount:     biz.Money(b.Amount),
+		Currency:   b.CurrencyCode,
+		Rollover:   b.Rollover,
+		StartDate:  b.StartDate.UTC(),
+	}
+}
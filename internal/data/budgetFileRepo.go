@@ -0,0 +1,133 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	v1 "accounter_go/api/accounter/v1"
+	"accounter_go/internal/biz"
+	"accounter_go/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// FileBudgetData represents the structure stored in the budgets JSON file
+type FileBudgetData struct {
+	ID         int64     `json:"id"`
+	UserID     int64     `json:"user_id"`
+	CategoryID int64     `json:"category_id"`
+	Period     int32     `json:"period"`
+	Amount     biz.Money `json:"amount"`
+	Currency   string    `json:"currency"`
+	Rollover   bool      `json:"rollover,omitempty"`
+	StartDate  time.Time `json:"start_date"`
+}
+
+func (b FileBudgetData) recordID() int64 { return b.ID }
+
+// budgetFileRepo keeps the budgets in budgets.json next to the accounter data
+type budgetFileRepo struct {
+	*jsonCollection[FileBudgetData]
+}
+
+// NewBudgetFileRepo creates a new file-based BudgetRepo
+func NewBudgetFileRepo(c *conf.Data, logger log.Logger) (biz.BudgetRepo, error) {
+	budgets, err := openJSONCollection[FileBudgetData](c, "budgets.json", "budgets", logger)
+	if err != nil {
+		return nil, err
+	}
+	return &budgetFileRepo{budgets}, nil
+}
+
+// NewBudgetMemoryRepo creates a BudgetRepo that keeps everything in memory
+func NewBudgetMemoryRepo(logger log.Logger) biz.BudgetRepo {
+	return &budgetFileRepo{newJSONCollection[FileBudgetData]("", "budgets", logger)}
+}
+
+func (r *budgetFileRepo) Save(ctx context.Context, budget *biz.Budget) (*biz.Budget, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	record := toFileBudget(budget)
+	record.ID = r.nextID
+	if err := r.insertLocked(ctx, record); err != nil {
+		return nil, err
+	}
+
+	return toBizBudgetFile(&record), nil
+}
+
+func (r *budgetFileRepo) Update(ctx context.Context, budget *biz.Budget) (*biz.Budget, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	i := r.indexOf(budget.ID)
+	if i < 0 {
+		return nil, biz.ErrBudgetNotFound
+	}
+	if err := r.replaceLocked(ctx, i, toFileBudget(budget)); err != nil {
+		return nil, err
+	}
+
+	return toBizBudgetFile(&r.records[i]), nil
+}
+
+func (r *budgetFileRepo) FindByID(ctx context.Context, id int64) (*biz.Budget, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if i := r.indexOf(id); i >= 0 {
+		return toBizBudgetFile(&r.records[i]), nil
+	}
+	return nil, biz.ErrBudgetNotFound
+}
+
+func (r *budgetFileRepo) ListByUserID(ctx context.Context, userID int64) ([]*biz.Budget, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	budgets := make([]*biz.Budget, 0)
+	for i := range r.records {
+		if r.records[i].UserID == userID {
+			budgets = append(budgets, toBizBudgetFile(&r.records[i]))
+		}
+	}
+	return budgets, nil
+}
+
+func (r *budgetFileRepo) Delete(ctx context.Context, id int64) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	i := r.indexOf(id)
+	if i < 0 {
+		return biz.ErrBudgetNotFound
+	}
+	return r.removeLocked(ctx, i)
+}
+
+func toFileBudget(b *biz.Budget) FileBudgetData {
+	return FileBudgetData{
+		ID:         b.ID,
+		UserID:     b.UserID,
+		CategoryID: b.CategoryID,
+		Period:     int32(b.Period),
+		Amount:     b.Amount,
+		Currency:   b.Currency,
+		Rollover:   b.Rollover,
+		StartDate:  b.StartDate,
+	}
+}
+
+func toBizBudgetFile(b *FileBudgetData) *biz.Budget {
+	return &biz.Budget{
+		ID:         b.ID,
+		UserID:     b.UserID,
+		CategoryID: b.CategoryID,
+		Period:     v1.PeriodType(b.Period),
+		Amount:     b.Amount,
+		Currency:   b.Currency,
+		Rollover:   b.Rollover,
+		StartDate:  b.StartDate,
+	}
+}
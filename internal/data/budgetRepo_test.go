@@ -0,0 +1,77 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	v1 "accounter_go/api/accounter/v1"
+	"accounter_go/internal/biz"
+)
+
+func runBudgetRepoContract(t *testing.T, repo biz.BudgetRepo) {
+	ctx := context.Background()
+
+	saved, err := repo.Save(ctx, &biz.Budget{
+		UserID: 1, CategoryID: int64(v1.Category_Food), Period: v1.PeriodType_MONTHLY, Amount: money("1500"), Currency: "CNY", StartDate: date("2025-06-01"),
+	})
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	second, err := repo.Save(ctx, &biz.Budget{UserID: 1, CategoryID: int64(v1.Category_Travel), Period: v1.PeriodType_YEARLY, Amount: money("800"), Currency: "USD", Rollover: true, StartDate: date("2025-01-01")})
+	if err != nil || second.ID == saved.ID {
+		t.Fatalf("Save = %+v, %v", second, err)
+	}
+
+	got, err := repo.FindByID(ctx, saved.ID)
+	if err != nil || got.UserID != 1 || got.CategoryID != int64(v1.Category_Food) || got.Period != v1.PeriodType_MONTHLY ||
+		got.Amount != money("1500") || got.Currency != "CNY" || got.Rollover || !got.StartDate.Equal(date("2025-06-01")) {
+		t.Errorf("FindByID = %+v, %v", got, err)
+	}
+	if _, err := repo.FindByID(ctx, 99999); !errors.Is(err, biz.ErrBudgetNotFound) {
+		t.Errorf("FindByID of an unknown budget = %v, want ErrBudgetNotFound", err)
+	}
+
+	list, err := repo.ListByUserID(ctx, 1)
+	if err != nil || len(list) != 2 || list[0].ID != saved.ID || !list[1].Rollover || list[1].Period != v1.PeriodType_YEARLY {
+		t.Errorf("ListByUserID = %+v, %v", list, err)
+	}
+	if others, _ := repo.ListByUserID(ctx, 2); len(others) != 0 {
+		t.Errorf("budgets leaked to another user: %d", len(others))
+	}
+
+	got.Amount = money("1200.5")
+	got.Rollover = true
+	got.StartDate = date("2025-07-01")
+	updated, err := repo.Update(ctx, got)
+	if err != nil || updated.Amount != money("1200.5") || !updated.Rollover || !updated.StartDate.Equal(date("2025-07-01")) || updated.CategoryID != int64(v1.Category_Food) {
+		t.Errorf("Update = %+v, %v", updated, err)
+	}
+
+	if err := repo.Delete(ctx, saved.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := repo.FindByID(ctx, saved.ID); !errors.Is(err, biz.ErrBudgetNotFound) {
+		t.Errorf("deleted budget is still found: %v", err)
+	}
+	if err := repo.Delete(ctx, saved.ID); !errors.Is(err, biz.ErrBudgetNotFound) {
+		t.Errorf("Delete of a deleted budget = %v, want ErrBudgetNotFound", err)
+	}
+}
+
+func TestBudgetRepo(t *testing.T) {
+	runRepoBackends(t, NewBudgetFileRepo, NewBudgetDbRepo, NewBudgetMemoryRepo, runBudgetRepoContract,
+		func(t *testing.T, repo biz.BudgetRepo, reopen func() biz.BudgetRepo) {
+			kept, err := repo.Save(context.Background(), &biz.Budget{UserID: 1, CategoryID: int64(v1.Category_Snacks), Period: v1.PeriodType_MONTHLY, Amount: money("99.9"), Currency: "CNY", StartDate: date("2025-03-01")})
+			if err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+			reopened := reopen()
+			if got, err := reopened.FindByID(context.Background(), kept.ID); err != nil || got.Amount != money("99.9") || !got.StartDate.Equal(date("2025-03-01")) {
+				t.Errorf("budget not persisted: %+v, %v", got, err)
+			}
+			if next, err := reopened.Save(context.Background(), &biz.Budget{UserID: 1, CategoryID: int64(v1.Category_Game), Period: v1.PeriodType_MONTHLY, Amount: money("50"), Currency: "CNY"}); err != nil || next.ID != kept.ID+1 {
+				t.Errorf("Save after reopen = %+v, %v", next, err)
+			}
+		})
+}
@@ -0,0 +1,154 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	v1 "accounter_go/api/accounter/v1"
+	"accounter_go/internal/biz"
+	"accounter_go/internal/data/model"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"gorm.io/gorm"
+)
+
+type categoryDbRepo struct {
+	data *Data
+	log  *log.Helper
+}
+
+// NewCategoryDbRepo creates a new database-based CategoryRepo backed by the accounter_categories table
+func NewCategoryDbRepo(data *Data, logger log.Logger) biz.CategoryRepo {
+	return &categoryDbRepo{
+		data: data,
+		log:  log.NewHelper(logger),
+	}
+}
+
+func (r *categoryDbRepo) Save(ctx context.Context, category *biz.Category) (*biz.Category, error) {
+	record := toModelCategory(category)
+	record.CategoryID = 0
+	if err := r.data.db.WithContext(ctx).Create(record).Error; err != nil {
+		r.log.WithContext(ctx).Errorf("Failed to save category: %v", err)
+		return nil, err
+	}
+
+	return toBizCategory(record), nil
+}
+
+func (r *categoryDbRepo) Update(ctx context.Context, category *biz.Category) (*biz.Category, error) {
+	record := toModelCategory(category)
+	result := r.data.db.WithContext(ctx).
+		Model(&model.AccounterCategory{}).
+		Where("category_id = ?", category.ID).
+		Updates(map[string]interface{}{
+			"category_name": record.CategoryName,
+			"parent_id":     record.ParentID,
+			"type":          record.Type,
+			"icon":          record.Icon,
+			"color":         record.Color,
+		})
+	if result.Error != nil {
+		r.log.WithContext(ctx).Errorf("Failed to update category: %v", result.Error)
+		return nil, result.Error
+	}
+
+	return r.FindByID(ctx, category.ID)
+}
+
+func (r *categoryDbRepo) FindByID(ctx context.Context, id int64) (*biz.Category, error) {
+	var record model.AccounterCategory
+	if err := r.data.db.WithContext(ctx).First(&record, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, biz.ErrCategoryNotFound
+		}
+		r.log.WithContext(ctx).Errorf("Failed to find category %d: %v", id, err)
+		return nil, err
+	}
+
+	return toBizCategory(&record), nil
+}
+
+func (r *categoryDbRepo) ListByUserID(ctx context.Context, userID int64) ([]*biz.Category, error) {
+	var records []model.AccounterCategory
+	if err := r.data.db.WithContext(ctx).
+		Where("user_id IN ?", []int64{0, userID}).
+		Order("user_id, category_id").
+		Find(&records).Error; err != nil {
+		r.log.WithContext(ctx).Errorf("Failed to list categories of user %d: %v", userID, err)
+		return nil, err
+	}
+
+	categories := make([]*biz.Category, len(records))
+	for i := range records {
+		categories[i] = toBizCategory(&records[i])
+	}
+	return categories, nil
+}
+
+func (r *categoryDbRepo) Delete(ctx context.Context, id int64) error {
+	result := r.data.db.WithContext(ctx).Delete(&model.AccounterCategory{}, id)
+	if result.Error != nil {
+		r.log.WithContext(ctx).Errorf("Failed to delete category %d: %v", id, result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return biz.ErrCategoryNotFound
+	}
+	return nil
+}
+
+// seedCategories inserts the built-in categories that are missing, existing rows are left alone.
+// A row that holds the id of a built-in category but isn't that category fails the migration,
+// its transactions would otherwise silently change category.
+func seedCategories(db *gorm.DB) error {
+	for i := range biz.DefaultCategories {
+		builtIn := &biz.DefaultCategories[i]
+		record := toModelCategory(builtIn)
+		if err := db.Where("category_id = ?", record.CategoryID).FirstOrCreate(record).Error; err != nil {
+			return err
+		}
+		if record.UserID != 0 || record.CategoryName != builtIn.Name {
+			return fmt.Errorf("category %d is %q of user %d, but the built-in category %q needs its id",
+				record.CategoryID, record.CategoryName, record.UserID, builtIn.Name)
+		}
+	}
+	return nil
+}
+
+func toModelCategory(c *biz.Category) *model.AccounterCategory {
+	record := &model.AccounterCategory{
+		CategoryID:   int(c.ID),
+		UserID:       c.UserID,
+		CategoryName: c.Name,
+		Icon:         c.Icon,
+		Color:        c.Color,
+	}
+	if c.ParentID != 0 {
+		parentID := int(c.ParentID)
+		record.ParentID = &parentID
+	}
+	if c.Type != v1.Type_None {
+		categoryType := int8(c.Type)
+		record.Type = &categoryType
+	}
+	return record
+}
+
+func toBizCategory(c *model.AccounterCategory) *biz.Category {
+	category := &biz.Category{
+		ID:     int64(c.CategoryID),
+		UserID: c.UserID,
+		Name:   c.CategoryName,
+		Icon:   c.Icon,
+		Color:  c.Color,
+	}
+	if c.ParentID != nil {
+		category.ParentID = int64(*c.ParentID)
+	}
+	if c.Type != nil {
+		category.Type = v1.Type(*c.Type)
+	}
+	return category
+}
@@ -0,0 +1,151 @@
+package data
+
+import (
+	"context"
+
+	v1 "accounter_go/api/accounter/v1"
+	"accounter_go/internal/biz"
+	"accounter_go/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// FileCategoryData represents the structure stored in the categories JSON file
+type FileCategoryData struct {
+	ID       int64  `json:"id"`
+	UserID   int64  `json:"user_id"`
+	ParentID int64  `json:"parent_id,omitempty"`
+	Name     string `json:"name"`
+	Type     int32  `json:"type,omitempty"`
+	Icon     string `json:"icon,omitempty"`
+	Color    string `json:"color,omitempty"`
+}
+
+func (c FileCategoryData) recordID() int64 { return c.ID }
+
+// categoryFileRepo keeps the user categories in categories.json next to the accounter data.
+// The built-in categories aren't stored, they always come from biz.DefaultCategories.
+type categoryFileRepo struct {
+	*jsonCollection[FileCategoryData]
+}
+
+// NewCategoryFileRepo creates a new file-based CategoryRepo
+func NewCategoryFileRepo(c *conf.Data, logger log.Logger) (biz.CategoryRepo, error) {
+	categories, err := openJSONCollection[FileCategoryData](c, "categories.json", "categories", logger)
+	if err != nil {
+		return nil, err
+	}
+	return newCategoryFileRepo(categories), nil
+}
+
+// NewCategoryMemoryRepo creates a CategoryRepo that keeps everything in memory
+func NewCategoryMemoryRepo(logger log.Logger) biz.CategoryRepo {
+	return newCategoryFileRepo(newJSONCollection[FileCategoryData]("", "categories", logger))
+}
+
+func newCategoryFileRepo(categories *jsonCollection[FileCategoryData]) *categoryFileRepo {
+	// User categories are numbered after the built-in ones
+	for _, c := range biz.DefaultCategories {
+		if c.ID >= categories.nextID {
+			categories.nextID = c.ID + 1
+		}
+	}
+	return &categoryFileRepo{categories}
+}
+
+func (r *categoryFileRepo) Save(ctx context.Context, category *biz.Category) (*biz.Category, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	record := toFileCategory(category)
+	record.ID = r.nextID
+	if err := r.insertLocked(ctx, record); err != nil {
+		return nil, err
+	}
+
+	return toBizCategoryFile(&record), nil
+}
+
+func (r *categoryFileRepo) Update(ctx context.Context, category *biz.Category) (*biz.Category, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	i := r.indexOf(category.ID)
+	if i < 0 {
+		return nil, biz.ErrCategoryNotFound
+	}
+	if err := r.replaceLocked(ctx, i, toFileCategory(category)); err != nil {
+		return nil, err
+	}
+
+	return toBizCategoryFile(&r.records[i]), nil
+}
+
+func (r *categoryFileRepo) FindByID(ctx context.Context, id int64) (*biz.Category, error) {
+	for i := range biz.DefaultCategories {
+		if biz.DefaultCategories[i].ID == id {
+			c := biz.DefaultCategories[i]
+			return &c, nil
+		}
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if i := r.indexOf(id); i >= 0 {
+		return toBizCategoryFile(&r.records[i]), nil
+	}
+	return nil, biz.ErrCategoryNotFound
+}
+
+func (r *categoryFileRepo) ListByUserID(ctx context.Context, userID int64) ([]*biz.Category, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	categories := make([]*biz.Category, 0, len(biz.DefaultCategories))
+	for i := range biz.DefaultCategories {
+		c := biz.DefaultCategories[i]
+		categories = append(categories, &c)
+	}
+	for i := range r.records {
+		if r.records[i].UserID == userID {
+			categories = append(categories, toBizCategoryFile(&r.records[i]))
+		}
+	}
+	return categories, nil
+}
+
+func (r *categoryFileRepo) Delete(ctx context.Context, id int64) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	i := r.indexOf(id)
+	if i < 0 {
+		return biz.ErrCategoryNotFound
+	}
+	return r.removeLocked(ctx, i)
+}
+
+func toFileCategory(c *biz.Category) FileCategoryData {
+	return FileCategoryData{
+		ID:       c.ID,
+		UserID:   c.UserID,
+		ParentID: c.ParentID,
+		Name:     c.Name,
+		Type:     int32(c.Type),
+		Icon:     c.Icon,
+		Color:    c.Color,
+	}
+}
+
+func toBizCategoryFile(c *FileCategoryData) *biz.Category {
+	return &biz.Category{
+		ID:       c.ID,
+		UserID:   c.UserID,
+		ParentID: c.ParentID,
+		Name:     c.Name,
+		Type:     v1.Type(c.Type),
+		Icon:     c.Icon,
+		Color:    c.Color,
+	}
+}
@@ -0,0 +1,110 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+
+	v1 "accounter_go/api/accounter/v1"
+	"accounter_go/internal/biz"
+	"accounter_go/internal/conf"
+	"accounter_go/internal/data/model"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+func runCategoryRepoContract(t *testing.T, repo biz.CategoryRepo) {
+	ctx := context.Background()
+
+	builtIn, err := repo.FindByID(ctx, int64(v1.Category_Food))
+	if err != nil || builtIn.Name != "餐饮" || !builtIn.BuiltIn() {
+		t.Errorf("FindByID of a built-in category = %+v, %v", builtIn, err)
+	}
+
+	saved, err := repo.Save(ctx, &biz.Category{
+		UserID: 1, ParentID: int64(v1.Category_Food), Name: "咖啡", Type: v1.Type_Expense, Icon: "☕", Color: "#6f4e37",
+	})
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if saved.ID <= int64(v1.Category_Snacks) {
+		t.Errorf("user category ID %d collides with the built-in categories", saved.ID)
+	}
+
+	got, err := repo.FindByID(ctx, saved.ID)
+	if err != nil || got.UserID != 1 || got.ParentID != int64(v1.Category_Food) || got.Type != v1.Type_Expense || got.Icon != "☕" || got.Color != "#6f4e37" {
+		t.Errorf("FindByID = %+v, %v", got, err)
+	}
+	if _, err := repo.FindByID(ctx, 99999); !errors.Is(err, biz.ErrCategoryNotFound) {
+		t.Errorf("FindByID of an unknown category = %v, want ErrCategoryNotFound", err)
+	}
+
+	list, err := repo.ListByUserID(ctx, 1)
+	if err != nil || len(list) != len(biz.DefaultCategories)+1 || list[len(list)-1].ID != saved.ID {
+		t.Errorf("ListByUserID = %d categories, %v", len(list), err)
+	}
+	if others, _ := repo.ListByUserID(ctx, 2); len(others) != len(biz.DefaultCategories) {
+		t.Errorf("categories leaked to another user: %d", len(others))
+	}
+
+	got.Name = "咖啡茶饮"
+	got.ParentID = 0
+	updated, err := repo.Update(ctx, got)
+	if err != nil || updated.Name != "咖啡茶饮" || updated.ParentID != 0 || updated.Icon != "☕" {
+		t.Errorf("Update = %+v, %v", updated, err)
+	}
+
+	if err := repo.Delete(ctx, saved.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := repo.FindByID(ctx, saved.ID); !errors.Is(err, biz.ErrCategoryNotFound) {
+		t.Errorf("deleted category is still found: %v", err)
+	}
+	if err := repo.Delete(ctx, saved.ID); !errors.Is(err, biz.ErrCategoryNotFound) {
+		t.Errorf("Delete of a deleted category = %v, want ErrCategoryNotFound", err)
+	}
+}
+
+func TestCategoryRepo(t *testing.T) {
+	runRepoBackends(t, NewCategoryFileRepo, NewCategoryDbRepo, NewCategoryMemoryRepo, runCategoryRepoContract,
+		func(t *testing.T, repo biz.CategoryRepo, reopen func() biz.CategoryRepo) {
+			kept, err := repo.Save(context.Background(), &biz.Category{UserID: 1, Name: "宠物"})
+			if err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+			reopened := reopen()
+			if got, err := reopened.FindByID(context.Background(), kept.ID); err != nil || got.Name != "宠物" {
+				t.Errorf("category not persisted: %+v, %v", got, err)
+			}
+			// Reopening must not seed the built-in categories twice
+			if list, err := reopened.ListByUserID(context.Background(), 1); err != nil || len(list) != len(biz.DefaultCategories)+1 {
+				t.Errorf("ListByUserID after reopen = %d categories, %v", len(list), err)
+			}
+			if next, err := reopened.Save(context.Background(), &biz.Category{UserID: 1, Name: "园艺"}); err != nil || next.ID != kept.ID+1 {
+				t.Errorf("Save after reopen = %+v, %v", next, err)
+			}
+		})
+}
+
+func TestSeedCategoriesRejectsTakenIDs(t *testing.T) {
+	c := &conf.Data{Sqlite: &conf.Data_Sqlite{Path: filepath.Join(t.TempDir(), "accounters.db")}}
+	logger := log.NewStdLogger(io.Discard)
+	db, cleanup, err := NewSqliteDB(c, logger)
+	if err != nil {
+		t.Fatalf("NewSqliteDB: %v", err)
+	}
+	// A category of an older database that was created before the built-in ones had fixed ids
+	err = db.Model(&model.AccounterCategory{}).Where("category_id = ?", int(v1.Category_Food)).
+		Updates(map[string]interface{}{"user_id": 1, "category_name": "房租"}).Error
+	cleanup()
+	if err != nil {
+		t.Fatalf("take the id of a built-in category: %v", err)
+	}
+
+	if _, cleanup, err := NewSqliteDB(c, logger); err == nil {
+		cleanup()
+		t.Error("the migration adopted a category that took the id of a built-in one")
+	}
+}
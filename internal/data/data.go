@@ -1,54 +1,166 @@
 package data
 
 import (
-	"accounter_go/internal/conf"
 	"context"
-	"github.com/go-redis/redis/v8"
-	"gorm.io/driver/mysql"
-	"gorm.io/gorm"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"accounter_go/internal/biz"
+	"accounter_go/internal/conf"
+	"accounter_go/internal/data/model"
 
+	"github.com/glebarez/sqlite"
 	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-redis/redis/v8"
 	"github.com/google/wire"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // ProviderSet is data providers.
 var ProviderSet = wire.NewSet(
 	NewData,
 	NewGreeterRepo,
-	// Use file-based storage for now
-	NewAccounterFileRepo,
-	// For future database usage, uncomment the line below and comment the line above
-	// NewAccounterDbRepo,
+	// The AccounterRepo implementation is chosen by data.storage.backend
+	NewAccounterRepo,
+	NewUserRepo,
+	NewAPITokenRepo,
+	NewCategoryRepo,
+	NewExchangeRateRepo,
+	NewAccountRepo,
+	NewBudgetRepo,
+	NewRecurringRepo,
 )
 
+// Storage backends accepted by data.storage.backend
+const (
+	StorageBackendFile   = "file"
+	StorageBackendMysql  = "mysql"
+	StorageBackendSqlite = "sqlite"
+	StorageBackendMemory = "memory"
+)
+
+// StorageBackend returns the configured storage backend, defaulting to file storage
+func StorageBackend(c *conf.Data) string {
+	if backend := c.GetStorage().GetBackend(); backend != "" {
+		return backend
+	}
+	return StorageBackendFile
+}
+
 // Data .
 type Data struct {
 	db    *gorm.DB
 	redis *redis.Client
 }
 
-// NewData .
+// NewData connects only the resources the configured storage backend needs.
 func NewData(c *conf.Data, logger log.Logger) (*Data, func(), error) {
+	data := &Data{}
+	var cleanups []func()
+	cleanup := func() {
+		log.NewHelper(logger).Info("closing the data resources")
+		for i := len(cleanups) - 1; i >= 0; i-- {
+			cleanups[i]()
+		}
+	}
 
-	db, err := NewGormDB(c, logger)
-	if err != nil {
-		return nil, nil, err
+	switch StorageBackend(c) {
+	case StorageBackendSqlite:
+		db, cleanupDB, err := NewSqliteDB(c, logger)
+		if err != nil {
+			return nil, nil, err
+		}
+		data.db = db
+		cleanups = append(cleanups, cleanupDB)
+	case StorageBackendMysql:
+		db, cleanupDB, err := NewGormDB(c, logger)
+		if err != nil {
+			return nil, nil, err
+		}
+		data.db = db
+		cleanups = append(cleanups, cleanupDB)
+
+		if c.GetRedis().GetAddr() != "" {
+			redisClient, cleanupRedis, err := NewRedisClient(c, logger)
+			if err != nil {
+				cleanup()
+				return nil, nil, err
+			}
+			data.redis = redisClient
+			cleanups = append(cleanups, cleanupRedis)
+		}
 	}
 
-	redisClient, cleanupRedis, err := NewRedisClient(c, logger)
-	if err != nil {
-		return nil, nil, err
+	return data, cleanup, nil
+}
+
+// NewAccounterRepo creates the AccounterRepo selected by data.storage.backend
+func NewAccounterRepo(c *conf.Data, data *Data, logger log.Logger) (biz.AccounterRepo, func(), error) {
+	backend := StorageBackend(c)
+	log.NewHelper(logger).Infof("Using %s storage backend", backend)
+
+	switch backend {
+	case StorageBackendFile:
+		return NewAccounterFileRepo(c, logger)
+	case StorageBackendMysql, StorageBackendSqlite:
+		return NewAccounterDbRepo(data, logger), func() {}, nil
+	case StorageBackendMemory:
+		return NewAccounterMemoryRepo(logger), func() {}, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown storage backend %q", backend)
 	}
+}
 
-	cleanup := func() {
-		log.NewHelper(logger).Info("closing the data resources")
-		cleanupRedis()
+// newBackendRepo creates a repo with the constructor of the storage backend selected by data.storage.backend
+func newBackendRepo[T any](c *conf.Data, data *Data, logger log.Logger,
+	newFile func(*conf.Data, log.Logger) (T, error),
+	newDb func(*Data, log.Logger) T,
+	newMemory func(log.Logger) T,
+) (T, error) {
+	switch backend := StorageBackend(c); backend {
+	case StorageBackendFile:
+		return newFile(c, logger)
+	case StorageBackendMysql, StorageBackendSqlite:
+		return newDb(data, logger), nil
+	case StorageBackendMemory:
+		return newMemory(logger), nil
+	default:
+		var none T
+		return none, fmt.Errorf("unknown storage backend %q", backend)
 	}
+}
+
+// The providers below create each repo for the storage backend selected by data.storage.backend
 
-	return &Data{
-		db:    db,
-		redis: redisClient,
-	}, cleanup, nil
+func NewUserRepo(c *conf.Data, data *Data, logger log.Logger) (biz.UserRepo, error) {
+	return newBackendRepo(c, data, logger, NewUserFileRepo, NewUserDbRepo, NewUserMemoryRepo)
+}
+
+func NewAPITokenRepo(c *conf.Data, data *Data, logger log.Logger) (biz.APITokenRepo, error) {
+	return newBackendRepo(c, data, logger, NewAPITokenFileRepo, NewAPITokenDbRepo, NewAPITokenMemoryRepo)
+}
+
+func NewCategoryRepo(c *conf.Data, data *Data, logger log.Logger) (biz.CategoryRepo, error) {
+	return newBackendRepo(c, data, logger, NewCategoryFileRepo, NewCategoryDbRepo, NewCategoryMemoryRepo)
+}
+
+func NewExchangeRateRepo(c *conf.Data, data *Data, logger log.Logger) (biz.ExchangeRateRepo, error) {
+	return newBackendRepo(c, data, logger, NewExchangeRateFileRepo, NewExchangeRateDbRepo, NewExchangeRateMemoryRepo)
+}
+
+func NewAccountRepo(c *conf.Data, data *Data, logger log.Logger) (biz.AccountRepo, error) {
+	return newBackendRepo(c, data, logger, NewAccountFileRepo, NewAccountDbRepo, NewAccountMemoryRepo)
+}
+
+func NewBudgetRepo(c *conf.Data, data *Data, logger log.Logger) (biz.BudgetRepo, error) {
+	return newBackendRepo(c, data, logger, NewBudgetFileRepo, NewBudgetDbRepo, NewBudgetMemoryRepo)
+}
+
+func NewRecurringRepo(c *conf.Data, data *Data, logger log.Logger) (biz.RecurringRepo, error) {
+	return newBackendRepo(c, data, logger, NewRecurringFileRepo, NewRecurringDbRepo, NewRecurringMemoryRepo)
 }
 
 func NewRedisClient(conf *conf.Data, logger log.Logger) (*redis.Client, func(), error) {
@@ -57,7 +169,6 @@ func NewRedisClient(conf *conf.Data, logger log.Logger) (*redis.Client, func(),
 		Password: conf.Redis.Password,
 	})
 	cleanup := func() {
-		log.NewHelper(logger).Info("closing the data resources")
 		if err := client.Close(); err != nil {
 			log.NewHelper(logger).Error("failed to close redis client: ", err)
 		}
@@ -65,17 +176,138 @@ func NewRedisClient(conf *conf.Data, logger log.Logger) (*redis.Client, func(),
 
 	if err := client.Ping(context.Background()).Err(); err != nil {
 		log.NewHelper(logger).Error("failed to ping redis: ", err)
-		return nil, cleanup, err
+		cleanup()
+		return nil, nil, err
 	}
 
 	return client, cleanup, nil
 }
 
-func NewGormDB(conf *conf.Data, logger log.Logger) (*gorm.DB, error) {
+func NewGormDB(conf *conf.Data, logger log.Logger) (*gorm.DB, func(), error) {
+	if conf.GetDatabase().GetSource() == "" {
+		return nil, nil, fmt.Errorf("data.database.source is required for the %s backend", StorageBackendMysql)
+	}
 	db, err := gorm.Open(mysql.Open(conf.Database.Source), &gorm.Config{})
 	if err != nil {
 		log.NewHelper(logger).Error("failed to open mysql: ", err)
-		return nil, err
+		return nil, nil, err
+	}
+	cleanup := func() {
+		if sqlDB, err := db.DB(); err == nil {
+			if err := sqlDB.Close(); err != nil {
+				log.NewHelper(logger).Error("failed to close mysql: ", err)
+			}
+		}
+	}
+	if err := migrateSchema(db); err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("failed to migrate mysql schema: %v", err)
+	}
+	return db, cleanup, nil
+}
+
+// schemaModels are the tables of the database backends
+var schemaModels = []interface{}{
+	&model.AccounterTransaction{},
+	&model.AccounterTag{},
+	&model.AccounterTransactionTag{},
+	&model.AccounterCategory{},
+	&model.Currency{},
+	&model.User{},
+	&model.APIToken{},
+	&model.ExchangeRate{},
+	&model.Account{},
+	&model.Budget{},
+	&model.RecurringRule{},
+}
+
+// migrateSchema creates the tables and columns the database backends need, moves the amounts of older databases
+// into integer columns, fills in the search text of older transactions, and seeds the default currencies and built-in categories
+func migrateSchema(db *gorm.DB) error {
+	if err := db.AutoMigrate(schemaModels...); err != nil {
+		return err
+	}
+	if err := migrateMoneyColumns(db); err != nil {
+		return err
+	}
+	if err := seedCurrencies(db); err != nil {
+		return err
+	}
+	if err := backfillSearchText(db); err != nil {
+		return err
+	}
+	return seedCategories(db)
+}
+
+// legacyMoneyColumns are the decimal amount columns of older databases and the integer columns
+// that replaced them, which hold biz.Money, ten-thousandths of a currency unit
+var legacyMoneyColumns = []struct {
+	model    interface{}
+	from, to string
+}{
+	{&model.AccounterTransaction{}, "amount", "amount_e4"},
+	{&model.AccounterTransaction{}, "to_amount", "to_amount_e4"},
+	{&model.AccounterTransaction{}, "fee", "fee_e4"},
+	{&model.Account{}, "opening_balance", "opening_balance_e4"},
+	{&model.Budget{}, "amount", "amount_e4"},
+	{&model.RecurringRule{}, "amount", "amount_e4"},
+}
+
+// migrateMoneyColumns copies the amounts of the legacy decimal columns into their integer columns and drops
+// the decimal columns. Running it again after it was interrupted copies the remaining columns.
+func migrateMoneyColumns(db *gorm.DB) error {
+	for _, c := range legacyMoneyColumns {
+		if !db.Migrator().HasColumn(c.model, c.from) {
+			continue
+		}
+		// SQLite keeps decimal columns as floats, ROUND turns 25.549999999999997 back into 255500
+		if err := db.Model(c.model).Where("1 = 1").
+			Update(c.to, gorm.Expr("ROUND(? * 10000)", clause.Column{Name: c.from})).Error; err != nil {
+			return fmt.Errorf("failed to copy %s into %s: %v", c.from, c.to, err)
+		}
+		if err := db.Migrator().DropColumn(c.model, c.from); err != nil {
+			return fmt.Errorf("failed to drop %s: %v", c.from, err)
+		}
+	}
+	return nil
+}
+
+// NewSqliteDB opens the embedded SQLite database and creates its schema
+func NewSqliteDB(c *conf.Data, logger log.Logger) (*gorm.DB, func(), error) {
+	path := c.GetSqlite().GetPath()
+	if path == "" {
+		path = filepath.Join(fileStorageDir(c), "accounters.db")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, nil, fmt.Errorf("failed to create sqlite directory: %v", err)
+	}
+
+	// WAL keeps the database consistent after a crash and lets readers run alongside the writer
+	dsn := path + "?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_pragma=synchronous(FULL)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if err != nil {
+		log.NewHelper(logger).Error("failed to open sqlite: ", err)
+		return nil, nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, nil, err
+	}
+	// SQLite allows a single writer, serialize access instead of failing with SQLITE_BUSY
+	sqlDB.SetMaxOpenConns(1)
+
+	if err := migrateSchema(db); err != nil {
+		sqlDB.Close()
+		return nil, nil, fmt.Errorf("failed to migrate sqlite schema: %v", err)
+	}
+
+	log.NewHelper(logger).Infof("Initialized sqlite storage at: %s", path)
+
+	cleanup := func() {
+		if err := sqlDB.Close(); err != nil {
+			log.NewHelper(logger).Error("failed to close sqlite: ", err)
+		}
 	}
-	return db, nil
+	return db, cleanup, nil
 }
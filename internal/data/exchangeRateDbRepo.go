@@ -0,0 +1,90 @@
+package data
+
+import (
+	"context"
+	"errors"
+
+	"accounter_go/internal/biz"
+	"accounter_go/internal/data/model"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"gorm.io/gorm"
+)
+
+type exchangeRateDbRepo struct {
+	data *Data
+	log  *log.Helper
+}
+
+// NewExchangeRateDbRepo creates a new database-based ExchangeRateRepo backed by the exchange_rates table
+func NewExchangeRateDbRepo(data *Data, logger log.Logger) biz.ExchangeRateRepo {
+	return &exchangeRateDbRepo{
+		data: data,
+		log:  log.NewHelper(logger),
+	}
+}
+
+func (r *exchangeRateDbRepo) Save(ctx context.Context, rate *biz.ExchangeRate) (*biz.ExchangeRate, error) {
+	var record model.ExchangeRate
+	err := r.data.db.WithContext(ctx).
+		Where("user_id = ? AND from_currency = ? AND to_currency = ? AND rate_date = ?", rate.UserID, rate.From, rate.To, rate.Date).
+		First(&record).Error
+	switch {
+	case err == nil:
+		// A rate for the same day replaces the old one
+		record.Rate = rate.Rate
+		err = r.data.db.WithContext(ctx).Model(&record).Update("rate", rate.Rate).Error
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		record = model.ExchangeRate{
+			UserID:       rate.UserID,
+			FromCurrency: rate.From,
+			ToCurrency:   rate.To,
+			RateDate:     rate.Date,
+			Rate:         rate.Rate,
+		}
+		err = r.data.db.WithContext(ctx).Create(&record).Error
+	}
+	if err != nil {
+		r.log.WithContext(ctx).Errorf("Failed to save exchange rate: %v", err)
+		return nil, err
+	}
+
+	return toBizExchangeRate(&record), nil
+}
+
+func (r *exchangeRateDbRepo) ListByUserID(ctx context.Context, userID int64) ([]*biz.ExchangeRate, error) {
+	var records []model.ExchangeRate
+	if err := r.data.db.WithContext(ctx).Where("user_id = ?", userID).Order("rate_date, rate_id").Find(&records).Error; err != nil {
+		r.log.WithContext(ctx).Errorf("Failed to list exchange rates of user %d: %v", userID, err)
+		return nil, err
+	}
+
+	rates := make([]*biz.ExchangeRate, len(records))
+	for i := range records {
+		rates[i] = toBizExchangeRate(&records[i])
+	}
+	return rates, nil
+}
+
+func (r *exchangeRateDbRepo) Delete(ctx context.Context, userID, id int64) error {
+	result := r.data.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&model.ExchangeRate{}, id)
+	if result.Error != nil {
+		r.log.WithContext(ctx).Errorf("Failed to delete exchange rate %d: %v", id, result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return biz.ErrExchangeRateNotFound
+	}
+	return nil
+}
+
+func toBizExchangeRate(r *model.ExchangeRate) *biz.ExchangeRate {
+	return &biz.ExchangeRate{
+		ID:     r.RateID,
+		UserID: r.UserID,
+		From:   r.FromCurrency,
+		To:     r.ToCurrency,
+		Rate:   r.Rate,
+		Date:   r.RateDate.UTC(),
+	}
+}
@@ -0,0 +1,111 @@
+package data
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"accounter_go/internal/biz"
+	"accounter_go/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// FileExchangeRateData represents the structure stored in the exchange rates JSON file
+type FileExchangeRateData struct {
+	ID     int64     `json:"id"`
+	UserID int64     `json:"user_id"`
+	From   string    `json:"from"`
+	To     string    `json:"to"`
+	Rate   float64   `json:"rate"`
+	Date   time.Time `json:"date"`
+}
+
+func (r FileExchangeRateData) recordID() int64 { return r.ID }
+
+// exchangeRateFileRepo keeps the exchange rates in exchange_rates.json next to the accounter data
+type exchangeRateFileRepo struct {
+	*jsonCollection[FileExchangeRateData]
+}
+
+// NewExchangeRateFileRepo creates a new file-based ExchangeRateRepo
+func NewExchangeRateFileRepo(c *conf.Data, logger log.Logger) (biz.ExchangeRateRepo, error) {
+	rates, err := openJSONCollection[FileExchangeRateData](c, "exchange_rates.json", "exchange rates", logger)
+	if err != nil {
+		return nil, err
+	}
+	return &exchangeRateFileRepo{rates}, nil
+}
+
+// NewExchangeRateMemoryRepo creates an ExchangeRateRepo that keeps everything in memory
+func NewExchangeRateMemoryRepo(logger log.Logger) biz.ExchangeRateRepo {
+	return &exchangeRateFileRepo{newJSONCollection[FileExchangeRateData]("", "exchange rates", logger)}
+}
+
+func (r *exchangeRateFileRepo) Save(ctx context.Context, rate *biz.ExchangeRate) (*biz.ExchangeRate, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i := range r.records {
+		existing := r.records[i]
+		if existing.UserID == rate.UserID && existing.From == rate.From && existing.To == rate.To && existing.Date.Equal(rate.Date) {
+			// A rate for the same day replaces the old one
+			existing.Rate = rate.Rate
+			if err := r.replaceLocked(ctx, i, existing); err != nil {
+				return nil, err
+			}
+			return toBizExchangeRateFile(&existing), nil
+		}
+	}
+
+	record := FileExchangeRateData{
+		ID:     r.nextID,
+		UserID: rate.UserID,
+		From:   rate.From,
+		To:     rate.To,
+		Rate:   rate.Rate,
+		Date:   rate.Date,
+	}
+	if err := r.insertLocked(ctx, record); err != nil {
+		return nil, err
+	}
+
+	return toBizExchangeRateFile(&record), nil
+}
+
+func (r *exchangeRateFileRepo) ListByUserID(ctx context.Context, userID int64) ([]*biz.ExchangeRate, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	rates := make([]*biz.ExchangeRate, 0)
+	for i := range r.records {
+		if r.records[i].UserID == userID {
+			rates = append(rates, toBizExchangeRateFile(&r.records[i]))
+		}
+	}
+	sort.SliceStable(rates, func(i, j int) bool {
+		return rates[i].Date.Before(rates[j].Date)
+	})
+	return rates, nil
+}
+
+func (r *exchangeRateFileRepo) Delete(ctx context.Context, userID, id int64) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if i := r.indexOf(id); i >= 0 && r.records[i].UserID == userID {
+		return r.removeLocked(ctx, i)
+	}
+	return biz.ErrExchangeRateNotFound
+}
+
+func toBizExchangeRateFile(r *FileExchangeRateData) *biz.ExchangeRate {
+	return &biz.ExchangeRate{
+		ID:     r.ID,
+		UserID: r.UserID,
+		From:   r.From,
+		To:     r.To,
+		Rate:   r.Rate,
+		Date:   r.Date,
+	}
+}
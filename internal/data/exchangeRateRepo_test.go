@@ -0,0 +1,64 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"accounter_go/internal/biz"
+)
+
+func runExchangeRateRepoContract(t *testing.T, repo biz.ExchangeRateRepo) {
+	ctx := context.Background()
+
+	later, err := repo.Save(ctx, &biz.ExchangeRate{UserID: 1, From: "USD", To: "CNY", Rate: 7.2, Date: date("2025-07-01")})
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	earlier, err := repo.Save(ctx, &biz.ExchangeRate{UserID: 1, From: "USD", To: "CNY", Rate: 7, Date: date("2025-06-01")})
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if _, err := repo.Save(ctx, &biz.ExchangeRate{UserID: 2, From: "USD", To: "CNY", Rate: 6.9, Date: date("2025-06-01")}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	list, err := repo.ListByUserID(ctx, 1)
+	if err != nil || len(list) != 2 || list[0].ID != earlier.ID || list[1].ID != later.ID {
+		t.Fatalf("ListByUserID = %+v, %v", list, err)
+	}
+	if !list[0].Date.Equal(date("2025-06-01")) || list[0].From != "USD" || list[0].To != "CNY" || !approx(list[0].Rate, 7) {
+		t.Errorf("ListByUserID()[0] = %+v", list[0])
+	}
+
+	// Saving the same pair and date again replaces the rate
+	replaced, err := repo.Save(ctx, &biz.ExchangeRate{UserID: 1, From: "USD", To: "CNY", Rate: 7.1, Date: date("2025-06-01")})
+	if err != nil || replaced.ID != earlier.ID {
+		t.Errorf("Save of an existing date = %+v, %v", replaced, err)
+	}
+	if list, _ := repo.ListByUserID(ctx, 1); len(list) != 2 || !approx(list[0].Rate, 7.1) {
+		t.Errorf("ListByUserID after replace = %+v", list)
+	}
+
+	if err := repo.Delete(ctx, 2, later.ID); !errors.Is(err, biz.ErrExchangeRateNotFound) {
+		t.Errorf("Delete of another user's rate = %v, want ErrExchangeRateNotFound", err)
+	}
+	if err := repo.Delete(ctx, 1, later.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := repo.Delete(ctx, 1, later.ID); !errors.Is(err, biz.ErrExchangeRateNotFound) {
+		t.Errorf("Delete of a deleted rate = %v, want ErrExchangeRateNotFound", err)
+	}
+	if list, _ := repo.ListByUserID(ctx, 1); len(list) != 1 {
+		t.Errorf("ListByUserID after delete = %+v", list)
+	}
+}
+
+func TestExchangeRateRepo(t *testing.T) {
+	runRepoBackends(t, NewExchangeRateFileRepo, NewExchangeRateDbRepo, NewExchangeRateMemoryRepo, runExchangeRateRepoContract,
+		func(t *testing.T, _ biz.ExchangeRateRepo, reopen func() biz.ExchangeRateRepo) {
+			if list, err := reopen().ListByUserID(context.Background(), 1); err != nil || len(list) != 1 || !approx(list[0].Rate, 7.1) {
+				t.Errorf("rates not persisted: %+v, %v", list, err)
+			}
+		})
+}
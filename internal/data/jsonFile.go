@@ -0,0 +1,138 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"accounter_go/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// The small, rarely written collections of the file backend (everything but the transactions)
+// are kept as a single JSON array that is rewritten atomically on every change.
+
+// jsonRecord is a record of a JSON collection, its ID is unique within the collection
+type jsonRecord interface {
+	recordID() int64
+}
+
+// jsonCollection holds the records of one JSON file and hands out their IDs,
+// the repo embedding it holds mutex around every access to records
+type jsonCollection[T jsonRecord] struct {
+	path    string
+	name    string
+	records []T
+	nextID  int64
+	mutex   sync.RWMutex
+	log     *log.Helper
+}
+
+// openJSONCollection loads the collection kept in file of the data directory,
+// name tells what the records are in the log
+func openJSONCollection[T jsonRecord](c *conf.Data, file, name string, logger log.Logger) (*jsonCollection[T], error) {
+	dataDir := fileStorageDir(c)
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %v", err)
+	}
+
+	col := newJSONCollection[T](filepath.Join(dataDir, file), name, logger)
+	if err := loadJSONFile(col.path, &col.records); err != nil {
+		return nil, err
+	}
+	for _, record := range col.records {
+		if id := record.recordID(); id >= col.nextID {
+			col.nextID = id + 1
+		}
+	}
+	return col, nil
+}
+
+// newJSONCollection creates an empty collection, an empty path keeps it in memory only
+func newJSONCollection[T jsonRecord](path, name string, logger log.Logger) *jsonCollection[T] {
+	return &jsonCollection[T]{
+		path:   path,
+		name:   name,
+		nextID: 1,
+		log:    log.NewHelper(logger),
+	}
+}
+
+// saveLocked persists records and makes them current, the caller holds the write lock
+func (c *jsonCollection[T]) saveLocked(ctx context.Context, records []T) error {
+	if err := saveJSONFile(c.path, records); err != nil {
+		c.log.WithContext(ctx).Errorf("Failed to save %s: %v", c.name, err)
+		return err
+	}
+	c.records = records
+	return nil
+}
+
+// insertLocked persists the records with record appended and moves on to the next ID,
+// record takes nextID before, the caller holds the write lock
+func (c *jsonCollection[T]) insertLocked(ctx context.Context, record T) error {
+	if err := c.saveLocked(ctx, append(c.records[:len(c.records):len(c.records)], record)); err != nil {
+		return err
+	}
+	c.nextID++
+	return nil
+}
+
+// replaceLocked persists the records with the one at i replaced, the caller holds the write lock
+func (c *jsonCollection[T]) replaceLocked(ctx context.Context, i int, record T) error {
+	records := append([]T(nil), c.records...)
+	records[i] = record
+	return c.saveLocked(ctx, records)
+}
+
+// removeLocked persists the records without the one at i, the caller holds the write lock
+func (c *jsonCollection[T]) removeLocked(ctx context.Context, i int) error {
+	records := make([]T, 0, len(c.records)-1)
+	records = append(records, c.records[:i]...)
+	records = append(records, c.records[i+1:]...)
+	return c.saveLocked(ctx, records)
+}
+
+// indexOf returns the position of the record with an ID, the caller holds the lock
+func (c *jsonCollection[T]) indexOf(id int64) int {
+	for i := range c.records {
+		if c.records[i].recordID() == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// loadJSONFile decodes path into v, a missing or empty file leaves v untouched
+func loadJSONFile(path string, v interface{}) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	if len(content) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(content, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	return nil
+}
+
+// saveJSONFile atomically replaces path with v, an empty path means memory only
+func saveJSONFile(path string, v interface{}) error {
+	if path == "" {
+		return nil
+	}
+	content, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, content)
+}
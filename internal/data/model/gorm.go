@@ -7,9 +7,12 @@ import (
 // AccounterCategory 交易分类表，用于记录各类支出或收入的分类
 type AccounterCategory struct {
 	CategoryID   int       `gorm:"column:category_id;primaryKey;autoIncrement" json:"category_id"`                                       // 分类主键ID，自增
+	UserID       int64     `gorm:"column:user_id;type:bigint;not null;default:0;index" json:"user_id"`                                   // 所属用户ID，0表示所有用户共用的内置分类
 	CategoryName string    `gorm:"column:category_name;type:varchar(50);not null" json:"category_name"`                                  // 分类名称，如餐饮、交通、工资等
 	ParentID     *int      `gorm:"column:parent_id;type:int" json:"parent_id"`                                                           // 父级分类ID，用于多级分类，自关联到本表category_id，可为空
 	Type         *int8     `gorm:"column:type;type:tinyint" json:"type"`                                                                 // 分类类型：0-支出，1-收入；可选字段，若不区分可不使用
+	Icon         string    `gorm:"column:icon;type:varchar(50);not null;default:''" json:"icon"`                                         // 分类图标，如emoji
+	Color        string    `gorm:"column:color;type:varchar(20);not null;default:''" json:"color"`                                       // 分类颜色，如#ff8800
 	CreatedAt    time.Time `gorm:"column:created_at;type:timestamp;default:CURRENT_TIMESTAMP;not null" json:"created_at"`                // 分类创建时间
 	UpdatedAt    time.Time `gorm:"column:updated_at;type:timestamp;default:CURRENT_TIMESTAMP;not null;autoUpdateTime" json:"updated_at"` // 分类更新时间
 }
@@ -22,13 +25,21 @@ func (AccounterCategory) TableName() string {
 // AccounterTransaction 交易明细表，记录每笔收入或支出信息
 type AccounterTransaction struct {
 	TransactionID   int64     `gorm:"column:transaction_id;primaryKey;autoIncrement" json:"transaction_id"`                                 // 交易主键ID，自增
-	UserID          int64     `gorm:"column:user_id;type:bigint;not null" json:"user_id"`                                                   // 用户ID, 关联users.user_id
-	CategoryID      int       `gorm:"column:category_id;type:int;not null" json:"category_id"`                                              // 交易所属分类ID，外键关联categories.category_id
+	UserID          int64     `gorm:"column:user_id;type:bigint;not null;index" json:"user_id"`                                             // 用户ID, 关联users.user_id
+	CategoryID      int       `gorm:"column:category_id;type:int;not null;index" json:"category_id"`                                        // 交易所属分类ID，外键关联categories.category_id
 	CurrencyID      int       `gorm:"column:currency_id;type:int;not null" json:"currency_id"`                                              // 使用的币种ID，外键关联currencies.currency_id
-	TransactionType int8      `gorm:"column:transaction_type;type:tinyint;not null" json:"transaction_type"`                                // 交易类型：0-支出，1-收入
-	Amount          float64   `gorm:"column:amount;type:decimal(18,5);not null" json:"amount"`                                              // 交易金额，一般保留两位小数
-	TransactionDate time.Time `gorm:"column:transaction_date;type:datetime;not null" json:"transaction_date"`                               // 交易实际发生时间
+	AccountID       int64     `gorm:"column:account_id;type:bigint;not null;default:0;index" json:"account_id"`                             // 交易所属账户ID，关联accounts.account_id，0表示未指定账户
+	ToAccountID     int64     `gorm:"column:to_account_id;type:bigint;not null;default:0" json:"to_account_id"`                             // 转账转入账户ID，非转账为0
+	ToCurrencyID    int       `gorm:"column:to_currency_id;type:int;not null;default:0" json:"to_currency_id"`                              // 转账转入币种ID，非转账为0
+	ToAmount        int64     `gorm:"column:to_amount_e4;type:bigint;not null;default:0" json:"to_amount"`                                  // 转账转入金额，以转入币种计，单位为万分之一元（即 biz.Money）
+	Fee             int64     `gorm:"column:fee_e4;type:bigint;not null;default:0" json:"fee"`                                              // 转账手续费，以转出币种计，从转出账户扣除，单位为万分之一元
+	RecurringID     int64     `gorm:"column:recurring_id;type:bigint;not null;default:0;index" json:"recurring_id"`                         // 生成该交易的周期规则ID，关联recurring_rules.recurring_id，手动记录为0
+	ExternalID      string    `gorm:"column:external_id;type:varchar(255);not null;default:'';index" json:"external_id"`                    // 导入来源中的交易ID，如银行对账单的FITID，用于导入去重，手动记录为空
+	TransactionType int8      `gorm:"column:transaction_type;type:tinyint;not null" json:"transaction_type"`                                // 交易类型：1-收入，2-支出，3-转账
+	Amount          int64     `gorm:"column:amount_e4;type:bigint;not null;default:0" json:"amount"`                                        // 交易金额，单位为万分之一元（即 biz.Money），如 25.50 存为 255000
+	TransactionDate time.Time `gorm:"column:transaction_date;type:datetime;not null;index" json:"transaction_date"`                         // 交易实际发生时间
 	Note            *string   `gorm:"column:note;type:varchar(255)" json:"note"`                                                            // 交易备注信息，如“早餐”、“地铁费”等
+	SearchText      *string   `gorm:"column:search_text;type:text" json:"-"`                                                                // 搜索文本：备注和标签的小写、拼音及拼音首字母，每行一项，NULL表示尚未生成
 	CreatedAt       time.Time `gorm:"column:created_at;type:timestamp;default:CURRENT_TIMESTAMP;not null" json:"created_at"`                // 记录创建时间
 	UpdatedAt       time.Time `gorm:"column:updated_at;type:timestamp;default:CURRENT_TIMESTAMP;not null;autoUpdateTime" json:"updated_at"` // 记录更新时间
 }
@@ -38,6 +49,30 @@ func (AccounterTransaction) TableName() string {
 	return "accounter_transactions"
 }
 
+// AccounterTag 交易标签表，同一用户的标签名唯一
+type AccounterTag struct {
+	TagID     int64     `gorm:"column:tag_id;primaryKey;autoIncrement" json:"tag_id"`                                  // 标签主键ID，自增
+	UserID    int64     `gorm:"column:user_id;type:bigint;not null;uniqueIndex:idx_tag_name" json:"user_id"`           // 所属用户ID, 关联users.user_id
+	Name      string    `gorm:"column:name;type:varchar(64);not null;uniqueIndex:idx_tag_name" json:"name"`            // 标签名，小写，空格转为连字符，如 travel、报销
+	CreatedAt time.Time `gorm:"column:created_at;type:timestamp;default:CURRENT_TIMESTAMP;not null" json:"created_at"` // 记录创建时间
+}
+
+// TableName 设置表名
+func (AccounterTag) TableName() string {
+	return "accounter_tags"
+}
+
+// AccounterTransactionTag 交易与标签的多对多关联表
+type AccounterTransactionTag struct {
+	TransactionID int64 `gorm:"column:transaction_id;primaryKey;autoIncrement:false" json:"transaction_id"` // 交易ID，关联accounter_transactions.transaction_id
+	TagID         int64 `gorm:"column:tag_id;primaryKey;autoIncrement:false;index" json:"tag_id"`           // 标签ID，关联accounter_tags.tag_id
+}
+
+// TableName 设置表名
+func (AccounterTransactionTag) TableName() string {
+	return "accounter_transaction_tags"
+}
+
 // Currency 币种信息表，用于维护可用的货币类型
 type Currency struct {
 	CurrencyID     int       `gorm:"column:currency_id;primaryKey;autoIncrement" json:"currency_id"`                                       // 币种主键ID，自增
@@ -66,3 +101,95 @@ type User struct {
 func (User) TableName() string {
 	return "users"
 }
+
+// APIToken 个人访问令牌表，只保存令牌的哈希值
+type APIToken struct {
+	TokenID    int64      `gorm:"column:token_id;primaryKey;autoIncrement" json:"token_id"`                              // 主键ID，自增
+	UserID     int64      `gorm:"column:user_id;type:bigint;not null;index" json:"user_id"`                              // 所属用户ID, 关联users.user_id
+	Name       string     `gorm:"column:name;type:varchar(100);not null" json:"name"`                                    // 令牌名称，如“定时任务”、“快捷指令”
+	Prefix     string     `gorm:"column:prefix;type:varchar(16);not null" json:"prefix"`                                 // 令牌开头几位，便于用户辨认
+	TokenHash  string     `gorm:"column:token_hash;type:char(64);not null;unique" json:"token_hash"`                     // 令牌的SHA-256哈希值
+	Scopes     string     `gorm:"column:scopes;type:varchar(100);not null" json:"scopes"`                                // 权限范围，逗号分隔：read,write,admin
+	LastUsedAt *time.Time `gorm:"column:last_used_at;type:datetime" json:"last_used_at"`                                 // 最近使用时间，从未使用时为空
+	CreatedAt  time.Time  `gorm:"column:created_at;type:timestamp;default:CURRENT_TIMESTAMP;not null" json:"created_at"` // 创建时间
+}
+
+// TableName 设置表名
+func (APIToken) TableName() string {
+	return "api_tokens"
+}
+
+// ExchangeRate 汇率表，记录某天起一单位原币种可兑换的目标币种数量
+type ExchangeRate struct {
+	RateID       int64     `gorm:"column:rate_id;primaryKey;autoIncrement" json:"rate_id"`                                            // 主键ID，自增
+	UserID       int64     `gorm:"column:user_id;type:bigint;not null;uniqueIndex:idx_exchange_rate" json:"user_id"`                  // 所属用户ID, 关联users.user_id
+	FromCurrency string    `gorm:"column:from_currency;type:varchar(10);not null;uniqueIndex:idx_exchange_rate" json:"from_currency"` // 原币种代码，例如 USD
+	ToCurrency   string    `gorm:"column:to_currency;type:varchar(10);not null;uniqueIndex:idx_exchange_rate" json:"to_currency"`     // 目标币种代码，例如 CNY
+	RateDate     time.Time `gorm:"column:rate_date;type:date;not null;uniqueIndex:idx_exchange_rate" json:"rate_date"`                // 汇率生效日期，直到同一币种对的下一个汇率
+	Rate         float64   `gorm:"column:rate;type:decimal(18,8);not null" json:"rate"`                                               // 汇率
+	CreatedAt    time.Time `gorm:"column:created_at;type:timestamp;default:CURRENT_TIMESTAMP;not null" json:"created_at"`             // 记录创建时间
+}
+
+// TableName 设置表名
+func (ExchangeRate) TableName() string {
+	return "exchange_rates"
+}
+
+// Account 账户表，记录银行卡、支付宝、微信、现金等资金账户
+type Account struct {
+	AccountID      int64     `gorm:"column:account_id;primaryKey;autoIncrement" json:"account_id"`                                         // 主键ID，自增
+	UserID         int64     `gorm:"column:user_id;type:bigint;not null;index" json:"user_id"`                                             // 所属用户ID, 关联users.user_id
+	AccountName    string    `gorm:"column:account_name;type:varchar(50);not null" json:"account_name"`                                    // 账户名称，如“招商银行储蓄卡”
+	AccountType    int8      `gorm:"column:account_type;type:tinyint;not null" json:"account_type"`                                        // 账户类型：1-现金，2-储蓄卡，3-信用卡，4-支付宝，5-微信，6-投资，7-其他
+	CurrencyCode   string    `gorm:"column:currency_code;type:varchar(10);not null" json:"currency_code"`                                  // 账户币种代码，账户下的交易都使用该币种
+	OpeningBalance int64     `gorm:"column:opening_balance_e4;type:bigint;not null;default:0" json:"opening_balance"`                      // 期初余额，单位为万分之一元
+	CreatedAt      time.Time `gorm:"column:created_at;type:timestamp;default:CURRENT_TIMESTAMP;not null" json:"created_at"`                // 记录创建时间
+	UpdatedAt      time.Time `gorm:"column:updated_at;type:timestamp;default:CURRENT_TIMESTAMP;not null;autoUpdateTime" json:"updated_at"` // 记录更新时间
+}
+
+// TableName 设置表名
+func (Account) TableName() string {
+	return "accounts"
+}
+
+// Budget 预算表，按月或按年限制某个分类（含子分类）的支出
+type Budget struct {
+	BudgetID     int64     `gorm:"column:budget_id;primaryKey;autoIncrement" json:"budget_id"`                                           // 主键ID，自增
+	UserID       int64     `gorm:"column:user_id;type:bigint;not null;index" json:"user_id"`                                             // 所属用户ID, 关联users.user_id
+	CategoryID   int64     `gorm:"column:category_id;type:bigint;not null" json:"category_id"`                                           // 预算分类ID，关联categories.category_id
+	PeriodType   int8      `gorm:"column:period_type;type:tinyint;not null" json:"period_type"`                                          // 预算周期：1-每月，2-每年
+	Amount       int64     `gorm:"column:amount_e4;type:bigint;not null;default:0" json:"amount"`                                        // 每个周期的预算金额，单位为万分之一元
+	CurrencyCode string    `gorm:"column:currency_code;type:varchar(10);not null" json:"currency_code"`                                  // 预算币种代码，其他币种的支出按汇率折算
+	Rollover     bool      `gorm:"column:rollover;not null;default:false" json:"rollover"`                                               // 未用完的预算是否结转到下个周期
+	StartDate    time.Time `gorm:"column:start_date;type:date;not null" json:"start_date"`                                               // 预算开始生效的周期的第一天
+	CreatedAt    time.Time `gorm:"column:created_at;type:timestamp;default:CURRENT_TIMESTAMP;not null" json:"created_at"`                // 记录创建时间
+	UpdatedAt    time.Time `gorm:"column:updated_at;type:timestamp;default:CURRENT_TIMESTAMP;not null;autoUpdateTime" json:"updated_at"` // 记录更新时间
+}
+
+// TableName 设置表名
+func (Budget) TableName() string {
+	return "budgets"
+}
+
+// RecurringRule 周期交易规则表，调度器按规则在每次到期时生成一笔交易
+type RecurringRule struct {
+	RecurringID     int64      `gorm:"column:recurring_id;primaryKey;autoIncrement" json:"recurring_id"`                                     // 主键ID，自增
+	UserID          int64      `gorm:"column:user_id;type:bigint;not null;index" json:"user_id"`                                             // 所属用户ID, 关联users.user_id
+	RRule           string     `gorm:"column:rrule;type:varchar(255);not null" json:"rrule"`                                                 // 重复规则，RFC 5545 RRULE 格式，如 FREQ=MONTHLY;BYMONTHDAY=1
+	StartDate       time.Time  `gorm:"column:start_date;type:date;not null" json:"start_date"`                                               // 规则开始日期，第一次发生不早于该日
+	TransactionType int8       `gorm:"column:transaction_type;type:tinyint;not null" json:"transaction_type"`                                // 生成的交易类型：1-收入，2-支出
+	CategoryID      int64      `gorm:"column:category_id;type:bigint;not null;default:0" json:"category_id"`                                 // 生成的交易分类ID，关联categories.category_id
+	Note            string     `gorm:"column:note;type:varchar(255);not null;default:''" json:"note"`                                        // 生成的交易备注，如“房租”
+	Amount          int64      `gorm:"column:amount_e4;type:bigint;not null;default:0" json:"amount"`                                        // 生成的交易金额，单位为万分之一元
+	CurrencyCode    string     `gorm:"column:currency_code;type:varchar(10);not null" json:"currency_code"`                                  // 生成的交易币种代码
+	AccountID       int64      `gorm:"column:account_id;type:bigint;not null;default:0" json:"account_id"`                                   // 生成的交易账户ID，0表示未指定账户
+	LastDate        *time.Time `gorm:"column:last_date;type:date" json:"last_date"`                                                          // 最近一次已生成交易的日期，尚未生成为空
+	NextDate        *time.Time `gorm:"column:next_date;type:date;index" json:"next_date"`                                                    // 下一次待生成交易的日期，规则结束后为空
+	CreatedAt       time.Time  `gorm:"column:created_at;type:timestamp;default:CURRENT_TIMESTAMP;not null" json:"created_at"`                // 记录创建时间
+	UpdatedAt       time.Time  `gorm:"column:updated_at;type:timestamp;default:CURRENT_TIMESTAMP;not null;autoUpdateTime" json:"updated_at"` // 记录更新时间
+}
+
+// TableName 设置表名
+func (RecurringRule) TableName() string {
+	return "recurring_rules"
+}
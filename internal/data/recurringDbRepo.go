@@ -0,0 +1,160 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	v1 "accounter_go/api/accounter/v1"
+	"accounter_go/internal/biz"
+	"accounter_go/internal/data/model"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"gorm.io/gorm"
+)
+
+type recurringDbRepo struct {
+	data *Data
+	log  *log.Helper
+}
+
+// NewRecurringDbRepo creates a new database-based RecurringRepo backed by the recurring_rules table
+func NewRecurringDbRepo(data *Data, logger log.Logger) biz.RecurringRepo {
+	return &recurringDbRepo{
+		data: data,
+		log:  log.NewHelper(logger),
+	}
+}
+
+func (r *recurringDbRepo) Save(ctx context.Context, rule *biz.Recurring) (*biz.Recurring, error) {
+	record := toModelRecurring(rule)
+	record.RecurringID = 0
+	if err := r.data.db.WithContext(ctx).Create(record).Error; err != nil {
+		r.log.WithContext(ctx).Errorf("Failed to save recurring transaction: %v", err)
+		return nil, err
+	}
+
+	return toBizRecurring(record), nil
+}
+
+func (r *recurringDbRepo) Update(ctx context.Context, rule *biz.Recurring) (*biz.Recurring, error) {
+	record := toModelRecurring(rule)
+	result := r.data.db.WithContext(ctx).
+		Model(&model.RecurringRule{}).
+		Where("recurring_id = ?", rule.ID).
+		Updates(map[string]interface{}{
+			"rrule":         record.RRule,
+			"start_date":    record.StartDate,
+			"category_id":   record.CategoryID,
+			"note":          record.Note,
+			"amount_e4":     record.Amount,
+			"currency_code": record.CurrencyCode,
+			"account_id":    record.AccountID,
+			"last_date":     record.LastDate,
+			"next_date":     record.NextDate,
+		})
+	if result.Error != nil {
+		r.log.WithContext(ctx).Errorf("Failed to update recurring transaction: %v", result.Error)
+		return nil, result.Error
+	}
+
+	return r.FindByID(ctx, rule.ID)
+}
+
+func (r *recurringDbRepo) FindByID(ctx context.Context, id int64) (*biz.Recurring, error) {
+	var record model.RecurringRule
+	if err := r.data.db.WithContext(ctx).First(&record, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, biz.ErrRecurringNotFound
+		}
+		r.log.WithContext(ctx).Errorf("Failed to find recurring transaction %d: %v", id, err)
+		return nil, err
+	}
+
+	return toBizRecurring(&record), nil
+}
+
+func (r *recurringDbRepo) ListByUserID(ctx context.Context, userID int64) ([]*biz.Recurring, error) {
+	return r.list(ctx, r.data.db.WithContext(ctx).Where("user_id = ?", userID))
+}
+
+func (r *recurringDbRepo) ListDue(ctx context.Context, until time.Time) ([]*biz.Recurring, error) {
+	return r.list(ctx, r.data.db.WithContext(ctx).Where("next_date IS NOT NULL AND next_date <= ?", until))
+}
+
+// list runs a query on the recurring_rules table
+func (r *recurringDbRepo) list(ctx context.Context, db *gorm.DB) ([]*biz.Recurring, error) {
+	var records []model.RecurringRule
+	if err := db.Order("recurring_id").Find(&records).Error; err != nil {
+		r.log.WithContext(ctx).Errorf("Failed to list recurring transactions: %v", err)
+		return nil, err
+	}
+
+	rules := make([]*biz.Recurring, len(records))
+	for i := range records {
+		rules[i] = toBizRecurring(&records[i])
+	}
+	return rules, nil
+}
+
+func (r *recurringDbRepo) Delete(ctx context.Context, id int64) error {
+	result := r.data.db.WithContext(ctx).Delete(&model.RecurringRule{}, id)
+	if result.Error != nil {
+		r.log.WithContext(ctx).Errorf("Failed to delete recurring transaction %d: %v", id, result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return biz.ErrRecurringNotFound
+	}
+	return nil
+}
+
+// nullDate stores the zero time as NULL
+func nullDate(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// dateOrZero reads a nullable date column
+func dateOrZero(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return t.UTC()
+}
+
+func toModelRecurring(r *biz.Recurring) *model.RecurringRule {
+	return &model.RecurringRule{
+		RecurringID:     r.ID,
+		UserID:          r.UserID,
+		RRule:           r.RRule,
+		StartDate:       r.StartDate,
+		TransactionType: int8(r.Type),
+		CategoryID:      r.CategoryID,
+		Note:            r.Desc,
+		Amount:          int64(r.Amount),
+		CurrencyCode:    r.Currency,
+		AccountID:       r.AccountID,
+		LastDate:        nullDate(r.LastDate),
+		NextDate:        nullDate(r.NextDate),
+	}
+}
+
+func toBizRecurring(r *model.RecurringRule) *biz.Recurring {
+	return &biz.Recurring{
+		ID:         r.RecurringID,
+		UserID:     r.UserID,
+		RRule:      r.RRule,
+		StartDate:  r.StartDate.UTC(),
+		Type:       v1.Type(r.TransactionType),
+		CategoryID: r.CategoryID,
+		Desc:       r.Note,
+		Amount:     biz.Money(r.Amount),
+		Currency:   r.CurrencyCode,
+		AccountID:  r.AccountID,
+		LastDate:   dateOrZero(r.LastDate),
+		NextDate:   dateOrZero(r.NextDate),
+	}
+}
@@ -0,0 +1,158 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	v1 "accounter_go/api/accounter/v1"
+	"accounter_go/internal/biz"
+	"accounter_go/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// FileRecurringData represents the structure stored in the recurring JSON file
+type FileRecurringData struct {
+	ID         int64     `json:"id"`
+	UserID     int64     `json:"user_id"`
+	RRule      string    `json:"rrule"`
+	StartDate  time.Time `json:"start_date"`
+	Type       int32     `json:"type"`
+	CategoryID int64     `json:"category_id"`
+	Desc       string    `json:"desc"`
+	Amount     biz.Money `json:"amount"`
+	Currency   string    `json:"currency"`
+	AccountID  int64     `json:"account_id,omitempty"`
+	LastDate   time.Time `json:"last_date"`
+	NextDate   time.Time `json:"next_date"`
+}
+
+func (r FileRecurringData) recordID() int64 { return r.ID }
+
+// recurringFileRepo keeps the recurring transactions in recurring.json next to the accounter data
+type recurringFileRepo struct {
+	*jsonCollection[FileRecurringData]
+}
+
+// NewRecurringFileRepo creates a new file-based RecurringRepo
+func NewRecurringFileRepo(c *conf.Data, logger log.Logger) (biz.RecurringRepo, error) {
+	rules, err := openJSONCollection[FileRecurringData](c, "recurring.json", "recurring transactions", logger)
+	if err != nil {
+		return nil, err
+	}
+	return &recurringFileRepo{rules}, nil
+}
+
+// NewRecurringMemoryRepo creates a RecurringRepo that keeps everything in memory
+func NewRecurringMemoryRepo(logger log.Logger) biz.RecurringRepo {
+	return &recurringFileRepo{newJSONCollection[FileRecurringData]("", "recurring transactions", logger)}
+}
+
+func (r *recurringFileRepo) Save(ctx context.Context, rule *biz.Recurring) (*biz.Recurring, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	record := toFileRecurring(rule)
+	record.ID = r.nextID
+	if err := r.insertLocked(ctx, record); err != nil {
+		return nil, err
+	}
+
+	return toBizRecurringFile(&record), nil
+}
+
+func (r *recurringFileRepo) Update(ctx context.Context, rule *biz.Recurring) (*biz.Recurring, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	i := r.indexOf(rule.ID)
+	if i < 0 {
+		return nil, biz.ErrRecurringNotFound
+	}
+	if err := r.replaceLocked(ctx, i, toFileRecurring(rule)); err != nil {
+		return nil, err
+	}
+
+	return toBizRecurringFile(&r.records[i]), nil
+}
+
+func (r *recurringFileRepo) FindByID(ctx context.Context, id int64) (*biz.Recurring, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if i := r.indexOf(id); i >= 0 {
+		return toBizRecurringFile(&r.records[i]), nil
+	}
+	return nil, biz.ErrRecurringNotFound
+}
+
+func (r *recurringFileRepo) ListByUserID(ctx context.Context, userID int64) ([]*biz.Recurring, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	rules := make([]*biz.Recurring, 0)
+	for i := range r.records {
+		if r.records[i].UserID == userID {
+			rules = append(rules, toBizRecurringFile(&r.records[i]))
+		}
+	}
+	return rules, nil
+}
+
+func (r *recurringFileRepo) ListDue(ctx context.Context, until time.Time) ([]*biz.Recurring, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	rules := make([]*biz.Recurring, 0)
+	for i := range r.records {
+		if next := r.records[i].NextDate; !next.IsZero() && !next.After(until) {
+			rules = append(rules, toBizRecurringFile(&r.records[i]))
+		}
+	}
+	return rules, nil
+}
+
+func (r *recurringFileRepo) Delete(ctx context.Context, id int64) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	i := r.indexOf(id)
+	if i < 0 {
+		return biz.ErrRecurringNotFound
+	}
+	return r.removeLocked(ctx, i)
+}
+
+func toFileRecurring(r *biz.Recurring) FileRecurringData {
+	return FileRecurringData{
+		ID:         r.ID,
+		UserID:     r.UserID,
+		RRule:      r.RRule,
+		StartDate:  r.StartDate,
+		Type:       int32(r.Type),
+		CategoryID: r.CategoryID,
+		Desc:       r.Desc,
+		Amount:     r.Amount,
+		Currency:   r.Currency,
+		AccountID:  r.AccountID,
+		LastDate:   r.LastDate,
+		NextDate:   r.NextDate,
+	}
+}
+
+func toBizRecurringFile(r *FileRecurringData) *biz.Recurring {
+	return &biz.Recurring{
+		ID:         r.ID,
+		UserID:     r.UserID,
+		RRule:      r.RRule,
+		StartDate:  r.StartDate,
+		Type:       v1.Type(r.Type),
+		CategoryID: r.CategoryID,
+		Desc:       r.Desc,
+		Amount:     r.Amount,
+		Currency:   r.Currency,
+		AccountID:  r.AccountID,
+		LastDate:   r.LastDate,
+		NextDate:   r.NextDate,
+	}
+}
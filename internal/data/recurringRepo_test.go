@@ -0,0 +1,97 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	v1 "accounter_go/api/accounter/v1"
+	"accounter_go/internal/biz"
+)
+
+func runRecurringRepoContract(t *testing.T, repo biz.RecurringRepo) {
+	ctx := context.Background()
+
+	saved, err := repo.Save(ctx, &biz.Recurring{
+		UserID: 1, RRule: "FREQ=MONTHLY;BYMONTHDAY=1", StartDate: date("2025-06-01"), Type: v1.Type_Expense, CategoryID: int64(v1.Category_Utility),
+		Desc: "房租", Amount: money("3000"), Currency: "CNY", AccountID: 3, NextDate: date("2025-06-01"),
+	})
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	second, err := repo.Save(ctx, &biz.Recurring{UserID: 1, RRule: "FREQ=MONTHLY;BYMONTHDAY=15", StartDate: date("2025-01-15"), Type: v1.Type_Income, CategoryID: int64(v1.Category_Salary), Amount: money("12000"), Currency: "CNY", NextDate: date("2025-08-15")})
+	if err != nil || second.ID == saved.ID {
+		t.Fatalf("Save = %+v, %v", second, err)
+	}
+	if _, err := repo.Save(ctx, &biz.Recurring{UserID: 2, RRule: "FREQ=DAILY;UNTIL=20250110", StartDate: date("2025-01-01"), Type: v1.Type_Expense, Amount: money("1"), Currency: "CNY", LastDate: date("2025-01-10")}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	got, err := repo.FindByID(ctx, saved.ID)
+	if err != nil || got.UserID != 1 || got.RRule != "FREQ=MONTHLY;BYMONTHDAY=1" || !got.StartDate.Equal(date("2025-06-01")) || got.Type != v1.Type_Expense ||
+		got.CategoryID != int64(v1.Category_Utility) || got.Desc != "房租" || got.Amount != money("3000") || got.Currency != "CNY" || got.AccountID != 3 ||
+		!got.LastDate.IsZero() || !got.NextDate.Equal(date("2025-06-01")) {
+		t.Errorf("FindByID = %+v, %v", got, err)
+	}
+	if _, err := repo.FindByID(ctx, 99999); !errors.Is(err, biz.ErrRecurringNotFound) {
+		t.Errorf("FindByID of an unknown rule = %v, want ErrRecurringNotFound", err)
+	}
+
+	list, err := repo.ListByUserID(ctx, 1)
+	if err != nil || len(list) != 2 || list[0].ID != saved.ID || list[1].Type != v1.Type_Income {
+		t.Errorf("ListByUserID = %+v, %v", list, err)
+	}
+	// Rules that ended have no next occurrence and are never due
+	for _, tc := range []struct {
+		until time.Time
+		want  int
+	}{
+		{date("2025-05-31"), 0},
+		{date("2025-06-01"), 1},
+		{date("2025-08-15").Add(time.Hour), 2},
+	} {
+		if due, err := repo.ListDue(ctx, tc.until); err != nil || len(due) != tc.want {
+			t.Errorf("ListDue(%s) = %d rules, %v, want %d", tc.until, len(due), err, tc.want)
+		}
+	}
+
+	got.LastDate = date("2025-06-01")
+	got.NextDate = date("2025-07-01")
+	got.Amount = money("3100.5")
+	updated, err := repo.Update(ctx, got)
+	if err != nil || !updated.LastDate.Equal(date("2025-06-01")) || !updated.NextDate.Equal(date("2025-07-01")) || updated.Amount != money("3100.5") {
+		t.Errorf("Update = %+v, %v", updated, err)
+	}
+	got.NextDate = time.Time{}
+	if updated, err = repo.Update(ctx, got); err != nil || !updated.NextDate.IsZero() {
+		t.Errorf("Update of an ended rule = %+v, %v", updated, err)
+	}
+
+	if err := repo.Delete(ctx, saved.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := repo.FindByID(ctx, saved.ID); !errors.Is(err, biz.ErrRecurringNotFound) {
+		t.Errorf("deleted rule is still found: %v", err)
+	}
+	if err := repo.Delete(ctx, saved.ID); !errors.Is(err, biz.ErrRecurringNotFound) {
+		t.Errorf("Delete of a deleted rule = %v, want ErrRecurringNotFound", err)
+	}
+}
+
+func TestRecurringRepo(t *testing.T) {
+	runRepoBackends(t, NewRecurringFileRepo, NewRecurringDbRepo, NewRecurringMemoryRepo, runRecurringRepoContract,
+		func(t *testing.T, repo biz.RecurringRepo, reopen func() biz.RecurringRepo) {
+			kept, err := repo.Save(context.Background(), &biz.Recurring{UserID: 1, RRule: "FREQ=WEEKLY", StartDate: date("2025-03-03"), Type: v1.Type_Expense, Amount: money("25"), Currency: "CNY", NextDate: date("2025-03-10")})
+			if err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+			reopened := reopen()
+			if got, err := reopened.FindByID(context.Background(), kept.ID); err != nil || got.RRule != "FREQ=WEEKLY" || !got.NextDate.Equal(date("2025-03-10")) {
+				t.Errorf("rule not persisted: %+v, %v", got, err)
+			}
+			if next, err := reopened.Save(context.Background(), &biz.Recurring{UserID: 1, RRule: "FREQ=DAILY", Type: v1.Type_Expense, Amount: money("5"), Currency: "CNY"}); err != nil || next.ID != kept.ID+1 {
+				t.Errorf("Save after reopen = %+v, %v", next, err)
+			}
+		})
+}
@@ -0,0 +1,58 @@
+package data
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+
+	"accounter_go/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// runRepoBackends runs contract against a new repo of every storage backend, like newBackendRepo
+// it takes the file, database and memory constructors. persisted then checks the repos of the
+// backends that keep their data, reopen creates the repo again on the same storage.
+func runRepoBackends[T any](t *testing.T,
+	newFile func(*conf.Data, log.Logger) (T, error),
+	newDb func(*Data, log.Logger) T,
+	newMemory func(log.Logger) T,
+	contract func(t *testing.T, repo T),
+	persisted func(t *testing.T, repo T, reopen func() T),
+) {
+	logger := log.NewStdLogger(io.Discard)
+	run := func(t *testing.T, open func() T) {
+		repo := open()
+		contract(t, repo)
+		if persisted != nil {
+			persisted(t, repo, open)
+		}
+	}
+
+	t.Run("File", func(t *testing.T) {
+		c := &conf.Data{FileStorage: &conf.Data_FileStorage{DataDir: t.TempDir()}}
+		run(t, func() T {
+			t.Helper()
+			repo, err := newFile(c, logger)
+			if err != nil {
+				t.Fatalf("new file repo: %v", err)
+			}
+			return repo
+		})
+	})
+	t.Run("Memory", func(t *testing.T) {
+		contract(t, newMemory(logger))
+	})
+	t.Run("Sqlite", func(t *testing.T) {
+		c := &conf.Data{Sqlite: &conf.Data_Sqlite{Path: filepath.Join(t.TempDir(), "accounters.db")}}
+		run(t, func() T {
+			t.Helper()
+			db, cleanup, err := NewSqliteDB(c, logger)
+			if err != nil {
+				t.Fatalf("NewSqliteDB: %v", err)
+			}
+			t.Cleanup(cleanup)
+			return newDb(&Data{db: db}, logger)
+		})
+	})
+}
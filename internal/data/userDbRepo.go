@@ -0,0 +1,79 @@
+package data
+
+import (
+	"context"
+	"errors"
+
+	"accounter_go/internal/biz"
+	"accounter_go/internal/data/model"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"gorm.io/gorm"
+)
+
+type userDbRepo struct {
+	data *Data
+	log  *log.Helper
+}
+
+// NewUserDbRepo creates a new database-based UserRepo backed by the users table
+func NewUserDbRepo(data *Data, logger log.Logger) biz.UserRepo {
+	return &userDbRepo{
+		data: data,
+		log:  log.NewHelper(logger),
+	}
+}
+
+func (r *userDbRepo) Save(ctx context.Context, user *biz.User) (*biz.User, error) {
+	record := model.User{
+		Username:     user.Username,
+		PasswordHash: user.PasswordHash,
+		CreateTime:   user.CreatedAt,
+		UpdateTime:   user.CreatedAt,
+	}
+	if err := r.data.db.WithContext(ctx).Create(&record).Error; err != nil {
+		// The unique index on username rejects duplicates, report them as such
+		if _, findErr := r.FindByUsername(ctx, user.Username); findErr == nil {
+			return nil, biz.ErrUsernameTaken
+		}
+		r.log.WithContext(ctx).Errorf("Failed to save user: %v", err)
+		return nil, err
+	}
+
+	return toBizUserModel(&record), nil
+}
+
+func (r *userDbRepo) FindByID(ctx context.Context, id int64) (*biz.User, error) {
+	var record model.User
+	if err := r.data.db.WithContext(ctx).First(&record, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, biz.ErrUserNotFound
+		}
+		r.log.WithContext(ctx).Errorf("Failed to find user by id %d: %v", id, err)
+		return nil, err
+	}
+
+	return toBizUserModel(&record), nil
+}
+
+func (r *userDbRepo) FindByUsername(ctx context.Context, username string) (*biz.User, error) {
+	var record model.User
+	if err := r.data.db.WithContext(ctx).Where("username = ?", username).First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, biz.ErrUserNotFound
+		}
+		r.log.WithContext(ctx).Errorf("Failed to find user %s: %v", username, err)
+		return nil, err
+	}
+
+	return toBizUserModel(&record), nil
+}
+
+func toBizUserModel(u *model.User) *biz.User {
+	return &biz.User{
+		UserID:       int64(u.UserID),
+		Username:     u.Username,
+		PasswordHash: u.PasswordHash,
+		CreatedAt:    u.CreateTime,
+	}
+}
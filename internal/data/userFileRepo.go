@@ -0,0 +1,95 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"accounter_go/internal/biz"
+	"accounter_go/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// FileUserData represents the structure stored in the users JSON file
+type FileUserData struct {
+	UserID       int64     `json:"user_id"`
+	Username     string    `json:"username"`
+	PasswordHash string    `json:"password_hash"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (u FileUserData) recordID() int64 { return u.UserID }
+
+// userFileRepo keeps the users in users.json next to the accounter data
+type userFileRepo struct {
+	*jsonCollection[FileUserData]
+}
+
+// NewUserFileRepo creates a new file-based UserRepo
+func NewUserFileRepo(c *conf.Data, logger log.Logger) (biz.UserRepo, error) {
+	users, err := openJSONCollection[FileUserData](c, "users.json", "users", logger)
+	if err != nil {
+		return nil, err
+	}
+	return &userFileRepo{users}, nil
+}
+
+// NewUserMemoryRepo creates a UserRepo that keeps everything in memory
+func NewUserMemoryRepo(logger log.Logger) biz.UserRepo {
+	return &userFileRepo{newJSONCollection[FileUserData]("", "users", logger)}
+}
+
+func (r *userFileRepo) Save(ctx context.Context, user *biz.User) (*biz.User, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, u := range r.records {
+		if u.Username == user.Username {
+			return nil, biz.ErrUsernameTaken
+		}
+	}
+
+	record := FileUserData{
+		UserID:       r.nextID,
+		Username:     user.Username,
+		PasswordHash: user.PasswordHash,
+		CreatedAt:    user.CreatedAt,
+	}
+	if err := r.insertLocked(ctx, record); err != nil {
+		return nil, err
+	}
+
+	r.log.WithContext(ctx).Infof("Saved user with ID: %d", record.UserID)
+	return toBizUser(&record), nil
+}
+
+func (r *userFileRepo) FindByID(ctx context.Context, id int64) (*biz.User, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if i := r.indexOf(id); i >= 0 {
+		return toBizUser(&r.records[i]), nil
+	}
+	return nil, biz.ErrUserNotFound
+}
+
+func (r *userFileRepo) FindByUsername(ctx context.Context, username string) (*biz.User, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for i := range r.records {
+		if r.records[i].Username == username {
+			return toBizUser(&r.records[i]), nil
+		}
+	}
+	return nil, biz.ErrUserNotFound
+}
+
+func toBizUser(u *FileUserData) *biz.User {
+	return &biz.User{
+		UserID:       u.UserID,
+		Username:     u.Username,
+		PasswordHash: u.PasswordHash,
+		CreatedAt:    u.CreatedAt,
+	}
+}
@@ -0,0 +1,55 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"accounter_go/internal/biz"
+)
+
+func runUserRepoContract(t *testing.T, repo biz.UserRepo) {
+	ctx := context.Background()
+
+	alice, err := repo.Save(ctx, &biz.User{Username: "alice", PasswordHash: "hash-a", CreatedAt: time.Now()})
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if alice.UserID != 1 {
+		t.Errorf("first user ID = %d, want 1", alice.UserID)
+	}
+	if _, err := repo.Save(ctx, &biz.User{Username: "alice", PasswordHash: "hash-b", CreatedAt: time.Now()}); !errors.Is(err, biz.ErrUsernameTaken) {
+		t.Errorf("duplicate Save = %v, want ErrUsernameTaken", err)
+	}
+
+	got, err := repo.FindByUsername(ctx, "alice")
+	if err != nil || got.UserID != alice.UserID || got.PasswordHash != "hash-a" {
+		t.Errorf("FindByUsername = %+v, %v", got, err)
+	}
+	got, err = repo.FindByID(ctx, alice.UserID)
+	if err != nil || got.Username != "alice" {
+		t.Errorf("FindByID = %+v, %v", got, err)
+	}
+
+	if _, err := repo.FindByUsername(ctx, "bob"); !errors.Is(err, biz.ErrUserNotFound) {
+		t.Errorf("FindByUsername of an unknown user = %v, want ErrUserNotFound", err)
+	}
+	if _, err := repo.FindByID(ctx, 99); !errors.Is(err, biz.ErrUserNotFound) {
+		t.Errorf("FindByID of an unknown user = %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestUserRepo(t *testing.T) {
+	runRepoBackends(t, NewUserFileRepo, NewUserDbRepo, NewUserMemoryRepo, runUserRepoContract,
+		func(t *testing.T, _ biz.UserRepo, reopen func() biz.UserRepo) {
+			reopened := reopen()
+			if _, err := reopened.FindByUsername(context.Background(), "alice"); err != nil {
+				t.Errorf("user not persisted: %v", err)
+			}
+			bob, err := reopened.Save(context.Background(), &biz.User{Username: "bob", PasswordHash: "hash", CreatedAt: time.Now()})
+			if err != nil || bob.UserID != 2 {
+				t.Errorf("Save after reopen = %+v, %v", bob, err)
+			}
+		})
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"context"
+	"strings"
+
+	accounterv1 "accounter_go/api/accounter/v1"
+	v1 "accounter_go/api/helloworld/v1"
+	"accounter_go/internal/biz"
+	"accounter_go/internal/service"
+
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/middleware/selector"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+// publicOperations can be called without a token
+var publicOperations = map[string]bool{
+	accounterv1.OperationAuthRegister: true,
+	accounterv1.OperationAuthLogin:    true,
+	accounterv1.OperationAuthRefresh:  true,
+	v1.OperationGreeterSayHello:       true,
+}
+
+// operationScopes is the scope a caller needs for an operation, operations
+// that are not listed need the admin scope
+var operationScopes = map[string]string{
+	accounterv1.OperationAccounterList:        biz.ScopeRead,
+	accounterv1.OperationAccounterGet:         biz.ScopeRead,
+	accounterv1.OperationAccounterStats:       biz.ScopeRead,
+	accounterv1.OperationAccounterPeriodStats: biz.ScopeRead,
+	accounterv1.OperationAccounterAdd:         biz.ScopeWrite,
+	accounterv1.OperationAccounterUpdate:      biz.ScopeWrite,
+	accounterv1.OperationAccounterDelete:      biz.ScopeWrite,
+	accounterv1.OperationAccounterBatchAdd:    biz.ScopeWrite,
+	accounterv1.OperationAccounterBatchDelete: biz.ScopeWrite,
+	accounterv1.OperationCategoriesList:       biz.ScopeRead,
+	accounterv1.OperationCategoriesCreate:     biz.ScopeWrite,
+	accounterv1.OperationCategoriesUpdate:     biz.ScopeWrite,
+	accounterv1.OperationCategoriesDelete:     biz.ScopeWrite,
+	accounterv1.OperationExchangeRatesList:    biz.ScopeRead,
+	accounterv1.OperationExchangeRatesSet:     biz.ScopeWrite,
+	accounterv1.OperationExchangeRatesDelete:  biz.ScopeWrite,
+	accounterv1.OperationAccountsList:         biz.ScopeRead,
+	accounterv1.OperationAccountsBalances:     biz.ScopeRead,
+	accounterv1.OperationAccountsCreate:       biz.ScopeWrite,
+	accounterv1.OperationAccountsUpdate:       biz.ScopeWrite,
+	accounterv1.OperationAccountsDelete:       biz.ScopeWrite,
+	accounterv1.OperationBudgetsList:          biz.ScopeRead,
+	accounterv1.OperationBudgetsStatus:        biz.ScopeRead,
+	accounterv1.OperationBudgetsCreate:        biz.ScopeWrite,
+	accounterv1.OperationBudgetsUpdate:        biz.ScopeWrite,
+	accounterv1.OperationBudgetsDelete:        biz.ScopeWrite,
+	accounterv1.OperationRecurringList:        biz.ScopeRead,
+	accounterv1.OperationRecurringCreate:      biz.ScopeWrite,
+	accounterv1.OperationRecurringUpdate:      biz.ScopeWrite,
+	accounterv1.OperationRecurringDelete:      biz.ScopeWrite,
+	accounterv1.OperationImportCSV:            biz.ScopeWrite,
+	accounterv1.OperationImportAlipay:         biz.ScopeWrite,
+	accounterv1.OperationImportWeChat:         biz.ScopeWrite,
+	accounterv1.OperationImportJournal:        biz.ScopeWrite,
+	accounterv1.OperationImportOFX:            biz.ScopeWrite,
+	accounterv1.OperationImportQIF:            biz.ScopeWrite,
+	accounterv1.OperationTagsList:             biz.ScopeRead,
+	accounterv1.OperationTagsSuggest:          biz.ScopeRead,
+	service.OperationExport:                   biz.ScopeRead,
+}
+
+// authMiddleware checks the bearer credential of every non-public operation and puts
+// the authenticated caller into the context, see biz.UserIDFromContext.
+// The credential is either a JWT access token or a personal API token.
+func authMiddleware(auth *biz.AuthUseCase) middleware.Middleware {
+	return selector.Server(
+		func(handler middleware.Handler) middleware.Handler {
+			return func(ctx context.Context, req interface{}) (interface{}, error) {
+				tr, ok := transport.FromServerContext(ctx)
+				if !ok {
+					return nil, biz.ErrUnauthorized
+				}
+				token, ok := strings.CutPrefix(tr.RequestHeader().Get("Authorization"), "Bearer ")
+				if !ok {
+					return nil, biz.ErrUnauthorized
+				}
+				principal, err := auth.Authenticate(ctx, token)
+				if err != nil {
+					return nil, err
+				}
+
+				scope, ok := operationScopes[tr.Operation()]
+				if !ok {
+					scope = biz.ScopeAdmin
+				}
+				if !principal.HasScope(scope) {
+					return nil, biz.ErrInsufficientScope
+				}
+				return handler(biz.NewContextWithPrincipal(ctx, principal), req)
+			}
+		},
+	).Match(func(ctx context.Context, operation string) bool {
+		return !publicOperations[operation]
+	}).Build()
+}
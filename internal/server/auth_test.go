@@ -0,0 +1,96 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	accounterv1 "accounter_go/api/accounter/v1"
+	"accounter_go/internal/biz"
+	"accounter_go/internal/conf"
+	"accounter_go/internal/data"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+type headerCarrier http.Header
+
+func (hc headerCarrier) Get(key string) string      { return http.Header(hc).Get(key) }
+func (hc headerCarrier) Set(key, value string)      { http.Header(hc).Set(key, value) }
+func (hc headerCarrier) Add(key, value string)      { http.Header(hc).Add(key, value) }
+func (hc headerCarrier) Keys() []string             { return nil }
+func (hc headerCarrier) Values(key string) []string { return http.Header(hc).Values(key) }
+
+type testTransport struct {
+	operation string
+	header    headerCarrier
+}
+
+func (tr *testTransport) Kind() transport.Kind            { return transport.KindHTTP }
+func (tr *testTransport) Endpoint() string                { return "" }
+func (tr *testTransport) Operation() string               { return tr.operation }
+func (tr *testTransport) RequestHeader() transport.Header { return tr.header }
+func (tr *testTransport) ReplyHeader() transport.Header   { return headerCarrier{} }
+
+func TestAuthMiddleware(t *testing.T) {
+	logger := log.NewStdLogger(io.Discard)
+	auth := biz.NewAuthUseCase(data.NewUserMemoryRepo(logger), data.NewAPITokenMemoryRepo(logger), &conf.Auth{JwtSecret: "test-secret"}, logger)
+	ctx := context.Background()
+
+	session, err := auth.Register(ctx, "alice", "correct horse")
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	_, readOnly, err := auth.CreateAPIToken(ctx, session.User.UserID, "dashboard", []string{biz.ScopeRead})
+	if err != nil {
+		t.Fatalf("CreateAPIToken: %v", err)
+	}
+
+	handler := authMiddleware(auth)(func(ctx context.Context, req interface{}) (interface{}, error) {
+		userID, _ := biz.UserIDFromContext(ctx)
+		return userID, nil
+	})
+	call := func(operation, credential string) (interface{}, error) {
+		header := headerCarrier{}
+		if credential != "" {
+			header.Set("Authorization", "Bearer "+credential)
+		}
+		return handler(transport.NewServerContext(ctx, &testTransport{operation: operation, header: header}), nil)
+	}
+
+	tests := []struct {
+		name       string
+		operation  string
+		credential string
+		wantErr    error
+	}{
+		{"public operation", accounterv1.OperationAuthLogin, "", nil},
+		{"missing token", accounterv1.OperationAccounterList, "", biz.ErrUnauthorized},
+		{"invalid token", accounterv1.OperationAccounterList, "not-a-token", biz.ErrUnauthorized},
+		{"session can write", accounterv1.OperationAccounterAdd, session.AccessToken, nil},
+		{"session can manage tokens", accounterv1.OperationAuthCreateToken, session.AccessToken, nil},
+		{"read token can list", accounterv1.OperationAccounterList, readOnly, nil},
+		{"read token can't write", accounterv1.OperationAccounterAdd, readOnly, biz.ErrInsufficientScope},
+		{"read token can't manage tokens", accounterv1.OperationAuthCreateToken, readOnly, biz.ErrInsufficientScope},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID, err := call(tt.operation, tt.credential)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.credential != "" && userID != session.User.UserID {
+				t.Errorf("user ID in context = %v, want %d", userID, session.User.UserID)
+			}
+		})
+	}
+}
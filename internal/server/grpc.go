@@ -1,8 +1,9 @@
 package server
 
 import (
-	v1 "accounter_go/api/helloworld/v1"
 	accounterv1 "accounter_go/api/accounter/v1"
+	v1 "accounter_go/api/helloworld/v1"
+	"accounter_go/internal/biz"
 	"accounter_go/internal/conf"
 	"accounter_go/internal/service"
 
@@ -12,10 +13,11 @@ import (
 )
 
 // NewGRPCServer new a gRPC server.
-func NewGRPCServer(c *conf.Server, greeter *service.GreeterService, accounter *service.AccounterService, logger log.Logger) *grpc.Server {
+func NewGRPCServer(c *conf.Server, greeter *service.GreeterService, accounter *service.AccounterService, auth *service.AuthService, category *service.CategoryService, rates *service.ExchangeRateService, accounts *service.AccountService, budgets *service.BudgetService, recurring *service.RecurringService, imports *service.ImportService, tags *service.TagService, authUC *biz.AuthUseCase, logger log.Logger) *grpc.Server {
 	var opts = []grpc.ServerOption{
 		grpc.Middleware(
 			recovery.Recovery(),
+			authMiddleware(authUC),
 		),
 	}
 	if c.Grpc.Network != "" {
@@ -30,5 +32,13 @@ func NewGRPCServer(c *conf.Server, greeter *service.GreeterService, accounter *s
 	srv := grpc.NewServer(opts...)
 	v1.RegisterGreeterServer(srv, greeter)
 	accounterv1.RegisterAccounterServer(srv, accounter)
+	accounterv1.RegisterAuthServer(srv, auth)
+	accounterv1.RegisterCategoriesServer(srv, category)
+	accounterv1.RegisterExchangeRatesServer(srv, rates)
+	accounterv1.RegisterAccountsServer(srv, accounts)
+	accounterv1.RegisterBudgetsServer(srv, budgets)
+	accounterv1.RegisterRecurringServer(srv, recurring)
+	accounterv1.RegisterImportServer(srv, imports)
+	accounterv1.RegisterTagsServer(srv, tags)
 	return srv
 }
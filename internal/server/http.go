@@ -3,6 +3,7 @@ package server
 import (
 	accounterv1 "accounter_go/api/accounter/v1"
 	v1 "accounter_go/api/helloworld/v1"
+	"accounter_go/internal/biz"
 	"accounter_go/internal/conf"
 	"accounter_go/internal/service"
 
@@ -19,7 +20,7 @@ func corsMiddleware() khttp.FilterFunc {
 			// 允许的源
 			w.Header().Set("Access-Control-Allow-Origin", "*")
 			// 允许的HTTP方法
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 			// 允许的请求头
 			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With")
 			// 允许携带认证信息
@@ -39,10 +40,11 @@ func corsMiddleware() khttp.FilterFunc {
 }
 
 // NewHTTPServer new an HTTP server.
-func NewHTTPServer(c *conf.Server, greeter *service.GreeterService, accounter *service.AccounterService, logger log.Logger) *khttp.Server {
+func NewHTTPServer(c *conf.Server, greeter *service.GreeterService, accounter *service.AccounterService, auth *service.AuthService, category *service.CategoryService, rates *service.ExchangeRateService, accounts *service.AccountService, budgets *service.BudgetService, recurring *service.RecurringService, imports *service.ImportService, exports *service.ExportService, tags *service.TagService, authUC *biz.AuthUseCase, logger log.Logger) *khttp.Server {
 	var opts = []khttp.ServerOption{
 		khttp.Middleware(
 			recovery.Recovery(),
+			authMiddleware(authUC),
 		),
 		khttp.Filter(corsMiddleware()), // 添加CORS中间件
 	}
@@ -58,6 +60,15 @@ func NewHTTPServer(c *conf.Server, greeter *service.GreeterService, accounter *s
 	srv := khttp.NewServer(opts...)
 	v1.RegisterGreeterHTTPServer(srv, greeter)
 	accounterv1.RegisterAccounterHTTPServer(srv, accounter)
-	
+	accounterv1.RegisterAuthHTTPServer(srv, auth)
+	accounterv1.RegisterCategoriesHTTPServer(srv, category)
+	accounterv1.RegisterExchangeRatesHTTPServer(srv, rates)
+	accounterv1.RegisterAccountsHTTPServer(srv, accounts)
+	accounterv1.RegisterBudgetsHTTPServer(srv, budgets)
+	accounterv1.RegisterRecurringHTTPServer(srv, recurring)
+	accounterv1.RegisterImportHTTPServer(srv, imports)
+	accounterv1.RegisterTagsHTTPServer(srv, tags)
+	srv.Route("/").GET("/api/export", exports.Export)
+
 	return srv
 }
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"accounter_go/internal/biz"
+	"accounter_go/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+var _ transport.Server = (*Scheduler)(nil)
+
+// defaultSchedulerInterval is how often the scheduler looks for due recurring transactions
+const defaultSchedulerInterval = time.Hour
+
+// Scheduler is a kratos server that records recurring transactions when they are due.
+// It runs once on start to catch up on occurrences missed while the service was down.
+type Scheduler struct {
+	recurring *biz.RecurringUseCase
+	interval  time.Duration
+	disabled  bool
+	now       func() time.Time
+	log       *log.Helper
+
+	cancel context.CancelFunc
+	done   sync.WaitGroup
+}
+
+// NewScheduler new a recurring transactions scheduler.
+func NewScheduler(c *conf.Server, recurring *biz.RecurringUseCase, logger log.Logger) *Scheduler {
+	s := &Scheduler{
+		recurring: recurring,
+		interval:  defaultSchedulerInterval,
+		disabled:  c.GetScheduler().GetDisabled(),
+		now:       time.Now,
+		log:       log.NewHelper(logger),
+	}
+	if interval := c.GetScheduler().GetInterval(); interval != nil && interval.AsDuration() > 0 {
+		s.interval = interval.AsDuration()
+	}
+	return s
+}
+
+// Start runs the scheduler until Stop is called
+func (s *Scheduler) Start(ctx context.Context) error {
+	if s.disabled {
+		s.log.Info("[Scheduler] recurring transactions are disabled")
+		return nil
+	}
+	ctx, s.cancel = context.WithCancel(ctx)
+	s.done.Add(1)
+	go func() {
+		defer s.done.Done()
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			s.run(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	s.log.Infof("[Scheduler] recording recurring transactions every %s", s.interval)
+	return nil
+}
+
+// Stop waits for a running catch-up to finish
+func (s *Scheduler) Stop(ctx context.Context) error {
+	if s.cancel == nil {
+		return nil
+	}
+	s.cancel()
+	done := make(chan struct{})
+	go func() {
+		s.done.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run records the due recurring transactions, errors are logged and retried on the next run
+func (s *Scheduler) run(ctx context.Context) {
+	if _, err := s.recurring.RunDue(ctx, s.now()); err != nil {
+		s.log.Errorf("[Scheduler] recording recurring transactions: %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	accounterv1 "accounter_go/api/accounter/v1"
+	"accounter_go/internal/biz"
+	"accounter_go/internal/conf"
+	"accounter_go/internal/data"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestSchedulerCatchesUpOnStart(t *testing.T) {
+	logger := log.NewStdLogger(io.Discard)
+	accounters := biz.NewAccounterUsecase(data.NewAccounterMemoryRepo(logger), data.NewCategoryMemoryRepo(logger), data.NewExchangeRateMemoryRepo(logger), data.NewAccountMemoryRepo(logger), logger)
+	recurring := biz.NewRecurringUseCase(data.NewRecurringMemoryRepo(logger), accounters, logger)
+	ctx := context.Background()
+
+	start := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+	amount, _ := biz.ParseMoney("3000")
+	if _, err := recurring.CreateRecurring(ctx, 1, &biz.Recurring{RRule: "FREQ=MONTHLY", StartDate: start, Type: accounterv1.Type_Expense, Amount: amount}); err != nil {
+		t.Fatalf("CreateRecurring: %v", err)
+	}
+	s := NewScheduler(&conf.Server{Scheduler: &conf.Server_Scheduler{Interval: durationpb.New(time.Hour)}}, recurring, logger)
+	s.now = func() time.Time { return time.Date(2025, 7, 15, 9, 0, 0, 0, time.UTC) }
+
+	if err := s.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	var total int32
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if _, total, _ = accounters.ListAccounters(ctx, &biz.ListFilter{UserID: 1, Page: 1, PageSize: 10}); total == 3 {
+			break
+		}
+	}
+	if err := s.Stop(ctx); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if total != 3 {
+		t.Errorf("recorded %d transactions on start, want 3", total)
+	}
+}
+
+func TestSchedulerDisabled(t *testing.T) {
+	s := NewScheduler(&conf.Server{Scheduler: &conf.Server_Scheduler{Disabled: true}}, nil, log.NewStdLogger(io.Discard))
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	v1 "accounter_go/api/accounter/v1"
+	"accounter_go/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+// AccountService is an account service.
+type AccountService struct {
+	v1.UnimplementedAccountsServer
+
+	uc *biz.AccountUseCase
+}
+
+// NewAccountService new an account service.
+func NewAccountService(uc *biz.AccountUseCase) *AccountService {
+	return &AccountService{uc: uc}
+}
+
+// List implements accounter.AccountsServer.
+func (s *AccountService) List(ctx context.Context, in *v1.ListAccountsRequest) (*v1.ListAccountsReply, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts, err := s.uc.ListAccounts(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	reply := &v1.ListAccountsReply{Accounts: make([]*v1.AccountInfo, len(accounts))}
+	for i, a := range accounts {
+		reply.Accounts[i] = toAccountInfo(a)
+	}
+	return reply, nil
+}
+
+// Create implements accounter.AccountsServer.
+func (s *AccountService) Create(ctx context.Context, in *v1.CreateAccountRequest) (*v1.AccountReply, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	openingBalance, err := requestAmount(in.OpeningBalanceDecimal, in.OpeningBalance)
+	if err != nil {
+		return nil, err
+	}
+	account, err := s.uc.CreateAccount(ctx, userID, &biz.Account{
+		Name:           in.Name,
+		Type:           in.Type,
+		Currency:       in.Currency,
+		OpeningBalance: openingBalance,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &v1.AccountReply{
+		Account: toAccountInfo(account),
+		Message: "Account created successfully",
+	}, nil
+}
+
+// Update implements accounter.AccountsServer.
+// Only the fields set in the request are changed, the currency of an account is fixed.
+func (s *AccountService) Update(ctx context.Context, in *v1.UpdateAccountRequest) (*v1.AccountReply, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	patch := &biz.AccountPatch{
+		Name: in.Name,
+		Type: in.Type,
+	}
+	if in.OpeningBalanceDecimal != nil || in.OpeningBalance != nil {
+		var amount float64
+		if in.OpeningBalance != nil {
+			amount = *in.OpeningBalance
+		}
+		openingBalance, err := requestAmount(in.GetOpeningBalanceDecimal(), amount)
+		if err != nil {
+			return nil, err
+		}
+		patch.OpeningBalance = &openingBalance
+	}
+
+	account, err := s.uc.UpdateAccount(ctx, userID, in.Id, patch)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.AccountReply{
+		Account: toAccountInfo(account),
+		Message: "Account updated successfully",
+	}, nil
+}
+
+// Delete implements accounter.AccountsServer.
+// Accounts that still have transactions can't be deleted.
+func (s *AccountService) Delete(ctx context.Context, in *v1.DeleteAccountRequest) (*v1.DeleteAccountReply, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.uc.DeleteAccount(ctx, userID, in.Id); err != nil {
+		return nil, err
+	}
+	return &v1.DeleteAccountReply{
+		Message: "Account deleted successfully",
+	}, nil
+}
+
+// Balances implements accounter.AccountsServer.
+// With as_of only the transactions up to that day are counted.
+func (s *AccountService) Balances(ctx context.Context, in *v1.AccountBalancesRequest) (*v1.AccountBalancesReply, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var asOf *time.Time
+	if in.AsOf != "" {
+		date, err := time.Parse("2006-01-02", in.AsOf)
+		if err != nil {
+			return nil, errors.BadRequest("INVALID_DATE", "as_of must be in YYYY-MM-DD format")
+		}
+		asOf = &date
+	}
+
+	balances, err := s.uc.ListBalances(ctx, userID, asOf)
+	if err != nil {
+		return nil, err
+	}
+	reply := &v1.AccountBalancesReply{Balances: make([]*v1.AccountBalance, len(balances))}
+	for i, b := range balances {
+		currency := b.Account.Currency
+		reply.Balances[i] = &v1.AccountBalance{
+			Account:            toAccountInfo(b.Account),
+			Income:             b.Income.Float64(),
+			Expense:            b.Expense.Float64(),
+			TransferIn:         b.TransferIn.Float64(),
+			TransferOut:        b.TransferOut.Float64(),
+			Balance:            b.Balance.Float64(),
+			IncomeDecimal:      b.Income.Format(currency),
+			ExpenseDecimal:     b.Expense.Format(currency),
+			TransferInDecimal:  b.TransferIn.Format(currency),
+			TransferOutDecimal: b.TransferOut.Format(currency),
+			BalanceDecimal:     b.Balance.Format(currency),
+			TransactionCount:   b.Count,
+		}
+	}
+	return reply, nil
+}
+
+func toAccountInfo(a *biz.Account) *v1.AccountInfo {
+	return &v1.AccountInfo{
+		Id:                    a.ID,
+		Name:                  a.Name,
+		Type:                  a.Type,
+		Currency:              a.Currency,
+		OpeningBalance:        a.OpeningBalance.Float64(),
+		OpeningBalanceDecimal: a.OpeningBalance.Format(a.Currency),
+	}
+}
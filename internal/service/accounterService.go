@@ -2,10 +2,14 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"time"
 
 	v1 "accounter_go/api/accounter/v1"
 	"accounter_go/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/errors"
 )
 
 // AccounterService is a accounter service.
@@ -22,21 +26,14 @@ func NewAccounterService(uc *biz.AccounterUseCase) *AccounterService {
 
 // Add implements accounter.AccounterServer.
 func (s *AccounterService) Add(ctx context.Context, in *v1.AddRequest) (*v1.AddReply, error) {
-	// Parse date string to time.Time
-	transactionDate, err := time.Parse("2006-01-02", in.Date)
+	userID, err := currentUserID(ctx)
 	if err != nil {
-		// If date parsing fails, use current time
-		transactionDate = time.Now()
+		return nil, err
 	}
 
-	// Create biz.Accounter from request
-	accounter := &biz.Accounter{
-		UserID:   1, // TODO: Get from context/auth
-		Type:     in.Type,
-		Category: in.Category,
-		Desc:     in.Desc,
-		Amount:   in.Amount,
-		Date:     transactionDate,
+	accounter, err := newAccounter(userID, in)
+	if err != nil {
+		return nil, err
 	}
 
 	result, err := s.uc.CreateAccounter(ctx, accounter)
@@ -50,24 +47,180 @@ func (s *AccounterService) Add(ctx context.Context, in *v1.AddRequest) (*v1.AddR
 	}, nil
 }
 
+// newAccounter creates a biz.Accounter from an Add request
+func newAccounter(userID int64, in *v1.AddRequest) (*biz.Accounter, error) {
+	// Parse date string to time.Time
+	transactionDate, err := time.Parse("2006-01-02", in.Date)
+	if err != nil {
+		// If date parsing fails, use current time
+		transactionDate = time.Now()
+	}
+
+	amount, err := requestAmount(in.AmountDecimal, in.Amount)
+	if err != nil {
+		return nil, err
+	}
+	toAmount, err := requestAmount(in.ToAmountDecimal, in.ToAmount)
+	if err != nil {
+		return nil, err
+	}
+	fee, err := requestAmount(in.FeeDecimal, in.Fee)
+	if err != nil {
+		return nil, err
+	}
+
+	return &biz.Accounter{
+		UserID:      userID,
+		Type:        in.Type,
+		CategoryID:  categoryID(in.CategoryId, in.Category),
+		Desc:        in.Desc,
+		Amount:      amount,
+		Currency:    in.Currency,
+		AccountID:   in.AccountId,
+		ToAccountID: in.ToAccountId,
+		ToAmount:    toAmount,
+		Fee:         fee,
+		Tags:        in.Tags,
+		Date:        transactionDate,
+	}, nil
+}
+
+// BatchAdd implements accounter.AccounterServer.
+func (s *AccounterService) BatchAdd(ctx context.Context, in *v1.BatchAddRequest) (*v1.BatchReply, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	accounters := make([]*biz.Accounter, len(in.Transactions))
+	for i, t := range in.Transactions {
+		if accounters[i], err = newAccounter(userID, t); err != nil {
+			// An amount that can't be parsed is a malformed request rather than an invalid item
+			e := errors.FromError(err)
+			return nil, errors.BadRequest(e.Reason, fmt.Sprintf("transaction %d: %s", i+1, e.Message))
+		}
+	}
+
+	result, err := s.uc.BatchCreateAccounters(ctx, userID, accounters, biz.BatchOptions{Partial: in.Partial})
+	if err != nil {
+		return nil, err
+	}
+	return toBatchReply(result, "created"), nil
+}
+
+// BatchDelete implements accounter.AccounterServer.
+func (s *AccounterService) BatchDelete(ctx context.Context, in *v1.BatchDeleteRequest) (*v1.BatchReply, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.uc.BatchDeleteAccounters(ctx, userID, in.Ids, biz.BatchOptions{Partial: in.Partial})
+	if err != nil {
+		return nil, err
+	}
+	return toBatchReply(result, "deleted"), nil
+}
+
+func toBatchReply(result *biz.BatchResult, done string) *v1.BatchReply {
+	reply := &v1.BatchReply{
+		Results: make([]*v1.BatchItemResult, len(result.Items)),
+		Applied: int32(result.Applied),
+		Invalid: int32(result.Invalid),
+	}
+	for i, item := range result.Items {
+		reply.Results[i] = &v1.BatchItemResult{Id: item.ID, Error: item.Error}
+		if item.Accounter != nil {
+			reply.Results[i].Transaction = toTransaction(item.Accounter)
+		}
+	}
+	if result.Applied == 0 && result.Invalid > 0 {
+		reply.Message = fmt.Sprintf("%d invalid transactions, nothing %s", result.Invalid, done)
+	} else {
+		reply.Message = fmt.Sprintf("%d transactions %s", result.Applied, done)
+	}
+	return reply
+}
+
 // List implements accounter.AccounterServer.
 func (s *AccounterService) List(ctx context.Context, in *v1.ListRequest) (*v1.ListReply, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	s.uc.Log.Errorf("ListAccounters with filters, params: %+v", in)
 
 	const defaultPageSize = 20
 	const defaultPage = 1
 
+	filter, err := listFilter(userID, in)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set default pagination
+	if filter.Page <= 0 {
+		filter.Page = defaultPage
+	}
+	if filter.PageSize <= 0 {
+		filter.PageSize = defaultPageSize
+	}
+
+	page, err := s.uc.ListAccounterPage(ctx, filter, in.PageToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to response format
+	transactions := make([]*v1.Transaction, len(page.Accounters))
+	terms := strings.Fields(filter.Query)
+	for i, acc := range page.Accounters {
+		transactions[i] = toTransaction(acc)
+		if len(terms) > 0 {
+			setHighlights(transactions[i], biz.HighlightSearch(acc, terms))
+		}
+	}
+
+	return &v1.ListReply{
+		Transactions:  transactions,
+		Total:         page.Total,
+		Page:          filter.Page,
+		PageSize:      filter.PageSize,
+		NextPageToken: page.NextPageToken,
+	}, nil
+}
+
+// listFilter converts the filters of a ListRequest, ignoring dates that can't be parsed.
+// The single type and category of older clients add to the lists of types and categories.
+func listFilter(userID int64, in *v1.ListRequest) (*biz.ListFilter, error) {
 	filter := &biz.ListFilter{
-		UserID:   1, // TODO: Get from context/auth
-		Page:     in.Page,
-		PageSize: in.PageSize,
+		UserID:      userID,
+		Types:       in.Types,
+		CategoryIDs: in.CategoryIds,
+		SortBy:      in.SortBy,
+		Descending:  in.Descending,
+		Page:        in.Page,
+		PageSize:    in.PageSize,
 	}
 
 	if in.Type != v1.Type_None {
-		filter.Type = &in.Type
+		filter.Types = append(filter.Types, in.Type)
+	}
+	if id := categoryID(in.CategoryId, in.Category); id != 0 {
+		filter.CategoryIDs = append(filter.CategoryIDs, id)
 	}
-	if in.Category != v1.Category_Default {
-		filter.Category = &in.Category
+	if in.AccountId != 0 {
+		filter.AccountID = &in.AccountId
+	}
+	filter.Tags = in.Tags
+	filter.Query = in.Q
+	var err error
+	if filter.MinAmount, err = filterAmount(in.MinAmount); err != nil {
+		return nil, err
+	}
+	if filter.MaxAmount, err = filterAmount(in.MaxAmount); err != nil {
+		return nil, err
 	}
 
 	// Parse date filters
@@ -81,46 +234,95 @@ func (s *AccounterService) List(ctx context.Context, in *v1.ListRequest) (*v1.Li
 			filter.EndDate = &endDate
 		}
 	}
+	return filter, nil
+}
 
-	// Set default pagination
-	if filter.Page <= 0 {
-		filter.Page = defaultPage
+// Get implements accounter.AccounterServer.
+func (s *AccounterService) Get(ctx context.Context, in *v1.GetRequest) (*v1.GetReply, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
 	}
-	if filter.PageSize <= 0 {
-		filter.PageSize = defaultPageSize
+
+	result, err := s.uc.GetAccounter(ctx, userID, in.Id)
+	if err != nil {
+		return nil, err
 	}
 
-	accounters, total, err := s.uc.ListAccounters(ctx, filter)
+	return &v1.GetReply{
+		Transaction: toTransaction(result),
+	}, nil
+}
+
+// Update implements accounter.AccounterServer.
+// Only the fields set in the request are changed.
+func (s *AccounterService) Update(ctx context.Context, in *v1.UpdateRequest) (*v1.UpdateReply, error) {
+	userID, err := currentUserID(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	// Convert to response format
-	transactions := make([]*v1.Transaction, len(accounters))
-	for i, acc := range accounters {
-		transactions[i] = &v1.Transaction{
-			Id:        acc.TransactionID,
-			Type:      acc.Type,
-			Category:  acc.Category,
-			Desc:      acc.Desc,
-			Amount:    acc.Amount,
-			Date:      acc.Date.Format("2006-01-02"),
-			CreatedAt: acc.Date.Format("2006-01-02 15:04:05"),
+	patch := &biz.AccounterPatch{
+		Type:        in.Type,
+		CategoryID:  in.CategoryId,
+		Desc:        in.Desc,
+		Currency:    in.Currency,
+		AccountID:   in.AccountId,
+		ToAccountID: in.ToAccountId,
+	}
+	if patch.CategoryID == nil && in.Category != nil {
+		id := int64(*in.Category)
+		patch.CategoryID = &id
+	}
+	// A repeated field can't tell an empty list from a missing one, clear_tags removes the tags
+	if len(in.Tags) > 0 || in.ClearTags {
+		tags := in.Tags
+		patch.Tags = &tags
+	}
+	if patch.Amount, err = optionalAmount(in.AmountDecimal, in.Amount); err != nil {
+		return nil, err
+	}
+	if patch.ToAmount, err = optionalAmount(in.ToAmountDecimal, in.ToAmount); err != nil {
+		return nil, err
+	}
+	if patch.Fee, err = optionalAmount(in.FeeDecimal, in.Fee); err != nil {
+		return nil, err
+	}
+	if in.Date != nil {
+		// Unlike Add, don't fall back to the current time and silently move the transaction
+		transactionDate, err := time.Parse("2006-01-02", *in.Date)
+		if err != nil {
+			return nil, errors.BadRequest("INVALID_DATE", "date must be in YYYY-MM-DD format")
 		}
+		patch.Date = &transactionDate
 	}
 
-	return &v1.ListReply{
-		Transactions: transactions,
-		Total:        total,
-		Page:         filter.Page,
-		PageSize:     filter.PageSize,
+	result, err := s.uc.UpdateAccounter(ctx, userID, in.Id, patch)
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.UpdateReply{
+		Transaction: toTransaction(result),
+		Message:     "Transaction updated successfully",
 	}, nil
 }
 
 // Stats implements accounter.AccounterServer.
 func (s *AccounterService) Stats(ctx context.Context, in *v1.StatsRequest) (*v1.StatsReply, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	filter := &biz.StatsFilter{
-		UserID: 1, // TODO: Get from context/auth
+		UserID:       userID,
+		Rollup:       in.Rollup,
+		BaseCurrency: in.BaseCurrency,
+		Tags:         in.Tags,
+	}
+	if in.AccountId != 0 {
+		filter.AccountID = &in.AccountId
 	}
 
 	// Parse date filters
@@ -144,39 +346,54 @@ func (s *AccounterService) Stats(ctx context.Context, in *v1.StatsRequest) (*v1.
 	incomeByCategory := make([]*v1.CategoryStats, len(stats.IncomeByCategory))
 	for i, cat := range stats.IncomeByCategory {
 		incomeByCategory[i] = &v1.CategoryStats{
-			Category:     cat.Category,
-			CategoryName: cat.CategoryName,
-			Amount:       cat.Amount,
-			Count:        cat.Count,
+			Category:      legacyCategory(cat.CategoryID),
+			CategoryId:    cat.CategoryID,
+			CategoryName:  cat.CategoryName,
+			Amount:        cat.Amount.Float64(),
+			AmountDecimal: cat.Amount.Format(stats.BaseCurrency),
+			Count:         cat.Count,
 		}
 	}
 
 	expenseByCategory := make([]*v1.CategoryStats, len(stats.ExpenseByCategory))
 	for i, cat := range stats.ExpenseByCategory {
 		expenseByCategory[i] = &v1.CategoryStats{
-			Category:     cat.Category,
-			CategoryName: cat.CategoryName,
-			Amount:       cat.Amount,
-			Count:        cat.Count,
+			Category:      legacyCategory(cat.CategoryID),
+			CategoryId:    cat.CategoryID,
+			CategoryName:  cat.CategoryName,
+			Amount:        cat.Amount.Float64(),
+			AmountDecimal: cat.Amount.Format(stats.BaseCurrency),
+			Count:         cat.Count,
 		}
 	}
 
 	return &v1.StatsReply{
-		TotalIncome:       stats.TotalIncome,
-		TotalExpense:      stats.TotalExpense,
-		Balance:           stats.Balance,
-		IncomeByCategory:  incomeByCategory,
-		ExpenseByCategory: expenseByCategory,
+		TotalIncome:         stats.TotalIncome.Float64(),
+		TotalExpense:        stats.TotalExpense.Float64(),
+		Balance:             stats.Balance.Float64(),
+		TotalIncomeDecimal:  stats.TotalIncome.Format(stats.BaseCurrency),
+		TotalExpenseDecimal: stats.TotalExpense.Format(stats.BaseCurrency),
+		BalanceDecimal:      stats.Balance.Format(stats.BaseCurrency),
+		IncomeByCategory:    incomeByCategory,
+		ExpenseByCategory:   expenseByCategory,
+		IncomeByTag:         toTagStats(stats.IncomeByTag, stats.BaseCurrency),
+		ExpenseByTag:        toTagStats(stats.ExpenseByTag, stats.BaseCurrency),
+		BaseCurrency:        stats.BaseCurrency,
+		ByCurrency:          toCurrencyStats(stats.ByCurrency),
 	}, nil
 }
 
 // Delete implements accounter.AccounterServer.
 func (s *AccounterService) Delete(ctx context.Context, in *v1.DeleteRequest) (*v1.DeleteReply, error) {
-	err := s.uc.DeleteAccounter(ctx, in.Id)
+	userID, err := currentUserID(ctx)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := s.uc.DeleteAccounter(ctx, userID, in.Id); err != nil {
+		return nil, err
+	}
+
 	return &v1.DeleteReply{
 		Message: "Transaction deleted successfully",
 	}, nil
@@ -184,12 +401,21 @@ func (s *AccounterService) Delete(ctx context.Context, in *v1.DeleteRequest) (*v
 
 // PeriodStats implements accounter.AccounterServer.
 func (s *AccounterService) PeriodStats(ctx context.Context, in *v1.PeriodStatsRequest) (*v1.PeriodStatsReply, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	filter := &biz.PeriodStatsFilter{
-		UserID:     1, // TODO: Get from context/auth
-		PeriodType: in.PeriodType,
-		Year:       in.Year,
-		Month:      in.Month,
-		Week:       in.Week,
+		UserID:       userID,
+		PeriodType:   in.PeriodType,
+		Year:         in.Year,
+		Month:        in.Month,
+		Week:         in.Week,
+		BaseCurrency: in.BaseCurrency,
+	}
+	if in.AccountId != 0 {
+		filter.AccountID = &in.AccountId
 	}
 
 	stats, err := s.uc.GetPeriodStats(ctx, filter)
@@ -202,17 +428,151 @@ func (s *AccounterService) PeriodStats(ctx context.Context, in *v1.PeriodStatsRe
 	for i, period := range stats.Periods {
 		periods[i] = &v1.PeriodData{
 			PeriodName:       period.PeriodName,
-			Income:           period.Income,
-			Expense:          period.Expense,
-			Balance:          period.Balance,
+			Income:           period.Income.Float64(),
+			Expense:          period.Expense.Float64(),
+			Balance:          period.Balance.Float64(),
+			IncomeDecimal:    period.Income.Format(stats.BaseCurrency),
+			ExpenseDecimal:   period.Expense.Format(stats.BaseCurrency),
+			BalanceDecimal:   period.Balance.Format(stats.BaseCurrency),
 			TransactionCount: period.TransactionCount,
 		}
 	}
 
 	return &v1.PeriodStatsReply{
-		Periods:      periods,
-		TotalIncome:  stats.TotalIncome,
-		TotalExpense: stats.TotalExpense,
-		TotalBalance: stats.TotalBalance,
+		Periods:             periods,
+		TotalIncome:         stats.TotalIncome.Float64(),
+		TotalExpense:        stats.TotalExpense.Float64(),
+		TotalBalance:        stats.TotalBalance.Float64(),
+		TotalIncomeDecimal:  stats.TotalIncome.Format(stats.BaseCurrency),
+		TotalExpenseDecimal: stats.TotalExpense.Format(stats.BaseCurrency),
+		TotalBalanceDecimal: stats.TotalBalance.Format(stats.BaseCurrency),
+		BaseCurrency:        stats.BaseCurrency,
+		ByCurrency:          toCurrencyStats(stats.ByCurrency),
 	}, nil
 }
+
+// toTransaction converts a biz.Accounter to the API representation
+func toTransaction(acc *biz.Accounter) *v1.Transaction {
+	t := &v1.Transaction{
+		Id:            acc.TransactionID,
+		Type:          acc.Type,
+		Category:      legacyCategory(acc.CategoryID),
+		CategoryId:    acc.CategoryID,
+		Desc:          acc.Desc,
+		Amount:        acc.Amount.Float64(),
+		AmountDecimal: acc.Amount.Format(acc.Currency),
+		Currency:      acc.Currency,
+		AccountId:     acc.AccountID,
+		RecurringId:   acc.RecurringID,
+		ExternalId:    acc.ExternalID,
+		Tags:          acc.Tags,
+		Date:          acc.Date.Format("2006-01-02"),
+		CreatedAt:     acc.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+	if acc.Type == v1.Type_Transfer {
+		t.ToAccountId = acc.ToAccountID
+		t.ToAmount = acc.ToAmount.Float64()
+		t.ToAmountDecimal = acc.ToAmount.Format(acc.ToCurrency)
+		t.ToCurrency = acc.ToCurrency
+		t.Fee = acc.Fee.Float64()
+		t.FeeDecimal = acc.Fee.Format(acc.Currency)
+	}
+	return t
+}
+
+// setHighlights marks where a search matched a transaction, in characters of its description
+func setHighlights(t *v1.Transaction, h *biz.SearchHighlight) {
+	for _, r := range h.Desc {
+		t.DescHighlights = append(t.DescHighlights, &v1.Highlight{Start: int32(r.Start), End: int32(r.End)})
+	}
+	t.MatchedTags = h.Tags
+}
+
+// toCurrencyStats converts the per-currency totals to the API representation
+func toCurrencyStats(stats []*biz.CurrencyStat) []*v1.CurrencyStats {
+	out := make([]*v1.CurrencyStats, len(stats))
+	for i, stat := range stats {
+		out[i] = &v1.CurrencyStats{
+			Currency:       stat.Currency,
+			Income:         stat.Income.Float64(),
+			Expense:        stat.Expense.Float64(),
+			Balance:        stat.Balance.Float64(),
+			IncomeDecimal:  stat.Income.Format(stat.Currency),
+			ExpenseDecimal: stat.Expense.Format(stat.Currency),
+			BalanceDecimal: stat.Balance.Format(stat.Currency),
+			Count:          stat.Count,
+		}
+	}
+	return out
+}
+
+// toTagStats converts the per-tag statistics to the API representation, amounts are in the base currency
+func toTagStats(stats []*biz.TagStat, base string) []*v1.TagStats {
+	out := make([]*v1.TagStats, len(stats))
+	for i, stat := range stats {
+		out[i] = &v1.TagStats{
+			Tag:           stat.Tag,
+			Amount:        stat.Amount.Float64(),
+			AmountDecimal: stat.Amount.Format(base),
+			Count:         stat.Count,
+		}
+	}
+	return out
+}
+
+// requestAmount picks the amount of a request, the exact decimal string wins over the double
+func requestAmount(decimal string, amount float64) (biz.Money, error) {
+	if decimal != "" {
+		return biz.ParseMoney(decimal)
+	}
+	return biz.MoneyFromFloat(amount)
+}
+
+// optionalAmount is requestAmount for the optional fields of an update, nil when neither is set
+func optionalAmount(decimal *string, amount *float64) (*biz.Money, error) {
+	if decimal == nil && amount == nil {
+		return nil, nil
+	}
+	var value float64
+	if amount != nil {
+		value = *amount
+	}
+	var exact string
+	if decimal != nil {
+		exact = *decimal
+	}
+	m, err := requestAmount(exact, value)
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// filterAmount parses an optional decimal amount of a filter, nil when it isn't set
+func filterAmount(decimal string) (*biz.Money, error) {
+	if decimal == "" {
+		return nil, nil
+	}
+	m, err := biz.ParseMoney(decimal)
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// categoryID picks the category of a request, clients that predate user categories
+// still send the deprecated category enum whose values are the built-in category IDs
+func categoryID(id int64, legacy v1.Category) int64 {
+	if id != 0 {
+		return id
+	}
+	return int64(legacy)
+}
+
+// legacyCategory fills the deprecated category enum for built-in categories
+func legacyCategory(id int64) v1.Category {
+	if id > 0 && id <= int64(v1.Category_Snacks) {
+		return v1.Category(id)
+	}
+	return v1.Category_Default
+}
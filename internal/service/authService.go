@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+
+	v1 "accounter_go/api/accounter/v1"
+	"accounter_go/internal/biz"
+)
+
+// AuthService is a user authentication service.
+type AuthService struct {
+	v1.UnimplementedAuthServer
+
+	uc *biz.AuthUseCase
+}
+
+// NewAuthService new an authentication service.
+func NewAuthService(uc *biz.AuthUseCase) *AuthService {
+	return &AuthService{uc: uc}
+}
+
+// Register implements accounter.AuthServer.
+func (s *AuthService) Register(ctx context.Context, in *v1.RegisterRequest) (*v1.AuthReply, error) {
+	tokens, err := s.uc.Register(ctx, in.Username, in.Password)
+	if err != nil {
+		return nil, err
+	}
+	return toAuthReply(tokens), nil
+}
+
+// Login implements accounter.AuthServer.
+func (s *AuthService) Login(ctx context.Context, in *v1.LoginRequest) (*v1.AuthReply, error) {
+	tokens, err := s.uc.Login(ctx, in.Username, in.Password)
+	if err != nil {
+		return nil, err
+	}
+	return toAuthReply(tokens), nil
+}
+
+// Refresh implements accounter.AuthServer.
+func (s *AuthService) Refresh(ctx context.Context, in *v1.RefreshRequest) (*v1.AuthReply, error) {
+	tokens, err := s.uc.Refresh(ctx, in.RefreshToken)
+	if err != nil {
+		return nil, err
+	}
+	return toAuthReply(tokens), nil
+}
+
+// CreateToken implements accounter.AuthServer.
+// The secret is only returned once, it can't be read back later.
+func (s *AuthService) CreateToken(ctx context.Context, in *v1.CreateTokenRequest) (*v1.CreateTokenReply, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, secret, err := s.uc.CreateAPIToken(ctx, userID, in.Name, in.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.CreateTokenReply{
+		Token:  toAPIToken(token),
+		Secret: secret,
+	}, nil
+}
+
+// ListTokens implements accounter.AuthServer.
+func (s *AuthService) ListTokens(ctx context.Context, in *v1.ListTokensRequest) (*v1.ListTokensReply, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.uc.ListAPITokens(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	reply := &v1.ListTokensReply{Tokens: make([]*v1.ApiToken, len(tokens))}
+	for i, token := range tokens {
+		reply.Tokens[i] = toAPIToken(token)
+	}
+	return reply, nil
+}
+
+// RevokeToken implements accounter.AuthServer.
+func (s *AuthService) RevokeToken(ctx context.Context, in *v1.RevokeTokenRequest) (*v1.RevokeTokenReply, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.uc.RevokeAPIToken(ctx, userID, in.Id); err != nil {
+		return nil, err
+	}
+	return &v1.RevokeTokenReply{
+		Message: "Token revoked successfully",
+	}, nil
+}
+
+func toAPIToken(token *biz.APIToken) *v1.ApiToken {
+	reply := &v1.ApiToken{
+		Id:        token.ID,
+		Name:      token.Name,
+		Prefix:    token.Prefix,
+		Scopes:    token.Scopes,
+		CreatedAt: token.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+	if token.LastUsedAt != nil {
+		reply.LastUsedAt = token.LastUsedAt.Format("2006-01-02 15:04:05")
+	}
+	return reply
+}
+
+func toAuthReply(tokens *biz.TokenPair) *v1.AuthReply {
+	return &v1.AuthReply{
+		UserId:       tokens.User.UserID,
+		Username:     tokens.User.Username,
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(tokens.ExpiresIn.Seconds()),
+	}
+}
+
+// currentUserID returns the user authenticated by the auth middleware
+func currentUserID(ctx context.Context) (int64, error) {
+	userID, ok := biz.UserIDFromContext(ctx)
+	if !ok {
+		return 0, biz.ErrUnauthorized
+	}
+	return userID, nil
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	v1 "accounter_go/api/accounter/v1"
+	"accounter_go/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+// BudgetService is a budget service.
+type BudgetService struct {
+	v1.UnimplementedBudgetsServer
+
+	uc *biz.BudgetUseCase
+}
+
+// NewBudgetService new a budget service.
+func NewBudgetService(uc *biz.BudgetUseCase) *BudgetService {
+	return &BudgetService{uc: uc}
+}
+
+// List implements accounter.BudgetsServer.
+func (s *BudgetService) List(ctx context.Context, in *v1.ListBudgetsRequest) (*v1.ListBudgetsReply, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	budgets, err := s.uc.ListBudgets(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	reply := &v1.ListBudgetsReply{Budgets: make([]*v1.BudgetInfo, len(budgets))}
+	for i, b := range budgets {
+		reply.Budgets[i] = toBudgetInfo(b)
+	}
+	return reply, nil
+}
+
+// Create implements accounter.BudgetsServer.
+// Without start_date the budget starts in the current period.
+func (s *BudgetService) Create(ctx context.Context, in *v1.CreateBudgetRequest) (*v1.BudgetReply, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	amount, err := requestAmount(in.AmountDecimal, in.Amount)
+	if err != nil {
+		return nil, err
+	}
+	budget := &biz.Budget{
+		CategoryID: in.CategoryId,
+		Period:     in.Period,
+		Amount:     amount,
+		Currency:   in.Currency,
+		Rollover:   in.Rollover,
+	}
+	if in.StartDate != "" {
+		if budget.StartDate, err = parseStartDate(in.StartDate); err != nil {
+			return nil, err
+		}
+	}
+
+	budget, err = s.uc.CreateBudget(ctx, userID, budget)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.BudgetReply{
+		Budget:  toBudgetInfo(budget),
+		Message: "Budget created successfully",
+	}, nil
+}
+
+// Update implements accounter.BudgetsServer.
+// Only the fields set in the request are changed, the category, period and currency of a budget are fixed.
+func (s *BudgetService) Update(ctx context.Context, in *v1.UpdateBudgetRequest) (*v1.BudgetReply, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	patch := &biz.BudgetPatch{Rollover: in.Rollover}
+	if patch.Amount, err = optionalAmount(in.AmountDecimal, in.Amount); err != nil {
+		return nil, err
+	}
+	if in.StartDate != nil {
+		startDate, err := parseStartDate(*in.StartDate)
+		if err != nil {
+			return nil, err
+		}
+		patch.StartDate = &startDate
+	}
+
+	budget, err := s.uc.UpdateBudget(ctx, userID, in.Id, patch)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.BudgetReply{
+		Budget:  toBudgetInfo(budget),
+		Message: "Budget updated successfully",
+	}, nil
+}
+
+// Delete implements accounter.BudgetsServer.
+func (s *BudgetService) Delete(ctx context.Context, in *v1.DeleteBudgetRequest) (*v1.DeleteBudgetReply, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.uc.DeleteBudget(ctx, userID, in.Id); err != nil {
+		return nil, err
+	}
+	return &v1.DeleteBudgetReply{
+		Message: "Budget deleted successfully",
+	}, nil
+}
+
+// Status implements accounter.BudgetsServer.
+// Monthly budgets are reported for year and month, yearly budgets for year, both default to the current month.
+func (s *BudgetService) Status(ctx context.Context, in *v1.BudgetStatusRequest) (*v1.BudgetStatusReply, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	year, month := int(in.Year), time.Month(in.Month)
+	if year == 0 {
+		year = now.Year()
+	}
+	if month == 0 {
+		month = now.Month()
+	}
+	if month < time.January || month > time.December {
+		return nil, errors.BadRequest("INVALID_MONTH", "month must be between 1 and 12")
+	}
+
+	statuses, err := s.uc.BudgetStatus(ctx, userID, year, month)
+	if err != nil {
+		return nil, err
+	}
+	reply := &v1.BudgetStatusReply{Statuses: make([]*v1.BudgetStatus, len(statuses))}
+	for i, st := range statuses {
+		currency := st.Budget.Currency
+		reply.Statuses[i] = &v1.BudgetStatus{
+			Budget:           toBudgetInfo(st.Budget),
+			CategoryName:     st.CategoryName,
+			PeriodName:       st.PeriodName,
+			StartDate:        st.StartDate.Format("2006-01-02"),
+			EndDate:          st.EndDate.Format("2006-01-02"),
+			Carried:          st.Carried.Float64(),
+			Available:        st.Available.Float64(),
+			Spent:            st.Spent.Float64(),
+			Remaining:        st.Remaining.Float64(),
+			CarriedDecimal:   st.Carried.Format(currency),
+			AvailableDecimal: st.Available.Format(currency),
+			SpentDecimal:     st.Spent.Format(currency),
+			RemainingDecimal: st.Remaining.Format(currency),
+			PercentUsed:      st.PercentUsed,
+			OverBudget:       st.Over(),
+		}
+	}
+	return reply, nil
+}
+
+// parseStartDate parses the start_date of a budget or recurring transaction
+func parseStartDate(s string) (time.Time, error) {
+	date, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return time.Time{}, errors.BadRequest("INVALID_DATE", "start_date must be in YYYY-MM-DD format")
+	}
+	return date, nil
+}
+
+func toBudgetInfo(b *biz.Budget) *v1.BudgetInfo {
+	return &v1.BudgetInfo{
+		Id:            b.ID,
+		CategoryId:    b.CategoryID,
+		Period:        b.Period,
+		Amount:        b.Amount.Float64(),
+		AmountDecimal: b.Amount.Format(b.Currency),
+		Currency:      b.Currency,
+		Rollover:      b.Rollover,
+		StartDate:     b.StartDate.Format("2006-01-02"),
+	}
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+
+	v1 "accounter_go/api/accounter/v1"
+	"accounter_go/internal/biz"
+)
+
+// CategoryService is a transaction category service.
+type CategoryService struct {
+	v1.UnimplementedCategoriesServer
+
+	uc *biz.CategoryUseCase
+}
+
+// NewCategoryService new a category service.
+func NewCategoryService(uc *biz.CategoryUseCase) *CategoryService {
+	return &CategoryService{uc: uc}
+}
+
+// List implements accounter.CategoriesServer.
+func (s *CategoryService) List(ctx context.Context, in *v1.ListCategoriesRequest) (*v1.ListCategoriesReply, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	categories, err := s.uc.ListCategories(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	reply := &v1.ListCategoriesReply{Categories: make([]*v1.CategoryInfo, len(categories))}
+	for i, c := range categories {
+		reply.Categories[i] = toCategoryInfo(c)
+	}
+	return reply, nil
+}
+
+// Create implements accounter.CategoriesServer.
+func (s *CategoryService) Create(ctx context.Context, in *v1.CreateCategoryRequest) (*v1.CategoryReply, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	category, err := s.uc.CreateCategory(ctx, userID, &biz.Category{
+		ParentID: in.ParentId,
+		Name:     in.Name,
+		Type:     in.Type,
+		Icon:     in.Icon,
+		Color:    in.Color,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &v1.CategoryReply{
+		Category: toCategoryInfo(category),
+		Message:  "Category created successfully",
+	}, nil
+}
+
+// Update implements accounter.CategoriesServer.
+// Only the fields set in the request are changed.
+func (s *CategoryService) Update(ctx context.Context, in *v1.UpdateCategoryRequest) (*v1.CategoryReply, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	category, err := s.uc.UpdateCategory(ctx, userID, in.Id, &biz.CategoryPatch{
+		ParentID: in.ParentId,
+		Name:     in.Name,
+		Type:     in.Type,
+		Icon:     in.Icon,
+		Color:    in.Color,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &v1.CategoryReply{
+		Category: toCategoryInfo(category),
+		Message:  "Category updated successfully",
+	}, nil
+}
+
+// Delete implements accounter.CategoriesServer.
+func (s *CategoryService) Delete(ctx context.Context, in *v1.DeleteCategoryRequest) (*v1.DeleteCategoryReply, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.uc.DeleteCategory(ctx, userID, in.Id); err != nil {
+		return nil, err
+	}
+	return &v1.DeleteCategoryReply{
+		Message: "Category deleted successfully",
+	}, nil
+}
+
+func toCategoryInfo(c *biz.Category) *v1.CategoryInfo {
+	return &v1.CategoryInfo{
+		Id:       c.ID,
+		ParentId: c.ParentID,
+		Name:     c.Name,
+		Type:     c.Type,
+		Icon:     c.Icon,
+		Color:    c.Color,
+		Builtin:  c.BuiltIn(),
+	}
+}
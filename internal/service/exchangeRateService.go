@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	v1 "accounter_go/api/accounter/v1"
+	"accounter_go/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+// ExchangeRateService is an exchange rate service.
+type ExchangeRateService struct {
+	v1.UnimplementedExchangeRatesServer
+
+	uc *biz.ExchangeRateUseCase
+}
+
+// NewExchangeRateService new an exchange rate service.
+func NewExchangeRateService(uc *biz.ExchangeRateUseCase) *ExchangeRateService {
+	return &ExchangeRateService{uc: uc}
+}
+
+// List implements accounter.ExchangeRatesServer.
+func (s *ExchangeRateService) List(ctx context.Context, in *v1.ListExchangeRatesRequest) (*v1.ListExchangeRatesReply, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rates, err := s.uc.ListRates(ctx, userID, in.From, in.To)
+	if err != nil {
+		return nil, err
+	}
+	reply := &v1.ListExchangeRatesReply{Rates: make([]*v1.ExchangeRate, len(rates))}
+	for i, rate := range rates {
+		reply.Rates[i] = toExchangeRate(rate)
+	}
+	return reply, nil
+}
+
+// Set implements accounter.ExchangeRatesServer.
+// A rate of the same currency pair and date is replaced.
+func (s *ExchangeRateService) Set(ctx context.Context, in *v1.SetExchangeRateRequest) (*v1.SetExchangeRateReply, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	date, err := time.Parse("2006-01-02", in.Date)
+	if err != nil {
+		return nil, errors.BadRequest("INVALID_DATE", "date must be in YYYY-MM-DD format")
+	}
+	rate, err := s.uc.SetRate(ctx, userID, &biz.ExchangeRate{
+		From: in.From,
+		To:   in.To,
+		Rate: in.Rate,
+		Date: date,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &v1.SetExchangeRateReply{
+		Rate: toExchangeRate(rate),
+	}, nil
+}
+
+// Delete implements accounter.ExchangeRatesServer.
+func (s *ExchangeRateService) Delete(ctx context.Context, in *v1.DeleteExchangeRateRequest) (*v1.DeleteExchangeRateReply, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.uc.DeleteRate(ctx, userID, in.Id); err != nil {
+		return nil, err
+	}
+	return &v1.DeleteExchangeRateReply{
+		Message: "Exchange rate deleted successfully",
+	}, nil
+}
+
+func toExchangeRate(rate *biz.ExchangeRate) *v1.ExchangeRate {
+	return &v1.ExchangeRate{
+		Id:   rate.ID,
+		From: rate.From,
+		To:   rate.To,
+		Rate: rate.Rate,
+		Date: rate.Date.Format("2006-01-02"),
+	}
+}
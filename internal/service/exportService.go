@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	v1 "accounter_go/api/accounter/v1"
+	"accounter_go/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	khttp "github.com/go-kratos/kratos/v2/transport/http"
+)
+
+// OperationExport is the operation of GET /api/export. The export is streamed as a file,
+// so it is a plain HTTP route rather than an rpc of the api protos.
+const OperationExport = "/accounter.v1.Export/Export"
+
+// ExportService is a transaction export service.
+type ExportService struct {
+	uc *biz.AccounterUseCase
+}
+
+// NewExportService new a transaction export service.
+func NewExportService(uc *biz.AccounterUseCase) *ExportService {
+	return &ExportService{uc: uc}
+}
+
+// Export handles GET /api/export. It takes the filters of ListRequest, without pagination,
+// and format csv (the default), xlsx, json, beancount or ledger. The journal formats take
+// an optional mapping, a JSON biz.JournalMapping.
+func (s *ExportService) Export(ctx khttp.Context) error {
+	var in v1.ListRequest
+	if err := ctx.BindQuery(&in); err != nil {
+		return err
+	}
+	format := ctx.Query().Get("format")
+	if format == "" {
+		format = biz.ExportCSV
+	}
+	contentType, err := biz.ExportContentType(format)
+	if err != nil {
+		return err
+	}
+	var mapping *biz.JournalMapping
+	if m := ctx.Query().Get("mapping"); m != "" && (format == biz.ExportBeancount || format == biz.ExportLedger) {
+		mapping = new(biz.JournalMapping)
+		if err := json.Unmarshal([]byte(m), mapping); err != nil {
+			return errors.BadRequest(biz.ErrInvalidJournalMapping.Reason, `mapping must be a JSON object like {"categories": {"餐饮": "Expenses:Food"}}`)
+		}
+	}
+
+	khttp.SetOperation(ctx, OperationExport)
+	h := ctx.Middleware(func(c context.Context, req interface{}) (interface{}, error) {
+		userID, err := currentUserID(c)
+		if err != nil {
+			return nil, err
+		}
+		w := &exportResponse{
+			ResponseWriter: ctx.Response(),
+			contentType:    contentType,
+			filename:       fmt.Sprintf("accounter-%s.%s", time.Now().Format("20060102"), format),
+		}
+		filter, err := listFilter(userID, req.(*v1.ListRequest))
+		if err != nil {
+			return nil, err
+		}
+		if err := s.uc.Export(c, filter, format, mapping, w); err != nil {
+			if !w.started {
+				return nil, err
+			}
+			// the headers are sent, all that is left is to cut the download short
+			s.uc.Log.WithContext(c).Errorf("Export failed after it started: %v", err)
+		}
+		return nil, nil
+	})
+	_, err = h(ctx, &in)
+	return err
+}
+
+// exportResponse sends the headers of the download on the first write,
+// so that errors before anything is written are still replied as errors
+type exportResponse struct {
+	http.ResponseWriter
+	contentType string
+	filename    string
+	started     bool
+}
+
+func (w *exportResponse) Write(p []byte) (int, error) {
+	if !w.started {
+		w.started = true
+		w.Header().Set("Content-Type", w.contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", w.filename))
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(p)
+}
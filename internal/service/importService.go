@@ -0,0 +1,183 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	v1 "accounter_go/api/accounter/v1"
+	"accounter_go/internal/biz"
+)
+
+// ImportService is a transaction import service.
+type ImportService struct {
+	v1.UnimplementedImportServer
+
+	uc *biz.AccounterUseCase
+}
+
+// NewImportService new a transaction import service.
+func NewImportService(uc *biz.AccounterUseCase) *ImportService {
+	return &ImportService{uc: uc}
+}
+
+// CSV implements accounter.ImportServer.
+// With dry_run nothing is stored and the reply previews every row.
+func (s *ImportService) CSV(ctx context.Context, in *v1.ImportCSVRequest) (*v1.ImportReply, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m := in.GetMapping()
+	if m == nil {
+		return nil, biz.ErrInvalidImport
+	}
+	mapping := &biz.CSVMapping{
+		Delimiter:         m.Delimiter,
+		NoHeader:          m.NoHeader,
+		SkipLines:         int(m.SkipLines),
+		DateColumn:        m.DateColumn,
+		DateFormat:        m.DateFormat,
+		AmountColumn:      m.AmountColumn,
+		AmountSign:        m.AmountSign,
+		IncomeColumn:      m.IncomeColumn,
+		ExpenseColumn:     m.ExpenseColumn,
+		TypeColumn:        m.TypeColumn,
+		IncomeValues:      m.IncomeValues,
+		ExpenseValues:     m.ExpenseValues,
+		DescColumns:       m.DescColumns,
+		CategoryColumn:    m.CategoryColumn,
+		Categories:        m.Categories,
+		DefaultCategoryID: m.DefaultCategoryId,
+		CurrencyColumn:    m.CurrencyColumn,
+		Currency:          m.Currency,
+		AccountID:         m.AccountId,
+	}
+	opts := biz.ImportOptions{DryRun: in.DryRun, SkipInvalid: in.SkipInvalid}
+
+	result, err := s.uc.ImportCSV(ctx, userID, strings.NewReader(in.Content), mapping, opts)
+	if err != nil {
+		return nil, err
+	}
+	return toImportReply(result, opts), nil
+}
+
+// Alipay implements accounter.ImportServer.
+// content is the statement as exported, Alipay exports GBK.
+func (s *ImportService) Alipay(ctx context.Context, in *v1.ImportBillRequest) (*v1.ImportReply, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := biz.ImportOptions{DryRun: in.DryRun, SkipInvalid: in.SkipInvalid}
+	result, err := s.uc.ImportAlipay(ctx, userID, bytes.NewReader(in.Content), in.AccountId, opts)
+	if err != nil {
+		return nil, err
+	}
+	return toImportReply(result, opts), nil
+}
+
+// WeChat implements accounter.ImportServer.
+func (s *ImportService) WeChat(ctx context.Context, in *v1.ImportBillRequest) (*v1.ImportReply, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := biz.ImportOptions{DryRun: in.DryRun, SkipInvalid: in.SkipInvalid}
+	result, err := s.uc.ImportWeChat(ctx, userID, bytes.NewReader(in.Content), in.AccountId, opts)
+	if err != nil {
+		return nil, err
+	}
+	return toImportReply(result, opts), nil
+}
+
+// OFX implements accounter.ImportServer.
+// content is the statement as downloaded, transactions are deduplicated by their FITID.
+func (s *ImportService) OFX(ctx context.Context, in *v1.ImportBillRequest) (*v1.ImportReply, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := biz.ImportOptions{DryRun: in.DryRun, SkipInvalid: in.SkipInvalid}
+	result, err := s.uc.ImportOFX(ctx, userID, bytes.NewReader(in.Content), in.AccountId, opts)
+	if err != nil {
+		return nil, err
+	}
+	return toImportReply(result, opts), nil
+}
+
+// QIF implements accounter.ImportServer.
+func (s *ImportService) QIF(ctx context.Context, in *v1.ImportBillRequest) (*v1.ImportReply, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := biz.ImportOptions{DryRun: in.DryRun, SkipInvalid: in.SkipInvalid}
+	result, err := s.uc.ImportQIF(ctx, userID, bytes.NewReader(in.Content), in.AccountId, opts)
+	if err != nil {
+		return nil, err
+	}
+	return toImportReply(result, opts), nil
+}
+
+// Journal implements accounter.ImportServer.
+// content is a beancount or ledger journal, the mapping is optional.
+func (s *ImportService) Journal(ctx context.Context, in *v1.ImportJournalRequest) (*v1.ImportReply, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var mapping *biz.JournalMapping
+	if m := in.GetMapping(); m != nil {
+		mapping = &biz.JournalMapping{Categories: m.Categories, Accounts: m.Accounts}
+	}
+	opts := biz.ImportOptions{DryRun: in.DryRun, SkipInvalid: in.SkipInvalid}
+	result, err := s.uc.ImportJournal(ctx, userID, strings.NewReader(in.Content), mapping, opts)
+	if err != nil {
+		return nil, err
+	}
+	return toImportReply(result, opts), nil
+}
+
+// toImportReply converts an import result to the API representation
+func toImportReply(result *biz.ImportResult, opts biz.ImportOptions) *v1.ImportReply {
+	reply := &v1.ImportReply{
+		Rows:       make([]*v1.ImportedRow, len(result.Rows)),
+		Imported:   int32(result.Imported),
+		Duplicates: int32(result.Duplicates),
+		Skipped:    int32(result.Skipped),
+		Invalid:    int32(result.Invalid),
+	}
+	for i, row := range result.Rows {
+		reply.Rows[i] = &v1.ImportedRow{
+			Line:      int32(row.Line),
+			Category:  row.Category,
+			Duplicate: row.Duplicate,
+			Skip:      row.Skip,
+			Error:     row.Error,
+		}
+		if row.Accounter != nil {
+			reply.Rows[i].Transaction = toTransaction(row.Accounter)
+		}
+	}
+
+	toImport := len(result.Rows) - result.Duplicates - result.Skipped - result.Invalid
+	switch {
+	case opts.DryRun:
+		reply.Message = fmt.Sprintf("Preview: %d transactions to import, %d duplicates, %d other rows and %d invalid rows skipped",
+			toImport, result.Duplicates, result.Skipped, result.Invalid)
+	case result.Invalid > 0 && !opts.SkipInvalid:
+		reply.Message = fmt.Sprintf("Nothing was imported, %d rows are invalid", result.Invalid)
+	default:
+		reply.Message = fmt.Sprintf("Imported %d transactions, %d duplicates, %d other rows and %d invalid rows skipped",
+			result.Imported, result.Duplicates, result.Skipped, result.Invalid)
+	}
+	return reply
+}
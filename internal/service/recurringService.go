@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	v1 "accounter_go/api/accounter/v1"
+	"accounter_go/internal/biz"
+)
+
+// RecurringService is a recurring transaction service.
+type RecurringService struct {
+	v1.UnimplementedRecurringServer
+
+	uc *biz.RecurringUseCase
+}
+
+// NewRecurringService new a recurring transaction service.
+func NewRecurringService(uc *biz.RecurringUseCase) *RecurringService {
+	return &RecurringService{uc: uc}
+}
+
+// List implements accounter.RecurringServer.
+func (s *RecurringService) List(ctx context.Context, in *v1.ListRecurringRequest) (*v1.ListRecurringReply, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := s.uc.ListRecurring(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	reply := &v1.ListRecurringReply{Rules: make([]*v1.RecurringRule, len(rules))}
+	for i, r := range rules {
+		reply.Rules[i] = toRecurringRule(r)
+	}
+	return reply, nil
+}
+
+// Create implements accounter.RecurringServer.
+// Without start_date the rule starts today, without currency it is in the currency of its account.
+func (s *RecurringService) Create(ctx context.Context, in *v1.CreateRecurringRequest) (*v1.RecurringReply, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	amount, err := requestAmount(in.AmountDecimal, in.Amount)
+	if err != nil {
+		return nil, err
+	}
+	rule := &biz.Recurring{
+		RRule:      in.Rrule,
+		Type:       in.Type,
+		CategoryID: in.CategoryId,
+		Desc:       in.Desc,
+		Amount:     amount,
+		Currency:   in.Currency,
+		AccountID:  in.AccountId,
+	}
+	if in.StartDate != "" {
+		if rule.StartDate, err = parseStartDate(in.StartDate); err != nil {
+			return nil, err
+		}
+	}
+
+	rule, err = s.uc.CreateRecurring(ctx, userID, rule)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.RecurringReply{
+		Rule:    toRecurringRule(rule),
+		Message: "Recurring transaction created successfully",
+	}, nil
+}
+
+// Update implements accounter.RecurringServer.
+// Only the fields set in the request are changed, the type of a rule is fixed.
+func (s *RecurringService) Update(ctx context.Context, in *v1.UpdateRecurringRequest) (*v1.RecurringReply, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	patch := &biz.RecurringPatch{
+		RRule:      in.Rrule,
+		CategoryID: in.CategoryId,
+		Desc:       in.Desc,
+		AccountID:  in.AccountId,
+	}
+	if patch.Amount, err = optionalAmount(in.AmountDecimal, in.Amount); err != nil {
+		return nil, err
+	}
+	if in.StartDate != nil {
+		startDate, err := parseStartDate(*in.StartDate)
+		if err != nil {
+			return nil, err
+		}
+		patch.StartDate = &startDate
+	}
+
+	rule, err := s.uc.UpdateRecurring(ctx, userID, in.Id, patch)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.RecurringReply{
+		Rule:    toRecurringRule(rule),
+		Message: "Recurring transaction updated successfully",
+	}, nil
+}
+
+// Delete implements accounter.RecurringServer.
+// The transactions the rule already recorded are kept.
+func (s *RecurringService) Delete(ctx context.Context, in *v1.DeleteRecurringRequest) (*v1.DeleteRecurringReply, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.uc.DeleteRecurring(ctx, userID, in.Id); err != nil {
+		return nil, err
+	}
+	return &v1.DeleteRecurringReply{
+		Message: "Recurring transaction deleted successfully",
+	}, nil
+}
+
+// formatOptionalDate formats a date that may be unset, as an empty string
+func formatOptionalDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
+func toRecurringRule(r *biz.Recurring) *v1.RecurringRule {
+	return &v1.RecurringRule{
+		Id:            r.ID,
+		Rrule:         r.RRule,
+		StartDate:     r.StartDate.Format("2006-01-02"),
+		Type:          r.Type,
+		CategoryId:    r.CategoryID,
+		Desc:          r.Desc,
+		Amount:        r.Amount.Float64(),
+		AmountDecimal: r.Amount.Format(r.Currency),
+		Currency:      r.Currency,
+		AccountId:     r.AccountID,
+		LastDate:      formatOptionalDate(r.LastDate),
+		NextDate:      formatOptionalDate(r.NextDate),
+	}
+}
@@ -3,4 +3,4 @@ package service
 import "github.com/google/wire"
 
 // ProviderSet is service providers.
-var ProviderSet = wire.NewSet(NewGreeterService, NewAccounterService)
+var ProviderSet = wire.NewSet(NewGreeterService, NewAccounterService, NewAuthService, NewCategoryService, NewExchangeRateService, NewAccountService, NewBudgetService, NewRecurringService, NewImportService, NewExportService, NewTagService)
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+
+	v1 "accounter_go/api/accounter/v1"
+	"accounter_go/internal/biz"
+)
+
+// TagService lists the tags of the transactions and completes them while they are typed.
+type TagService struct {
+	v1.UnimplementedTagsServer
+
+	uc *biz.AccounterUseCase
+}
+
+// NewTagService new a tag service.
+func NewTagService(uc *biz.AccounterUseCase) *TagService {
+	return &TagService{uc: uc}
+}
+
+// List implements accounter.TagsServer.
+func (s *TagService) List(ctx context.Context, in *v1.ListTagsRequest) (*v1.ListTagsReply, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := s.uc.ListTags(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return toListTagsReply(tags), nil
+}
+
+// Suggest implements accounter.TagsServer.
+func (s *TagService) Suggest(ctx context.Context, in *v1.SuggestTagsRequest) (*v1.ListTagsReply, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := s.uc.SuggestTags(ctx, userID, in.Prefix, int(in.Limit))
+	if err != nil {
+		return nil, err
+	}
+	return toListTagsReply(tags), nil
+}
+
+func toListTagsReply(tags []*biz.Tag) *v1.ListTagsReply {
+	reply := &v1.ListTagsReply{Tags: make([]*v1.TagInfo, len(tags))}
+	for i, tag := range tags {
+		reply.Tags[i] = &v1.TagInfo{Name: tag.Name, Count: tag.Count}
+	}
+	return reply
+}